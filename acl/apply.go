@@ -0,0 +1,155 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ApplyOptions controls Apply
+type ApplyOptions struct {
+	Prune  bool // Delete users that are not in the spec; default is never deleted
+	DryRun bool // Report the changes without sending them
+	Save   bool // Persist with ACL SAVE, or CONFIG REWRITE when there is no aclfile
+}
+
+// Change is one user created, updated or deleted by Apply
+type Change struct {
+	User   string
+	Action string   // create, update or delete
+	Rules  []string // Redacted ACL SETUSER rules
+}
+
+// Apply provisions every user in spec with ACL SETUSER. It stops at the
+// first rejected user and returns the changes made until then.
+func Apply(ctx context.Context, rdb redis.UniversalClient, spec *Spec, opts ApplyOptions) ([]Change, error) {
+	existing, err := Users(ctx, rdb)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		current[name] = true
+	}
+
+	var changes []Change
+	for _, u := range spec.Users {
+		rules, err := u.Rules()
+		if err != nil {
+			return changes, err
+		}
+		action := "create"
+		if current[u.Name] {
+			action = "update"
+		}
+		if !opts.DryRun {
+			if err := rdb.ACLSetUser(ctx, u.Name, rules...).Err(); err != nil {
+				return changes, fmt.Errorf("acl setuser %s: %w", u.Name, err)
+			}
+		}
+		changes = append(changes, Change{User: u.Name, Action: action, Rules: Redact(rules)})
+	}
+
+	if opts.Prune {
+		for _, name := range existing {
+			if _, ok := spec.Lookup(name); ok || name == "default" {
+				continue
+			}
+			if !opts.DryRun {
+				if err := rdb.ACLDelUser(ctx, name).Err(); err != nil {
+					return changes, fmt.Errorf("acl deluser %s: %w", name, err)
+				}
+			}
+			changes = append(changes, Change{User: name, Action: "delete"})
+		}
+	}
+
+	if opts.Save && !opts.DryRun {
+		if err := save(ctx, rdb); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// save persists users to the aclfile, or to redis.conf when the server has
+// no aclfile configured
+func save(ctx context.Context, rdb redis.UniversalClient) error {
+	err := rdb.Do(ctx, "acl", "save").Err()
+	if err != nil && strings.Contains(err.Error(), "ACL file") {
+		err = rdb.ConfigRewrite(ctx).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("saving users: %w", err)
+	}
+	return nil
+}
+
+// Users returns the names of the users on the server, sorted
+func Users(ctx context.Context, rdb redis.UniversalClient) ([]string, error) {
+	names, err := rdb.Do(ctx, "acl", "users").StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CheckResult is the outcome of one expected permission
+type CheckResult struct {
+	User    string
+	Command string
+	Want    bool   // Whether the spec expects the command to be allowed
+	Allowed bool   // What the server says
+	Reason  string // The server's explanation when denied
+}
+
+// OK reports whether the server agrees with the spec
+func (r CheckResult) OK() bool {
+	return r.Want == r.Allowed
+}
+
+// Verify runs the Can and Cannot commands of every user through ACL DRYRUN.
+// Nothing is executed, so destructive commands are safe to list.
+func Verify(ctx context.Context, rdb redis.UniversalClient, spec *Spec) ([]CheckResult, error) {
+	var results []CheckResult
+	for _, u := range spec.Users {
+		for _, c := range []struct {
+			commands []string
+			want     bool
+		}{{u.Can, true}, {u.Cannot, false}} {
+			for _, command := range c.commands {
+				allowed, reason, err := DryRun(ctx, rdb, u.Name, command)
+				if err != nil {
+					return results, fmt.Errorf("%s: %s: %w", u.Name, command, err)
+				}
+				results = append(results, CheckResult{User: u.Name, Command: command, Want: c.want, Allowed: allowed, Reason: reason})
+			}
+		}
+	}
+	return results, nil
+}
+
+// DryRun asks the server whether user may run command, given as a space
+// separated string such as "SET queue:jobs x"
+func DryRun(ctx context.Context, rdb redis.UniversalClient, user, command string) (bool, string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false, "", fmt.Errorf("empty command")
+	}
+	args := make([]interface{}, len(fields))
+	for i, f := range fields {
+		args[i] = f
+	}
+	reply, err := rdb.ACLDryRun(ctx, user, args...).Result()
+	if err != nil {
+		return false, "", err
+	}
+	if reply == "OK" {
+		return true, "", nil
+	}
+	return false, reply, nil
+}
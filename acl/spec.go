@@ -0,0 +1,149 @@
+// Package acl provisions ACL users from a declarative YAML file with ACL
+// SETUSER and verifies what each user can run with ACL DRYRUN.
+package acl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a set of users to provision
+type Spec struct {
+	Users []User `yaml:"users"`
+}
+
+// User declares one ACL user. Applying it replaces whatever rules the user
+// had before, so the file is the single source of truth.
+type User struct {
+	Name    string `yaml:"name"`
+	Enabled *bool  `yaml:"enabled"` // Default true
+
+	// Passwords are plain text and may reference environment variables as
+	// ${NAME}; NoPass allows any password instead
+	Passwords []string `yaml:"passwords"`
+	NoPass    bool     `yaml:"nopass"`
+
+	Keys      []string `yaml:"keys"`       // Key patterns with read and write access (~pattern)
+	ReadKeys  []string `yaml:"read_keys"`  // Read-only key patterns (%R~pattern)
+	WriteKeys []string `yaml:"write_keys"` // Write-only key patterns (%W~pattern)
+	Channels  []string `yaml:"channels"`   // Pub/Sub channel patterns (&pattern)
+	Commands  []string `yaml:"commands"`   // Command rules such as +@read, -@dangerous, +info
+
+	// Can and Cannot are commands, such as "GET queue:jobs", that Verify
+	// expects the user to be allowed or denied
+	Can    []string `yaml:"can"`
+	Cannot []string `yaml:"cannot"`
+}
+
+// LoadSpec reads a spec file
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ParseSpec parses and validates YAML
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, spec.Validate()
+}
+
+// Validate checks names and that every user has a way to authenticate
+func (s *Spec) Validate() error {
+	seen := make(map[string]bool)
+	for i, u := range s.Users {
+		if u.Name == "" || strings.ContainsAny(u.Name, " \t\r\n") {
+			return fmt.Errorf("user %d: invalid name %q", i, u.Name)
+		}
+		if seen[u.Name] {
+			return fmt.Errorf("user %s: declared twice", u.Name)
+		}
+		seen[u.Name] = true
+		if u.NoPass && len(u.Passwords) > 0 {
+			return fmt.Errorf("user %s: nopass and passwords are exclusive", u.Name)
+		}
+		if !u.NoPass && len(u.Passwords) == 0 && u.enabled() {
+			return fmt.Errorf("user %s: needs passwords or nopass", u.Name)
+		}
+		for _, rule := range u.Commands {
+			if !strings.HasPrefix(rule, "+") && !strings.HasPrefix(rule, "-") && rule != "allcommands" && rule != "nocommands" {
+				return fmt.Errorf("user %s: command rule %q must start with + or -", u.Name, rule)
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the user with the given name
+func (s *Spec) Lookup(name string) (User, bool) {
+	for _, u := range s.Users {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+func (u User) enabled() bool {
+	return u.Enabled == nil || *u.Enabled
+}
+
+// Rules returns the ACL SETUSER arguments after the user name. They start
+// with reset, so the user ends up with exactly these permissions.
+func (u User) Rules() ([]string, error) {
+	rules := []string{"reset"}
+	if u.enabled() {
+		rules = append(rules, "on")
+	} else {
+		rules = append(rules, "off")
+	}
+
+	if u.NoPass {
+		rules = append(rules, "nopass")
+	}
+	for _, p := range u.Passwords {
+		pass := os.ExpandEnv(p)
+		if pass == "" {
+			return nil, fmt.Errorf("user %s: password %q is empty", u.Name, p)
+		}
+		rules = append(rules, ">"+pass)
+	}
+
+	for _, k := range u.Keys {
+		rules = append(rules, "~"+k)
+	}
+	for _, k := range u.ReadKeys {
+		rules = append(rules, "%R~"+k)
+	}
+	for _, k := range u.WriteKeys {
+		rules = append(rules, "%W~"+k)
+	}
+	for _, c := range u.Channels {
+		rules = append(rules, "&"+c)
+	}
+	return append(rules, u.Commands...), nil
+}
+
+// Redact returns rules with passwords hidden, for printing
+func Redact(rules []string) []string {
+	out := make([]string, len(rules))
+	for i, r := range rules {
+		if strings.HasPrefix(r, ">") {
+			r = ">***"
+		}
+		out[i] = r
+	}
+	return out
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"Redis/batcher"
+	"Redis/namespace"
+	"Redis/pipeline"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +19,12 @@ import (
 func PipelineOperations() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -31,40 +39,36 @@ func PipelineOperations() {
 	// 1. Basic Pipeline
 	fmt.Println("\n=== Basic Pipeline ===")
 
-	// Create pipeline
-	pipe := rdb.Pipeline()
+	// Create pipeline; every queued command returns a typed handle
+	b := pipeline.New(ctx, rdb)
 
 	// Add commands to pipeline
-	pipe.Set(ctx, "key1", "value1", 0)
-	pipe.Set(ctx, "key2", "value2", 0)
-	pipe.Set(ctx, "key3", "value3", 0)
-	pipe.Get(ctx, "key1")
-	pipe.Get(ctx, "key2")
-	pipe.Get(ctx, "key3")
+	basicKeys := []string{"key1", "key2", "key3"}
+	sets := make([]*pipeline.Handle[string], len(basicKeys))
+	gets := make([]*pipeline.Handle[string], len(basicKeys))
+	for i, key := range basicKeys {
+		sets[i] = b.Set(key, fmt.Sprintf("value%d", i+1), 0)
+	}
+	for i, key := range basicKeys {
+		gets[i] = b.Get(key)
+	}
 
 	// Execute pipeline
-	cmds, err := pipe.Exec(ctx)
+	report, err := b.Exec()
 	if err != nil {
 		log.Fatalf("Error executing pipeline: %v", err)
 	}
 
-	// Process results
-	fmt.Printf("Executed %d commands\n", len(cmds))
-	for i, cmd := range cmds {
-		if i < 3 {
-			// SET commands
-			fmt.Printf("Command %d (SET): %v\n", i+1, cmd.Err())
-		} else {
-			// GET commands
-			val, _ := cmd.(*redis.StringCmd).Result()
-			fmt.Printf("Command %d (GET): %s\n", i+1, val)
-		}
+	// Process results through the handles instead of by position
+	fmt.Printf("Executed %d commands\n", report.Total)
+	for i, key := range basicKeys {
+		fmt.Printf("SET %s: %v, GET %s: %s\n", key, sets[i].Err(), key, gets[i].Val())
 	}
 
 	// 2. Pipeline with different data types
 	fmt.Println("\n=== Pipeline with Different Data Types ===")
 
-	pipe = rdb.Pipeline()
+	pipe := rdb.Pipeline()
 
 	// String operations
 	pipe.Set(ctx, "user:name", "Alice", 0)
@@ -85,7 +89,7 @@ func PipelineOperations() {
 	pipe.ZAdd(ctx, "user:scores", redis.Z{Score: 95, Member: "science"})
 
 	// Execute pipeline
-	cmds, err = pipe.Exec(ctx)
+	cmds, err := pipe.Exec(ctx)
 	if err != nil {
 		log.Fatalf("Error executing mixed pipeline: %v", err)
 	}
@@ -95,29 +99,29 @@ func PipelineOperations() {
 	// 3. Pipeline with error handling
 	fmt.Println("\n=== Pipeline with Error Handling ===")
 
-	pipe = rdb.Pipeline()
+	b = pipeline.New(ctx, rdb)
 
 	// Valid commands
-	pipe.Set(ctx, "valid_key", "valid_value", 0)
-	pipe.Get(ctx, "valid_key")
+	b.Set("valid_key", "valid_value", 0)
+	valid := b.Get("valid_key")
 
-	// Invalid command (will cause error)
-	pipe.HGet(ctx, "nonexistent_hash", "field")
+	// Missing field (redis.Nil, not a failure)
+	missing := b.HGet("nonexistent_hash", "field")
 
-	// Execute pipeline
-	cmds, err = pipe.Exec(ctx)
+	// Wrong type (will cause error)
+	wrongType := b.HGet("valid_key", "field")
+
+	// Execute pipeline; the report lists which commands failed and why
+	report, err = b.Exec()
 	if err != nil {
 		fmt.Printf("Pipeline execution error: %v\n", err)
 	}
+	fmt.Println(report)
 
 	// Check individual command results
-	for i, cmd := range cmds {
-		if cmd.Err() != nil {
-			fmt.Printf("Command %d error: %v\n", i+1, cmd.Err())
-		} else {
-			fmt.Printf("Command %d success\n", i+1)
-		}
-	}
+	fmt.Printf("GET valid_key: %s\n", valid.Val())
+	fmt.Printf("HGET nonexistent_hash is nil: %v\n", missing.IsNil())
+	fmt.Printf("HGET valid_key failed: %v (%v)\n", wrongType.Failed(), wrongType.Err())
 
 	// 4. Batch operations with Pipeline
 	fmt.Println("\n=== Batch Operations ===")
@@ -157,28 +161,24 @@ func PipelineOperations() {
 	// 5. Batch read operations
 	fmt.Println("\n=== Batch Read Operations ===")
 
-	pipe = rdb.Pipeline()
+	b = pipeline.New(ctx, rdb)
 
 	// Read all users
+	profiles := make(map[string]*pipeline.Handle[map[string]string], len(users))
 	for _, user := range users {
-		pipe.HGetAll(ctx, user.ID)
+		profiles[user.ID] = b.HGetAll(user.ID)
 	}
 
 	// Execute batch read
-	cmds, err = pipe.Exec(ctx)
+	_, err = b.Exec()
 	if err != nil {
 		log.Fatalf("Error executing batch read: %v", err)
 	}
 
 	// Process read results
 	fmt.Println("Batch read results:")
-	for i, cmd := range cmds {
-		userData, err := cmd.(*redis.MapStringStringCmd).Result()
-		if err != nil {
-			fmt.Printf("User %d read error: %v\n", i+1, err)
-		} else {
-			fmt.Printf("User %d: %v\n", i+1, userData)
-		}
+	for _, user := range users {
+		fmt.Printf("%s: %v\n", user.ID, profiles[user.ID].Val())
 	}
 
 	// 6. Performance comparison
@@ -211,6 +211,7 @@ func PipelineOperations() {
 	// Calculate speedup
 	speedup := float64(individualTime) / float64(pipelineTime)
 	fmt.Printf("Pipeline speedup: %.2fx\n", speedup)
+	fmt.Println("For repeatable numbers: go test ./tests -run '^$' -bench BenchmarkWrites -benchmem")
 
 	// 7. Pipeline with transactions
 	fmt.Println("\n=== Pipeline with Transactions ===")
@@ -235,54 +236,87 @@ func PipelineOperations() {
 	// 8. Pipeline with conditional operations
 	fmt.Println("\n=== Pipeline with Conditional Operations ===")
 
-	pipe = rdb.Pipeline()
+	b = pipeline.New(ctx, rdb)
 
 	// Conditional operations
-	pipe.SetNX(ctx, "conditional:key1", "value1", 0)
-	pipe.SetNX(ctx, "conditional:key1", "value2", 0) // Should fail
-	pipe.HSetNX(ctx, "conditional:hash", "field1", "value1")
-	pipe.HSetNX(ctx, "conditional:hash", "field1", "value2") // Should fail
+	conditionals := []*pipeline.Handle[bool]{
+		b.SetNX("conditional:key1", "value1", 0),
+		b.SetNX("conditional:key1", "value2", 0), // Should fail
+		b.HSetNX("conditional:hash", "field1", "value1"),
+		b.HSetNX("conditional:hash", "field1", "value2"), // Should fail
+	}
 
 	// Execute pipeline
-	cmds, err = pipe.Exec(ctx)
+	_, err = b.Exec()
 	if err != nil {
 		log.Fatalf("Error executing conditional pipeline: %v", err)
 	}
 
 	// Check results
-	for i, cmd := range cmds {
-		result, _ := cmd.(*redis.BoolCmd).Result()
-		fmt.Printf("Conditional command %d result: %v\n", i+1, result)
+	for i, h := range conditionals {
+		fmt.Printf("Conditional command %d result: %v\n", i+1, h.Val())
 	}
 
 	// 9. Pipeline with expiration
 	fmt.Println("\n=== Pipeline with Expiration ===")
 
-	pipe = rdb.Pipeline()
+	b = pipeline.New(ctx, rdb)
 
 	// Set keys with different expiration times
-	pipe.Set(ctx, "expire:1s", "value1", 1*time.Second)
-	pipe.Set(ctx, "expire:5s", "value2", 5*time.Second)
-	pipe.Set(ctx, "expire:10s", "value3", 10*time.Second)
-
-	// Check TTL
-	pipe.TTL(ctx, "expire:1s")
-	pipe.TTL(ctx, "expire:5s")
-	pipe.TTL(ctx, "expire:10s")
+	expiring := map[string]time.Duration{
+		"expire:1s":  1 * time.Second,
+		"expire:5s":  5 * time.Second,
+		"expire:10s": 10 * time.Second,
+	}
+	ttls := make(map[string]*pipeline.Handle[time.Duration], len(expiring))
+	for key, ttl := range expiring {
+		b.Set(key, "value", ttl)
+		ttls[key] = b.TTL(key)
+	}
 
 	// Execute pipeline
-	cmds, err = pipe.Exec(ctx)
+	_, err = b.Exec()
 	if err != nil {
 		log.Fatalf("Error executing expiration pipeline: %v", err)
 	}
 
 	// Process TTL results
-	for i := 3; i < len(cmds); i++ {
-		ttl, _ := cmds[i].(*redis.DurationCmd).Result()
-		fmt.Printf("TTL for expire key %d: %v\n", i-2, ttl)
+	for _, key := range []string{"expire:1s", "expire:5s", "expire:10s"} {
+		fmt.Printf("TTL for %s: %v\n", key, ttls[key].Val())
 	}
 
-	// 10. Cleanup
+	// 10. Auto-batching concurrent writers
+	fmt.Println("\n=== Auto-batching Concurrent Writers ===")
+
+	// Independent goroutines share pipelines without coordinating
+	auto := batcher.New(rdb, batcher.Options{MaxBatch: 50, MaxDelay: 2 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	start = time.Now()
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			futures := make([]*batcher.Future, 0, 20)
+			for i := 0; i < 20; i++ {
+				futures = append(futures, auto.Do(ctx, "SET", fmt.Sprintf("batched:%d:%d", w, i), i))
+			}
+			for _, f := range futures {
+				if err := f.Err(); err != nil {
+					log.Printf("Error in batched SET: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	auto.Close()
+
+	stats := auto.Stats()
+	fmt.Printf("200 commands from 10 goroutines took: %v\n", time.Since(start))
+	fmt.Printf("Flushes: %d (by size: %d, by time: %d), average batch: %.1f commands\n",
+		stats.Flushes, stats.FlushedBySize, stats.FlushedByTime, stats.AvgBatch())
+
+	// 11. Cleanup
 	fmt.Println("\n=== Cleanup ===")
 
 	// Clean up all test keys
@@ -300,6 +334,11 @@ func PipelineOperations() {
 		keys = append(keys, fmt.Sprintf("individual:%d", i))
 		keys = append(keys, fmt.Sprintf("pipeline:%d", i))
 	}
+	for w := 0; w < 10; w++ {
+		for i := 0; i < 20; i++ {
+			keys = append(keys, fmt.Sprintf("batched:%d:%d", w, i))
+		}
+	}
 
 	// Delete all keys
 	deleted, err := rdb.Del(ctx, keys...).Result()
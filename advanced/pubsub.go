@@ -6,6 +6,12 @@ import (
 	"log"
 	"time"
 
+	"Redis/namespace"
+	"Redis/notify"
+	"Redis/presence"
+	"Redis/pubsub"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +19,12 @@ import (
 func main() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -35,20 +43,8 @@ func main() {
 	subscriber := rdb.Subscribe(ctx, "channel1")
 	defer subscriber.Close()
 
-	// Start a goroutine to handle messages
-	go func() {
-		for {
-			msg, err := subscriber.ReceiveMessage(ctx)
-			if err != nil {
-				log.Printf("Error receiving message: %v", err)
-				return
-			}
-			fmt.Printf("Received message on %s: %s\n", msg.Channel, msg.Payload)
-		}
-	}()
-
-	// Wait a moment for subscriber to be ready
-	time.Sleep(100 * time.Millisecond)
+	// Wait for Redis to confirm the subscription instead of sleeping
+	waitSubscribed(ctx, subscriber, 1)
 
 	// Publish messages
 	for i := 1; i <= 3; i++ {
@@ -57,8 +53,8 @@ func main() {
 			log.Fatalf("Error publishing message: %v", err)
 		}
 		fmt.Printf("Published: Hello %d\n", i)
-		time.Sleep(100 * time.Millisecond)
 	}
+	receiveMessages(ctx, subscriber, 3, "Received message on %s: %s\n")
 
 	// 2. Multiple channels subscription
 	fmt.Println("\n=== Multiple Channels ===")
@@ -67,20 +63,8 @@ func main() {
 	multiSub := rdb.Subscribe(ctx, "channel2", "channel3", "channel4")
 	defer multiSub.Close()
 
-	// Handle messages from multiple channels
-	go func() {
-		for {
-			msg, err := multiSub.ReceiveMessage(ctx)
-			if err != nil {
-				log.Printf("Error receiving message: %v", err)
-				return
-			}
-			fmt.Printf("Multi-channel message on %s: %s\n", msg.Channel, msg.Payload)
-		}
-	}()
-
-	// Wait for subscriber to be ready
-	time.Sleep(100 * time.Millisecond)
+	// One confirmation per channel
+	waitSubscribed(ctx, multiSub, 3)
 
 	// Publish to different channels
 	channels := []string{"channel2", "channel3", "channel4"}
@@ -89,8 +73,8 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error publishing to %s: %v", channel, err)
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
+	receiveMessages(ctx, multiSub, 3, "Multi-channel message on %s: %s\n")
 
 	// 3. Pattern-based subscription
 	fmt.Println("\n=== Pattern Subscription ===")
@@ -99,20 +83,7 @@ func main() {
 	patternSub := rdb.PSubscribe(ctx, "news:*")
 	defer patternSub.Close()
 
-	// Handle pattern messages
-	go func() {
-		for {
-			msg, err := patternSub.ReceiveMessage(ctx)
-			if err != nil {
-				log.Printf("Error receiving pattern message: %v", err)
-				return
-			}
-			fmt.Printf("Pattern message on %s: %s\n", msg.Channel, msg.Payload)
-		}
-	}()
-
-	// Wait for subscriber to be ready
-	time.Sleep(100 * time.Millisecond)
+	waitSubscribed(ctx, patternSub, 1)
 
 	// Publish to channels matching pattern
 	newsChannels := []string{"news:sports", "news:tech", "news:politics"}
@@ -121,8 +92,8 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error publishing to %s: %v", channel, err)
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
+	receiveMessages(ctx, patternSub, 3, "Pattern message on %s: %s\n")
 
 	// 4. Unsubscribe from channels
 	fmt.Println("\n=== Unsubscribe ===")
@@ -132,6 +103,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error unsubscribing from channel2: %v", err)
 	}
+	waitSubscribed(ctx, multiSub, 1)
 	fmt.Println("Unsubscribed from channel2")
 
 	// Publish to unsubscribed channel (should not be received)
@@ -145,6 +117,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error publishing to subscribed channel: %v", err)
 	}
+	receiveMessages(ctx, multiSub, 1, "Multi-channel message on %s: %s\n")
 
 	// 5. Channel information
 	fmt.Println("\n=== Channel Information ===")
@@ -177,20 +150,34 @@ func main() {
 	chatSub := rdb.Subscribe(ctx, "chat:room1")
 	defer chatSub.Close()
 
-	// Handle chat messages
-	go func() {
-		for {
-			msg, err := chatSub.ReceiveMessage(ctx)
-			if err != nil {
-				log.Printf("Error receiving chat message: %v", err)
-				return
-			}
-			fmt.Printf("[CHAT] %s\n", msg.Payload)
-		}
-	}()
+	waitSubscribed(ctx, chatSub, 1)
 
-	// Wait for subscriber to be ready
-	time.Sleep(100 * time.Millisecond)
+	// Who is in the room: clients heartbeat, the presence package announces
+	// joins, leaves and typing on presence:{room1}:events
+	tracker := presence.New(rdb, presence.Config{})
+	roomEvents := make(chan presence.Event, 10)
+	watcher, err := tracker.Watch(ctx, func(e presence.Event) { roomEvents <- e }, "room1")
+	if err != nil {
+		log.Fatalf("Error watching room presence: %v", err)
+	}
+	for _, user := range []string{"Alice", "Bob", "Charlie"} {
+		if _, err := tracker.Heartbeat(ctx, user, "room1"); err != nil {
+			log.Fatalf("Error sending heartbeat: %v", err)
+		}
+	}
+	if _, err := tracker.Typing(ctx, "Alice", "room1"); err != nil {
+		log.Fatalf("Error sending typing indicator: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		e := <-roomEvents
+		fmt.Printf("[PRESENCE] %s %s\n", e.User, e.Type)
+	}
+	online, err := tracker.Online(ctx, "room1")
+	if err != nil {
+		log.Fatalf("Error listing online users: %v", err)
+	}
+	fmt.Printf("Online in room1: %v\n", online)
+	tracker.StopTyping(ctx, "Alice", "room1")
 
 	// Simulate chat messages
 	chatMessages := []string{
@@ -206,48 +193,122 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error publishing chat message: %v", err)
 		}
-		time.Sleep(200 * time.Millisecond)
 	}
+	receiveMessages(ctx, chatSub, len(chatMessages), "[CHAT] %[2]s\n")
+
+	for _, user := range []string{"Alice", "Bob", "Charlie"} {
+		tracker.Leave(ctx, user, "room1")
+	}
+	watcher.Close()
+	rdb.Del(ctx, "presence:last_seen", "presence:rooms")
 
 	// 7. Notification system example
 	fmt.Println("\n=== Notification System Example ===")
 
-	// Subscribe to user notifications
-	userSub := rdb.Subscribe(ctx, "notifications:user123")
-	defer userSub.Close()
-
-	// Handle notifications
-	go func() {
-		for {
-			msg, err := userSub.ReceiveMessage(ctx)
-			if err != nil {
-				log.Printf("Error receiving notification: %v", err)
-				return
-			}
-			fmt.Printf("[NOTIFICATION] %s\n", msg.Payload)
-		}
-	}()
-
-	// Wait for subscriber to be ready
-	time.Sleep(100 * time.Millisecond)
+	// Pub/Sub alone only reaches users who are online. The notify package
+	// keeps every notification in a per-user inbox as well, and pushes it
+	// live on notifications:<user> when someone is listening.
+	notifications := notify.New(rdb, notify.Config{})
+	pushed := make(chan notify.Notification, 10)
+	listener, err := notifications.Listen(ctx, "user123", func(n notify.Notification) { pushed <- n })
+	if err != nil {
+		log.Fatalf("Error listening for notifications: %v", err)
+	}
 
 	// Send notifications
-	notifications := []string{
+	var sent []string
+	for _, title := range []string{
 		"New message from John",
 		"Your order has been shipped",
 		"Reminder: Meeting at 3 PM",
 		"System maintenance scheduled",
+	} {
+		n := &notify.Notification{Type: "info", Title: title}
+		if _, err := notifications.Send(ctx, "user123", n); err != nil {
+			log.Fatalf("Error sending notification: %v", err)
+		}
+		sent = append(sent, n.ID)
 	}
+	for range sent {
+		fmt.Printf("[NOTIFICATION] %s\n", (<-pushed).Title)
+	}
+	listener.Close()
 
-	for _, notification := range notifications {
-		err = rdb.Publish(ctx, "notifications:user123", notification).Err()
-		if err != nil {
-			log.Fatalf("Error publishing notification: %v", err)
+	// Reading the inbox later, e.g. after logging in
+	if _, err := notifications.MarkRead(ctx, "user123", sent[0]); err != nil {
+		log.Fatalf("Error marking notification read: %v", err)
+	}
+	unread, err := notifications.UnreadCount(ctx, "user123")
+	if err != nil {
+		log.Fatalf("Error counting unread notifications: %v", err)
+	}
+	fmt.Printf("Unread notifications: %d\n", unread)
+	inbox, err := notifications.Inbox(ctx, "user123", 0, 10)
+	if err != nil {
+		log.Fatalf("Error reading inbox: %v", err)
+	}
+	for _, n := range inbox {
+		mark := "*"
+		if n.Read {
+			mark = " "
+		}
+		fmt.Printf("  %s %s\n", mark, n.Title)
+	}
+	notifications.Delete(ctx, "user123", sent...)
+
+	// 8. Reliable Pub/Sub: plain Pub/Sub drops messages sent while a
+	// subscriber is disconnected. The pubsub package numbers each message and
+	// logs it to a capped stream, so a subscriber that comes back catches up.
+	fmt.Println("\n=== Reliable Pub/Sub ===")
+
+	rdb.Del(ctx, "pubsub:{orders}:seq", "pubsub:{orders}:log")
+	publisher := pubsub.NewPublisher(rdb, pubsub.PublisherConfig{MaxLen: 1000})
+	handled := make(chan struct{}, 10)
+	printOrder := func(m pubsub.Message) {
+		source := "live"
+		if m.Backfilled {
+			source = "backfilled"
+		}
+		fmt.Printf("[ORDERS #%d, %s] %s\n", m.Seq, source, m.Payload)
+		handled <- struct{}{}
+	}
+
+	orders := pubsub.NewSubscriber(rdb, pubsub.Config{Channels: []string{"orders"}}, printOrder)
+	if err := orders.Start(ctx); err != nil {
+		log.Fatalf("Error starting reliable subscriber: %v", err)
+	}
+	for _, order := range []string{"order 1001 placed", "order 1002 placed"} {
+		if _, err := publisher.Publish(ctx, "orders", order); err != nil {
+			log.Fatalf("Error publishing order: %v", err)
+		}
+	}
+
+	// Wait until both are handled, then simulate the subscriber going away
+	<-handled
+	<-handled
+	orders.Close()
+	position := orders.Position("orders")
+	fmt.Printf("Subscriber stopped at #%d\n", position)
+
+	for _, order := range []string{"order 1001 shipped", "order 1003 placed"} {
+		if _, err := publisher.Publish(ctx, "orders", order); err != nil {
+			log.Fatalf("Error publishing order: %v", err)
 		}
-		time.Sleep(300 * time.Millisecond)
 	}
+	fmt.Println("Published 2 orders while no one was listening")
+
+	// Resuming from the saved position backfills them before Start returns
+	resumed := pubsub.NewSubscriber(rdb, pubsub.Config{
+		Channels: []string{"orders"},
+		After:    map[string]int64{"orders": position},
+	}, printOrder)
+	if err := resumed.Start(ctx); err != nil {
+		log.Fatalf("Error resuming reliable subscriber: %v", err)
+	}
+	resumed.Close()
+	rdb.Del(ctx, "pubsub:{orders}:seq", "pubsub:{orders}:log")
 
-	// 8. Cleanup and final messages
+	// 9. Cleanup and final messages
 	fmt.Println("\n=== Cleanup ===")
 
 	// Unsubscribe from all channels
@@ -271,10 +332,29 @@ func main() {
 		log.Fatalf("Error unsubscribing from chat: %v", err)
 	}
 
-	err = userSub.Unsubscribe(ctx)
-	if err != nil {
-		log.Fatalf("Error unsubscribing from notifications: %v", err)
+	fmt.Println("All subscriptions closed")
+}
+
+// waitSubscribed reads one confirmation per channel or pattern, so messages
+// published afterwards are guaranteed to reach the subscriber
+func waitSubscribed(ctx context.Context, sub *redis.PubSub, n int) {
+	for i := 0; i < n; i++ {
+		if _, err := sub.Receive(ctx); err != nil {
+			log.Fatalf("Error waiting for subscription: %v", err)
+		}
 	}
+}
 
-	fmt.Println("All subscriptions closed")
+// receiveMessages prints the next n messages, or stops after a second of silence
+func receiveMessages(ctx context.Context, sub *redis.PubSub, n int, format string) {
+	for i := 0; i < n; i++ {
+		msg, err := sub.ReceiveTimeout(ctx, time.Second)
+		if err != nil {
+			log.Printf("Error receiving message: %v", err)
+			return
+		}
+		if m, ok := msg.(*redis.Message); ok {
+			fmt.Printf(format, m.Channel, m.Payload)
+		}
+	}
 }
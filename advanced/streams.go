@@ -4,8 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
+	"Redis/namespace"
+	"Redis/tlsconf"
+	"Redis/tracing"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +19,12 @@ import (
 func main() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -305,11 +313,61 @@ func main() {
 		fmt.Println()
 	}
 
-	// 14. Cleanup
+	// 14. Trace context in stream messages
+	fmt.Println("\n=== Traced Producer and Consumer ===")
+
+	// Spans for every command from here on go to a local OTLP/JSON file
+	traceFile := filepath.Join(os.TempDir(), "streams_trace.jsonl")
+	exporter, err := tracing.NewFileExporter(traceFile)
+	if err != nil {
+		log.Fatalf("Error opening trace file: %v", err)
+	}
+	defer exporter.Close()
+	tracer := tracing.NewTracer("streams-demo", exporter)
+	tracing.Instrument(rdb, tracer)
+
+	orderStream := "orders"
+	err = rdb.XGroupCreateMkStream(ctx, orderStream, "fulfillment", "0").Err()
+	if err != nil {
+		log.Fatalf("Error creating order group: %v", err)
+	}
+
+	// The producer adds its traceparent to the entry fields
+	produceCtx, produce := tracer.Start(ctx, "publish order", tracing.KindProducer)
+	orderID, err := rdb.XAdd(produceCtx, &redis.XAddArgs{
+		Stream: orderStream,
+		Values: tracing.StreamValues(produceCtx, "order_id", "order_1001", "total", "59.90"),
+	}).Result()
+	produce.End()
+	if err != nil {
+		log.Fatalf("Error publishing order: %v", err)
+	}
+	fmt.Printf("Published %s in trace %s\n", orderID, produce.SpanContext().TraceID)
+
+	// The consumer continues the producer's trace from those fields
+	orderStreams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "fulfillment",
+		Consumer: "worker1",
+		Streams:  []string{orderStream, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil {
+		log.Fatalf("Error reading orders: %v", err)
+	}
+	for _, msg := range orderStreams[0].Messages {
+		msgCtx, process := tracer.Start(tracing.ExtractStream(ctx, msg.Values), "process order", tracing.KindConsumer)
+		err = rdb.XAck(msgCtx, orderStream, "fulfillment", msg.ID).Err()
+		process.SetError(err)
+		process.End()
+		fmt.Printf("Processed %s in trace %s\n", msg.Values["order_id"], process.SpanContext().TraceID)
+	}
+	fmt.Printf("Spans written to %s\n", traceFile)
+
+	// 15. Cleanup
 	fmt.Println("\n=== Cleanup ===")
 
 	// Delete streams
-	_, err = rdb.Del(ctx, "events", eventStore).Result()
+	_, err = rdb.Del(ctx, "events", eventStore, orderStream).Result()
 	if err != nil {
 		log.Fatalf("Error cleaning up streams: %v", err)
 	}
@@ -6,6 +6,9 @@ import (
 	"log"
 	"time"
 
+	"Redis/namespace"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +16,12 @@ import (
 func main() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -275,6 +280,7 @@ func main() {
 	}
 	transactionTime := time.Since(start)
 	fmt.Printf("100 transaction commands took: %v\n", transactionTime)
+	fmt.Println("For repeatable numbers: go test ./tests -run '^$' -bench BenchmarkWrites/txpipelined -benchmem")
 
 	// 8. Cleanup
 	fmt.Println("\n=== Cleanup ===")
@@ -0,0 +1,380 @@
+// Package analytics computes unique-visitor statistics from the events
+// stream: unique users per event type per hour and day, daily, weekly and
+// monthly active users, retention of new-user cohorts and funnel
+// conversion.
+//
+// Unique counts use one HyperLogLog per event type and bucket, 12KB at most
+// with a standard error of 0.81% however many users there are. Activity is
+// kept as one bitmap per event type and day, with a bit per user, so that
+// users can be combined across days and events with BITOP: OR for weekly
+// and monthly actives, AND for retention and funnels. Users get consecutive
+// bit positions the first time they are seen.
+//
+// Both structures are idempotent, so replaying events, or processing a
+// batch again after a crash, does not change the numbers.
+package analytics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AllEvents stands for events of any type in queries
+const AllEvents = "*"
+
+// Granularity is the bucket size of unique counts
+type Granularity int
+
+const (
+	Hour Granularity = iota
+	Day
+)
+
+// Event is one thing a user did
+type Event struct {
+	UserID string
+	Type   string
+	Time   time.Time
+}
+
+// Config controls an Analytics
+type Config struct {
+	Prefix          string         // Key prefix; default "analytics:"
+	Stream          string         // Stream to consume; default "events"
+	Group           string         // Consumer group; default "analytics"
+	Consumer        string         // Consumer name; default host-pid
+	BatchSize       int64          // Entries per read; default 500
+	Block           time.Duration  // How long a read waits for new entries; default 5s
+	Location        *time.Location // Where days start; default UTC
+	HourlyRetention time.Duration  // How long hourly counts are kept; default 8 days, negative for ever
+	DailyRetention  time.Duration  // How long daily counts and bitmaps are kept; default 400 days, negative for ever
+}
+
+// Analytics records events and answers queries about them
+type Analytics struct {
+	rdb redis.UniversalClient
+	cfg Config
+
+	mu  sync.Mutex
+	ids map[string]int64 // Bit positions of users already seen
+}
+
+// New creates an Analytics
+func New(rdb redis.UniversalClient, cfg Config) *Analytics {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "analytics:"
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = "events"
+	}
+	if cfg.Group == "" {
+		cfg.Group = "analytics"
+	}
+	if cfg.Consumer == "" {
+		host, _ := os.Hostname()
+		cfg.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = 5 * time.Second
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.HourlyRetention == 0 {
+		cfg.HourlyRetention = 8 * 24 * time.Hour
+	}
+	if cfg.DailyRetention == 0 {
+		cfg.DailyRetention = 400 * 24 * time.Hour
+	}
+	return &Analytics{rdb: rdb, cfg: cfg, ids: make(map[string]int64)}
+}
+
+// bucket returns the start of the hour or day t falls in
+func (a *Analytics) bucket(t time.Time, g Granularity) time.Time {
+	t = t.In(a.cfg.Location)
+	if g == Hour {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, a.cfg.Location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, a.cfg.Location)
+}
+
+// uniqueKey is the HyperLogLog of an event type in a bucket
+func (a *Analytics) uniqueKey(eventType string, g Granularity, start time.Time) string {
+	if g == Hour {
+		return a.cfg.Prefix + "uv:" + eventType + ":" + start.Format("2006010215")
+	}
+	return a.cfg.Prefix + "uv:" + eventType + ":" + start.Format("20060102")
+}
+
+// activeKey is the bitmap of users who had an event of a type on a day
+func (a *Analytics) activeKey(eventType string, day time.Time) string {
+	return a.cfg.Prefix + "active:" + eventType + ":" + day.Format("20060102")
+}
+
+// newKey is the bitmap of users first seen on a day
+func (a *Analytics) newKey(day time.Time) string {
+	return a.cfg.Prefix + "new:" + day.Format("20060102")
+}
+
+// tempKey returns a unique name for an intermediate result
+func (a *Analytics) tempKey() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return a.cfg.Prefix + "tmp:" + hex.EncodeToString(b)
+}
+
+// idScript returns the bit positions of users, giving the next free one to
+// users not seen before. Returns {id, new} per user.
+var idScript = redis.NewScript(`
+local out = {}
+for _, user in ipairs(ARGV) do
+	local id = redis.call('HGET', KEYS[1], user)
+	local new = 0
+	if not id then
+		id = redis.call('INCR', KEYS[2]) - 1
+		redis.call('HSET', KEYS[1], user, id)
+		new = 1
+	end
+	out[#out + 1] = tonumber(id)
+	out[#out + 1] = new
+end
+return out
+`)
+
+// userIDs returns the bit positions of users and which of them are new
+func (a *Analytics) userIDs(ctx context.Context, users []string) (map[string]int64, map[string]bool, error) {
+	ids := make(map[string]int64, len(users))
+	var missing []interface{}
+	a.mu.Lock()
+	for _, u := range users {
+		if id, ok := a.ids[u]; ok {
+			ids[u] = id
+		} else if _, queued := ids[u]; !queued {
+			ids[u] = -1
+			missing = append(missing, u)
+		}
+	}
+	a.mu.Unlock()
+
+	created := make(map[string]bool)
+	if len(missing) == 0 {
+		return ids, created, nil
+	}
+	reply, err := idScript.Run(ctx, a.rdb, []string{a.cfg.Prefix + "user_ids", a.cfg.Prefix + "user_seq"}, missing...).Int64Slice()
+	if err != nil {
+		return nil, nil, fmt.Errorf("analytics: assigning user IDs: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, u := range missing {
+		user := u.(string)
+		ids[user] = reply[2*i]
+		a.ids[user] = reply[2*i]
+		if reply[2*i+1] == 1 {
+			created[user] = true
+		}
+	}
+	return ids, created, nil
+}
+
+// expiry returns when the counters of the bucket starting at start expire,
+// and false if they are kept for ever
+func (a *Analytics) expiry(start time.Time, g Granularity) (time.Time, bool) {
+	if g == Hour {
+		return start.Add(time.Hour + a.cfg.HourlyRetention), a.cfg.HourlyRetention >= 0
+	}
+	return start.AddDate(0, 0, 1).Add(a.cfg.DailyRetention), a.cfg.DailyRetention >= 0
+}
+
+// expired reports whether the counters of the bucket starting at start
+// would already have expired
+func (a *Analytics) expired(start time.Time, g Granularity) bool {
+	at, ok := a.expiry(start, g)
+	return ok && !at.After(time.Now())
+}
+
+// Record counts events, in one pipeline. Events older than the daily
+// retention are skipped, as their counters would be deleted at once.
+func (a *Analytics) Record(ctx context.Context, events ...Event) error {
+	kept := events[:0:0]
+	for _, e := range events {
+		if !a.expired(a.bucket(e.Time, Day), Day) {
+			kept = append(kept, e)
+		}
+	}
+	events = kept
+	if len(events) == 0 {
+		return nil
+	}
+
+	users := make([]string, 0, len(events))
+	firstSeen := make(map[string]time.Time)
+	for _, e := range events {
+		users = append(users, e.UserID)
+		if t, ok := firstSeen[e.UserID]; !ok || e.Time.Before(t) {
+			firstSeen[e.UserID] = e.Time
+		}
+	}
+	ids, created, err := a.userIDs(ctx, users)
+	if err != nil {
+		return err
+	}
+
+	hlls := make(map[string][]interface{})
+	bits := make(map[string]map[int64]bool)
+	expires := make(map[string]time.Time)
+	expire := func(key string, start time.Time, g Granularity) {
+		if at, ok := a.expiry(start, g); ok {
+			expires[key] = at
+		}
+	}
+	setBit := func(key string, id int64) {
+		if bits[key] == nil {
+			bits[key] = make(map[int64]bool)
+		}
+		bits[key][id] = true
+	}
+	for _, e := range events {
+		hour, day := a.bucket(e.Time, Hour), a.bucket(e.Time, Day)
+		for _, typ := range []string{e.Type, AllEvents} {
+			if !a.expired(hour, Hour) {
+				hk := a.uniqueKey(typ, Hour, hour)
+				hlls[hk] = append(hlls[hk], e.UserID)
+				expire(hk, hour, Hour)
+			}
+			dk, ak := a.uniqueKey(typ, Day, day), a.activeKey(typ, day)
+			hlls[dk] = append(hlls[dk], e.UserID)
+			setBit(ak, ids[e.UserID])
+			expire(dk, day, Day)
+			expire(ak, day, Day)
+		}
+	}
+	for user := range created {
+		day := a.bucket(firstSeen[user], Day)
+		setBit(a.newKey(day), ids[user])
+		expire(a.newKey(day), day, Day)
+	}
+
+	pipe := a.rdb.Pipeline()
+	for key, members := range hlls {
+		pipe.PFAdd(ctx, key, members...)
+	}
+	for key, set := range bits {
+		for id := range set {
+			pipe.SetBit(ctx, key, id, 1)
+		}
+	}
+	for key, at := range expires {
+		pipe.ExpireAt(ctx, key, at)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("analytics: recording events: %w", err)
+	}
+	return nil
+}
+
+// parse reads an event from a stream entry with user_id and event_type
+// fields. The time is the timestamp field, in Unix seconds, if present,
+// else the time in the entry ID.
+func parse(msg redis.XMessage) (Event, bool) {
+	user, _ := msg.Values["user_id"].(string)
+	typ, _ := msg.Values["event_type"].(string)
+	if user == "" || typ == "" {
+		return Event{}, false
+	}
+	e := Event{UserID: user, Type: typ}
+	if ts, ok := msg.Values["timestamp"].(string); ok {
+		if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			e.Time = time.Unix(sec, 0)
+		}
+	}
+	if e.Time.IsZero() {
+		ms, _, _ := strings.Cut(msg.ID, "-")
+		n, err := strconv.ParseInt(ms, 10, 64)
+		if err != nil {
+			return Event{}, false
+		}
+		e.Time = time.UnixMilli(n)
+	}
+	return e, true
+}
+
+// Process records the events in stream entries. Entries without a user or
+// event type are skipped; the number recorded is returned.
+func (a *Analytics) Process(ctx context.Context, msgs []redis.XMessage) (int, error) {
+	events := make([]Event, 0, len(msgs))
+	for _, msg := range msgs {
+		if e, ok := parse(msg); ok {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	return len(events), a.Record(ctx, events...)
+}
+
+// Run consumes the stream as a member of the consumer group until ctx is
+// done, creating the group at the start of the stream if needed so history
+// is counted too. Entries this consumer read but did not acknowledge before
+// a crash are processed first. progress, if not nil, is called after each
+// batch with the number of events recorded so far.
+func (a *Analytics) Run(ctx context.Context, progress func(total int)) error {
+	err := a.rdb.XGroupCreateMkStream(ctx, a.cfg.Stream, a.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("analytics: creating group: %w", err)
+	}
+
+	start, block, total := "0", time.Duration(-1), 0
+	for {
+		streams, err := a.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    a.cfg.Group,
+			Consumer: a.cfg.Consumer,
+			Streams:  []string{a.cfg.Stream, start},
+			Count:    a.cfg.BatchSize,
+			Block:    block,
+		}).Result()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("analytics: reading %s: %w", a.cfg.Stream, err)
+		}
+		msgs := streams[0].Messages
+		if len(msgs) == 0 && start == "0" {
+			// Pending entries are done; wait for new ones
+			start, block = ">", a.cfg.Block
+			continue
+		}
+		n, err := a.Process(ctx, msgs)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.ID
+		}
+		if err := a.rdb.XAck(ctx, a.cfg.Stream, a.cfg.Group, ids...).Err(); err != nil {
+			return fmt.Errorf("analytics: acknowledging: %w", err)
+		}
+		total += n
+		if progress != nil {
+			progress(total)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Count is the number of unique users in one bucket
+type Count struct {
+	Start time.Time
+	Users int64
+}
+
+// buckets returns the starts of the hours or days from from to to, inclusive
+func (a *Analytics) buckets(g Granularity, from, to time.Time) []time.Time {
+	var starts []time.Time
+	last := a.bucket(to, g)
+	for t := a.bucket(from, g); !t.After(last); {
+		starts = append(starts, t)
+		if g == Hour {
+			t = a.bucket(t.Add(time.Hour), Hour)
+		} else {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return starts
+}
+
+// Uniques returns the unique users with an event of eventType, or
+// AllEvents, in each hour or day from from to to
+func (a *Analytics) Uniques(ctx context.Context, eventType string, g Granularity, from, to time.Time) ([]Count, error) {
+	starts := a.buckets(g, from, to)
+	pipe := a.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(starts))
+	for i, start := range starts {
+		cmds[i] = pipe.PFCount(ctx, a.uniqueKey(eventType, g, start))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+	counts := make([]Count, len(starts))
+	for i, start := range starts {
+		counts[i] = Count{Start: start, Users: cmds[i].Val()}
+	}
+	return counts, nil
+}
+
+// UniqueUsers returns the unique users with an event of eventType, or
+// AllEvents, over the whole period from from to to, counting users seen in
+// several buckets once
+func (a *Analytics) UniqueUsers(ctx context.Context, eventType string, g Granularity, from, to time.Time) (int64, error) {
+	var keys []string
+	for _, start := range a.buckets(g, from, to) {
+		keys = append(keys, a.uniqueKey(eventType, g, start))
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	// Merged into a temporary key, like the bitmaps, rather than counted
+	// with a multi-key PFCOUNT
+	tmp := a.tempKey()
+	pipe := a.rdb.Pipeline()
+	pipe.PFMerge(ctx, tmp, keys...)
+	count := pipe.PFCount(ctx, tmp)
+	pipe.Del(ctx, tmp)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("analytics: %w", err)
+	}
+	return count.Val(), nil
+}
+
+// activeKeys returns the activity bitmaps of eventType for the days from
+// from to to
+func (a *Analytics) activeKeys(eventType string, from, to time.Time) []string {
+	var keys []string
+	for _, day := range a.buckets(Day, from, to) {
+		keys = append(keys, a.activeKey(eventType, day))
+	}
+	return keys
+}
+
+// Active returns the users with any event in the days days ending on day.
+// days must be at least 1.
+func (a *Analytics) Active(ctx context.Context, day time.Time, days int) (int64, error) {
+	if days < 1 {
+		return 0, fmt.Errorf("analytics: active users over %d days: need at least one day", days)
+	}
+	keys := a.activeKeys(AllEvents, day.AddDate(0, 0, 1-days), day)
+	if len(keys) == 1 {
+		return a.rdb.BitCount(ctx, keys[0], nil).Result()
+	}
+	tmp := a.tempKey()
+	pipe := a.rdb.Pipeline()
+	pipe.BitOpOr(ctx, tmp, keys...)
+	count := pipe.BitCount(ctx, tmp, nil)
+	pipe.Del(ctx, tmp)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("analytics: %w", err)
+	}
+	return count.Val(), nil
+}
+
+// DAU returns the daily active users of day
+func (a *Analytics) DAU(ctx context.Context, day time.Time) (int64, error) {
+	return a.Active(ctx, day, 1)
+}
+
+// WAU returns the weekly active users of the 7 days ending on day
+func (a *Analytics) WAU(ctx context.Context, day time.Time) (int64, error) {
+	return a.Active(ctx, day, 7)
+}
+
+// MAU returns the monthly active users of the 30 days ending on day
+func (a *Analytics) MAU(ctx context.Context, day time.Time) (int64, error) {
+	return a.Active(ctx, day, 30)
+}
+
+// Cohort is the retention of the users first seen on one day
+type Cohort struct {
+	Day      time.Time
+	Users    int64
+	Retained []int64 // Retained[i] is how many were active i+1 days later
+}
+
+// Rate returns the share of the cohort active n days after its first day
+func (c Cohort) Rate(n int) float64 {
+	if c.Users == 0 || n < 1 || n > len(c.Retained) {
+		return 0
+	}
+	return float64(c.Retained[n-1]) / float64(c.Users)
+}
+
+// Retention returns how many of the users first seen on day were active on
+// each of the following days, from the AND of the day's new-user bitmap
+// with each later day's activity
+func (a *Analytics) Retention(ctx context.Context, day time.Time, days int) (Cohort, error) {
+	if days < 0 {
+		return Cohort{}, fmt.Errorf("analytics: retention over %d days: need zero or more days", days)
+	}
+	day = a.bucket(day, Day)
+	cohort := a.newKey(day)
+	tmp := a.tempKey()
+	pipe := a.rdb.Pipeline()
+	size := pipe.BitCount(ctx, cohort, nil)
+	retained := make([]*redis.IntCmd, days)
+	for i := range retained {
+		pipe.BitOpAnd(ctx, tmp, cohort, a.activeKey(AllEvents, day.AddDate(0, 0, i+1)))
+		retained[i] = pipe.BitCount(ctx, tmp, nil)
+	}
+	pipe.Del(ctx, tmp)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Cohort{}, fmt.Errorf("analytics: %w", err)
+	}
+	c := Cohort{Day: day, Users: size.Val(), Retained: make([]int64, days)}
+	for i, cmd := range retained {
+		c.Retained[i] = cmd.Val()
+	}
+	return c, nil
+}
+
+// Step is one step of a funnel
+type Step struct {
+	Event          string
+	Users          int64   // Users who had this event and every earlier one
+	Conversion     float64 // Users as a share of the first step's
+	StepConversion float64 // Users as a share of the previous step's
+}
+
+// Funnel returns how many of the users who had the first event in the days
+// from from to to also had each following one in that period. Bitmaps
+// record whether a user had an event on a day, not when, so the order of
+// events within the period is not checked. from must not be after to.
+func (a *Analytics) Funnel(ctx context.Context, from, to time.Time, events ...string) ([]Step, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	if len(a.buckets(Day, from, to)) == 0 {
+		return nil, fmt.Errorf("analytics: funnel from %s to %s: no days in range",
+			from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	acc, step := a.tempKey(), a.tempKey()
+	pipe := a.rdb.Pipeline()
+	counts := make([]*redis.IntCmd, len(events))
+	for i, event := range events {
+		keys := a.activeKeys(event, from, to)
+		if i == 0 {
+			pipe.BitOpOr(ctx, acc, keys...)
+		} else {
+			pipe.BitOpOr(ctx, step, keys...)
+			pipe.BitOpAnd(ctx, acc, acc, step)
+		}
+		counts[i] = pipe.BitCount(ctx, acc, nil)
+	}
+	pipe.Del(ctx, acc, step)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+
+	steps := make([]Step, len(events))
+	for i, event := range events {
+		steps[i] = Step{Event: event, Users: counts[i].Val()}
+		if first := steps[0].Users; first > 0 {
+			steps[i].Conversion = float64(steps[i].Users) / float64(first)
+		}
+		if i == 0 {
+			steps[i].StepConversion = steps[i].Conversion
+		} else if prev := steps[i-1].Users; prev > 0 {
+			steps[i].StepConversion = float64(steps[i].Users) / float64(prev)
+		}
+	}
+	return steps, nil
+}
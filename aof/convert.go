@@ -0,0 +1,83 @@
+package aof
+
+import (
+	"sort"
+	"strconv"
+
+	"Redis/dataset"
+	"Redis/rdbfile"
+)
+
+// itemsPerCommand matches the chunk size Redis uses when rewriting the AOF
+const itemsPerCommand = 64
+
+// entryCommands returns the commands that recreate a snapshot key, the same
+// way an AOF rewrite would. Module values cannot be expressed as commands and
+// produce none.
+func entryCommands(e *rdbfile.Entry) [][]string {
+	var cmds [][]string
+	chunked := func(cmd string, items []string, per int) {
+		for i := 0; i < len(items); i += itemsPerCommand * per {
+			end := min(i+itemsPerCommand*per, len(items))
+			cmds = append(cmds, append([]string{cmd, e.Key}, items[i:end]...))
+		}
+	}
+
+	switch v := e.Value.(type) {
+	case string:
+		cmds = append(cmds, []string{"SET", e.Key, v})
+
+	case []string:
+		if e.Type == dataset.TypeList {
+			chunked("RPUSH", v, 1)
+		} else {
+			chunked("SADD", v, 1)
+		}
+
+	case map[string]string:
+		fields := make([]string, 0, len(v))
+		for f := range v {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		pairs := make([]string, 0, 2*len(v))
+		for _, f := range fields {
+			pairs = append(pairs, f, v[f])
+		}
+		chunked("HSET", pairs, 2)
+		for _, f := range fields {
+			if at, ok := e.FieldExpires[f]; ok {
+				cmds = append(cmds, []string{"HPEXPIREAT", e.Key, strconv.FormatInt(at, 10), "FIELDS", "1", f})
+			}
+		}
+
+	case []dataset.ZMember:
+		pairs := make([]string, 0, 2*len(v))
+		for _, m := range v {
+			pairs = append(pairs, strconv.FormatFloat(m.Score, 'g', -1, 64), m.Member)
+		}
+		chunked("ZADD", pairs, 2)
+
+	case *dataset.Stream:
+		for _, entry := range v.Entries {
+			cmds = append(cmds, append([]string{"XADD", e.Key, entry.ID}, entry.Fields...))
+		}
+		if len(v.Entries) == 0 {
+			// An empty stream is created by adding an entry and trimming it away
+			cmds = append(cmds, []string{"XADD", e.Key, "MAXLEN", "0", v.LastID, "x", "y"})
+		} else if v.LastID != "" && v.LastID != v.Entries[len(v.Entries)-1].ID {
+			cmds = append(cmds, []string{"XSETID", e.Key, v.LastID})
+		}
+		for _, g := range v.Groups {
+			cmds = append(cmds, []string{"XGROUP", "CREATE", e.Key, g.Name, g.LastDelivered})
+		}
+
+	default:
+		return nil
+	}
+
+	if e.ExpireAt > 0 {
+		cmds = append(cmds, []string{"PEXPIREAT", e.Key, strconv.FormatInt(e.ExpireAt, 10)})
+	}
+	return cmds
+}
@@ -0,0 +1,95 @@
+package aof
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"Redis/keyspec"
+)
+
+// Filter selects the part of the log to print or replay
+type Filter struct {
+	// Until stops before the first command annotated with a later time.
+	// Requires aof-timestamp-enabled on the server that wrote the log.
+	Until time.Time
+
+	// UntilIndex stops before the command with this index; 0 means no limit
+	UntilIndex int64
+
+	// Patterns keeps only commands touching keys that match one of these
+	// glob patterns. Empty keeps everything.
+	Patterns []string
+
+	// Keys locates the keys of each command; nil uses keyspec.Static, as a
+	// log is often read with no server at hand. Commands it does not list
+	// are assumed to have a single key at position 1.
+	Keys *keyspec.Table
+}
+
+// Done reports whether c is past the cut-off point
+func (f *Filter) Done(c *Command) bool {
+	if f.UntilIndex > 0 && c.Index >= f.UntilIndex {
+		return true
+	}
+	return !f.Until.IsZero() && c.Time.After(f.Until)
+}
+
+// Apply returns the arguments to keep for c, or nil when the command should be
+// dropped. Commands that only delete or set independent keys (DEL, MSET, ...)
+// are trimmed to the matching keys; other commands are kept when any of their
+// keys match. SELECT, MULTI and EXEC always pass so that replay stays correct,
+// while other keyless commands such as FLUSHALL are dropped.
+func (f *Filter) Apply(c *Command) []string {
+	name := strings.ToLower(c.Args[0])
+	if len(f.Patterns) == 0 || name == "select" || name == "multi" || name == "exec" {
+		return c.Args
+	}
+
+	table := f.Keys
+	if table == nil {
+		table = keyspec.Static()
+	}
+	keys, err := table.Keys(c.Args)
+	if errors.Is(err, keyspec.ErrUnknownCommand) && len(c.Args) > 1 {
+		keys = []int{1}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	step, ok := independent[name]
+	if !ok {
+		for _, i := range keys {
+			if f.match(c.Args[i]) {
+				return c.Args
+			}
+		}
+		return nil
+	}
+
+	kept := []string{c.Args[0]}
+	for _, i := range keys {
+		if f.match(c.Args[i]) {
+			kept = append(kept, c.Args[i:min(i+step, len(c.Args))]...)
+		}
+	}
+	if len(kept) == 1 {
+		return nil
+	}
+	return kept
+}
+
+func (f *Filter) match(key string) bool {
+	for _, p := range f.Patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// independent are the commands whose keys stand alone and can be dropped one
+// by one, each with the given number of arguments
+var independent = map[string]int{"del": 1, "unlink": 1, "touch": 1, "mset": 2}
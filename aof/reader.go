@@ -0,0 +1,251 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Redis/rdbfile"
+)
+
+// Limits that stop a corrupt header from triggering a huge allocation
+const (
+	maxArgs = 1 << 20
+	maxBulk = 512 << 20 // proto-max-bulk-len default
+)
+
+// Command is one command read from the log
+type Command struct {
+	Args   []string
+	Index  int64     // Position in replay order across all files, from 0
+	File   string    // File the command was read from
+	Offset int64     // Byte offset of the command within File
+	Time   time.Time // Latest #TS annotation before the command, zero if none
+	DB     int       // Database the command runs against
+}
+
+// Name returns the upper-cased command name
+func (c *Command) Name() string {
+	return strings.ToUpper(c.Args[0])
+}
+
+// ErrTruncated is matched by errors.Is for every *TruncatedError
+var ErrTruncated = errors.New("aof truncated")
+
+// TruncatedError reports a file that ends in the middle of a command or
+// transaction, typically after a crash. Everything before Offset is intact.
+type TruncatedError struct {
+	File   string
+	Offset int64 // Length the file can be cut to
+	Size   int64 // Current file size
+	Reason string
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d (%d trailing bytes)", e.File, e.Reason, e.Offset, e.Size-e.Offset)
+}
+
+func (e *TruncatedError) Unwrap() error {
+	return ErrTruncated
+}
+
+// Read reads files in order and calls fn for every command. Base files may be
+// RDB snapshots or AOFs with an RDB preamble; their keys are delivered as the
+// equivalent write commands.
+//
+// A truncated tail in the last file is returned as a *TruncatedError after
+// every complete command has been delivered. Truncation in any earlier file
+// means the log is corrupt.
+func Read(files []string, fn func(*Command) error) error {
+	s := &scanner{fn: fn}
+	for i, path := range files {
+		err := s.readFile(path)
+		var te *TruncatedError
+		if errors.As(err, &te) && i < len(files)-1 {
+			return fmt.Errorf("%s: truncated at offset %d but followed by %s", path, te.Offset, files[i+1])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanner carries state that spans files
+type scanner struct {
+	fn    func(*Command) error
+	index int64
+	db    int
+	ts    time.Time
+}
+
+func (s *scanner) emit(path string, offset int64, args []string) error {
+	c := &Command{Args: args, Index: s.index, File: path, Offset: offset, Time: s.ts, DB: s.db}
+	if strings.EqualFold(args[0], "select") && len(args) == 2 {
+		if db, err := strconv.Atoi(args[1]); err == nil {
+			s.db, c.DB = db, db
+		}
+	}
+	s.index++
+	return s.fn(c)
+}
+
+// countingReader counts the bytes read from the underlying file
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (s *scanner) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// rdbfile.Parse reuses a reader of this size, so the preamble is consumed
+	// exactly and the commands after it stay in the buffer
+	cr := &countingReader{r: f}
+	br := bufio.NewReaderSize(cr, rdbfile.BufferSize)
+	offset := func() int64 { return cr.n - int64(br.Buffered()) }
+
+	if magic, err := br.Peek(5); err == nil && string(magic) == "REDIS" {
+		if err := s.readPreamble(path, br, offset); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for {
+		start := offset()
+		next, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch next[0] {
+		case '#':
+			line, err := readLine(br)
+			if err != nil {
+				return truncatedOr(err, path, start, st.Size())
+			}
+			s.annotate(line)
+		case '*':
+			args, err := readCommand(br)
+			if err != nil {
+				return truncatedOr(fmt.Errorf("%s: offset %d: %w", path, start, err), path, start, st.Size())
+			}
+			if err := s.emit(path, start, args); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: offset %d: unexpected byte %q, expected a command", path, start, next[0])
+		}
+	}
+}
+
+// annotate handles comment lines; only #TS:<unix seconds> carries meaning
+func (s *scanner) annotate(line string) {
+	if ts, ok := strings.CutPrefix(line, "#TS:"); ok {
+		if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			s.ts = time.Unix(sec, 0).UTC()
+		}
+	}
+}
+
+// readPreamble converts an RDB snapshot into commands
+func (s *scanner) readPreamble(path string, br *bufio.Reader, offset func() int64) error {
+	first := true
+	_, err := rdbfile.Parse(br, func(e *rdbfile.Entry) error {
+		start := offset() - e.Size
+		if first || e.DB != s.db {
+			if err := s.emit(path, start, []string{"SELECT", strconv.Itoa(e.DB)}); err != nil {
+				return err
+			}
+			first = false
+		}
+		for _, args := range entryCommands(e) {
+			if err := s.emit(path, start, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// truncatedOr turns an unexpected end of file into a *TruncatedError
+func truncatedOr(err error, path string, offset, size int64) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &TruncatedError{File: path, Offset: offset, Size: size, Reason: "incomplete command"}
+	}
+	return err
+}
+
+// readLine reads a CRLF-terminated line without the terminator
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("line not terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+// readCommand reads one RESP array of bulk strings
+func readCommand(br *bufio.Reader) ([]string, error) {
+	header, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(header[1:])
+	if err != nil || n < 1 || n > maxArgs {
+		return nil, fmt.Errorf("invalid argument count %q", header)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(br)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("expected bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulk {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("bulk string not terminated by CRLF")
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
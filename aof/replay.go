@@ -0,0 +1,201 @@
+package aof
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// errStop ends Read early once the filter's cut-off point is reached
+var errStop = errors.New("stop")
+
+// ReplayOptions controls Replay
+type ReplayOptions struct {
+	Filter    Filter
+	BatchSize int // Commands per pipeline round trip
+}
+
+// ReplayStats summarises a replay
+type ReplayStats struct {
+	Commands  int64           // Commands sent to the server
+	Skipped   int64           // Commands dropped by the key filter
+	Discarded int64           // Commands of a transaction left open at the cut-off or tail
+	LastIndex int64           // Index of the last command sent
+	LastTime  time.Time       // Timestamp annotation of the last command sent
+	Truncated *TruncatedError // Set when the log ended mid-command
+}
+
+// Replay sends the commands in files to rdb, in order, on a single connection.
+// SELECT commands are replayed, so keys land in their original databases.
+// MULTI/EXEC blocks are sent only once complete; a transaction cut short by
+// the filter or by a truncated tail is dropped, as Redis does when loading.
+func Replay(ctx context.Context, rdb *redis.Client, files []string, opts ReplayOptions) (*ReplayStats, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	conn := rdb.Conn()
+	defer conn.Close()
+
+	r := &replayer{ctx: ctx, pipe: conn.Pipeline(), batch: opts.BatchSize, stats: &ReplayStats{LastIndex: -1}}
+	err := Read(files, func(c *Command) error {
+		if opts.Filter.Done(c) {
+			return errStop
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return r.add(c, opts.Filter.Apply(c))
+	})
+
+	var te *TruncatedError
+	switch {
+	case errors.As(err, &te):
+		r.stats.Truncated = te
+	case err != nil && err != errStop:
+		return r.stats, err
+	}
+
+	r.stats.Discarded += int64(len(r.tx))
+	return r.stats, r.flush()
+}
+
+// replayer batches commands into pipelines and holds back open transactions
+type replayer struct {
+	ctx   context.Context
+	pipe  redis.Pipeliner
+	batch int
+	stats *ReplayStats
+
+	tx      []*Command // Buffered MULTI block, nil outside a transaction
+	pending []*Command // Commands queued on pipe
+}
+
+func (r *replayer) add(c *Command, args []string) error {
+	if args == nil {
+		r.stats.Skipped++
+		return nil
+	}
+	c.Args = args
+
+	switch c.Name() {
+	case "MULTI":
+		r.tx = []*Command{c}
+		return nil
+	case "EXEC":
+		if r.tx == nil {
+			return fmt.Errorf("%s: offset %d: EXEC without MULTI", c.File, c.Offset)
+		}
+		block := append(r.tx, c)
+		r.tx = nil
+		for _, q := range block {
+			r.queue(q)
+		}
+	default:
+		if r.tx != nil {
+			r.tx = append(r.tx, c)
+			return nil
+		}
+		r.queue(c)
+	}
+
+	if len(r.pending) >= r.batch {
+		return r.flush()
+	}
+	return nil
+}
+
+func (r *replayer) queue(c *Command) {
+	args := make([]interface{}, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = a
+	}
+	r.pipe.Do(r.ctx, args...)
+	r.pending = append(r.pending, c)
+}
+
+func (r *replayer) flush() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	cmds, err := r.pipe.Exec(r.ctx)
+	// Server replies are checked per command below; any other error, like a
+	// dropped connection, is not copied onto the commands
+	var reply redis.Error
+	if err != nil && !errors.As(err, &reply) {
+		first := r.pending[0]
+		return fmt.Errorf("command %d (%s) from %s: %w", first.Index, first.Name(), first.File, err)
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			c := r.pending[i]
+			return fmt.Errorf("command %d (%s) from %s: %w", c.Index, c.Name(), c.File, err)
+		}
+	}
+
+	last := r.pending[len(r.pending)-1]
+	r.stats.Commands += int64(len(r.pending))
+	r.stats.LastIndex, r.stats.LastTime = last.Index, last.Time
+	r.pending = r.pending[:0]
+	return nil
+}
+
+// CheckResult describes the state of an AOF
+type CheckResult struct {
+	Files     []string
+	Commands  int64
+	First     time.Time // First timestamp annotation, zero if none
+	Last      time.Time // Last timestamp annotation
+	Truncated *TruncatedError
+}
+
+// Check reads the whole log, reporting a truncated tail or a transaction that
+// was never closed by EXEC. Any other damage is returned as an error.
+func Check(files []string) (*CheckResult, error) {
+	res := &CheckResult{Files: files}
+	var multi *Command
+	err := Read(files, func(c *Command) error {
+		res.Commands++
+		if !c.Time.IsZero() {
+			if res.First.IsZero() {
+				res.First = c.Time
+			}
+			res.Last = c.Time
+		}
+		switch {
+		case strings.EqualFold(c.Args[0], "multi"):
+			multi = c
+		case strings.EqualFold(c.Args[0], "exec"):
+			multi = nil
+		}
+		return nil
+	})
+
+	var te *TruncatedError
+	switch {
+	case errors.As(err, &te):
+		res.Truncated = te
+	case err != nil:
+		return res, err
+	}
+
+	// An open MULTI moves the safe cut-off point back to where it started
+	if multi != nil {
+		size := int64(0)
+		if st, err := os.Stat(multi.File); err == nil {
+			size = st.Size()
+		}
+		res.Truncated = &TruncatedError{File: multi.File, Offset: multi.Offset, Size: size, Reason: "unterminated MULTI"}
+	}
+	return res, nil
+}
+
+// Fix cuts the file back to the last intact command, like redis-check-aof --fix
+func Fix(t *TruncatedError) error {
+	return os.Truncate(t.File, t.Offset)
+}
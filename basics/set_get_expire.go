@@ -6,6 +6,9 @@ import (
 	"log"
 	"time"
 
+	"Redis/namespace"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +16,12 @@ import (
 func main() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -6,6 +6,10 @@ import (
 	"log"
 	"time"
 
+	"Redis/keyspace"
+	"Redis/namespace"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,10 +17,12 @@ import (
 func main() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	hook := namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -141,31 +147,44 @@ func main() {
 	}
 	fmt.Printf("TTL after PERSIST: %v\n", ttl)
 
-	// 6. Monitor expiration in real-time
+	// 6. React to expiration with keyspace notifications
 	fmt.Println("\n=== Monitoring Expiration ===")
 
+	// Subscribe to expired events instead of polling TTL
+	listener := keyspace.NewListener(rdb, keyspace.DefaultConfig())
+	expiredKeys := make(chan keyspace.Event, 1)
+	// Notifications carry the key as stored, under the namespace if any
+	monitorKey := "monitor_key"
+	if hook != nil {
+		monitorKey = hook.Key(monitorKey)
+	}
+	listener.Handle(monitorKey, func(e keyspace.Event) {
+		// Never block dispatch: later events for the key are dropped
+		select {
+		case expiredKeys <- e:
+		default:
+		}
+	}, keyspace.Expired)
+
+	err = listener.Start(ctx)
+	if err != nil {
+		log.Fatalf("Error starting keyspace listener: %v", err)
+	}
+	defer listener.Close()
+
 	// Set a key with short expiration for monitoring
 	err = rdb.Set(ctx, "monitor_key", "monitor_value", 3*time.Second).Err()
 	if err != nil {
 		log.Fatalf("Error setting monitor_key: %v", err)
 	}
-	fmt.Println("Set 'monitor_key' with 3-second expiration for monitoring")
-
-	// Monitor TTL every second
-	for i := 0; i < 5; i++ {
-		ttl, err = rdb.TTL(ctx, "monitor_key").Result()
-		if err != nil {
-			log.Fatalf("Error getting TTL during monitoring: %v", err)
-		}
-
-		if ttl < 0 {
-			fmt.Printf("Time %d: Key has expired or doesn't exist\n", i+1)
-			break
-		} else {
-			fmt.Printf("Time %d: TTL remaining: %.2f seconds\n", i+1, ttl.Seconds())
-		}
+	setAt := time.Now()
+	fmt.Println("Set 'monitor_key' with 3-second expiration, waiting for the expired event")
 
-		time.Sleep(1 * time.Second)
+	select {
+	case e := <-expiredKeys:
+		fmt.Printf("Key '%s' expired in db %d after %.2f seconds\n", e.Key, e.DB, e.Received.Sub(setAt).Seconds())
+	case <-time.After(10 * time.Second):
+		fmt.Println("No expired event received within 10 seconds")
 	}
 
 	// 7. Check TTL for non-existent key
@@ -0,0 +1,270 @@
+// Package batcher coalesces commands issued from many goroutines into
+// pipelines, so independent callers share round trips without coordinating.
+//
+// Each call to Do returns a Future right away. Queued commands are flushed as
+// one pipeline when a batch fills up or when the oldest command has waited
+// for the configured window, whichever comes first.
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrClosed is returned for commands issued after Close
+var ErrClosed = errors.New("batcher: closed")
+
+// Flush reasons reported in FlushInfo
+const (
+	FlushSize  = "size"
+	FlushTime  = "time"
+	FlushClose = "close"
+)
+
+// Options tunes batching. Zero values select the defaults.
+type Options struct {
+	MaxBatch   int           // Commands per pipeline (default 100)
+	MaxDelay   time.Duration // Longest a command waits for its batch to fill (default 1ms)
+	MaxPending int           // Commands queued or in flight before Do blocks (default 10 * MaxBatch)
+	Flushers   int           // Pipelines executing concurrently (default 4)
+
+	// OnFlush, when set, is called after every pipeline round trip
+	OnFlush func(FlushInfo)
+}
+
+func (o *Options) setDefaults() {
+	if o.MaxBatch <= 0 {
+		o.MaxBatch = 100
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = time.Millisecond
+	}
+	if o.MaxPending <= 0 {
+		o.MaxPending = 10 * o.MaxBatch
+	}
+	if o.MaxPending < o.MaxBatch {
+		o.MaxPending = o.MaxBatch
+	}
+	if o.Flushers <= 0 {
+		o.Flushers = 4
+	}
+}
+
+// FlushInfo describes one pipeline round trip
+type FlushInfo struct {
+	Size     int
+	Reason   string // FlushSize, FlushTime or FlushClose
+	Duration time.Duration
+	Err      error // Error returned by Exec; per-command errors are on the futures
+}
+
+// Stats are cumulative counters since the batcher was created
+type Stats struct {
+	Commands      int64
+	Flushes       int64
+	FlushedBySize int64
+	FlushedByTime int64
+	FailedFlushes int64         // Round trips where Exec itself failed, e.g. network errors
+	Pending       int64         // Commands queued or in flight right now
+	FlushTime     time.Duration // Total time spent in Exec
+}
+
+// AvgBatch is the mean number of commands per pipeline
+func (s Stats) AvgBatch() float64 {
+	if s.Flushes == 0 {
+		return 0
+	}
+	return float64(s.Commands) / float64(s.Flushes)
+}
+
+// Batcher queues commands and executes them in pipelines
+type Batcher struct {
+	rdb  redis.Cmdable
+	opts Options
+
+	slots   chan struct{} // Bounds queued plus in-flight commands
+	queue   chan *Future
+	batches chan batch
+	done    chan struct{} // Closed by Close to wake callers waiting for a slot
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	commands, flushes, bySize, byTime, failed, flushNanos atomic.Int64
+}
+
+type batch struct {
+	futures []*Future
+	reason  string
+}
+
+// New starts a batcher on top of rdb. Call Close to flush and stop it.
+func New(rdb redis.Cmdable, opts Options) *Batcher {
+	opts.setDefaults()
+	b := &Batcher{
+		rdb:     rdb,
+		opts:    opts,
+		slots:   make(chan struct{}, opts.MaxPending),
+		queue:   make(chan *Future, opts.MaxPending),
+		batches: make(chan batch, opts.Flushers),
+		done:    make(chan struct{}),
+	}
+
+	b.wg.Add(1 + opts.Flushers)
+	go b.collect()
+	for i := 0; i < opts.Flushers; i++ {
+		go b.flusher()
+	}
+	return b
+}
+
+// Do queues a command and returns its future. It blocks only while
+// MaxPending commands are already outstanding.
+func (b *Batcher) Do(ctx context.Context, args ...interface{}) *Future {
+	f := &Future{cmd: redis.NewCmd(ctx, args...), done: make(chan struct{})}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		f.fail(ctx.Err())
+		return f
+	case <-b.done:
+		f.fail(ErrClosed)
+		return f
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		<-b.slots
+		f.fail(ErrClosed)
+		return f
+	}
+	// Never blocks: the queue holds as many entries as there are slots
+	b.queue <- f
+	return f
+}
+
+// Close flushes everything queued, waits for outstanding pipelines and stops
+// the background goroutines. It does not close the underlying client.
+func (b *Batcher) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	close(b.queue)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// Stats returns a snapshot of the counters
+func (b *Batcher) Stats() Stats {
+	return Stats{
+		Commands:      b.commands.Load(),
+		Flushes:       b.flushes.Load(),
+		FlushedBySize: b.bySize.Load(),
+		FlushedByTime: b.byTime.Load(),
+		FailedFlushes: b.failed.Load(),
+		Pending:       int64(len(b.slots)),
+		FlushTime:     time.Duration(b.flushNanos.Load()),
+	}
+}
+
+// collect groups queued commands into batches
+func (b *Batcher) collect() {
+	defer b.wg.Done()
+	defer close(b.batches)
+
+	timer := time.NewTimer(b.opts.MaxDelay)
+	timer.Stop()
+	var pending []*Future
+
+	send := func(reason string) {
+		if len(pending) == 0 {
+			return
+		}
+		b.batches <- batch{futures: pending, reason: reason}
+		pending = make([]*Future, 0, b.opts.MaxBatch)
+	}
+
+	for {
+		select {
+		case f, ok := <-b.queue:
+			if !ok {
+				timer.Stop()
+				send(FlushClose)
+				return
+			}
+			if len(pending) == 0 {
+				timer.Reset(b.opts.MaxDelay)
+			}
+			pending = append(pending, f)
+			if len(pending) >= b.opts.MaxBatch {
+				timer.Stop()
+				send(FlushSize)
+			}
+		case <-timer.C:
+			send(FlushTime)
+		}
+	}
+}
+
+// flusher executes batches as pipelines and completes their futures
+func (b *Batcher) flusher() {
+	defer b.wg.Done()
+	ctx := context.Background()
+
+	for bt := range b.batches {
+		pipe := b.rdb.Pipeline()
+		for _, f := range bt.futures {
+			pipe.Process(ctx, f.cmd)
+		}
+
+		start := time.Now()
+		_, err := pipe.Exec(ctx)
+		elapsed := time.Since(start)
+
+		// Exec returns the first failed command's error. Server replies such
+		// as WRONGTYPE or nil belong to single futures; anything else, like a
+		// broken connection, failed the whole round trip.
+		var reply redis.Error
+		if errors.As(err, &reply) {
+			err = nil
+		} else if err != nil {
+			b.failed.Add(1)
+		}
+
+		for _, f := range bt.futures {
+			// go-redis leaves the commands alone when the round trip fails
+			if err != nil && f.cmd.Err() == nil {
+				f.cmd.SetErr(err)
+			}
+			close(f.done)
+			<-b.slots
+		}
+
+		b.commands.Add(int64(len(bt.futures)))
+		b.flushes.Add(1)
+		b.flushNanos.Add(int64(elapsed))
+		switch bt.reason {
+		case FlushSize:
+			b.bySize.Add(1)
+		case FlushTime:
+			b.byTime.Add(1)
+		}
+		if b.opts.OnFlush != nil {
+			b.opts.OnFlush(FlushInfo{Size: len(bt.futures), Reason: bt.reason, Duration: elapsed, Err: err})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package batcher
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Future is the pending result of a command queued with Do
+type Future struct {
+	cmd  *redis.Cmd
+	done chan struct{}
+}
+
+func (f *Future) fail(err error) {
+	f.cmd.SetErr(err)
+	close(f.done)
+}
+
+// Done is closed once the command has a result
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the command has run or ctx is done. The returned command
+// carries the reply and any per-command error, including redis.Nil.
+func (f *Future) Wait(ctx context.Context) (*redis.Cmd, error) {
+	select {
+	case <-f.done:
+		return f.cmd, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Result waits and returns the reply
+func (f *Future) Result() (interface{}, error) {
+	<-f.done
+	return f.cmd.Result()
+}
+
+// Err waits and returns the command's error
+func (f *Future) Err() error {
+	<-f.done
+	return f.cmd.Err()
+}
+
+// Text waits and returns the reply as a string
+func (f *Future) Text() (string, error) {
+	<-f.done
+	return f.cmd.Text()
+}
+
+// Int64 waits and returns the reply as an integer
+func (f *Future) Int64() (int64, error) {
+	<-f.done
+	return f.cmd.Int64()
+}
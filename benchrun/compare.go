@@ -0,0 +1,103 @@
+package benchrun
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// Delta compares one benchmark between a baseline and a new run
+type Delta struct {
+	Name      string
+	Base, New Benchmark
+	Change    map[string]float64 // Percent change per unit shared by both runs
+	Regressed bool
+	Missing   bool // Present in the baseline only
+	Added     bool // Present in the new run only
+}
+
+// Compare matches benchmarks by name. A benchmark regresses when ns/op grows
+// (or ops/s drops) by more than threshold percent, or when allocs/op grows.
+// allocs/op is compared rounded, as averaging -count runs can make it
+// fractional.
+func Compare(base, cur *Run, threshold float64) []Delta {
+	var deltas []Delta
+	for _, b := range base.Benchmarks {
+		n, ok := cur.Get(b.Name)
+		if !ok {
+			deltas = append(deltas, Delta{Name: b.Name, Base: b, Missing: true})
+			continue
+		}
+
+		d := Delta{Name: b.Name, Base: b, New: n, Change: make(map[string]float64)}
+		for unit, old := range b.Metrics {
+			if v, ok := n.Metrics[unit]; ok && old != 0 {
+				d.Change[unit] = (v - old) / old * 100
+			}
+		}
+		if c, ok := d.Change[NsPerOp]; ok && c > threshold {
+			d.Regressed = true
+		}
+		if c, ok := d.Change[OpsPerSec]; ok && c < -threshold {
+			d.Regressed = true
+		}
+		if math.Round(n.Metrics[AllocsPerOp]) > math.Round(b.Metrics[AllocsPerOp]) {
+			d.Regressed = true
+		}
+		deltas = append(deltas, d)
+	}
+
+	for _, n := range cur.Benchmarks {
+		if _, ok := base.Get(n.Name); !ok {
+			deltas = append(deltas, Delta{Name: n.Name, New: n, Added: true})
+		}
+	}
+	return deltas
+}
+
+// Regressions counts the deltas flagged as regressed
+func Regressions(deltas []Delta) int {
+	n := 0
+	for _, d := range deltas {
+		if d.Regressed {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTable prints the comparison, one benchmark per row
+func WriteTable(w io.Writer, deltas []Delta) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "BENCHMARK\tOLD ns/op\tNEW ns/op\tDELTA\tOLD ops/s\tNEW ops/s\tOLD allocs\tNEW allocs\t\t")
+	for _, d := range deltas {
+		switch {
+		case d.Missing:
+			fmt.Fprintf(tw, "%s\t%s\t-\t\t\t\t\t\tmissing\t\n", d.Name, num(d.Base.Metrics[NsPerOp]))
+			continue
+		case d.Added:
+			fmt.Fprintf(tw, "%s\t-\t%s\t\t\t\t\t\tnew\t\n", d.Name, num(d.New.Metrics[NsPerOp]))
+			continue
+		}
+
+		mark := ""
+		if d.Regressed {
+			mark = "REGRESSION"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%+.1f%%\t%s\t%s\t%s\t%s\t%s\t\n",
+			d.Name,
+			num(d.Base.Metrics[NsPerOp]), num(d.New.Metrics[NsPerOp]), d.Change[NsPerOp],
+			num(d.Base.Metrics[OpsPerSec]), num(d.New.Metrics[OpsPerSec]),
+			num(d.Base.Metrics[AllocsPerOp]), num(d.New.Metrics[AllocsPerOp]),
+			mark)
+	}
+	return tw.Flush()
+}
+
+func num(v float64) string {
+	if v >= 100 || v == float64(int64(v)) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,145 @@
+// Package benchrun parses `go test -bench` output into JSON results and
+// compares a run against a stored baseline.
+package benchrun
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units reported by the benchmarks in this repository
+const (
+	NsPerOp     = "ns/op"
+	OpsPerSec   = "ops/s"
+	BytesPerOp  = "B/op"
+	AllocsPerOp = "allocs/op"
+)
+
+// Benchmark holds the metrics of one benchmark, averaged over its runs
+type Benchmark struct {
+	Name       string             `json:"name"`
+	Runs       int                `json:"runs"`
+	Iterations int64              `json:"iterations"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// Run is one invocation of the benchmark suite
+type Run struct {
+	Date       time.Time         `json:"date"`
+	Env        map[string]string `json:"env,omitempty"` // goos, goarch, pkg, cpu
+	Benchmarks []Benchmark       `json:"benchmarks"`
+}
+
+// Get returns the benchmark with the given name
+func (r *Run) Get(name string) (Benchmark, bool) {
+	for _, b := range r.Benchmarks {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Benchmark{}, false
+}
+
+// benchLine matches "BenchmarkName-8   1000   1234 ns/op   ..."
+var benchLine = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+(\d+)\s+(.+)$`)
+
+// Parse reads `go test -bench` output. Repeated results for the same
+// benchmark (from -count) are averaged.
+func Parse(r io.Reader) (*Run, error) {
+	run := &Run{Date: time.Now().UTC(), Env: make(map[string]string)}
+	index := make(map[string]int)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		if key, val, ok := strings.Cut(line, ": "); ok && !strings.Contains(key, " ") {
+			switch key {
+			case "goos", "goarch", "pkg", "cpu":
+				run.Env[key] = val
+			}
+			continue
+		}
+
+		m := benchLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		iterations, _ := strconv.ParseInt(m[2], 10, 64)
+		metrics, err := parseMetrics(m[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m[1], err)
+		}
+
+		i, seen := index[m[1]]
+		if !seen {
+			index[m[1]] = len(run.Benchmarks)
+			run.Benchmarks = append(run.Benchmarks, Benchmark{Name: m[1], Runs: 1, Iterations: iterations, Metrics: metrics})
+			continue
+		}
+
+		// Running mean over repeated runs
+		b := &run.Benchmarks[i]
+		b.Runs++
+		b.Iterations += iterations
+		for unit, v := range metrics {
+			b.Metrics[unit] += (v - b.Metrics[unit]) / float64(b.Runs)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(run.Benchmarks) == 0 {
+		return nil, fmt.Errorf("no benchmark results found")
+	}
+	return run, nil
+}
+
+// parseMetrics reads the "value unit" pairs after the iteration count
+func parseMetrics(s string) (map[string]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("malformed metrics %q", s)
+	}
+	metrics := make(map[string]float64, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed metric value %q", fields[i])
+		}
+		metrics[fields[i+1]] = v
+	}
+	return metrics, nil
+}
+
+// Save writes the run as indented JSON
+func (r *Run) Save(path string) error {
+	sort.Slice(r.Benchmarks, func(i, j int) bool { return r.Benchmarks[i].Name < r.Benchmarks[j].Name })
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a run saved with Save
+func Load(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &run, nil
+}
@@ -0,0 +1,221 @@
+// Package bloom implements probabilistic set membership on plain Redis
+// strings, without the RedisBloom module.
+//
+// A Filter is a classic Bloom filter: a bitmap of m bits where each item
+// sets k of them, updated with SETBIT and read with GETBIT. It answers
+// "probably added" or "definitely not added" using a fixed amount of memory
+// sized from the expected number of items and the false-positive rate
+// wanted at that size. A Scalable filter chains filters of growing capacity
+// for when the number of items is not known up front, and a Counting filter
+// keeps 4-bit counters with BITFIELD instead of bits so items can be removed.
+//
+// Filters record their size next to the bitmap, in key:meta, so processes
+// opening the same key agree on it whatever Config they pass. Batches of
+// items are sent in one pipeline.
+package bloom
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxBits is the size limit of a Redis string, 512MB
+const maxBits = 1 << 32
+
+// Config sizes a filter
+type Config struct {
+	Capacity  int64   // Items the filter is sized for; default 100000
+	ErrorRate float64 // False-positive rate at Capacity items; default 0.01
+	BatchSize int     // Items per pipeline; default 1000
+}
+
+func (c Config) withDefaults() Config {
+	if c.Capacity <= 0 {
+		c.Capacity = 100000
+	}
+	if c.ErrorRate <= 0 || c.ErrorRate >= 1 {
+		c.ErrorRate = 0.01
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+	return c
+}
+
+// Optimal returns the number of bits and of hashes that keep the
+// false-positive rate of n items at p: m = -n ln p / (ln 2)^2, k = m/n ln 2
+func Optimal(n int64, p float64) (bits uint64, hashes int) {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	return uint64(m), max(int(k), 1)
+}
+
+// hasher maps items to positions by double hashing: the two halves of a
+// 128-bit FNV-1a hash give h1 + i*h2 for i < k
+type hasher struct {
+	size   uint64
+	hashes int
+}
+
+// positions returns the distinct positions of item
+func (h hasher) positions(item string) []uint64 {
+	f := fnv.New128a()
+	f.Write([]byte(item))
+	sum := f.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+	pos := make([]uint64, 0, h.hashes)
+next:
+	for i := 0; i < h.hashes; i++ {
+		p := (h1 + uint64(i)*h2) % h.size
+		for _, q := range pos {
+			if q == p {
+				continue next
+			}
+		}
+		pos = append(pos, p)
+	}
+	return pos
+}
+
+// createScript records the parameters of a filter unless the key already
+// has some, and returns those in effect: {type, size, hashes}
+var createScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('HSET', KEYS[1], unpack(ARGV))
+end
+return redis.call('HMGET', KEYS[1], 'type', 'size', 'hashes')
+`)
+
+// open creates or opens the parameters of a filter of the given type
+func open(ctx context.Context, rdb redis.UniversalClient, key, kind string, cfg Config, size uint64, hashes int) (hasher, error) {
+	reply, err := createScript.Run(ctx, rdb, []string{key + ":meta"},
+		"type", kind, "size", size, "hashes", hashes, "capacity", cfg.Capacity, "error_rate", cfg.ErrorRate).Slice()
+	if err != nil {
+		return hasher{}, fmt.Errorf("bloom: opening %s: %w", key, err)
+	}
+	if len(reply) != 3 || reply[0] != kind {
+		return hasher{}, fmt.Errorf("bloom: %s is not a %s filter", key, kind)
+	}
+	h := hasher{}
+	h.size, err = strconv.ParseUint(fmt.Sprint(reply[1]), 10, 64)
+	if err == nil {
+		h.hashes, err = strconv.Atoi(fmt.Sprint(reply[2]))
+	}
+	if err != nil || h.size == 0 || h.hashes <= 0 {
+		return hasher{}, fmt.Errorf("bloom: %s:meta is corrupt", key)
+	}
+	return h, nil
+}
+
+// Filter is a Bloom filter stored in a bitmap
+type Filter struct {
+	rdb   redis.UniversalClient
+	key   string
+	h     hasher
+	batch int
+}
+
+// New creates the filter at key, or opens the one already there
+func New(ctx context.Context, rdb redis.UniversalClient, key string, cfg Config) (*Filter, error) {
+	cfg = cfg.withDefaults()
+	bits, hashes := Optimal(cfg.Capacity, cfg.ErrorRate)
+	if bits > maxBits {
+		return nil, errors.New("bloom: filter would exceed 512MB; lower the capacity or raise the error rate")
+	}
+	h, err := open(ctx, rdb, key, "bloom", cfg, bits, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{rdb: rdb, key: key, h: h, batch: cfg.BatchSize}, nil
+}
+
+// Bits returns the size of the bitmap
+func (f *Filter) Bits() uint64 {
+	return f.h.size
+}
+
+// Hashes returns the number of bits each item sets
+func (f *Filter) Hashes() int {
+	return f.h.hashes
+}
+
+// Add adds items and reports, for each, whether it was new. False means it
+// was probably added before, or is a false positive.
+func (f *Filter) Add(ctx context.Context, items ...string) ([]bool, error) {
+	return eachBatch(ctx, f.rdb, f.batch, items, func(pipe redis.Pipeliner, item string) func() bool {
+		cmds := make([]*redis.IntCmd, 0, f.h.hashes)
+		for _, p := range f.h.positions(item) {
+			cmds = append(cmds, pipe.SetBit(ctx, f.key, int64(p), 1))
+		}
+		return func() bool { return anyZero(cmds) }
+	})
+}
+
+// Exists reports, for each item, whether it was probably added
+func (f *Filter) Exists(ctx context.Context, items ...string) ([]bool, error) {
+	return eachBatch(ctx, f.rdb, f.batch, items, func(pipe redis.Pipeliner, item string) func() bool {
+		cmds := make([]*redis.IntCmd, 0, f.h.hashes)
+		for _, p := range f.h.positions(item) {
+			cmds = append(cmds, pipe.GetBit(ctx, f.key, int64(p)))
+		}
+		return func() bool { return !anyZero(cmds) }
+	})
+}
+
+// Count estimates the number of items added from the bits set:
+// n = -m/k ln(1 - X/m)
+func (f *Filter) Count(ctx context.Context) (int64, error) {
+	set, err := f.rdb.BitCount(ctx, f.key, nil).Result()
+	if err != nil {
+		return 0, fmt.Errorf("bloom: %w", err)
+	}
+	m, k := float64(f.h.size), float64(f.h.hashes)
+	if float64(set) >= m {
+		return math.MaxInt64, nil
+	}
+	return int64(math.Round(-m / k * math.Log(1-float64(set)/m))), nil
+}
+
+// Delete removes the filter
+func (f *Filter) Delete(ctx context.Context) error {
+	return f.rdb.Del(ctx, f.key, f.key+":meta").Err()
+}
+
+// eachBatch queues the commands for each item with queue, sends them in
+// pipelines of batch items and collects what the functions queue returned
+// report once the replies are in
+func eachBatch(ctx context.Context, rdb redis.UniversalClient, batch int, items []string, queue func(redis.Pipeliner, string) func() bool) ([]bool, error) {
+	result := make([]bool, len(items))
+	for start := 0; start < len(items); start += batch {
+		end := min(start+batch, len(items))
+		pipe := rdb.Pipeline()
+		replies := make([]func() bool, 0, end-start)
+		for _, item := range items[start:end] {
+			replies = append(replies, queue(pipe, item))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("bloom: %w", err)
+		}
+		for i, reply := range replies {
+			result[start+i] = reply()
+		}
+	}
+	return result, nil
+}
+
+func anyZero(cmds []*redis.IntCmd) bool {
+	for _, c := range cmds {
+		if c.Val() == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Counting is a Bloom filter of 4-bit counters, so items can be removed.
+// It takes four times the memory of a Filter of the same size.
+type Counting struct {
+	rdb   redis.UniversalClient
+	key   string
+	h     hasher
+	batch int
+}
+
+// NewCounting creates the counting filter at key, or opens the one already
+// there
+func NewCounting(ctx context.Context, rdb redis.UniversalClient, key string, cfg Config) (*Counting, error) {
+	cfg = cfg.withDefaults()
+	counters, hashes := Optimal(cfg.Capacity, cfg.ErrorRate)
+	if counters*4 > maxBits {
+		return nil, errors.New("bloom: filter would exceed 512MB; lower the capacity or raise the error rate")
+	}
+	h, err := open(ctx, rdb, key, "counting", cfg, counters, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return &Counting{rdb: rdb, key: key, h: h, batch: cfg.BatchSize}, nil
+}
+
+// bitfield returns BITFIELD arguments applying op to each counter of item
+func (c *Counting) bitfield(item string, op ...interface{}) []interface{} {
+	var args []interface{}
+	for _, p := range c.h.positions(item) {
+		args = append(args, op[0], "u4", fmt.Sprintf("#%d", p))
+		args = append(args, op[1:]...)
+	}
+	return args
+}
+
+// Add adds items and reports, for each, whether it was new. Counters stop
+// at 15 rather than wrapping.
+func (c *Counting) Add(ctx context.Context, items ...string) ([]bool, error) {
+	return eachBatch(ctx, c.rdb, c.batch, items, func(pipe redis.Pipeliner, item string) func() bool {
+		args := append([]interface{}{"OVERFLOW", "SAT"}, c.bitfield(item, "INCRBY", 1)...)
+		cmd := pipe.BitField(ctx, c.key, args...)
+		return func() bool {
+			for _, v := range cmd.Val() {
+				if v == 1 {
+					return true
+				}
+			}
+			return false
+		}
+	})
+}
+
+// Exists reports, for each item, whether it was probably added
+func (c *Counting) Exists(ctx context.Context, items ...string) ([]bool, error) {
+	return eachBatch(ctx, c.rdb, c.batch, items, func(pipe redis.Pipeliner, item string) func() bool {
+		cmd := pipe.BitField(ctx, c.key, c.bitfield(item, "GET")...)
+		return func() bool {
+			counts := cmd.Val()
+			for _, v := range counts {
+				if v == 0 {
+					return false
+				}
+			}
+			return len(counts) > 0
+		}
+	})
+}
+
+// removeScript decrements the counters of one item, given as BITFIELD
+// offsets, unless one is zero and the item cannot have been added. Returns
+// 1 when removed.
+var removeScript = redis.NewScript(`
+local get = {}
+for _, off in ipairs(ARGV) do
+	get[#get + 1] = 'GET'
+	get[#get + 1] = 'u4'
+	get[#get + 1] = off
+end
+local counts = redis.call('BITFIELD', KEYS[1], unpack(get))
+local dec = {}
+for i, n in ipairs(counts) do
+	if n == 0 then
+		return 0
+	end
+	-- A saturated counter may stand for more adds than it holds, so it stays
+	if n < 15 then
+		dec[#dec + 1] = 'INCRBY'
+		dec[#dec + 1] = 'u4'
+		dec[#dec + 1] = ARGV[i]
+		dec[#dec + 1] = -1
+	end
+end
+if #dec > 0 then
+	redis.call('BITFIELD', KEYS[1], unpack(dec))
+end
+return 1
+`)
+
+// Remove removes items and reports, for each, whether it was found. Only
+// remove items that were added: removing a false positive decrements the
+// counters of other items and can make them disappear.
+func (c *Counting) Remove(ctx context.Context, items ...string) ([]bool, error) {
+	if err := removeScript.Load(ctx, c.rdb).Err(); err != nil {
+		return nil, fmt.Errorf("bloom: loading script: %w", err)
+	}
+	return eachBatch(ctx, c.rdb, c.batch, items, func(pipe redis.Pipeliner, item string) func() bool {
+		var offsets []interface{}
+		for _, p := range c.h.positions(item) {
+			offsets = append(offsets, fmt.Sprintf("#%d", p))
+		}
+		cmd := removeScript.EvalSha(ctx, pipe, []string{c.key}, offsets...)
+		return func() bool {
+			n, _ := cmd.Int()
+			return n == 1
+		}
+	})
+}
+
+// Delete removes the filter
+func (c *Counting) Delete(ctx context.Context) error {
+	return c.rdb.Del(ctx, c.key, c.key+":meta").Err()
+}
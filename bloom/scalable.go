@@ -0,0 +1,231 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ScalableConfig sizes a scalable filter
+type ScalableConfig struct {
+	Config             // Capacity of the first layer and the overall false-positive rate
+	Growth     int     // Capacity multiplier of each new layer; default 2
+	Tightening float64 // Ratio of the error rates of consecutive layers; default 0.5
+}
+
+// Scalable is a chain of Bloom filters. Items go into the newest layer until
+// it holds its capacity, then a layer with Growth times the capacity and
+// Tightening times the error rate is added. With layer i given the rate
+// p(1-r)r^i, the rates of all layers sum to less than p.
+//
+// Layers are stored at key:0, key:1 and so on, and key:meta holds how many
+// there are and how many items each holds. Processes adding concurrently can
+// put a few more items than its capacity into a layer.
+type Scalable struct {
+	rdb redis.UniversalClient
+	key string
+	cfg ScalableConfig
+
+	mu     sync.Mutex
+	layers []*Filter
+}
+
+// NewScalable creates the scalable filter at key, or opens the one already
+// there
+func NewScalable(ctx context.Context, rdb redis.UniversalClient, key string, cfg ScalableConfig) (*Scalable, error) {
+	cfg.Config = cfg.Config.withDefaults()
+	if cfg.Growth < 1 {
+		cfg.Growth = 2
+	}
+	if cfg.Tightening <= 0 || cfg.Tightening >= 1 {
+		cfg.Tightening = 0.5
+	}
+	s := &Scalable{rdb: rdb, key: key, cfg: cfg}
+	if err := rdb.HSetNX(ctx, s.metaKey(), "layers", 1).Err(); err != nil {
+		return nil, fmt.Errorf("bloom: opening %s: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sync(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Scalable) metaKey() string {
+	return s.key + ":meta"
+}
+
+// layerConfig returns the size of layer i
+func (s *Scalable) layerConfig(i int) Config {
+	cfg := s.cfg.Config
+	cfg.Capacity = int64(float64(cfg.Capacity) * math.Pow(float64(s.cfg.Growth), float64(i)))
+	cfg.ErrorRate = cfg.ErrorRate * (1 - s.cfg.Tightening) * math.Pow(s.cfg.Tightening, float64(i))
+	return cfg
+}
+
+// sync opens the layers other processes added
+func (s *Scalable) sync(ctx context.Context) error {
+	n, err := s.rdb.HGet(ctx, s.metaKey(), "layers").Int()
+	if err != nil {
+		return fmt.Errorf("bloom: reading %s: %w", s.metaKey(), err)
+	}
+	for i := len(s.layers); i < n; i++ {
+		f, err := New(ctx, s.rdb, s.key+":"+strconv.Itoa(i), s.layerConfig(i))
+		if err != nil {
+			return err
+		}
+		s.layers = append(s.layers, f)
+	}
+	return nil
+}
+
+// growScript adds a layer unless another process already did. ARGV[1] is
+// the number of layers the caller knows of.
+var growScript = redis.NewScript(`
+local n = tonumber(redis.call('HGET', KEYS[1], 'layers') or 0)
+if n == tonumber(ARGV[1]) then
+	n = redis.call('HINCRBY', KEYS[1], 'layers', 1)
+end
+return n
+`)
+
+// Add adds items and reports, for each, whether it was new. Items found in
+// any layer are not added again.
+func (s *Scalable) Add(ctx context.Context, items ...string) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found, err := s.exists(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]bool, len(items))
+	var pending []int
+	for i, ok := range found {
+		if !ok {
+			pending = append(pending, i)
+		}
+	}
+
+	for len(pending) > 0 {
+		last := len(s.layers) - 1
+		field := "count:" + strconv.Itoa(last)
+		count, err := s.rdb.HGet(ctx, s.metaKey(), field).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("bloom: %w", err)
+		}
+		room := s.layerConfig(last).Capacity - count
+		if room <= 0 {
+			if err := growScript.Run(ctx, s.rdb, []string{s.metaKey()}, len(s.layers)).Err(); err != nil {
+				return nil, fmt.Errorf("bloom: adding a layer: %w", err)
+			}
+			if err := s.sync(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		take := pending[:min(int(room), len(pending))]
+		batch := make([]string, len(take))
+		for i, idx := range take {
+			batch[i] = items[idx]
+		}
+		isNew, err := s.layers[last].Add(ctx, batch...)
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		for i, idx := range take {
+			if isNew[i] {
+				added[idx] = true
+				n++
+			}
+		}
+		if err := s.rdb.HIncrBy(ctx, s.metaKey(), field, n).Err(); err != nil {
+			return nil, fmt.Errorf("bloom: %w", err)
+		}
+		pending = pending[len(take):]
+	}
+	return added, nil
+}
+
+// Exists reports, for each item, whether it was probably added
+func (s *Scalable) Exists(ctx context.Context, items ...string) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exists(ctx, items)
+}
+
+func (s *Scalable) exists(ctx context.Context, items []string) ([]bool, error) {
+	if err := s.sync(ctx); err != nil {
+		return nil, err
+	}
+	found := make([]bool, len(items))
+	// Newest layers hold the most items, so check them first
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		var rest []int
+		var batch []string
+		for j, ok := range found {
+			if !ok {
+				rest = append(rest, j)
+				batch = append(batch, items[j])
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+		in, err := s.layers[i].Exists(ctx, batch...)
+		if err != nil {
+			return nil, err
+		}
+		for k, j := range rest {
+			found[j] = in[k]
+		}
+	}
+	return found, nil
+}
+
+// Layers returns the number of layers
+func (s *Scalable) Layers(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sync(ctx); err != nil {
+		return 0, err
+	}
+	return len(s.layers), nil
+}
+
+// Count returns the number of items added, counting false positives at the
+// time of adding as already present
+func (s *Scalable) Count(ctx context.Context) (int64, error) {
+	meta, err := s.rdb.HGetAll(ctx, s.metaKey()).Result()
+	if err != nil {
+		return 0, fmt.Errorf("bloom: %w", err)
+	}
+	layers, _ := strconv.Atoi(meta["layers"])
+	var total int64
+	for i := 0; i < layers; i++ {
+		n, _ := strconv.ParseInt(meta["count:"+strconv.Itoa(i)], 10, 64)
+		total += n
+	}
+	return total, nil
+}
+
+// Delete removes every layer
+func (s *Scalable) Delete(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+	keys := []string{s.metaKey()}
+	for _, f := range s.layers {
+		keys = append(keys, f.key, f.key+":meta")
+	}
+	s.layers = nil
+	return s.rdb.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,212 @@
+package capture
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"Redis/keyspec"
+	"Redis/tracing"
+)
+
+// Kind classifies a command by its effect on the dataset
+type Kind int
+
+const (
+	OtherCommand Kind = iota
+	ReadCommand
+	WriteCommand
+)
+
+// Classify returns whether the named command reads, writes or neither, by
+// its flags in the built-in command table. A capture is often analyzed
+// away from the server it was taken on.
+func Classify(name string) Kind {
+	c, ok := keyspec.Static().Lookup([]string{name})
+	if !ok {
+		return OtherCommand
+	}
+	for _, f := range c.Flags {
+		switch f {
+		case "write":
+			return WriteCommand
+		case "readonly":
+			return ReadCommand
+		}
+	}
+	return OtherCommand
+}
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+// Prefix groups a key by its first segment, "user:1001:cart" becoming
+// "user:*". Keys without the separator are returned as they are.
+func Prefix(key, sep string) string {
+	if i := strings.Index(key, sep); sep != "" && i >= 0 {
+		return key[:i+len(sep)] + "*"
+	}
+	return key
+}
+
+// Count is one row of a top-N table
+type Count struct {
+	Name    string
+	Count   int64
+	Percent float64 // Share of the total
+}
+
+// ClientStats summarises one client's traffic
+type ClientStats struct {
+	Client   string
+	Commands int64
+	PerSec   float64
+	Reads    int64
+	Writes   int64
+	Top      string // Most frequent command
+}
+
+// Report summarises a capture
+type Report struct {
+	Start, End     time.Time
+	Duration       time.Duration
+	Total          int64
+	PerSec         float64
+	Reads          int64
+	Writes         int64
+	Other          int64
+	ReadWriteRatio float64 // Reads per write; 0 when there were no writes
+	Commands       []Count
+	Prefixes       []Count // Key accesses by prefix
+	Clients        []ClientStats
+}
+
+// Analyzer aggregates entries into a Report
+type Analyzer struct {
+	sep        string
+	start, end time.Time
+	total      int64
+	kinds      [3]int64
+	keys       int64
+	commands   map[string]int64
+	prefixes   map[string]int64
+	clients    map[string]*clientCounts
+}
+
+type clientCounts struct {
+	kinds    [3]int64
+	commands map[string]int64
+}
+
+// NewAnalyzer returns an analyzer that groups keys on sep (usually ":")
+func NewAnalyzer(sep string) *Analyzer {
+	return &Analyzer{
+		sep:      sep,
+		commands: make(map[string]int64),
+		prefixes: make(map[string]int64),
+		clients:  make(map[string]*clientCounts),
+	}
+}
+
+// Add counts one entry
+func (a *Analyzer) Add(e Entry) {
+	if len(e.Args) == 0 {
+		return
+	}
+	if a.start.IsZero() || e.Time.Before(a.start) {
+		a.start = e.Time
+	}
+	if e.Time.After(a.end) {
+		a.end = e.Time
+	}
+
+	name := e.Name()
+	kind := Classify(name)
+	a.total++
+	a.kinds[kind]++
+	a.commands[name]++
+	for _, key := range tracing.Keys(e.Args) {
+		a.keys++
+		a.prefixes[Prefix(key, a.sep)]++
+	}
+
+	c := a.clients[e.Client]
+	if c == nil {
+		c = &clientCounts{commands: make(map[string]int64)}
+		a.clients[e.Client] = c
+	}
+	c.kinds[kind]++
+	c.commands[name]++
+}
+
+// Report returns the summary so far with at most top rows per table; 0 means
+// no limit
+func (a *Analyzer) Report(top int) *Report {
+	r := &Report{
+		Start:  a.start,
+		End:    a.end,
+		Total:  a.total,
+		Reads:  a.kinds[ReadCommand],
+		Writes: a.kinds[WriteCommand],
+		Other:  a.kinds[OtherCommand],
+	}
+	r.Duration = a.end.Sub(a.start)
+	secs := r.Duration.Seconds()
+	if secs > 0 {
+		r.PerSec = float64(a.total) / secs
+	}
+	if r.Writes > 0 {
+		r.ReadWriteRatio = float64(r.Reads) / float64(r.Writes)
+	}
+	r.Commands = topCounts(a.commands, a.total, top)
+	r.Prefixes = topCounts(a.prefixes, a.keys, top)
+
+	for client, c := range a.clients {
+		cs := ClientStats{Client: client, Reads: c.kinds[ReadCommand], Writes: c.kinds[WriteCommand]}
+		cs.Commands = c.kinds[ReadCommand] + c.kinds[WriteCommand] + c.kinds[OtherCommand]
+		if secs > 0 {
+			cs.PerSec = float64(cs.Commands) / secs
+		}
+		if most := topCounts(c.commands, cs.Commands, 1); len(most) > 0 {
+			cs.Top = most[0].Name
+		}
+		r.Clients = append(r.Clients, cs)
+	}
+	sort.Slice(r.Clients, func(i, j int) bool {
+		if r.Clients[i].Commands != r.Clients[j].Commands {
+			return r.Clients[i].Commands > r.Clients[j].Commands
+		}
+		return r.Clients[i].Client < r.Clients[j].Client
+	})
+	if top > 0 && len(r.Clients) > top {
+		r.Clients = r.Clients[:top]
+	}
+	return r
+}
+
+// topCounts sorts counts by value, ties by name, and keeps the first n
+func topCounts(counts map[string]int64, total int64, n int) []Count {
+	rows := make([]Count, 0, len(counts))
+	for name, c := range counts {
+		row := Count{Name: name, Count: c}
+		if total > 0 {
+			row.Percent = float64(c) / float64(total) * 100
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	if n > 0 && len(rows) > n {
+		rows = rows[:n]
+	}
+	return rows
+}
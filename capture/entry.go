@@ -0,0 +1,197 @@
+// Package capture records the output of MONITOR, parses it into commands,
+// summarises the traffic and replays it against another server.
+//
+// Capture files use the line format MONITOR itself produces, so the output
+// of `redis-cli monitor > capture.log` can be analyzed and replayed as well:
+//
+//	1700000000.123456 [0 127.0.0.1:52344] "set" "user:1001" "Alice"
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is one command seen by MONITOR
+type Entry struct {
+	Time   time.Time
+	DB     int
+	Client string // ip:port, unix:path, or "lua" for commands run by a script
+	Args   []string
+}
+
+// Name returns the lowercased command name
+func (e Entry) Name() string {
+	if len(e.Args) == 0 {
+		return ""
+	}
+	return strings.ToLower(e.Args[0])
+}
+
+// String formats e as a MONITOR line
+func (e Entry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d.%06d [%d %s]", e.Time.Unix(), e.Time.Nanosecond()/1000, e.DB, e.Client)
+	for _, a := range e.Args {
+		sb.WriteByte(' ')
+		quote(&sb, a)
+	}
+	return sb.String()
+}
+
+// ParseLine parses one line of MONITOR output
+func ParseLine(line string) (Entry, error) {
+	var e Entry
+	ts, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return e, fmt.Errorf("missing timestamp in %q", line)
+	}
+	secs, frac, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return e, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	var usec int64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		if usec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return e, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+	e.Time = time.Unix(sec, usec*1000)
+
+	// IPv6 clients are bracketed too ("[0 [::1]:52344]"), so look for the
+	// bracket that is followed by the first argument
+	end := strings.Index(rest, `] "`)
+	if !strings.HasPrefix(rest, "[") || end < 0 {
+		return e, fmt.Errorf("missing [db client] in %q", line)
+	}
+	db, client, ok := strings.Cut(rest[1:end], " ")
+	if !ok {
+		return e, fmt.Errorf("missing client in %q", line)
+	}
+	if e.DB, err = strconv.Atoi(db); err != nil {
+		return e, fmt.Errorf("invalid db %q", db)
+	}
+	e.Client = client
+
+	e.Args, err = unquoteArgs(rest[end+2:])
+	if err != nil {
+		return e, fmt.Errorf("%v in %q", err, line)
+	}
+	return e, nil
+}
+
+// Read parses MONITOR lines from r and calls fn for each entry. The "OK"
+// reply that redis-cli prints first and blank lines are skipped.
+func Read(r io.Reader, fn func(Entry) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" || line == "OK" {
+			continue
+		}
+		e, err := ParseLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// unquoteArgs splits the space-separated, double-quoted arguments
+func unquoteArgs(s string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(s); {
+		if s[i] == ' ' {
+			i++
+			continue
+		}
+		if s[i] != '"' {
+			return nil, fmt.Errorf("expected quoted argument at offset %d", i)
+		}
+		var sb strings.Builder
+		i++
+		for {
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated argument")
+			}
+			c := s[i]
+			if c == '"' {
+				i++
+				break
+			}
+			if c != '\\' || i+1 >= len(s) {
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			switch s[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'a':
+				sb.WriteByte('\a')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'x':
+				if i+3 >= len(s) {
+					return nil, fmt.Errorf("short \\x escape")
+				}
+				b, err := strconv.ParseUint(s[i+2:i+4], 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("invalid escape \\x%s", s[i+2:i+4])
+				}
+				sb.WriteByte(byte(b))
+				i += 2
+			default:
+				sb.WriteByte(s[i+1])
+			}
+			i += 2
+		}
+		args = append(args, sb.String())
+	}
+	return args, nil
+}
+
+// quote escapes a the way the server does for MONITOR (sdscatrepr)
+func quote(sb *strings.Builder, a string) {
+	sb.WriteByte('"')
+	for i := 0; i < len(a); i++ {
+		switch c := a[i]; c {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if c >= 0x20 && c < 0x7f {
+				sb.WriteByte(c)
+			} else {
+				fmt.Fprintf(sb, `\x%02x`, c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Record runs MONITOR on a dedicated connection to the server rdb points at
+// and calls fn for every command until ctx is done. The connection is dialed
+// outside the pool because a monitoring connection cannot run other commands.
+//
+// MONITOR costs the server throughput while it runs; keep captures bounded.
+func Record(ctx context.Context, rdb *redis.Client, fn func(Entry) error) error {
+	opt := rdb.Options()
+	var conn net.Conn
+	var err error
+	// redis.NewClient fills in a dialer that already speaks TLS when
+	// TLSConfig is set; the other cases cover options without one
+	d := &net.Dialer{Timeout: opt.DialTimeout}
+	switch {
+	case opt.Dialer != nil:
+		conn, err = opt.Dialer(ctx, opt.Network, opt.Addr)
+	case opt.TLSConfig != nil:
+		conn, err = (&tls.Dialer{NetDialer: d, Config: opt.TLSConfig}).DialContext(ctx, "tcp", opt.Addr)
+	default:
+		conn, err = d.DialContext(ctx, "tcp", opt.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Closing the connection unblocks the read below
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	rd := bufio.NewReader(conn)
+	if opt.Password != "" {
+		auth := []string{"AUTH", opt.Password}
+		if opt.Username != "" {
+			auth = []string{"AUTH", opt.Username, opt.Password}
+		}
+		if err := call(conn, rd, auth...); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := call(conn, rd, "MONITOR"); err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+
+	for {
+		line, err := readLine(rd)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		e, err := ParseLine(line)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// call sends a command and expects a simple string reply
+func call(conn net.Conn, rd *bufio.Reader, args ...string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := conn.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	_, err := readLine(rd)
+	return err
+}
+
+// readLine reads one simple string reply, turning error replies into errors
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch {
+	case strings.HasPrefix(line, "+"):
+		return line[1:], nil
+	case strings.HasPrefix(line, "-"):
+		return "", errors.New(line[1:])
+	}
+	return "", fmt.Errorf("unexpected reply %q", line)
+}
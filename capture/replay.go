@@ -0,0 +1,211 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ReplayOptions controls Replay
+type ReplayOptions struct {
+	// Speed scales the gaps between commands: 1 keeps the captured pace, 2
+	// replays twice as fast. 0 sends every command as soon as the previous
+	// one completes.
+	Speed float64
+}
+
+// ReplayStats summarises a replay
+type ReplayStats struct {
+	Commands     int64         // Commands sent, including those inside transactions
+	Transactions int64         // MULTI/EXEC blocks sent
+	Skipped      int64         // Commands not replayed (see Replay)
+	Discarded    int64         // Commands in transactions that were discarded or never executed
+	Errors       int64         // Error replies; the replay continues past them
+	FirstErr     error         // First error reply
+	MaxLag       time.Duration // Furthest the replay fell behind the captured schedule
+}
+
+// notReplayed are commands that change connection state, block, or cannot
+// be reproduced from a shared connection. SELECT is applied from Entry.DB
+// instead.
+var notReplayed = set(
+	"auth", "hello", "client", "select", "reset", "quit", "monitor", "sync", "psync", "replconf",
+	"readonly", "readwrite", "asking", "shutdown", "debug", "wait", "waitaof", "watch", "unwatch",
+	"subscribe", "psubscribe", "ssubscribe", "unsubscribe", "punsubscribe", "sunsubscribe",
+	"blpop", "brpop", "brpoplpush", "blmove", "blmpop", "bzpopmin", "bzpopmax", "bzmpop",
+)
+
+// Replay reads a capture from r and sends it to rdb on a single connection,
+// in captured order and, unless opts.Speed is 0, at the captured pace.
+//
+// Commands run by Lua scripts are skipped because the EVAL that ran them is
+// replayed, as are connection-state, pub/sub and blocking commands. XREAD
+// and XREADGROUP lose their BLOCK option. Each client's MULTI block is held
+// back until its EXEC and sent as one transaction, so commands from other
+// clients cannot interleave with it. Error replies are counted, not fatal.
+func Replay(ctx context.Context, rdb *redis.Client, r io.Reader, opts ReplayOptions) (*ReplayStats, error) {
+	conn := rdb.Conn()
+	defer conn.Close()
+
+	rp := &replayer{ctx: ctx, conn: conn, db: rdb.Options().DB, speed: opts.Speed, stats: &ReplayStats{}, tx: make(map[string][]Entry)}
+	err := Read(r, rp.add)
+	for _, block := range rp.tx {
+		rp.stats.Discarded += int64(len(block))
+	}
+	return rp.stats, err
+}
+
+// replayer paces commands and holds back open transactions per client
+type replayer struct {
+	ctx   context.Context
+	conn  *redis.Conn
+	db    int
+	speed float64
+	stats *ReplayStats
+
+	first time.Time // Capture time of the first command sent
+	start time.Time // Wall time it was sent
+
+	tx map[string][]Entry // Open MULTI blocks by client
+}
+
+func (rp *replayer) add(e Entry) error {
+	if err := rp.ctx.Err(); err != nil {
+		return err
+	}
+	name := e.Name()
+	if e.Client == "lua" || notReplayed[name] {
+		rp.stats.Skipped++
+		return nil
+	}
+	if name == "xread" || name == "xreadgroup" {
+		e.Args = dropBlock(e.Args)
+	}
+
+	block, open := rp.tx[e.Client]
+	switch {
+	case name == "multi":
+		rp.stats.Discarded += int64(len(block))
+		rp.tx[e.Client] = []Entry{}
+		return nil
+	case name == "discard" && open:
+		rp.stats.Discarded += int64(len(block))
+		delete(rp.tx, e.Client)
+		return nil
+	case name == "exec" && open:
+		delete(rp.tx, e.Client)
+		if err := rp.wait(e.Time); err != nil {
+			return err
+		}
+		return rp.send(block, true)
+	case name == "exec" || name == "discard":
+		// The MULTI happened before the capture started
+		rp.stats.Skipped++
+		return nil
+	case open:
+		rp.tx[e.Client] = append(block, e)
+		return nil
+	}
+
+	if err := rp.wait(e.Time); err != nil {
+		return err
+	}
+	return rp.send([]Entry{e}, false)
+}
+
+// wait sleeps until the scaled capture time of t
+func (rp *replayer) wait(t time.Time) error {
+	if rp.speed <= 0 {
+		return nil
+	}
+	if rp.start.IsZero() {
+		rp.first, rp.start = t, time.Now()
+		return nil
+	}
+	due := rp.start.Add(time.Duration(float64(t.Sub(rp.first)) / rp.speed))
+	d := time.Until(due)
+	if d <= 0 {
+		rp.stats.MaxLag = max(rp.stats.MaxLag, -d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-rp.ctx.Done():
+		return rp.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// send runs the entries as a pipeline or a transaction, selecting each
+// entry's database first
+func (rp *replayer) send(block []Entry, tx bool) error {
+	if len(block) == 0 {
+		return nil
+	}
+	pipe := rp.conn.Pipeline()
+	if tx {
+		pipe = rp.conn.TxPipeline()
+	}
+	var sent []redis.Cmder
+	for _, e := range block {
+		if e.DB != rp.db {
+			pipe.Select(rp.ctx, e.DB)
+			rp.db = e.DB
+		}
+		args := make([]interface{}, len(e.Args))
+		for i, a := range e.Args {
+			args[i] = a
+		}
+		sent = append(sent, pipe.Do(rp.ctx, args...))
+	}
+	// Error replies are counted per command below; any other error, like a
+	// dropped connection, is not copied onto the commands
+	var rerr redis.Error
+	if _, err := pipe.Exec(rp.ctx); err != nil && !errors.As(err, &rerr) {
+		return err
+	}
+
+	rp.stats.Commands += int64(len(block))
+	if tx {
+		rp.stats.Transactions++
+	}
+	for _, cmd := range sent {
+		err := cmd.Err()
+		if err == nil || err == redis.Nil {
+			continue
+		}
+		var rerr redis.Error
+		if !errors.As(err, &rerr) {
+			// Not a reply from the server: the connection is gone
+			return err
+		}
+		rp.stats.Errors++
+		if rp.stats.FirstErr == nil {
+			rp.stats.FirstErr = err
+		}
+	}
+	return nil
+}
+
+// dropBlock removes the BLOCK option so a replayed read cannot stall the
+// connection
+func dropBlock(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if strings.EqualFold(args[i], "streams") {
+			return append(out, args[i:]...)
+		}
+		if strings.EqualFold(args[i], "block") && i+1 < len(args) {
+			i++
+			continue
+		}
+		out = append(out, args[i])
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"Redis/acl"
+)
+
+func runACL(ctx context.Context, args []string) error {
+	actions := map[string]func(context.Context, []string) error{
+		"apply":  runACLApply,
+		"verify": runACLVerify,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: redisctl acl <apply|verify> [flags] [acl.yaml]")
+		return errors.New("expected apply or verify")
+	}
+	return actions[args[0]](ctx, args[1:])
+}
+
+// aclSpec loads the positional spec file, defaulting to the project's
+func aclSpec(fs *flag.FlagSet) (*acl.Spec, error) {
+	path := "scripts/acl.yaml"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path = fs.Arg(0)
+	default:
+		fs.Usage()
+		return nil, errors.New("expected at most one spec file")
+	}
+	return acl.LoadSpec(path)
+}
+
+func runACLApply(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("acl apply", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "print the ACL SETUSER commands without sending them")
+	prune := fs.Bool("prune", false, "delete users that are not in the file (never default)")
+	save := fs.Bool("save", false, "persist with ACL SAVE, or CONFIG REWRITE without an aclfile")
+	fs.Parse(args)
+
+	spec, err := aclSpec(fs)
+	if err != nil {
+		return err
+	}
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	changes, err := acl.Apply(ctx, rdb, spec, acl.ApplyOptions{Prune: *prune, DryRun: *dryRun, Save: *save})
+	for _, c := range changes {
+		if c.Action == "delete" {
+			fmt.Printf("%-6s %s\n", c.Action, c.User)
+			continue
+		}
+		fmt.Printf("%-6s ACL SETUSER %s %s\n", c.Action, c.User, strings.Join(c.Rules, " "))
+	}
+	if err == nil && !*dryRun && !*save {
+		fmt.Fprintln(os.Stderr, "Users live in memory until saved; rerun with -save to keep them across restarts")
+	}
+	return err
+}
+
+func runACLVerify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("acl verify", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	fs.Parse(args)
+
+	spec, err := aclSpec(fs)
+	if err != nil {
+		return err
+	}
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	results, err := acl.Verify(ctx, rdb, spec)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tUSER\tEXPECT\tCOMMAND\tSERVER")
+	for _, r := range results {
+		result, expect, server := "ok", "deny", "denied: "+r.Reason
+		if !r.OK() {
+			result = "FAIL"
+			failed++
+		}
+		if r.Want {
+			expect = "allow"
+		}
+		if r.Allowed {
+			server = "allowed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result, r.User, expect, r.Command, server)
+	}
+	tw.Flush()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"Redis/analytics"
+)
+
+func runAnalytics(ctx context.Context, args []string) error {
+	actions := map[string]func(context.Context, []string) error{
+		"run":    runAnalyticsRun,
+		"report": runAnalyticsReport,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: redisctl analytics <run|report> [flags]")
+		return errors.New("expected run or report")
+	}
+	return actions[args[0]](ctx, args[1:])
+}
+
+// addAnalyticsFlags registers the flags that locate the analytics keys
+func addAnalyticsFlags(fs *flag.FlagSet) *analytics.Config {
+	cfg := &analytics.Config{}
+	fs.StringVar(&cfg.Prefix, "prefix", "analytics:", "key prefix of the counters")
+	fs.StringVar(&cfg.Stream, "stream", "events", "stream of events with user_id and event_type fields")
+	return cfg
+}
+
+func runAnalyticsRun(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("analytics run", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	cfg := addAnalyticsFlags(fs)
+	fs.StringVar(&cfg.Group, "group", "analytics", "consumer group")
+	fs.StringVar(&cfg.Consumer, "consumer", "", "consumer name; default host-pid")
+	keep := fs.Bool("keep", false, "keep counters for ever rather than 8 days hourly and 400 days daily, e.g. for the seed data's 2024 events")
+	fs.Parse(args)
+	if *keep {
+		cfg.HourlyRetention, cfg.DailyRetention = -1, -1
+	}
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	a := analytics.New(rdb, *cfg)
+	last := time.Now()
+	err = a.Run(ctx, func(total int) {
+		if time.Since(last) >= time.Second {
+			fmt.Printf("%d events recorded\n", total)
+			last = time.Now()
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func runAnalyticsReport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("analytics report", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	cfg := addAnalyticsFlags(fs)
+	dayFlag := fs.String("day", "", "day to report on, YYYY-MM-DD; default today (UTC)")
+	cohorts := fs.Int("cohorts", 7, "new-user cohorts to show retention for, ending on -day")
+	var funnel listFlag
+	fs.Var(&funnel, "funnel", "event type of the next funnel step over the last 7 days (repeatable)")
+	fs.Parse(args)
+
+	day := time.Now().UTC()
+	if *dayFlag != "" {
+		var err error
+		if day, err = time.Parse("2006-01-02", *dayFlag); err != nil {
+			return fmt.Errorf("invalid -day %q: %w", *dayFlag, err)
+		}
+	}
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+	a := analytics.New(rdb, *cfg)
+
+	dau, err := a.DAU(ctx, day)
+	if err != nil {
+		return err
+	}
+	wau, _ := a.WAU(ctx, day)
+	mau, _ := a.MAU(ctx, day)
+	fmt.Printf("%s  DAU %d  WAU %d  MAU %d\n", day.Format("2006-01-02"), dau, wau, mau)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nCOHORT\tUSERS\tD1\tD2\tD3\tD7")
+	for i := *cohorts - 1; i >= 0; i-- {
+		c, err := a.Retention(ctx, day.AddDate(0, 0, -i), 7)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d", c.Day.Format("2006-01-02"), c.Users)
+		for _, n := range []int{1, 2, 3, 7} {
+			// Later days have not happened yet
+			if c.Day.AddDate(0, 0, n).After(day) {
+				fmt.Fprint(w, "\t-")
+			} else {
+				fmt.Fprintf(w, "\t%.1f%%", 100*c.Rate(n))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(funnel) > 0 {
+		steps, err := a.Funnel(ctx, day.AddDate(0, 0, -6), day, funnel...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "\nSTEP\tUSERS\tCONVERSION\tFROM PREVIOUS")
+		for _, s := range steps {
+			fmt.Fprintf(w, "%s\t%d\t%.1f%%\t%.1f%%\n", s.Event, s.Users, 100*s.Conversion, 100*s.StepConversion)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"Redis/aof"
+	"Redis/keyspec"
+)
+
+func runAOF(ctx context.Context, args []string) error {
+	actions := map[string]func(context.Context, []string) error{
+		"print":  runAOFPrint,
+		"check":  runAOFCheck,
+		"replay": runAOFReplay,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: redisctl aof <print|check|replay> [flags] <appendonlydir|manifest|file.aof>")
+		return errors.New("expected print, check or replay")
+	}
+	return actions[args[0]](ctx, args[1:])
+}
+
+// addFilterFlags registers the point-in-time and key filter flags
+func addFilterFlags(fs *flag.FlagSet) func() (aof.Filter, error) {
+	until := fs.String("until", "", "stop at this time (RFC 3339 or unix seconds); needs aof-timestamp-enabled")
+	untilIndex := fs.Int64("until-index", 0, "stop before the command with this index (0 for no limit)")
+	var patterns listFlag
+	fs.Var(&patterns, "match", "only keep commands on keys matching this glob pattern (repeatable)")
+
+	return func() (aof.Filter, error) {
+		f := aof.Filter{UntilIndex: *untilIndex, Patterns: patterns}
+		if *until != "" {
+			t, err := parseTime(*until)
+			if err != nil {
+				return f, err
+			}
+			f.Until = t
+		}
+		return f, nil
+	}
+}
+
+// parseTime accepts RFC 3339 or unix seconds
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q: use RFC 3339 or unix seconds", s)
+	}
+	return t, nil
+}
+
+// aofFiles resolves the single positional argument to the files to read
+func aofFiles(fs *flag.FlagSet) ([]string, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, errors.New("expected an appendonlydir, manifest or AOF file")
+	}
+	return aof.Files(fs.Arg(0))
+}
+
+func runAOFPrint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("aof print", flag.ExitOnError)
+	filter := addFilterFlags(fs)
+	output := fs.String("o", "-", "output file, - for stdout")
+	fs.Parse(args)
+
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+	files, err := aofFiles(fs)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	err = aof.Read(files, func(c *aof.Command) error {
+		if f.Done(c) {
+			return io.EOF
+		}
+		kept := f.Apply(c)
+		if kept == nil {
+			return ctx.Err()
+		}
+		ts := "-"
+		if !c.Time.IsZero() {
+			ts = c.Time.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\tdb%d\t%s\n", c.Index, ts, c.DB, quoteArgs(kept))
+		return ctx.Err()
+	})
+	if errors.Is(err, aof.ErrTruncated) {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return nil
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func runAOFCheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("aof check", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "cut a truncated tail or unterminated MULTI off the last file")
+	fs.Parse(args)
+
+	files, err := aofFiles(fs)
+	if err != nil {
+		return err
+	}
+	res, err := aof.Check(files)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range res.Files {
+		fmt.Println("File:", f)
+	}
+	fmt.Println("Commands:", res.Commands)
+	if !res.First.IsZero() {
+		fmt.Printf("Timestamps: %s .. %s\n", res.First.Format(time.RFC3339), res.Last.Format(time.RFC3339))
+	}
+	if res.Truncated == nil {
+		fmt.Println("Status: OK")
+		return nil
+	}
+
+	fmt.Println("Status:", res.Truncated)
+	if !*fix {
+		return errors.New("log has a damaged tail, rerun with -fix to truncate it")
+	}
+	if err := aof.Fix(res.Truncated); err != nil {
+		return err
+	}
+	fmt.Printf("Truncated %s to %d bytes\n", res.Truncated.File, res.Truncated.Offset)
+	return nil
+}
+
+func runAOFReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("aof replay", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	filter := addFilterFlags(fs)
+	batch := fs.Int("batch", 500, "commands per pipeline round trip")
+	fs.Parse(args)
+
+	f, err := filter()
+	if err != nil {
+		return err
+	}
+	files, err := aofFiles(fs)
+	if err != nil {
+		return err
+	}
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	// The target's command table also knows its modules' commands
+	if len(f.Patterns) > 0 {
+		if f.Keys, err = keyspec.Load(ctx, rdb); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, using the built-in command table\n", err)
+		}
+	}
+
+	stats, err := aof.Replay(ctx, rdb, files, aof.ReplayOptions{Filter: f, BatchSize: *batch})
+	fmt.Fprintf(os.Stderr, "Replayed %d commands, skipped %d, discarded %d from an open transaction\n",
+		stats.Commands, stats.Skipped, stats.Discarded)
+	if stats.LastIndex >= 0 {
+		ts := "no timestamp"
+		if !stats.LastTime.IsZero() {
+			ts = stats.LastTime.Format(time.RFC3339)
+		}
+		fmt.Fprintf(os.Stderr, "Last command: index %d (%s)\n", stats.LastIndex, ts)
+	}
+	if stats.Truncated != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", stats.Truncated)
+	}
+	return err
+}
+
+// quoteArgs renders a command the way redis-cli would accept it
+func quoteArgs(args []string) string {
+	var sb strings.Builder
+	for i, a := range args {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if a == "" || strings.ContainsAny(a, " \t\r\n\"'\\") || !isPrintable(a) {
+			sb.WriteString(strconv.Quote(a))
+		} else {
+			sb.WriteString(a)
+		}
+	}
+	return sb.String()
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !strconv.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
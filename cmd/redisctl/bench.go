@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"Redis/benchrun"
+)
+
+func runBench(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	pattern := fs.String("bench", ".", "benchmarks to run (regexp passed to go test -bench)")
+	pkg := fs.String("pkg", "./tests", "package holding the benchmarks")
+	count := fs.Int("count", 1, "run each benchmark this many times and average the results")
+	benchtime := fs.String("benchtime", "", "go test -benchtime value, e.g. 2s or 500x")
+	input := fs.String("input", "", "parse this saved go test -bench output instead of running the suite")
+	output := fs.String("o", "", "write the results as JSON to this file")
+	baseline := fs.String("baseline", "", "compare against results saved with -o")
+	threshold := fs.Float64("threshold", 10, "percent slowdown that counts as a regression")
+	failOnRegression := fs.Bool("fail", false, "exit with an error when a benchmark regresses")
+	fs.Parse(args)
+
+	var raw []byte
+	var err error
+	if *input != "" {
+		raw, err = os.ReadFile(*input)
+	} else {
+		raw, err = goTestBench(ctx, *pkg, *pattern, *benchtime, *count)
+	}
+	if err != nil {
+		return err
+	}
+
+	run, err := benchrun.Parse(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Parsed %d benchmarks\n", len(run.Benchmarks))
+
+	if *output != "" {
+		if err := run.Save(*output); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Results written to %s\n", *output)
+	}
+
+	if *baseline == "" {
+		return nil
+	}
+	base, err := benchrun.Load(*baseline)
+	if err != nil {
+		return err
+	}
+	deltas := benchrun.Compare(base, run, *threshold)
+	if err := benchrun.WriteTable(os.Stdout, deltas); err != nil {
+		return err
+	}
+
+	n := benchrun.Regressions(deltas)
+	fmt.Printf("\n%d regression(s) above %.0f%%\n", n, *threshold)
+	if n > 0 && *failOnRegression {
+		return fmt.Errorf("%d benchmark(s) regressed", n)
+	}
+	return nil
+}
+
+// goTestBench runs the benchmark suite, echoing its output to stderr
+func goTestBench(ctx context.Context, pkg, pattern, benchtime string, count int) ([]byte, error) {
+	args := []string{"test", pkg, "-run", "^$", "-bench", pattern, "-benchmem", "-count", fmt.Sprint(count)}
+	if benchtime != "" {
+		args = append(args, "-benchtime", benchtime)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Stdout = io.MultiWriter(&out, os.Stderr)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go %v: %w", args, err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"Redis/capture"
+)
+
+func runCapture(ctx context.Context, args []string) error {
+	actions := map[string]func(context.Context, []string) error{
+		"record":  runCaptureRecord,
+		"analyze": runCaptureAnalyze,
+		"replay":  runCaptureReplay,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: redisctl capture <record|analyze|replay> [flags] [capture.log]")
+		return errors.New("expected record, analyze or replay")
+	}
+	return actions[args[0]](ctx, args[1:])
+}
+
+// addReportFlags registers the flags that shape an analysis report
+func addReportFlags(fs *flag.FlagSet) (top *int, sep *string, asJSON *bool) {
+	top = fs.Int("top", 10, "rows per table")
+	sep = fs.String("sep", ":", "key prefix separator")
+	asJSON = fs.Bool("json", false, "print the report as JSON")
+	return
+}
+
+func runCaptureRecord(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("capture record", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	duration := fs.Duration("d", 30*time.Second, "how long to capture")
+	out := fs.String("o", "capture.log", "capture file (- for stdout)")
+	report := fs.Bool("report", true, "print an analysis when the capture ends")
+	top, sep, asJSON := addReportFlags(fs)
+	fs.Parse(args)
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	w := os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	fmt.Fprintf(os.Stderr, "Capturing MONITOR output from %s for %v...\n", conn.addr, *duration)
+
+	analyzer := capture.NewAnalyzer(*sep)
+	var n int64
+	err = capture.Record(ctx, rdb, func(e capture.Entry) error {
+		n++
+		analyzer.Add(e)
+		_, err := fmt.Fprintln(bw, e)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Captured %d commands to %s\n", n, *out)
+
+	if *report && *out != "-" {
+		return printCaptureReport(os.Stdout, analyzer.Report(*top), *asJSON)
+	}
+	return nil
+}
+
+func runCaptureAnalyze(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("capture analyze", flag.ExitOnError)
+	top, sep, asJSON := addReportFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected a capture file")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	analyzer := capture.NewAnalyzer(*sep)
+	err = capture.Read(f, func(e capture.Entry) error {
+		analyzer.Add(e)
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+	return printCaptureReport(os.Stdout, analyzer.Report(*top), *asJSON)
+}
+
+func runCaptureReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("capture replay", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	speed := fs.Float64("speed", 1, "pace multiplier: 1 is the captured pace, 2 twice as fast, 0 as fast as possible")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected a capture file")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	start := time.Now()
+	stats, err := capture.Replay(ctx, rdb, f, capture.ReplayOptions{Speed: *speed})
+	fmt.Fprintf(os.Stderr, "Replayed %d commands (%d MULTI/EXEC blocks) in %v, skipped %d, discarded %d\n",
+		stats.Commands, stats.Transactions, time.Since(start).Round(time.Millisecond), stats.Skipped, stats.Discarded)
+	if stats.MaxLag > 0 {
+		fmt.Fprintf(os.Stderr, "Fell behind the captured pace by up to %v\n", stats.MaxLag.Round(time.Millisecond))
+	}
+	if stats.Errors > 0 {
+		fmt.Fprintf(os.Stderr, "%d error replies, first: %v\n", stats.Errors, stats.FirstErr)
+	}
+	return err
+}
+
+func printCaptureReport(w io.Writer, r *capture.Report, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Fprintf(w, "\n%d commands over %v (%.1f/s)\n", r.Total, r.Duration.Round(time.Millisecond), r.PerSec)
+	ratio := "no writes"
+	if r.Writes > 0 {
+		ratio = fmt.Sprintf("%.2f reads per write", r.ReadWriteRatio)
+	}
+	fmt.Fprintf(w, "Reads %d, writes %d, other %d (%s)\n", r.Reads, r.Writes, r.Other, ratio)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nCOMMAND\tCOUNT\tSHARE")
+	for _, c := range r.Commands {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", c.Name, c.Count, c.Percent)
+	}
+	fmt.Fprintln(tw, "\nKEY PREFIX\tACCESSES\tSHARE")
+	for _, c := range r.Prefixes {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", c.Name, c.Count, c.Percent)
+	}
+	fmt.Fprintln(tw, "\nCLIENT\tCOMMANDS\tRATE\tREADS\tWRITES\tTOP")
+	for _, c := range r.Clients {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f/s\t%d\t%d\t%s\n", c.Client, c.Commands, c.PerSec, c.Reads, c.Writes, c.Top)
+	}
+	return tw.Flush()
+}
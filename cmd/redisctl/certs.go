@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Redis/tlsconf"
+)
+
+func runCerts(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("certs", flag.ExitOnError)
+	out := fs.String("out", "scripts/tls", "directory to write the CA, server and client certificates to")
+	days := fs.Int("days", 365, "validity of the server and client certificates in days")
+	force := fs.Bool("force", false, "replace existing certificates, including the CA")
+	var hosts listFlag
+	fs.Var(&hosts, "host", "extra DNS name or IP for the server certificate (repeatable)")
+	fs.Parse(args)
+
+	err := tlsconf.Generate(*out, tlsconf.GenerateOptions{
+		Hosts:    hosts,
+		Validity: time.Duration(*days) * 24 * time.Hour,
+		Force:    *force,
+	})
+	if errors.Is(err, iofs.ErrExist) {
+		return fmt.Errorf("%w; use -force to replace the certificates", err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{tlsconf.CACert, tlsconf.ServerCert, tlsconf.ServerKey, tlsconf.ClientCert, tlsconf.ClientKey} {
+		fmt.Println(filepath.Join(*out, name))
+	}
+	abs, _ := filepath.Abs(*out)
+	fmt.Fprintf(os.Stderr, "Connect examples and tests over TLS with: export %s=%s\n", tlsconf.EnvDir, abs)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"Redis/redisconf"
+)
+
+func runConfig(ctx context.Context, args []string) error {
+	actions := map[string]func(context.Context, []string) error{
+		"lint":  runConfigLint,
+		"drift": runConfigDrift,
+	}
+	if len(args) == 0 || actions[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: redisctl config <lint|drift> [flags] [redis.conf]")
+		return errors.New("expected lint or drift")
+	}
+	return actions[args[0]](ctx, args[1:])
+}
+
+// configFile parses the positional redis.conf, defaulting to the project's
+func configFile(fs *flag.FlagSet) (*redisconf.File, error) {
+	path := "scripts/redis.conf"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path = fs.Arg(0)
+	default:
+		fs.Usage()
+		return nil, errors.New("expected at most one redis.conf")
+	}
+	return redisconf.ParseFile(path)
+}
+
+func runConfigLint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("config lint", flag.ExitOnError)
+	fail := fs.String("fail", "critical", "exit non-zero on findings at or above this severity (info, warning, critical or none)")
+	fs.Parse(args)
+
+	threshold := redisconf.Critical + 1
+	if *fail != "none" {
+		sev, err := redisconf.ParseSeverity(*fail)
+		if err != nil {
+			return err
+		}
+		threshold = sev
+	}
+
+	conf, err := configFile(fs)
+	if err != nil {
+		return err
+	}
+	findings := redisconf.Lint(conf)
+	if len(findings) == 0 {
+		fmt.Printf("%s: no findings\n", conf.Path)
+		return nil
+	}
+
+	failed := 0
+	for _, f := range findings {
+		loc := conf.Path
+		if f.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", conf.Path, f.Line)
+		}
+		fmt.Printf("%s: %s [%s] %s\n", loc, f.Severity, f.Rule, f.Message)
+		if f.Severity >= threshold {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d findings at or above %s", failed, *fail)
+	}
+	return nil
+}
+
+func runConfigDrift(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("config drift", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	fs.Parse(args)
+
+	conf, err := configFile(fs)
+	if err != nil {
+		return err
+	}
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	server, err := redisconf.ServerConfig(ctx, rdb)
+	if err != nil {
+		return fmt.Errorf("config get: %w", err)
+	}
+	drift := redisconf.Compare(conf, server)
+	if len(drift) == 0 {
+		fmt.Printf("%s matches %s\n", conf.Path, conn.addr)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tSETTING\tSTATUS\tFILE\tSERVER")
+	for _, d := range drift {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", d.Line, d.Name, d.Kind, quoteEmpty(d.File), quoteEmpty(d.Server))
+	}
+	tw.Flush()
+	return fmt.Errorf("%d settings differ from %s", len(drift), conf.Path)
+}
+
+func quoteEmpty(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return s
+}
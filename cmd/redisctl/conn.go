@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+
+	"Redis/metrics"
+	"Redis/tlsconf"
+	"Redis/tracing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// connFlags holds the connection settings shared by every subcommand
+type connFlags struct {
+	addr     string
+	password string
+	db       int
+	poolSize int    // Connections kept by the client; 0 for the go-redis default
+	tlsDir   string // Certificates written by redisctl certs; empty for plaintext
+
+	metricsAddr string
+	traceFile   string
+}
+
+// addConnFlags registers the connection flags on fs
+func addConnFlags(fs *flag.FlagSet) *connFlags {
+	c := &connFlags{}
+	fs.StringVar(&c.addr, "addr", "localhost:6379", "Redis server address")
+	fs.StringVar(&c.password, "password", "", "Redis password")
+	fs.IntVar(&c.db, "db", 0, "Redis database number")
+	fs.StringVar(&c.tlsDir, "tls-dir", os.Getenv(tlsconf.EnvDir), "connect over TLS with the certificates in this directory (default $"+tlsconf.EnvDir+")")
+	fs.StringVar(&c.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address at /metrics, e.g. :9121")
+	fs.StringVar(&c.traceFile, "trace", "", "append a span per command to this file as OTLP/JSON, - for stdout")
+	return c
+}
+
+// client connects to Redis and checks the connection. With -metrics-addr
+// the client is instrumented and its metrics are served until the process
+// exits; with -trace every command is traced; with -tls-dir the connection
+// uses TLS.
+func (c *connFlags) client(ctx context.Context) (*redis.Client, error) {
+	var tlsConfig *tls.Config
+	if c.tlsDir != "" {
+		var err error
+		if tlsConfig, err = tlsconf.Load(c.tlsDir); err != nil {
+			return nil, fmt.Errorf("could not load TLS certificates: %w", err)
+		}
+	}
+	rdb := redis.NewClient(&redis.Options{
+		Addr:      c.addr,
+		Password:  c.password,
+		DB:        c.db,
+		PoolSize:  c.poolSize,
+		TLSConfig: tlsConfig,
+	})
+	if c.metricsAddr != "" {
+		if _, err := metrics.Serve(c.metricsAddr, metrics.Instrument(rdb)); err != nil {
+			rdb.Close()
+			return nil, fmt.Errorf("could not serve metrics: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", c.metricsAddr)
+	}
+	if c.traceFile != "" {
+		exporter, err := tracing.NewFileExporter(c.traceFile)
+		if err != nil {
+			rdb.Close()
+			return nil, fmt.Errorf("could not open trace file: %w", err)
+		}
+		tracing.Instrument(rdb, tracing.NewTracer("redisctl", exporter))
+	}
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return nil, fmt.Errorf("could not connect to Redis at %s: %w", c.addr, err)
+	}
+	return rdb, nil
+}
+
+// listFlag collects a repeatable string flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"Redis/dataset"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var patterns listFlag
+	fs.Var(&patterns, "match", "key pattern to export (repeatable, default \"*\")")
+	output := fs.String("o", "-", "output file, - for stdout")
+	count := fs.Int64("count", 500, "SCAN COUNT hint and pipeline size")
+	fs.Parse(args)
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	stats, err := dataset.Export(ctx, rdb, w, dataset.ExportOptions{
+		Patterns:  patterns,
+		ScanCount: *count,
+	})
+	if err != nil {
+		return err
+	}
+	printStats("Exported", stats)
+	return nil
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var renames, prefixes listFlag
+	fs.Var(&renames, "rename", "rename a key, as old=new (repeatable)")
+	fs.Var(&prefixes, "prefix", "rewrite a key prefix, as old:=new: (repeatable)")
+	input := fs.String("i", "-", "input file, - for stdin")
+	ttl := fs.String("ttl", string(dataset.TTLRelative), "expiry handling: relative, absolute or none")
+	replace := fs.Bool("replace", false, "overwrite existing keys instead of skipping them")
+	batch := fs.Int("batch", 100, "records per pipeline")
+	fs.Parse(args)
+
+	opts := dataset.ImportOptions{
+		Rename:    make(map[string]string),
+		TTL:       dataset.TTLMode(*ttl),
+		Replace:   *replace,
+		BatchSize: *batch,
+	}
+	switch opts.TTL {
+	case dataset.TTLRelative, dataset.TTLAbsolute, dataset.TTLNone:
+	default:
+		return fmt.Errorf("invalid -ttl %q", *ttl)
+	}
+	for _, r := range renames {
+		from, to, ok := strings.Cut(r, "=")
+		if !ok {
+			return fmt.Errorf("invalid -rename %q, expected old=new", r)
+		}
+		opts.Rename[from] = to
+	}
+	for _, p := range prefixes {
+		from, to, ok := strings.Cut(p, "=")
+		if !ok {
+			return fmt.Errorf("invalid -prefix %q, expected old=new", p)
+		}
+		opts.Prefixes = append(opts.Prefixes, dataset.PrefixRewrite{From: from, To: to})
+	}
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	stats, err := dataset.Import(ctx, rdb, r, opts)
+	if err != nil {
+		return err
+	}
+	printStats("Imported", stats)
+	return nil
+}
+
+// printStats reports key counts on stderr so stdout can carry the dump
+func printStats(verb string, stats dataset.Stats) {
+	types := make([]string, 0, len(stats.ByType))
+	for t := range stats.ByType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%s=%d", t, stats.ByType[t]))
+	}
+	fmt.Fprintf(os.Stderr, "%s %d keys (%s), skipped %d\n", verb, stats.Keys, strings.Join(parts, " "), stats.Skipped)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"Redis/gateway"
+)
+
+func runGateway(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gateway", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	listen := fs.String("listen", "localhost:8080", "address to serve HTTP on")
+	keepAlive := fs.Duration("keepalive", 10*time.Second, "interval of keep-alive comments on event streams")
+	spec := fs.Bool("openapi", false, "print the OpenAPI document and exit")
+	var origins listFlag
+	fs.Var(&origins, "cors", "browser origin allowed to call the gateway, * for any (repeatable)")
+	fs.Parse(args)
+
+	cfg := gateway.Config{AllowOrigins: origins, KeepAlive: *keepAlive}
+	if *spec {
+		_, err := os.Stdout.Write(gateway.New(nil, cfg).OpenAPI())
+		return err
+	}
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           gateway.New(rdb, cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Event streams end when the command is interrupted
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the gateway on http://%s, OpenAPI document at /openapi.json\n", *listen)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
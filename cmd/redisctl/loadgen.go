@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"Redis/loadgen"
+)
+
+func runLoadgen(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	workload := fs.String("workload", "scripts/loadgen.yaml", "YAML workload spec")
+	concurrency := fs.Int("c", 0, "override the spec's concurrency")
+	duration := fs.Duration("d", 0, "override the spec's duration")
+	rate := fs.Float64("rate", -1, "override the spec's target ops/s; 0 for closed loop")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	quiet := fs.Bool("q", false, "do not print progress every second")
+	fs.Parse(args)
+
+	spec, err := loadgen.LoadSpec(*workload)
+	if err != nil {
+		return err
+	}
+	if *concurrency > 0 {
+		spec.Concurrency = *concurrency
+	}
+	if *duration > 0 {
+		spec.Duration = *duration
+	}
+	if *rate >= 0 {
+		spec.Rate = *rate
+	}
+
+	// One connection per worker so workers never queue for the pool
+	conn.poolSize = spec.Concurrency
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	mode := "closed loop"
+	if spec.Rate > 0 {
+		mode = fmt.Sprintf("open loop at %.0f ops/s", spec.Rate)
+	}
+	fmt.Fprintf(os.Stderr, "Running %d ops with %d workers for %v, %s\n", len(spec.Ops), spec.Concurrency, spec.Duration, mode)
+
+	var progress func(loadgen.Progress)
+	if !*quiet {
+		progress = func(p loadgen.Progress) {
+			fmt.Fprintf(os.Stderr, "%6v  %10d ops  %10.0f ops/s  %d errors\n", p.Elapsed, p.Ops, p.Rate, p.Errors)
+		}
+	}
+
+	report, err := loadgen.Run(ctx, rdb, spec, progress)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printLoadReport(report)
+	if report.Total.Count == 0 {
+		return errors.New("no commands completed")
+	}
+	return nil
+}
+
+func printLoadReport(r *loadgen.Report) {
+	fmt.Printf("\n%s, %d workers, %v\n", r.Mode, r.Concurrency, r.Duration.Round(time.Millisecond))
+	if r.Mode == loadgen.OpenLoop {
+		fmt.Printf("Target %.0f ops/s, achieved %.0f ops/s, %d scheduled ops not sent\n", r.TargetRate, r.Total.Throughput, r.Missed)
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "OP\tCOUNT\tOPS/S\tERRORS\tNIL\tMEAN\tP50\tP90\tP99\tP99.9\tMAX\t")
+	for _, op := range append(r.Ops, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t%v\t\n",
+			op.Name, op.Count, op.Throughput, op.Errors, op.Nil,
+			op.Mean, op.P50, op.P90, op.P99, op.P999, op.Max)
+	}
+	tw.Flush()
+
+	for _, op := range r.Ops {
+		classes := make([]string, 0, len(op.ErrorClasses))
+		for c := range op.ErrorClasses {
+			classes = append(classes, c)
+		}
+		sort.Strings(classes)
+		for _, c := range classes {
+			fmt.Printf("%s: %d %s errors\n", op.Name, op.ErrorClasses[c], c)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Command redisctl bundles the project's Redis tooling behind subcommands.
+//
+// Usage:
+//
+//	go run ./cmd/redisctl <command> [flags]
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// command is a redisctl subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"export", "Dump keys matching patterns to JSON Lines", runExport},
+	{"import", "Restore keys from a JSON Lines dump", runImport},
+	{"rdb", "Inspect an RDB snapshot offline or convert it to JSON Lines", runRDB},
+	{"aof", "Print, check or replay an append-only file up to a point in time", runAOF},
+	{"bench", "Run the benchmark suite, save results as JSON and diff against a baseline", runBench},
+	{"loadgen", "Drive a weighted mix of commands and report latency percentiles", runLoadgen},
+	{"monitor", "Dashboard of INFO rates, SLOWLOG and LATENCY, refreshed in the terminal", runMonitor},
+	{"capture", "Record MONITOR output, summarise command patterns and replay captures", runCapture},
+	{"config", "Lint redis.conf for risky settings and diff it against CONFIG GET", runConfig},
+	{"acl", "Provision ACL users from a YAML file and verify their permissions", runACL},
+	{"certs", "Generate a local CA with server and client certificates for TLS", runCerts},
+	{"gateway", "Serve strings, hashes, lists, sets, zsets, streams and Pub/Sub as JSON over HTTP", runGateway},
+	{"proxy", "Relay clients to Redis, logging commands, refusing commands outside an allow-list and prefixing keys per tenant", runProxy},
+	{"analytics", "Count unique and active users, retention cohorts and funnels from the events stream", runAnalytics},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			if err := cmd.run(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: redisctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'redisctl <command> -h' for command flags.")
+}
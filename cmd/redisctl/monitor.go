@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"Redis/health"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func runMonitor(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	interval := fs.Duration("interval", 2*time.Second, "time between polls")
+	once := fs.Bool("once", false, "poll once and exit")
+	sections := fs.String("sections", "", "comma-separated INFO sections to poll (default set when empty)")
+	slowCount := fs.Int64("slowlog", 128, "entries to fetch per SLOWLOG GET")
+	tail := fs.Int("tail", 10, "slow log entries to keep on screen")
+	top := fs.Int("top", 5, "slow command shapes to show, by total time")
+	noClear := fs.Bool("no-clear", false, "append each refresh instead of redrawing the screen")
+	fs.Parse(args)
+
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	opts := health.Options{SlowlogCount: *slowCount}
+	if *sections != "" {
+		opts.Sections = strings.Split(*sections, ",")
+	}
+	mon := health.NewMonitor(rdb, opts)
+
+	var recent []redis.SlowLog
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		snap, err := mon.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		recent = append(recent, snap.NewSlow...)
+		if len(recent) > *tail {
+			recent = recent[len(recent)-*tail:]
+		}
+
+		var buf bytes.Buffer
+		if !*once && !*noClear {
+			buf.WriteString("\033[H\033[2J")
+		}
+		renderHealth(&buf, conn.addr, snap, recent, *top)
+		os.Stdout.Write(buf.Bytes())
+
+		if *once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func renderHealth(w io.Writer, addr string, snap *health.Snapshot, recent []redis.SlowLog, top int) {
+	info, r := snap.Info, snap.Rates
+	s := info.Server
+
+	fmt.Fprintf(w, "%s  redis %s %s  up %v  role %s  clients %d (blocked %d)  %s\n\n",
+		addr, orDash(s.Version), orDash(s.Mode), time.Duration(s.UptimeSeconds)*time.Second,
+		orDash(info.Replication.Role), info.Clients.Connected, info.Clients.Blocked,
+		snap.Time.Format("15:04:05"))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Throughput\t%.0f ops/s\tin %.1f KB/s\tout %.1f KB/s\tconns %.1f/s\tcpu %.0f%%\n",
+		r.OpsPerSec, r.NetInKBps, r.NetOutKBps, r.ConnectionsPerSec, r.CPUPercent)
+
+	keys := int64(0)
+	for _, db := range info.Keyspace {
+		keys += db.Keys
+	}
+	fmt.Fprintf(tw, "Keyspace\thit ratio %s\tevictions %.1f/s\texpirations %.1f/s\tkeys %d\t\n",
+		hitRatio(r), r.EvictionsPerSec, r.ExpirationsPerSec, keys)
+
+	m := info.Memory
+	maxMem := "unlimited"
+	if m.Max > 0 {
+		maxMem = humanBytes(m.Max)
+	}
+	fmt.Fprintf(tw, "Memory\tused %s\trss %s\tpeak %s\tmax %s (%s)\tfragmentation %s\n",
+		humanBytes(m.Used), humanBytes(m.RSS), humanBytes(m.Peak), maxMem, orDash(m.MaxPolicy), fragmentation(r.Fragmentation))
+
+	if info.Has("persistence") {
+		p := info.Persistence
+		aof := "off"
+		if p.AOFEnabled == 1 {
+			aof = "on, last write " + orDash(p.AOFLastWriteStatus)
+		}
+		fmt.Fprintf(tw, "Persistence\trdb changes %d\tbgsave %s\taof %s\t\t\n", p.RDBChangesSinceSave, orDash(p.RDBLastBgsaveStatus), aof)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nSlow log")
+	if snap.SlowlogErr != nil {
+		fmt.Fprintf(w, "  unavailable: %v\n", snap.SlowlogErr)
+	} else if len(recent) == 0 {
+		fmt.Fprintln(w, "  no entries")
+	} else {
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  ID\tTIME\tDURATION\tCLIENT\tCOMMAND")
+		for _, e := range recent {
+			fmt.Fprintf(tw, "  %d\t%s\t%v\t%s\t%s\n", e.ID, e.Time.Format("15:04:05"), e.Duration, orDash(e.ClientAddr), truncate(strings.Join(e.Args, " "), 60))
+		}
+		tw.Flush()
+
+		fmt.Fprintln(w, "\nSlowest command shapes")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  COUNT\tTOTAL\tMAX\tSTATEMENT")
+		for i, g := range snap.SlowGroups {
+			if i == top {
+				break
+			}
+			fmt.Fprintf(tw, "  %d\t%v\t%v\t%s\n", g.Count, g.Total, g.Max, truncate(g.Statement, 60))
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w, "\nLatency")
+	switch {
+	case snap.LatencyErr != nil:
+		fmt.Fprintf(w, "  unavailable: %v\n", snap.LatencyErr)
+	case len(snap.Latency) == 0:
+		fmt.Fprintln(w, "  no events (is latency-monitor-threshold set?)")
+	default:
+		sort.Slice(snap.Latency, func(i, j int) bool { return snap.Latency[i].Max > snap.Latency[j].Max })
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  EVENT\tLATEST\tMAX\tAT\tHISTORY")
+		for _, e := range snap.Latency {
+			fmt.Fprintf(tw, "  %s\t%v\t%v\t%s\t%s\n", e.Event, e.Latest, e.Max, e.Time.Format("15:04:05"), sparkline(snap.History[e.Event]))
+		}
+		tw.Flush()
+	}
+}
+
+func hitRatio(r health.Rates) string {
+	if r.Lookups == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% of %d", r.HitRatio*100, r.Lookups)
+}
+
+func fragmentation(ratio float64) string {
+	switch {
+	case ratio == 0:
+		return "-"
+	case ratio > 1.5:
+		return fmt.Sprintf("%.2f (high)", ratio)
+	case ratio < 1:
+		return fmt.Sprintf("%.2f (swapping?)", ratio)
+	}
+	return fmt.Sprintf("%.2f", ratio)
+}
+
+// sparkline draws latency samples as block characters scaled to the maximum
+func sparkline(samples []health.LatencySample) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	if len(samples) > 30 {
+		samples = samples[len(samples)-30:]
+	}
+	var peak time.Duration
+	for _, s := range samples {
+		peak = max(peak, s.Latency)
+	}
+	var b strings.Builder
+	for _, s := range samples {
+		i := 0
+		if peak > 0 {
+			i = int(float64(s.Latency) / float64(peak) * float64(len(levels)-1))
+		}
+		b.WriteRune(levels[i])
+	}
+	return b.String()
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
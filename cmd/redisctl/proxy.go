@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"Redis/capture"
+	"Redis/keyspec"
+	"Redis/proxy"
+)
+
+func runProxy(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	listen := fs.String("listen", "localhost:6380", "address to accept clients on")
+	allow := fs.String("allow", "", "comma-separated commands clients may run, e.g. get,set,config|get; empty allows all")
+	deny := fs.String("deny", "", "comma-separated commands to refuse, e.g. flushall,flushdb,keys")
+	prefix := fs.String("prefix", "", "prefix the keys of every client")
+	logFile := fs.String("log", "-", "append the commands sent to Redis in MONITOR format to this file, - for stdout, empty for none")
+	statsEvery := fs.Duration("stats", 0, "print latency statistics at this interval as well as on exit")
+	var tenants listFlag
+	fs.Var(&tenants, "tenant", "user=prefix: prefix the keys of clients authenticated as user (repeatable)")
+	fs.Parse(args)
+
+	cfg := proxy.Config{Upstream: conn.addr, Allow: splitList(*allow), Deny: splitList(*deny)}
+	if *prefix != "" || len(tenants) > 0 {
+		prefixes := map[string]string{}
+		for _, t := range tenants {
+			user, p, ok := strings.Cut(t, "=")
+			if !ok {
+				return fmt.Errorf("invalid -tenant %q, expected user=prefix", t)
+			}
+			prefixes[user] = p
+		}
+		cfg.Prefix = func(c proxy.Client) string {
+			if p, ok := prefixes[c.User]; ok {
+				return p
+			}
+			return *prefix
+		}
+	}
+
+	// The command table tells the proxy where the keys are
+	rdb, err := conn.client(ctx)
+	if err != nil {
+		return err
+	}
+	cfg.TLSConfig = rdb.Options().TLSConfig
+	cfg.Keys, err = keyspec.Load(ctx, rdb)
+	rdb.Close()
+	if err != nil {
+		return err
+	}
+
+	var refusedMu sync.Mutex
+	logRefused := func(e proxy.Entry) {
+		refusedMu.Lock()
+		defer refusedMu.Unlock()
+		fmt.Fprintf(os.Stderr, "Refused %s from %s: %s\n", strings.ToUpper(e.Args[0]), e.Client.Addr, e.Err)
+	}
+	cfg.Log = func(e proxy.Entry) {
+		if e.Refused {
+			logRefused(e)
+		}
+	}
+	if *logFile != "" {
+		w := io.Writer(os.Stdout)
+		if *logFile != "-" {
+			f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		var mu sync.Mutex
+		bw := bufio.NewWriter(w)
+		defer bw.Flush()
+		cfg.Log = func(e proxy.Entry) {
+			if e.Refused {
+				logRefused(e)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintln(bw, capture.Entry{Time: e.Time, DB: e.Client.DB, Client: e.Client.Addr, Args: e.Args})
+			bw.Flush()
+		}
+	}
+
+	p, err := proxy.New(cfg)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		p.Close()
+	}()
+	if *statsEvery > 0 {
+		go func() {
+			ticker := time.NewTicker(*statsEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					printProxyStats(os.Stderr, p.Stats())
+				}
+			}
+		}()
+	}
+
+	fmt.Fprintf(os.Stderr, "Proxying %s to Redis at %s\n", l.Addr(), conn.addr)
+	if err := p.Serve(l); err != nil {
+		return err
+	}
+	return printProxyStats(os.Stderr, p.Stats())
+}
+
+// splitList splits a comma-separated flag value
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func printProxyStats(w io.Writer, stats []proxy.CommandStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tCALLS\tERRORS\tREFUSED\tMEAN\tP50\tP99\tMAX\t")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%v\t%v\t%v\t%v\t\n", s.Name, s.Calls, s.Errors, s.Refused, s.Mean, s.P50, s.P99, s.Max)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"Redis/dataset"
+	"Redis/rdbfile"
+)
+
+func runRDB(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rdb", flag.ExitOnError)
+	format := fs.String("format", "table", "output: table (keys, sizes, expiries), summary or jsonl (export format)")
+	match := fs.String("match", "*", "only include keys matching this glob pattern")
+	db := fs.Int("db", -1, "only include keys from this database (-1 for all)")
+	output := fs.String("o", "-", "output file, - for stdout")
+	expired := fs.Bool("include-expired", false, "include keys whose expiry has already passed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: redisctl rdb [flags] dump.rdb")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one RDB file")
+	}
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	now := time.Now().UnixMilli()
+	include := func(e *rdbfile.Entry) bool {
+		if *db >= 0 && e.DB != *db {
+			return false
+		}
+		if !*expired && e.ExpireAt > 0 && e.ExpireAt <= now {
+			return false
+		}
+		ok, _ := path.Match(*match, e.Key)
+		return ok
+	}
+
+	switch *format {
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DB\tKEY\tTYPE\tENCODING\tSIZE\tEXPIRES")
+		info, err := rdbfile.Parse(in, func(e *rdbfile.Entry) error {
+			if !include(e) {
+				return ctx.Err()
+			}
+			expires := "-"
+			if e.ExpireAt > 0 {
+				expires = time.UnixMilli(e.ExpireAt).UTC().Format(time.RFC3339)
+			}
+			typ := e.Type
+			if e.Module != "" {
+				typ += ":" + e.Module
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\n", e.DB, e.Key, typ, e.Encoding, e.Size, expires)
+			return ctx.Err()
+		})
+		tw.Flush()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "RDB version %d, %d keys\n", info.Version, info.Keys)
+		return nil
+
+	case "jsonl":
+		w := dataset.NewWriter(out)
+		written, skipped := 0, 0
+		info, err := rdbfile.Parse(in, func(e *rdbfile.Entry) error {
+			if !include(e) {
+				return ctx.Err()
+			}
+			if e.Type == rdbfile.TypeModule {
+				skipped++
+				return ctx.Err()
+			}
+			rec := e.Record()
+			if rec.ExpireAt > now {
+				rec.TTL = rec.ExpireAt - now
+			}
+			written++
+			if err := w.Write(rec); err != nil {
+				return err
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "RDB version %d: converted %d of %d keys, skipped %d module values\n",
+			info.Version, written, info.Keys, skipped)
+		return w.Flush()
+
+	case "summary":
+		return rdbSummary(ctx, in, out, include)
+	}
+	return fmt.Errorf("unknown -format %q", *format)
+}
+
+// rdbSummary prints metadata, per-type totals and the biggest keys
+func rdbSummary(ctx context.Context, in io.Reader, out io.Writer, include func(*rdbfile.Entry) bool) error {
+	type total struct {
+		keys  int
+		bytes int64
+	}
+	type bigKey struct {
+		key  string
+		typ  string
+		size int64
+	}
+	const topN = 10
+
+	totals := make(map[string]*total)
+	var biggest []bigKey
+	expiring := 0
+
+	info, err := rdbfile.Parse(in, func(e *rdbfile.Entry) error {
+		if !include(e) {
+			return ctx.Err()
+		}
+		name := e.Type + "/" + e.Encoding
+		if totals[name] == nil {
+			totals[name] = &total{}
+		}
+		totals[name].keys++
+		totals[name].bytes += e.Size
+		if e.ExpireAt > 0 {
+			expiring++
+		}
+
+		biggest = append(biggest, bigKey{e.Key, e.Type, e.Size})
+		sort.Slice(biggest, func(i, j int) bool { return biggest[i].size > biggest[j].size })
+		if len(biggest) > topN {
+			biggest = biggest[:topN]
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "RDB version: %d\n", info.Version)
+	auxKeys := make([]string, 0, len(info.Aux))
+	for k := range info.Aux {
+		auxKeys = append(auxKeys, k)
+	}
+	sort.Strings(auxKeys)
+	for _, k := range auxKeys {
+		fmt.Fprintf(out, "  %s: %s\n", k, info.Aux[k])
+	}
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nTYPE/ENCODING\tKEYS\tBYTES")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", name, totals[name].keys, totals[name].bytes)
+	}
+	fmt.Fprintf(tw, "\nKeys with expiry:\t%d\n", expiring)
+	fmt.Fprintln(tw, "\nBIGGEST KEYS\tTYPE\tBYTES")
+	for _, b := range biggest {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", b.key, b.typ, b.size)
+	}
+	return tw.Flush()
+}
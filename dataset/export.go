@@ -0,0 +1,246 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ExportOptions selects which keys are dumped
+type ExportOptions struct {
+	Patterns  []string // SCAN MATCH patterns; empty means "*"
+	ScanCount int64    // SCAN COUNT hint, also the pipeline batch size
+}
+
+// Stats counts the keys handled by an export or import
+type Stats struct {
+	Keys    int
+	Skipped int
+	ByType  map[string]int
+}
+
+func (s *Stats) add(typ string) {
+	if s.ByType == nil {
+		s.ByType = make(map[string]int)
+	}
+	s.ByType[typ]++
+	s.Keys++
+}
+
+// Export scans the matching keys and writes one record per key to w
+func Export(ctx context.Context, rdb redis.Cmdable, w io.Writer, opts ExportOptions) (Stats, error) {
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	count := opts.ScanCount
+	if count <= 0 {
+		count = 500
+	}
+
+	var stats Stats
+	out := NewWriter(w)
+
+	// Overlapping patterns would otherwise dump a key twice
+	var seen map[string]bool
+	if len(patterns) > 1 {
+		seen = make(map[string]bool)
+	}
+
+	for _, pattern := range patterns {
+		var cursor uint64
+		for {
+			keys, next, err := rdb.Scan(ctx, cursor, pattern, count).Result()
+			if err != nil {
+				return stats, fmt.Errorf("scanning %q: %w", pattern, err)
+			}
+
+			if seen != nil {
+				unique := keys[:0]
+				for _, k := range keys {
+					if !seen[k] {
+						seen[k] = true
+						unique = append(unique, k)
+					}
+				}
+				keys = unique
+			}
+
+			records, err := fetch(ctx, rdb, keys)
+			if err != nil {
+				return stats, err
+			}
+			for _, rec := range records {
+				if rec == nil {
+					stats.Skipped++
+					continue
+				}
+				stats.add(rec.Type)
+				if err := out.Write(rec); err != nil {
+					return stats, fmt.Errorf("writing %q: %w", rec.Key, err)
+				}
+			}
+
+			cursor = next
+			if cursor == 0 {
+				break
+			}
+		}
+	}
+	return stats, out.Flush()
+}
+
+// fetch reads type, TTL and value for a batch of keys using two pipelines.
+// Keys that vanished or have unsupported types come back as nil.
+func fetch(ctx context.Context, rdb redis.Cmdable, keys []string) ([]*Record, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	// First round trip: types and TTLs
+	pipe := rdb.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("reading key types: %w", err)
+	}
+
+	// Second round trip: values
+	now := time.Now()
+	records := make([]*Record, len(keys))
+	values := make([][]redis.Cmder, len(keys))
+	pipe = rdb.Pipeline()
+	for i, key := range keys {
+		rec := &Record{Key: key, Type: types[i].Val()}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			rec.TTL = ttl.Milliseconds()
+			rec.ExpireAt = now.Add(ttl).UnixMilli()
+		}
+
+		switch rec.Type {
+		case TypeString:
+			values[i] = []redis.Cmder{pipe.Get(ctx, key)}
+		case TypeHash:
+			values[i] = []redis.Cmder{pipe.HGetAll(ctx, key)}
+		case TypeList:
+			values[i] = []redis.Cmder{pipe.LRange(ctx, key, 0, -1)}
+		case TypeSet:
+			values[i] = []redis.Cmder{pipe.SMembers(ctx, key)}
+		case TypeZSet:
+			values[i] = []redis.Cmder{pipe.ZRangeWithScores(ctx, key, 0, -1)}
+		case TypeStream:
+			values[i] = []redis.Cmder{
+				pipe.Do(ctx, "XRANGE", key, "-", "+"),
+				pipe.XInfoStream(ctx, key),
+				pipe.XInfoGroups(ctx, key),
+			}
+		default:
+			continue
+		}
+		records[i] = rec
+	}
+	// Exec only reports the first failed command; each key is checked below
+	pipe.Exec(ctx)
+
+	for i, rec := range records {
+		if rec == nil {
+			continue
+		}
+		gone, err := vanished(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", rec.Key, err)
+		}
+		if gone {
+			records[i] = nil
+			continue
+		}
+
+		switch cmd := values[i][0].(type) {
+		case *redis.StringCmd:
+			rec.Value = cmd.Val()
+		case *redis.MapStringStringCmd:
+			rec.Value = cmd.Val()
+		case *redis.StringSliceCmd:
+			rec.Value = cmd.Val()
+		case *redis.ZSliceCmd:
+			members := make([]ZMember, 0, len(cmd.Val()))
+			for _, z := range cmd.Val() {
+				members = append(members, ZMember{Member: fmt.Sprint(z.Member), Score: z.Score})
+			}
+			rec.Value = members
+		case *redis.Cmd:
+			stream, err := parseStream(cmd, values[i][1].(*redis.XInfoStreamCmd), values[i][2].(*redis.XInfoGroupsCmd))
+			if err != nil {
+				return nil, fmt.Errorf("reading stream %q: %w", rec.Key, err)
+			}
+			rec.Value = stream
+		}
+	}
+	return records, nil
+}
+
+// vanished reports whether a key expired, was deleted or changed type
+// between the two round trips. Redis removes hashes, lists, sets and sorted
+// sets once they are empty, so an empty one is gone too. Other errors are
+// returned.
+func vanished(cmds []redis.Cmder) (bool, error) {
+	for _, cmd := range cmds {
+		err := cmd.Err()
+		switch {
+		case err == nil:
+		case err == redis.Nil, strings.HasPrefix(err.Error(), "WRONGTYPE"), strings.Contains(err.Error(), "no such key"):
+			return true, nil
+		default:
+			return false, err
+		}
+	}
+	switch cmd := cmds[0].(type) {
+	case *redis.MapStringStringCmd:
+		return len(cmd.Val()) == 0, nil
+	case *redis.StringSliceCmd:
+		return len(cmd.Val()) == 0, nil
+	case *redis.ZSliceCmd:
+		return len(cmd.Val()) == 0, nil
+	}
+	return false, nil
+}
+
+// parseStream converts a raw XRANGE reply, keeping field order
+func parseStream(xrange *redis.Cmd, info *redis.XInfoStreamCmd, groups *redis.XInfoGroupsCmd) (*Stream, error) {
+	raw, err := xrange.Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &Stream{Entries: make([]StreamEntry, 0, len(raw))}
+	for _, item := range raw {
+		parts, ok := item.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected XRANGE entry %v", item)
+		}
+		entry := StreamEntry{ID: fmt.Sprint(parts[0])}
+		fields, _ := parts[1].([]interface{})
+		for _, f := range fields {
+			entry.Fields = append(entry.Fields, fmt.Sprint(f))
+		}
+		stream.Entries = append(stream.Entries, entry)
+	}
+
+	if info.Err() == nil {
+		stream.LastID = info.Val().LastGeneratedID
+	}
+	if groups.Err() == nil {
+		for _, g := range groups.Val() {
+			stream.Groups = append(stream.Groups, StreamGroup{Name: g.Name, LastDelivered: g.LastDeliveredID})
+		}
+	}
+	return stream, nil
+}
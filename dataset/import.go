@@ -0,0 +1,203 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTLMode controls how recorded expiries are restored
+type TTLMode string
+
+const (
+	TTLRelative TTLMode = "relative" // Apply the TTL that remained when the dump was taken
+	TTLAbsolute TTLMode = "absolute" // Expire at the recorded wall-clock time
+	TTLNone     TTLMode = "none"     // Restore keys without expiry
+)
+
+// PrefixRewrite replaces a leading key prefix
+type PrefixRewrite struct {
+	From string
+	To   string
+}
+
+// ImportOptions controls how records are restored
+type ImportOptions struct {
+	Rename    map[string]string // Exact key renames, applied before prefix rewrites
+	Prefixes  []PrefixRewrite   // First matching rewrite wins
+	TTL       TTLMode           // Defaults to TTLRelative
+	Replace   bool              // Overwrite existing keys instead of skipping them
+	BatchSize int               // Records per pipeline
+}
+
+// maxArgs caps the elements sent in one command when restoring big collections
+const maxArgs = 1000
+
+// TargetKey returns the key a record is restored to
+func (o ImportOptions) TargetKey(key string) string {
+	if renamed, ok := o.Rename[key]; ok {
+		return renamed
+	}
+	for _, p := range o.Prefixes {
+		if strings.HasPrefix(key, p.From) {
+			return p.To + strings.TrimPrefix(key, p.From)
+		}
+	}
+	return key
+}
+
+// Import restores every record read from r
+func Import(ctx context.Context, rdb redis.Cmdable, r io.Reader, opts ImportOptions) (Stats, error) {
+	if opts.TTL == "" {
+		opts.TTL = TTLRelative
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	var stats Stats
+	in := NewReader(r)
+	batch := make([]*Record, 0, opts.BatchSize)
+	for {
+		rec, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("reading record %d: %w", stats.Keys+stats.Skipped+len(batch)+1, err)
+		}
+		rec.Key = opts.TargetKey(rec.Key)
+		batch = append(batch, rec)
+
+		if len(batch) == opts.BatchSize {
+			if err := restoreBatch(ctx, rdb, batch, opts, &stats); err != nil {
+				return stats, err
+			}
+			batch = batch[:0]
+		}
+	}
+	err := restoreBatch(ctx, rdb, batch, opts, &stats)
+	return stats, err
+}
+
+func restoreBatch(ctx context.Context, rdb redis.Cmdable, batch []*Record, opts ImportOptions, stats *Stats) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// Without Replace, existing keys are left untouched
+	skip := make([]bool, len(batch))
+	if !opts.Replace {
+		pipe := rdb.Pipeline()
+		exists := make([]*redis.IntCmd, len(batch))
+		for i, rec := range batch {
+			exists[i] = pipe.Exists(ctx, rec.Key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("checking existing keys: %w", err)
+		}
+		for i := range batch {
+			skip[i] = exists[i].Val() > 0
+		}
+	}
+
+	now := time.Now()
+	pipe := rdb.Pipeline()
+	for i, rec := range batch {
+		if skip[i] || (opts.TTL == TTLAbsolute && rec.ExpireAt > 0 && rec.ExpireAt <= now.UnixMilli()) {
+			stats.Skipped++
+			continue
+		}
+		if err := queueRestore(ctx, pipe, rec, opts); err != nil {
+			return err
+		}
+		stats.add(rec.Type)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("restoring keys: %w", err)
+	}
+	return nil
+}
+
+// queueRestore adds the commands that recreate one record to the pipeline
+func queueRestore(ctx context.Context, pipe redis.Pipeliner, rec *Record, opts ImportOptions) error {
+	key := rec.Key
+	pipe.Del(ctx, key)
+
+	switch v := rec.Value.(type) {
+	case string:
+		pipe.Set(ctx, key, v, 0)
+	case map[string]string:
+		args := make([]interface{}, 0, 2*maxArgs)
+		for field, val := range v {
+			args = append(args, field, val)
+			if len(args) >= 2*maxArgs {
+				pipe.HSet(ctx, key, args...)
+				args = args[:0]
+			}
+		}
+		if len(args) > 0 {
+			pipe.HSet(ctx, key, args...)
+		}
+	case []string:
+		for _, chunk := range chunks(v) {
+			if rec.Type == TypeSet {
+				pipe.SAdd(ctx, key, chunk...)
+			} else {
+				pipe.RPush(ctx, key, chunk...)
+			}
+		}
+	case []ZMember:
+		for start := 0; start < len(v); start += maxArgs {
+			end := min(start+maxArgs, len(v))
+			members := make([]redis.Z, 0, end-start)
+			for _, m := range v[start:end] {
+				members = append(members, redis.Z{Score: m.Score, Member: m.Member})
+			}
+			pipe.ZAdd(ctx, key, members...)
+		}
+	case *Stream:
+		for _, e := range v.Entries {
+			args := []interface{}{"XADD", key, e.ID}
+			for _, f := range e.Fields {
+				args = append(args, f)
+			}
+			pipe.Do(ctx, args...)
+		}
+		for _, g := range v.Groups {
+			pipe.XGroupCreateMkStream(ctx, key, g.Name, g.LastDelivered)
+		}
+		// The last generated ID can be ahead of the newest entry after deletions
+		if v.LastID != "" && len(v.Entries) > 0 && v.LastID != v.Entries[len(v.Entries)-1].ID {
+			pipe.Do(ctx, "XSETID", key, v.LastID)
+		}
+	default:
+		return fmt.Errorf("key %q: unsupported value %T for type %s", key, rec.Value, rec.Type)
+	}
+
+	switch {
+	case opts.TTL == TTLRelative && rec.TTL > 0:
+		pipe.PExpire(ctx, key, time.Duration(rec.TTL)*time.Millisecond)
+	case opts.TTL == TTLAbsolute && rec.ExpireAt > 0:
+		pipe.PExpireAt(ctx, key, time.UnixMilli(rec.ExpireAt))
+	}
+	return nil
+}
+
+// chunks splits values into argument lists of at most maxArgs elements
+func chunks(values []string) [][]interface{} {
+	var out [][]interface{}
+	for start := 0; start < len(values); start += maxArgs {
+		end := min(start+maxArgs, len(values))
+		chunk := make([]interface{}, 0, end-start)
+		for _, v := range values[start:end] {
+			chunk = append(chunk, v)
+		}
+		out = append(out, chunk)
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// Writer writes records as JSON Lines
+type Writer struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewWriter creates a JSON Lines writer
+func NewWriter(w io.Writer) *Writer {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return &Writer{buf: buf, enc: enc}
+}
+
+// Write encodes one record on its own line
+func (w *Writer) Write(rec *Record) error {
+	rec.encodeBinary()
+	return w.enc.Encode(rec)
+}
+
+// Flush writes any buffered data
+func (w *Writer) Flush() error {
+	return w.buf.Flush()
+}
+
+// Reader reads records from JSON Lines
+type Reader struct {
+	dec *json.Decoder
+}
+
+// NewReader creates a JSON Lines reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Read returns the next record, or io.EOF when the input is exhausted
+func (r *Reader) Read() (*Record, error) {
+	var rec Record
+	if err := r.dec.Decode(&rec); err != nil {
+		return nil, err
+	}
+	if err := rec.decodeBinary(); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
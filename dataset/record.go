@@ -0,0 +1,173 @@
+package dataset
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Redis value types supported in dumps
+const (
+	TypeString = "string"
+	TypeHash   = "hash"
+	TypeList   = "list"
+	TypeSet    = "set"
+	TypeZSet   = "zset"
+	TypeStream = "stream"
+)
+
+// EncodingBase64 marks records whose key and values are base64 encoded
+// because they are not valid UTF-8
+const EncodingBase64 = "base64"
+
+// Record is one key in a JSON Lines dump.
+//
+// Value holds a string, map[string]string (hash), []string (list, set),
+// []ZMember (zset) or *Stream depending on Type.
+type Record struct {
+	Key      string      `json:"key"`
+	Type     string      `json:"type"`
+	TTL      int64       `json:"ttl_ms,omitempty"`       // Remaining time to live when dumped
+	ExpireAt int64       `json:"expire_at_ms,omitempty"` // Absolute expiry, unix milliseconds
+	Encoding string      `json:"encoding,omitempty"`
+	Value    interface{} `json:"value"`
+}
+
+// ZMember is a sorted set member with its score
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// Stream holds stream entries with their IDs plus consumer group positions
+type Stream struct {
+	LastID  string        `json:"last_id,omitempty"`
+	Entries []StreamEntry `json:"entries"`
+	Groups  []StreamGroup `json:"groups,omitempty"`
+}
+
+// StreamEntry is a single stream entry; Fields alternates names and values
+// so the original field order is kept
+type StreamEntry struct {
+	ID     string   `json:"id"`
+	Fields []string `json:"fields"`
+}
+
+// StreamGroup records a consumer group and its last delivered ID
+type StreamGroup struct {
+	Name          string `json:"name"`
+	LastDelivered string `json:"last_delivered_id"`
+}
+
+// UnmarshalJSON decodes Value into the Go type matching Type
+func (r *Record) UnmarshalJSON(data []byte) error {
+	type plain Record
+	var raw struct {
+		plain
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = Record(raw.plain)
+
+	var err error
+	switch r.Type {
+	case TypeString:
+		var v string
+		err = json.Unmarshal(raw.Value, &v)
+		r.Value = v
+	case TypeHash:
+		var v map[string]string
+		err = json.Unmarshal(raw.Value, &v)
+		r.Value = v
+	case TypeList, TypeSet:
+		var v []string
+		err = json.Unmarshal(raw.Value, &v)
+		r.Value = v
+	case TypeZSet:
+		var v []ZMember
+		err = json.Unmarshal(raw.Value, &v)
+		r.Value = v
+	case TypeStream:
+		var v Stream
+		err = json.Unmarshal(raw.Value, &v)
+		r.Value = &v
+	default:
+		return fmt.Errorf("key %q: unsupported type %q", r.Key, r.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: decoding %s value: %w", r.Key, r.Type, err)
+	}
+	return nil
+}
+
+// encodeBinary switches the record to base64 if any string is not valid UTF-8,
+// since JSON would otherwise silently replace the invalid bytes
+func (r *Record) encodeBinary() {
+	binary := false
+	r.eachString(func(s string) string {
+		if !utf8.ValidString(s) {
+			binary = true
+		}
+		return s
+	})
+	if binary {
+		r.eachString(func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		})
+		r.Encoding = EncodingBase64
+	}
+}
+
+// decodeBinary reverses encodeBinary
+func (r *Record) decodeBinary() error {
+	switch r.Encoding {
+	case "":
+		return nil
+	case EncodingBase64:
+	default:
+		return fmt.Errorf("key %q: unsupported encoding %q", r.Key, r.Encoding)
+	}
+
+	var err error
+	r.eachString(func(s string) string {
+		b, decErr := base64.StdEncoding.DecodeString(s)
+		if decErr != nil && err == nil {
+			err = fmt.Errorf("key %q: invalid base64: %w", r.Key, decErr)
+		}
+		return string(b)
+	})
+	r.Encoding = ""
+	return err
+}
+
+// eachString applies f to the key and every string in the value
+func (r *Record) eachString(f func(string) string) {
+	r.Key = f(r.Key)
+	switch v := r.Value.(type) {
+	case string:
+		r.Value = f(v)
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for field, val := range v {
+			m[f(field)] = f(val)
+		}
+		r.Value = m
+	case []string:
+		for i := range v {
+			v[i] = f(v[i])
+		}
+	case []ZMember:
+		for i := range v {
+			v[i].Member = f(v[i].Member)
+		}
+	case *Stream:
+		for i := range v.Entries {
+			for j := range v.Entries[i].Fields {
+				v.Entries[i].Fields[j] = f(v.Entries[i].Fields[j])
+			}
+		}
+	}
+}
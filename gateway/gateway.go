@@ -0,0 +1,257 @@
+// Package gateway exposes Redis data structures as JSON over HTTP, for
+// clients that cannot speak RESP, such as a browser.
+//
+// Every endpoint is declared once, in a route table, as a handler taking a
+// request struct and returning a response struct. The request's struct
+// tags drive decoding of path, query and body parameters and their
+// validation, and the OpenAPI document served at /openapi.json is generated
+// from the same table, so the two cannot drift apart. Pub/Sub channels and
+// streams can be followed as Server-Sent Events.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config controls a Server
+type Config struct {
+	AllowOrigins []string      // Browser origins allowed by CORS, "*" for any; default none
+	KeepAlive    time.Duration // Interval of keep-alive comments on event streams; default 10s
+	MaxBody      int64         // Largest request body in bytes; default 1 MiB
+}
+
+// Server serves the gateway's endpoints
+type Server struct {
+	rdb    redis.UniversalClient
+	cfg    Config
+	mux    *http.ServeMux
+	routes []route
+	spec   []byte
+}
+
+// route is an endpoint of the gateway
+type route struct {
+	id       string // operationId, the name of the handler
+	method   string
+	path     string
+	summary  string
+	request  reflect.Type
+	response reflect.Type // Body of a JSON response, or data of each event
+	fields   []field
+	stream   bool // Responds with Server-Sent Events
+	handle   func(w http.ResponseWriter, r *http.Request, req any) error
+}
+
+// endpoint declares a route answering with JSON
+func endpoint[Req, Resp any](method, path, summary string, fn func(context.Context, *Req) (*Resp, error)) route {
+	return route{
+		id: handlerName(fn), method: method, path: path, summary: summary,
+		request: reflect.TypeFor[Req](), response: reflect.TypeFor[Resp](),
+		handle: func(w http.ResponseWriter, r *http.Request, req any) error {
+			resp, err := fn(r.Context(), req.(*Req))
+			if err != nil {
+				return err
+			}
+			writeJSON(w, http.StatusOK, resp)
+			return nil
+		},
+	}
+}
+
+// eventStream declares a GET route answering with Server-Sent Events whose
+// data is an Event. Errors after the stream opened end it with an error event.
+func eventStream[Req, Event any](path, summary string, fn func(context.Context, *Req, *events) error) route {
+	return route{
+		id: handlerName(fn), method: http.MethodGet, path: path, summary: summary,
+		request: reflect.TypeFor[Req](), response: reflect.TypeFor[Event](), stream: true,
+		handle: func(w http.ResponseWriter, r *http.Request, req any) error {
+			ev := &events{w: w, rc: http.NewResponseController(w)}
+			err := fn(r.Context(), req.(*Req), ev)
+			if r.Context().Err() != nil {
+				return nil // Client went away
+			}
+			if err != nil && ev.started {
+				_, msg := errorStatus(err)
+				ev.send("", "error", errorResponse{Error: msg})
+				return nil
+			}
+			return err
+		},
+	}
+}
+
+// handlerName returns the name of a method value, e.g. getHash
+func handlerName(fn any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// New creates a gateway to rdb
+func New(rdb redis.UniversalClient, cfg Config) *Server {
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = 10 * time.Second
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = 1 << 20
+	}
+	s := &Server{rdb: rdb, cfg: cfg, mux: http.NewServeMux()}
+	s.routes = s.table()
+	for i := range s.routes {
+		rt := &s.routes[i]
+		rt.fields = fieldsOf(rt.request)
+		s.mux.HandleFunc(rt.method+" "+rt.path, s.serve(rt))
+	}
+
+	spec, err := json.MarshalIndent(s.openAPI(), "", "  ")
+	if err != nil {
+		panic("gateway: encoding OpenAPI document: " + err.Error())
+	}
+	s.spec = spec
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.spec)
+	})
+	return s
+}
+
+// table lists the endpoints
+func (s *Server) table() []route {
+	return []route{
+		endpoint("GET", "/keys", "Scan keys matching a pattern", s.scanKeys),
+		endpoint("GET", "/keys/{key}", "Type and TTL of a key", s.getKey),
+		endpoint("DELETE", "/keys/{key}", "Delete a key", s.deleteKey),
+		endpoint("PUT", "/keys/{key}/ttl", "Set the TTL of a key", s.setTTL),
+		endpoint("DELETE", "/keys/{key}/ttl", "Remove the TTL of a key", s.persist),
+
+		endpoint("GET", "/string/{key}", "Get a string", s.getString),
+		endpoint("PUT", "/string/{key}", "Set a string", s.setString),
+		endpoint("POST", "/string/{key}/incr", "Increment an integer string", s.incrString),
+
+		endpoint("GET", "/hash/{key}", "Get every field of a hash", s.getHash),
+		endpoint("PUT", "/hash/{key}", "Set fields of a hash", s.setHash),
+		endpoint("POST", "/hash/{key}/incr", "Increment an integer field of a hash", s.incrHash),
+		endpoint("GET", "/hash/{key}/{field}", "Get a field of a hash", s.getHashField),
+		endpoint("DELETE", "/hash/{key}/{field}", "Delete a field of a hash", s.deleteHashField),
+
+		endpoint("GET", "/list/{key}", "Get a range of a list", s.getList),
+		endpoint("POST", "/list/{key}/push", "Push values onto a list", s.pushList),
+		endpoint("POST", "/list/{key}/pop", "Pop values off a list", s.popList),
+
+		endpoint("GET", "/set/{key}", "Get the members of a set", s.getSet),
+		endpoint("POST", "/set/{key}", "Add members to a set", s.addSet),
+		endpoint("POST", "/set/{key}/remove", "Remove members from a set", s.removeSet),
+		endpoint("GET", "/set/{key}/{member}", "Check set membership", s.isSetMember),
+
+		endpoint("GET", "/zset/{key}", "Get a range of a sorted set by rank", s.getZSet),
+		endpoint("POST", "/zset/{key}", "Add members to a sorted set", s.addZSet),
+		endpoint("POST", "/zset/{key}/incr", "Increment the score of a member", s.incrZSet),
+		endpoint("GET", "/zset/{key}/{member}", "Score and rank of a member", s.getZSetMember),
+
+		endpoint("GET", "/stream/{key}", "Get a range of stream entries", s.getStream),
+		endpoint("POST", "/stream/{key}", "Append an entry to a stream", s.addStream),
+		eventStream[streamEventsRequest, streamEntry]("/stream/{key}/events", "Follow new stream entries", s.streamEvents),
+
+		endpoint("POST", "/pubsub/{channel}", "Publish a message", s.publish),
+		eventStream[subscribeRequest, pubsubMessage]("/pubsub/{channel}/events", "Follow the messages of a channel", s.subscribe),
+	}
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the gateway, as JSON
+func (s *Server) OpenAPI() []byte {
+	return s.spec
+}
+
+// ServeHTTP answers CORS preflight requests and dispatches to the endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && s.allowed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) allowed(origin string) bool {
+	return slices.Contains(s.cfg.AllowOrigins, "*") || slices.Contains(s.cfg.AllowOrigins, origin)
+}
+
+// serve decodes and validates the request of rt and runs its handler
+func (s *Server) serve(rt *route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBody)
+		req := reflect.New(rt.request)
+		err := decode(r, req, rt.fields)
+		if err == nil {
+			err = validate(req.Elem(), rt.fields, "")
+		}
+		if err == nil {
+			err = rt.handle(w, r, req.Interface())
+		}
+		if err != nil {
+			status, msg := errorStatus(err)
+			writeJSON(w, status, errorResponse{Error: msg})
+		}
+	}
+}
+
+// errorResponse is the body of every error
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// apiError is an error with its HTTP status
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func notFound(key string) error {
+	return &apiError{http.StatusNotFound, fmt.Sprintf("%s not found", key)}
+}
+
+// errorStatus maps err to a status: errors Redis replied with are the
+// client's fault, anything else means Redis could not be reached
+func errorStatus(err error) (int, string) {
+	var apiErr *apiError
+	var redisErr redis.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.status, apiErr.msg
+	case errors.Is(err, redis.Nil):
+		return http.StatusNotFound, "not found"
+	case errors.As(err, &redisErr) && strings.HasPrefix(redisErr.Error(), "WRONGTYPE"):
+		return http.StatusConflict, redisErr.Error()
+	case errors.As(err, &redisErr):
+		return http.StatusBadRequest, redisErr.Error()
+	}
+	return http.StatusBadGateway, "redis: " + err.Error()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,542 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPath is the {key} of the data structure endpoints
+type keyPath struct {
+	Key string `path:"key" doc:"Redis key; escape a / in it as %2F"`
+}
+
+// expiry sets the TTL of the key a write creates or updates
+type expiry struct {
+	TTL int64 `json:"ttl_seconds,omitempty" min:"0" doc:"Expire the key after this many seconds; 0 keeps its current TTL"`
+}
+
+// expire queues an EXPIRE on pipe when a TTL was requested
+func (e expiry) expire(ctx context.Context, pipe redis.Pipeliner, key string) {
+	if e.TTL > 0 {
+		pipe.Expire(ctx, key, time.Duration(e.TTL)*time.Second)
+	}
+}
+
+// exists turns an empty read into a 404 when the key does not exist
+func (s *Server) exists(ctx context.Context, key string, empty bool) error {
+	if !empty {
+		return nil
+	}
+	n, err := s.rdb.Exists(ctx, key).Result()
+	if err == nil && n == 0 {
+		return notFound(key)
+	}
+	return err
+}
+
+// found turns redis.Nil into a 404 naming what was missing
+func found(what string, err error) error {
+	if err == redis.Nil {
+		return notFound(what)
+	}
+	return err
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+type deleteResponse struct {
+	Deleted bool `json:"deleted"`
+}
+
+type updateResponse struct {
+	Updated bool `json:"updated"`
+}
+
+type addResponse struct {
+	Added int64 `json:"added" doc:"New members or fields; updated ones are not counted"`
+}
+
+// Keys
+
+type scanRequest struct {
+	Match  string `query:"match" default:"*" doc:"Glob-style pattern"`
+	Type   string `query:"type" enum:"string,list,set,zset,hash,stream" doc:"Only return keys of this type"`
+	Cursor uint64 `query:"cursor" doc:"Cursor returned with the previous page; 0 starts a scan"`
+	Count  int64  `query:"count" default:"100" min:"1" max:"1000" doc:"Keys to examine for this page, a hint"`
+}
+
+type scanResponse struct {
+	Keys   []string `json:"keys"`
+	Cursor uint64   `json:"cursor" doc:"Cursor of the next page; 0 when the scan is complete"`
+}
+
+func (s *Server) scanKeys(ctx context.Context, req *scanRequest) (*scanResponse, error) {
+	var cmd *redis.ScanCmd
+	if req.Type != "" {
+		cmd = s.rdb.ScanType(ctx, req.Cursor, req.Match, req.Count, req.Type)
+	} else {
+		cmd = s.rdb.Scan(ctx, req.Cursor, req.Match, req.Count)
+	}
+	keys, cursor, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	return &scanResponse{Keys: orEmpty(keys), Cursor: cursor}, nil
+}
+
+type keyInfo struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	TTL  int64  `json:"ttl_seconds" doc:"Seconds until the key expires, -1 if it does not"`
+}
+
+func (s *Server) getKey(ctx context.Context, req *keyPath) (*keyInfo, error) {
+	pipe := s.rdb.Pipeline()
+	typ := pipe.Type(ctx, req.Key)
+	ttl := pipe.TTL(ctx, req.Key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if typ.Val() == "none" {
+		return nil, notFound(req.Key)
+	}
+	info := &keyInfo{Key: req.Key, Type: typ.Val(), TTL: -1}
+	if d := ttl.Val(); d > 0 {
+		info.TTL = int64((d + time.Second - 1) / time.Second)
+	}
+	return info, nil
+}
+
+func (s *Server) deleteKey(ctx context.Context, req *keyPath) (*deleteResponse, error) {
+	n, err := s.rdb.Del(ctx, req.Key).Result()
+	return &deleteResponse{Deleted: n == 1}, err
+}
+
+type setTTLRequest struct {
+	keyPath
+	TTL int64 `json:"ttl_seconds" required:"true" min:"1" doc:"Expire the key after this many seconds"`
+}
+
+func (s *Server) setTTL(ctx context.Context, req *setTTLRequest) (*updateResponse, error) {
+	ok, err := s.rdb.Expire(ctx, req.Key, time.Duration(req.TTL)*time.Second).Result()
+	if err == nil && !ok {
+		return nil, notFound(req.Key)
+	}
+	return &updateResponse{Updated: ok}, err
+}
+
+func (s *Server) persist(ctx context.Context, req *keyPath) (*updateResponse, error) {
+	ok, err := s.rdb.Persist(ctx, req.Key).Result()
+	return &updateResponse{Updated: ok}, err
+}
+
+// Strings
+
+type stringResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (s *Server) getString(ctx context.Context, req *keyPath) (*stringResponse, error) {
+	v, err := s.rdb.Get(ctx, req.Key).Result()
+	if err != nil {
+		return nil, found(req.Key, err)
+	}
+	return &stringResponse{Key: req.Key, Value: v}, nil
+}
+
+type setStringRequest struct {
+	keyPath
+	Value  string `json:"value"`
+	OnlyIf string `json:"only_if,omitempty" enum:"absent,present" doc:"Only write when the key is absent (NX) or present (XX)"`
+	expiry
+}
+
+func (s *Server) setString(ctx context.Context, req *setStringRequest) (*updateResponse, error) {
+	args := redis.SetArgs{Mode: map[string]string{"absent": "NX", "present": "XX"}[req.OnlyIf]}
+	if req.TTL > 0 {
+		args.TTL = time.Duration(req.TTL) * time.Second
+	} else {
+		args.KeepTTL = true
+	}
+	err := s.rdb.SetArgs(ctx, req.Key, req.Value, args).Err()
+	if err == redis.Nil {
+		return &updateResponse{Updated: false}, nil
+	}
+	return &updateResponse{Updated: err == nil}, err
+}
+
+type incrRequest struct {
+	keyPath
+	By int64 `json:"by" default:"1"`
+	expiry
+}
+
+type counterResponse struct {
+	Key   string `json:"key"`
+	Value int64  `json:"value" doc:"Value after the increment"`
+}
+
+func (s *Server) incrString(ctx context.Context, req *incrRequest) (*counterResponse, error) {
+	var incr *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, req.Key, req.By)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &counterResponse{Key: req.Key, Value: incr.Val()}, nil
+}
+
+// Hashes
+
+type hashResponse struct {
+	Key    string            `json:"key"`
+	Fields map[string]string `json:"fields"`
+}
+
+func (s *Server) getHash(ctx context.Context, req *keyPath) (*hashResponse, error) {
+	fields, err := s.rdb.HGetAll(ctx, req.Key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, notFound(req.Key)
+	}
+	return &hashResponse{Key: req.Key, Fields: fields}, nil
+}
+
+type setHashRequest struct {
+	keyPath
+	Fields map[string]string `json:"fields" required:"true"`
+	expiry
+}
+
+func (s *Server) setHash(ctx context.Context, req *setHashRequest) (*addResponse, error) {
+	var hset *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		hset = pipe.HSet(ctx, req.Key, req.Fields)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &addResponse{Added: hset.Val()}, nil
+}
+
+type hashFieldRequest struct {
+	keyPath
+	Field string `path:"field"`
+}
+
+type hashFieldResponse struct {
+	Key   string `json:"key"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (s *Server) getHashField(ctx context.Context, req *hashFieldRequest) (*hashFieldResponse, error) {
+	v, err := s.rdb.HGet(ctx, req.Key, req.Field).Result()
+	if err != nil {
+		return nil, found(req.Key+" field "+req.Field, err)
+	}
+	return &hashFieldResponse{Key: req.Key, Field: req.Field, Value: v}, nil
+}
+
+func (s *Server) deleteHashField(ctx context.Context, req *hashFieldRequest) (*deleteResponse, error) {
+	n, err := s.rdb.HDel(ctx, req.Key, req.Field).Result()
+	return &deleteResponse{Deleted: n == 1}, err
+}
+
+type incrHashRequest struct {
+	keyPath
+	Field string `json:"field" required:"true"`
+	By    int64  `json:"by" default:"1"`
+	expiry
+}
+
+type hashCounterResponse struct {
+	Key   string `json:"key"`
+	Field string `json:"field"`
+	Value int64  `json:"value" doc:"Value after the increment"`
+}
+
+func (s *Server) incrHash(ctx context.Context, req *incrHashRequest) (*hashCounterResponse, error) {
+	var incr *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.HIncrBy(ctx, req.Key, req.Field, req.By)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hashCounterResponse{Key: req.Key, Field: req.Field, Value: incr.Val()}, nil
+}
+
+// Lists
+
+type listRangeRequest struct {
+	keyPath
+	Start int64 `query:"start" default:"0" doc:"First index; negative ones count from the end"`
+	Stop  int64 `query:"stop" default:"-1" doc:"Last index, inclusive"`
+}
+
+type listResponse struct {
+	Key   string   `json:"key"`
+	Items []string `json:"items"`
+}
+
+func (s *Server) getList(ctx context.Context, req *listRangeRequest) (*listResponse, error) {
+	items, err := s.rdb.LRange(ctx, req.Key, req.Start, req.Stop).Result()
+	if err == nil {
+		err = s.exists(ctx, req.Key, len(items) == 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &listResponse{Key: req.Key, Items: orEmpty(items)}, nil
+}
+
+type pushRequest struct {
+	keyPath
+	Values []string `json:"values" required:"true" max:"1000"`
+	Side   string   `json:"side,omitempty" enum:"left,right" default:"right" doc:"End to push onto"`
+	expiry
+}
+
+type lengthResponse struct {
+	Length int64 `json:"length" doc:"Length of the list after the push"`
+}
+
+func (s *Server) pushList(ctx context.Context, req *pushRequest) (*lengthResponse, error) {
+	values := make([]interface{}, len(req.Values))
+	for i, v := range req.Values {
+		values[i] = v
+	}
+	var push *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if req.Side == "left" {
+			push = pipe.LPush(ctx, req.Key, values...)
+		} else {
+			push = pipe.RPush(ctx, req.Key, values...)
+		}
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lengthResponse{Length: push.Val()}, nil
+}
+
+type popRequest struct {
+	keyPath
+	Side  string `json:"side,omitempty" enum:"left,right" default:"left" doc:"End to pop from"`
+	Count int    `json:"count,omitempty" default:"1" min:"1" max:"1000"`
+}
+
+func (s *Server) popList(ctx context.Context, req *popRequest) (*listResponse, error) {
+	var items []string
+	var err error
+	if req.Side == "right" {
+		items, err = s.rdb.RPopCount(ctx, req.Key, req.Count).Result()
+	} else {
+		items, err = s.rdb.LPopCount(ctx, req.Key, req.Count).Result()
+	}
+	if err != nil {
+		return nil, found(req.Key, err)
+	}
+	return &listResponse{Key: req.Key, Items: items}, nil
+}
+
+// Sets
+
+type membersResponse struct {
+	Key     string   `json:"key"`
+	Members []string `json:"members"`
+}
+
+func (s *Server) getSet(ctx context.Context, req *keyPath) (*membersResponse, error) {
+	members, err := s.rdb.SMembers(ctx, req.Key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, notFound(req.Key)
+	}
+	return &membersResponse{Key: req.Key, Members: members}, nil
+}
+
+type membersRequest struct {
+	keyPath
+	Members []string `json:"members" required:"true" max:"1000"`
+	expiry
+}
+
+func (s *Server) addSet(ctx context.Context, req *membersRequest) (*addResponse, error) {
+	members := make([]interface{}, len(req.Members))
+	for i, m := range req.Members {
+		members[i] = m
+	}
+	var sadd *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		sadd = pipe.SAdd(ctx, req.Key, members...)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &addResponse{Added: sadd.Val()}, nil
+}
+
+type removeRequest struct {
+	keyPath
+	Members []string `json:"members" required:"true" max:"1000"`
+}
+
+type removeResponse struct {
+	Removed int64 `json:"removed"`
+}
+
+func (s *Server) removeSet(ctx context.Context, req *removeRequest) (*removeResponse, error) {
+	members := make([]interface{}, len(req.Members))
+	for i, m := range req.Members {
+		members[i] = m
+	}
+	n, err := s.rdb.SRem(ctx, req.Key, members...).Result()
+	return &removeResponse{Removed: n}, err
+}
+
+type memberRequest struct {
+	keyPath
+	Member string `path:"member"`
+}
+
+type isMemberResponse struct {
+	Key      string `json:"key"`
+	Member   string `json:"member"`
+	IsMember bool   `json:"is_member"`
+}
+
+func (s *Server) isSetMember(ctx context.Context, req *memberRequest) (*isMemberResponse, error) {
+	ok, err := s.rdb.SIsMember(ctx, req.Key, req.Member).Result()
+	return &isMemberResponse{Key: req.Key, Member: req.Member, IsMember: ok}, err
+}
+
+// Sorted sets
+
+type scoredMember struct {
+	Member string  `json:"member" required:"true"`
+	Score  float64 `json:"score"`
+}
+
+type zsetRangeRequest struct {
+	keyPath
+	Start int64 `query:"start" default:"0" doc:"First rank; negative ones count from the end"`
+	Stop  int64 `query:"stop" default:"-1" doc:"Last rank, inclusive"`
+	Rev   bool  `query:"rev" doc:"Rank from the highest score down"`
+}
+
+type zsetResponse struct {
+	Key     string         `json:"key"`
+	Members []scoredMember `json:"members"`
+}
+
+func (s *Server) getZSet(ctx context.Context, req *zsetRangeRequest) (*zsetResponse, error) {
+	zs, err := s.rdb.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{Key: req.Key, Start: req.Start, Stop: req.Stop, Rev: req.Rev}).Result()
+	if err == nil {
+		err = s.exists(ctx, req.Key, len(zs) == 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	members := make([]scoredMember, len(zs))
+	for i, z := range zs {
+		members[i] = scoredMember{Member: z.Member.(string), Score: z.Score}
+	}
+	return &zsetResponse{Key: req.Key, Members: members}, nil
+}
+
+type addZSetRequest struct {
+	keyPath
+	Members []scoredMember `json:"members" required:"true" max:"1000"`
+	expiry
+}
+
+func (s *Server) addZSet(ctx context.Context, req *addZSetRequest) (*addResponse, error) {
+	zs := make([]redis.Z, len(req.Members))
+	for i, m := range req.Members {
+		zs[i] = redis.Z{Member: m.Member, Score: m.Score}
+	}
+	var zadd *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		zadd = pipe.ZAdd(ctx, req.Key, zs...)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &addResponse{Added: zadd.Val()}, nil
+}
+
+type incrZSetRequest struct {
+	keyPath
+	Member string  `json:"member" required:"true"`
+	By     float64 `json:"by" default:"1"`
+	expiry
+}
+
+type zsetMemberResponse struct {
+	Key    string  `json:"key"`
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+	Rank   *int64  `json:"rank,omitempty" doc:"Position from the lowest score, or the highest with rev"`
+}
+
+func (s *Server) incrZSet(ctx context.Context, req *incrZSetRequest) (*zsetMemberResponse, error) {
+	var incr *redis.FloatCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.ZIncrBy(ctx, req.Key, req.By, req.Member)
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &zsetMemberResponse{Key: req.Key, Member: req.Member, Score: incr.Val()}, nil
+}
+
+type zsetMemberRequest struct {
+	keyPath
+	Member string `path:"member"`
+	Rev    bool   `query:"rev" doc:"Rank from the highest score down"`
+}
+
+func (s *Server) getZSetMember(ctx context.Context, req *zsetMemberRequest) (*zsetMemberResponse, error) {
+	pipe := s.rdb.Pipeline()
+	score := pipe.ZScore(ctx, req.Key, req.Member)
+	var rank *redis.IntCmd
+	if req.Rev {
+		rank = pipe.ZRevRank(ctx, req.Key, req.Member)
+	} else {
+		rank = pipe.ZRank(ctx, req.Key, req.Member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, found(req.Key+" member "+req.Member, err)
+	}
+	r := rank.Val()
+	return &zsetMemberResponse{Key: req.Key, Member: req.Member, Score: score.Val(), Rank: &r}, nil
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// openAPI builds the OpenAPI document from the route table
+func (s *Server) openAPI() map[string]any {
+	paths := map[string]any{}
+	for _, rt := range s.routes {
+		op := map[string]any{
+			"operationId": rt.id,
+			"summary":     rt.summary,
+			"tags":        []string{strings.SplitN(strings.TrimPrefix(rt.path, "/"), "/", 2)[0]},
+		}
+
+		var params []any
+		var body []field
+		for _, f := range rt.fields {
+			if f.in == "body" {
+				body = append(body, f)
+				continue
+			}
+			schema := f.schema()
+			delete(schema, "description")
+			p := map[string]any{"name": f.name, "in": f.in, "schema": schema}
+			if f.required {
+				p["required"] = true
+			}
+			if f.doc != "" {
+				p["description"] = f.doc
+			}
+			params = append(params, p)
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if len(body) > 0 {
+			schema := objectSchema(body)
+			op["requestBody"] = map[string]any{
+				"required": schema["required"] != nil,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+			}
+		}
+
+		ok := map[string]any{
+			"description": "Success",
+			"content":     map[string]any{"application/json": map[string]any{"schema": typeSchema(rt.response)}},
+		}
+		if rt.stream {
+			ok = map[string]any{
+				"description": "Server-Sent Events whose data is this schema as JSON; an error event carries an Error before the stream ends",
+				"content":     map[string]any{"text/event-stream": map[string]any{"schema": typeSchema(rt.response)}},
+			}
+		}
+		op["responses"] = map[string]any{
+			"200":     ok,
+			"default": map[string]any{"$ref": "#/components/responses/Error"},
+		}
+
+		item, _ := paths[rt.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Redis gateway",
+			"version":     "1.0.0",
+			"description": "JSON over HTTP for Redis strings, hashes, lists, sets, sorted sets, streams and Pub/Sub.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{"Error": typeSchema(reflect.TypeFor[errorResponse]())},
+			"responses": map[string]any{
+				"Error": map[string]any{
+					"description": "400 for invalid parameters or a command Redis rejected, 404 for a missing key, 409 for a key of another type, 502 when Redis cannot be reached",
+					"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}},
+				},
+			},
+		},
+	}
+}
+
+// typeSchema returns the JSON Schema of values of t as encoding/json writes them
+func typeSchema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return objectSchema(fieldsOf(t))
+	}
+	return map[string]any{}
+}
+
+func objectSchema(fields []field) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for _, f := range fields {
+		props[f.name] = f.schema()
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schema returns the JSON Schema of a field, with its constraints
+func (f field) schema() map[string]any {
+	schema := typeSchema(f.typ)
+	if f.doc != "" {
+		schema["description"] = f.doc
+	}
+	if f.enum != nil {
+		schema["enum"] = f.enum
+	}
+	if f.def != "" {
+		v := reflect.New(f.typ).Elem()
+		if setString(v, f.def) == nil {
+			schema["default"] = v.Interface()
+		}
+	}
+	lower, upper := "minimum", "maximum"
+	switch f.typ.Kind() {
+	case reflect.String:
+		lower, upper = "minLength", "maxLength"
+	case reflect.Slice:
+		lower, upper = "minItems", "maxItems"
+	case reflect.Map:
+		lower, upper = "minProperties", "maxProperties"
+	}
+	if f.min != nil {
+		schema[lower] = *f.min
+	}
+	if f.max != nil {
+		schema[upper] = *f.max
+	}
+	if f.required && lower != "minimum" && f.min == nil {
+		schema[lower] = 1 // required means not empty
+	}
+	return schema
+}
@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Request structs describe the parameters of an endpoint with struct tags:
+//
+//	path:"name"    the {name} wildcard of the route
+//	query:"name"   a query string parameter
+//	header:"Name"  a request header
+//	json:"name"    a property of the JSON body, for fields without the above
+//
+// and constrain them with required:"true", min, max (a value for numbers,
+// a length otherwise), enum:"a,b" and default. doc describes the parameter
+// in the OpenAPI document. Embedded structs are flattened like JSON does.
+
+// field is one parameter of a request, or property of a body or response
+type field struct {
+	index    []int
+	name     string
+	in       string // "path", "query", "header" or "body"
+	typ      reflect.Type
+	doc      string
+	def      string
+	enum     []string
+	min, max *float64
+	required bool
+}
+
+func fieldsOf(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			for _, f := range fieldsOf(sf.Type) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := field{
+			index:    []int{i},
+			typ:      sf.Type,
+			doc:      sf.Tag.Get("doc"),
+			def:      sf.Tag.Get("default"),
+			required: sf.Tag.Get("required") == "true",
+			min:      bound(sf.Tag.Get("min")),
+			max:      bound(sf.Tag.Get("max")),
+		}
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			f.enum = strings.Split(enum, ",")
+		}
+		switch {
+		case sf.Tag.Get("path") != "":
+			f.in, f.name, f.required = "path", sf.Tag.Get("path"), true
+		case sf.Tag.Get("query") != "":
+			f.in, f.name = "query", sf.Tag.Get("query")
+		case sf.Tag.Get("header") != "":
+			f.in, f.name = "header", sf.Tag.Get("header")
+		default:
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			f.in, f.name = "body", name
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func bound(tag string) *float64 {
+	if tag == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(tag, 64)
+	if err != nil {
+		panic("gateway: bad bound " + tag)
+	}
+	return &v
+}
+
+// decode fills req, a pointer to a request struct, from r
+func decode(r *http.Request, req reflect.Value, fields []field) error {
+	s := req.Elem()
+	hasBody := false
+	for _, f := range fields {
+		if f.def != "" {
+			if err := setString(s.FieldByIndex(f.index), f.def); err != nil {
+				panic(fmt.Sprintf("gateway: bad default for %s: %v", f.name, err))
+			}
+		}
+		hasBody = hasBody || f.in == "body"
+	}
+
+	if hasBody {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(req.Interface()); err != nil && err != io.EOF {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return &apiError{http.StatusRequestEntityTooLarge, fmt.Sprintf("body larger than %d bytes", tooLarge.Limit)}
+			}
+			return badRequest("invalid JSON body: %v", err)
+		}
+	}
+
+	// Path, query and header values win over body properties of the same name
+	query := r.URL.Query()
+	for _, f := range fields {
+		var raw string
+		switch f.in {
+		case "path":
+			raw = r.PathValue(f.name)
+		case "query":
+			if !query.Has(f.name) {
+				continue
+			}
+			raw = query.Get(f.name)
+		case "header":
+			if raw = r.Header.Get(f.name); raw == "" {
+				continue
+			}
+		default:
+			continue
+		}
+		if err := setString(s.FieldByIndex(f.index), raw); err != nil {
+			return badRequest("%s parameter %s: %v", f.in, f.name, err)
+		}
+	}
+	return nil
+}
+
+// setString parses s into v according to its kind
+func setString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return errors.New("not a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.New("not a number")
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// validate checks the constraints of fields on s, and of the structs in
+// its slices, naming offending fields with prefix
+func validate(s reflect.Value, fields []field, prefix string) error {
+	for _, f := range fields {
+		v := s.FieldByIndex(f.index)
+		name := prefix + f.name
+		if f.required && v.IsZero() {
+			return badRequest("%s is required", name)
+		}
+		if v.Kind() == reflect.String && len(f.enum) > 0 && v.String() != "" && !slices.Contains(f.enum, v.String()) {
+			return badRequest("%s must be one of %s", name, strings.Join(f.enum, ", "))
+		}
+		if n, isLen, ok := measure(v); ok {
+			what := "%s must be at least %g"
+			if isLen {
+				what = "%s must have a length of at least %g"
+			}
+			if f.min != nil && n < *f.min {
+				return badRequest(what, name, *f.min)
+			}
+			what = strings.Replace(what, "at least", "at most", 1)
+			if f.max != nil && n > *f.max {
+				return badRequest(what, name, *f.max)
+			}
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct {
+			elemFields := fieldsOf(v.Type().Elem())
+			for i := 0; i < v.Len(); i++ {
+				if err := validate(v.Index(i), elemFields, fmt.Sprintf("%s[%d].", name, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// measure returns what min and max bound: the value of a number, the
+// length of anything else
+func measure(v reflect.Value) (n float64, isLen, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
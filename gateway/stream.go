@@ -0,0 +1,242 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// events writes a Server-Sent Events response
+type events struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+// open sends the headers, which is when a browser's EventSource fires open
+func (e *events) open() error {
+	if e.started {
+		return nil
+	}
+	h := e.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // Stop proxies such as nginx from buffering
+	e.w.WriteHeader(http.StatusOK)
+	e.started = true
+	return e.rc.Flush()
+}
+
+// send writes an event whose data is v as JSON. A client that reconnects
+// sends the last id it saw in the Last-Event-ID header.
+func (e *events) send(id, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := e.open(); err != nil {
+		return err
+	}
+	if id != "" {
+		fmt.Fprintf(e.w, "id: %s\n", id)
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data)
+	return e.rc.Flush()
+}
+
+// ping writes a comment, keeping proxies from closing an idle stream
+func (e *events) ping() error {
+	if err := e.open(); err != nil {
+		return err
+	}
+	fmt.Fprint(e.w, ": keep-alive\n\n")
+	return e.rc.Flush()
+}
+
+// Streams
+
+type streamEntry struct {
+	ID     string            `json:"id"`
+	Values map[string]string `json:"values"`
+}
+
+func entries(msgs []redis.XMessage) []streamEntry {
+	list := make([]streamEntry, len(msgs))
+	for i, m := range msgs {
+		values := make(map[string]string, len(m.Values))
+		for k, v := range m.Values {
+			values[k] = fmt.Sprint(v)
+		}
+		list[i] = streamEntry{ID: m.ID, Values: values}
+	}
+	return list
+}
+
+type streamRangeRequest struct {
+	keyPath
+	Start string `query:"start" default:"-" doc:"First entry ID, - for the oldest"`
+	End   string `query:"end" default:"+" doc:"Last entry ID, + for the newest"`
+	Count int64  `query:"count" default:"100" min:"1" max:"1000"`
+}
+
+type streamResponse struct {
+	Key     string        `json:"key"`
+	Entries []streamEntry `json:"entries"`
+}
+
+func (s *Server) getStream(ctx context.Context, req *streamRangeRequest) (*streamResponse, error) {
+	msgs, err := s.rdb.XRangeN(ctx, req.Key, req.Start, req.End, req.Count).Result()
+	if err == nil {
+		err = s.exists(ctx, req.Key, len(msgs) == 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &streamResponse{Key: req.Key, Entries: entries(msgs)}, nil
+}
+
+type addStreamRequest struct {
+	keyPath
+	Values map[string]string `json:"values" required:"true"`
+	MaxLen int64             `json:"maxlen,omitempty" min:"0" doc:"Trim the stream to about this many entries; 0 keeps all"`
+	expiry
+}
+
+type streamIDResponse struct {
+	Key string `json:"key"`
+	ID  string `json:"id"`
+}
+
+func (s *Server) addStream(ctx context.Context, req *addStreamRequest) (*streamIDResponse, error) {
+	var xadd *redis.StringCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		xadd = pipe.XAdd(ctx, &redis.XAddArgs{Stream: req.Key, MaxLen: req.MaxLen, Approx: req.MaxLen > 0, Values: req.Values})
+		req.expire(ctx, pipe, req.Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &streamIDResponse{Key: req.Key, ID: xadd.Val()}, nil
+}
+
+type streamEventsRequest struct {
+	keyPath
+	After       string `query:"after" default:"$" doc:"Send entries after this ID; $ for only new ones"`
+	LastEventID string `header:"Last-Event-ID" doc:"Sent by a reconnecting EventSource; overrides after"`
+}
+
+// streamEvents sends the entries of a stream as they are added. Each
+// blocking XREAD holds a connection of the pool while the client follows.
+func (s *Server) streamEvents(ctx context.Context, req *streamEventsRequest, ev *events) error {
+	last := req.After
+	if req.LastEventID != "" {
+		last = req.LastEventID
+	}
+	if last == "$" {
+		// Resolve now, or entries added between two reads would be skipped
+		newest, err := s.rdb.XRevRangeN(ctx, req.Key, "+", "-", 1).Result()
+		if err != nil {
+			return err
+		}
+		last = "0-0"
+		if len(newest) > 0 {
+			last = newest[0].ID
+		}
+	}
+	if err := ev.open(); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		streams, err := s.rdb.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{req.Key, last}, Count: 100, Block: s.cfg.KeepAlive,
+		}).Result()
+		if err == redis.Nil {
+			if err := ev.ping(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range entries(streams[0].Messages) {
+			if err := ev.send(e.ID, "entry", e); err != nil {
+				return err
+			}
+			last = e.ID
+		}
+	}
+	return nil
+}
+
+// Pub/Sub
+
+type publishRequest struct {
+	Channel string `path:"channel"`
+	Message string `json:"message"`
+}
+
+type publishResponse struct {
+	Receivers int64 `json:"receivers" doc:"Subscribers the message was delivered to"`
+}
+
+func (s *Server) publish(ctx context.Context, req *publishRequest) (*publishResponse, error) {
+	n, err := s.rdb.Publish(ctx, req.Channel, req.Message).Result()
+	return &publishResponse{Receivers: n}, err
+}
+
+type subscribeRequest struct {
+	Channel string `path:"channel"`
+	Pattern bool   `query:"pattern" doc:"Treat the channel as a glob-style pattern (PSUBSCRIBE)"`
+}
+
+type pubsubMessage struct {
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern,omitempty"`
+	Message string `json:"message"`
+}
+
+// subscribe sends the messages of a channel. The stream opens once Redis
+// has confirmed the subscription, so nothing published after is missed.
+func (s *Server) subscribe(ctx context.Context, req *subscribeRequest, ev *events) error {
+	var sub *redis.PubSub
+	if req.Pattern {
+		sub = s.rdb.PSubscribe(ctx, req.Channel)
+	} else {
+		sub = s.rdb.Subscribe(ctx, req.Channel)
+	}
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	if err := ev.open(); err != nil {
+		return err
+	}
+
+	msgs := sub.Channel()
+	ticker := time.NewTicker(s.cfg.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+			if err := ev.send("", "message", pubsubMessage{Channel: msg.Channel, Pattern: msg.Pattern, Message: msg.Payload}); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := ev.ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
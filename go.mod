@@ -2,7 +2,10 @@ module Redis
 
 go 1.25.0
 
-require github.com/redis/go-redis/v9 v9.13.0
+require (
+	github.com/redis/go-redis/v9 v9.13.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
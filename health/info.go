@@ -0,0 +1,228 @@
+// Package health polls a server's INFO, SLOWLOG and LATENCY output, parses
+// it into typed structs and derives rates between polls.
+package health
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Server is the server section of INFO
+type Server struct {
+	Version       string `info:"redis_version"`
+	Mode          string `info:"redis_mode"`
+	OS            string `info:"os"`
+	ProcessID     int64  `info:"process_id"`
+	TCPPort       int64  `info:"tcp_port"`
+	UptimeSeconds int64  `info:"uptime_in_seconds"`
+	Hz            int64  `info:"hz"`
+	ConfigFile    string `info:"config_file"`
+}
+
+// Clients is the clients section of INFO
+type Clients struct {
+	Connected int64 `info:"connected_clients"`
+	Blocked   int64 `info:"blocked_clients"`
+	Tracking  int64 `info:"tracking_clients"`
+	Max       int64 `info:"maxclients"`
+}
+
+// Memory is the memory section of INFO
+type Memory struct {
+	Used               int64   `info:"used_memory"`
+	RSS                int64   `info:"used_memory_rss"`
+	Peak               int64   `info:"used_memory_peak"`
+	Dataset            int64   `info:"used_memory_dataset"`
+	Lua                int64   `info:"used_memory_lua"`
+	Max                int64   `info:"maxmemory"`
+	MaxPolicy          string  `info:"maxmemory_policy"`
+	TotalSystem        int64   `info:"total_system_memory"`
+	FragmentationRatio float64 `info:"mem_fragmentation_ratio"`
+	FragmentationBytes int64   `info:"mem_fragmentation_bytes"`
+}
+
+// Stats is the stats section of INFO
+type Stats struct {
+	ConnectionsReceived  int64   `info:"total_connections_received"`
+	CommandsProcessed    int64   `info:"total_commands_processed"`
+	InstantaneousOps     int64   `info:"instantaneous_ops_per_sec"`
+	NetInputBytes        int64   `info:"total_net_input_bytes"`
+	NetOutputBytes       int64   `info:"total_net_output_bytes"`
+	InstantaneousInKbps  float64 `info:"instantaneous_input_kbps"`
+	InstantaneousOutKbps float64 `info:"instantaneous_output_kbps"`
+	RejectedConnections  int64   `info:"rejected_connections"`
+	ExpiredKeys          int64   `info:"expired_keys"`
+	EvictedKeys          int64   `info:"evicted_keys"`
+	KeyspaceHits         int64   `info:"keyspace_hits"`
+	KeyspaceMisses       int64   `info:"keyspace_misses"`
+	PubsubChannels       int64   `info:"pubsub_channels"`
+	PubsubPatterns       int64   `info:"pubsub_patterns"`
+	LatestForkUsec       int64   `info:"latest_fork_usec"`
+	ErrorReplies         int64   `info:"total_error_replies"`
+}
+
+// Persistence is the persistence section of INFO
+type Persistence struct {
+	Loading                int64  `info:"loading"`
+	RDBChangesSinceSave    int64  `info:"rdb_changes_since_last_save"`
+	RDBBgsaveInProgress    int64  `info:"rdb_bgsave_in_progress"`
+	RDBLastSaveTime        int64  `info:"rdb_last_save_time"`
+	RDBLastBgsaveStatus    string `info:"rdb_last_bgsave_status"`
+	AOFEnabled             int64  `info:"aof_enabled"`
+	AOFRewriteInProgress   int64  `info:"aof_rewrite_in_progress"`
+	AOFLastBgrewriteStatus string `info:"aof_last_bgrewrite_status"`
+	AOFLastWriteStatus     string `info:"aof_last_write_status"`
+}
+
+// Replication is the replication section of INFO
+type Replication struct {
+	Role             string `info:"role"`
+	ConnectedSlaves  int64  `info:"connected_slaves"`
+	MasterHost       string `info:"master_host"`
+	MasterPort       int64  `info:"master_port"`
+	MasterLinkStatus string `info:"master_link_status"`
+	MasterReplOffset int64  `info:"master_repl_offset"`
+}
+
+// CPU is the cpu section of INFO
+type CPU struct {
+	Sys  float64 `info:"used_cpu_sys"`
+	User float64 `info:"used_cpu_user"`
+}
+
+// KeyspaceDB is one dbN line of the keyspace section
+type KeyspaceDB struct {
+	Keys    int64
+	Expires int64
+	AvgTTL  int64 // Milliseconds
+}
+
+// CommandStat is one cmdstat_* line of the commandstats section
+type CommandStat struct {
+	Calls         int64
+	Usec          int64
+	UsecPerCall   float64
+	RejectedCalls int64
+	FailedCalls   int64
+}
+
+// Info is a parsed INFO reply. Fields missing from the reply are left zero;
+// Raw holds every field by lowercased section name.
+type Info struct {
+	Server       Server
+	Clients      Clients
+	Memory       Memory
+	Stats        Stats
+	Persistence  Persistence
+	Replication  Replication
+	CPU          CPU
+	Keyspace     map[int]KeyspaceDB
+	Commandstats map[string]CommandStat
+	Raw          map[string]map[string]string
+}
+
+// Has reports whether the reply included the section
+func (i *Info) Has(section string) bool {
+	_, ok := i.Raw[section]
+	return ok
+}
+
+// ParseInfo parses the text returned by INFO
+func ParseInfo(text string) *Info {
+	info := &Info{
+		Keyspace:     make(map[int]KeyspaceDB),
+		Commandstats: make(map[string]CommandStat),
+		Raw:          make(map[string]map[string]string),
+	}
+
+	section := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			if info.Raw[section] == nil {
+				info.Raw[section] = make(map[string]string)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if info.Raw[section] == nil {
+			info.Raw[section] = make(map[string]string)
+		}
+		info.Raw[section][key] = value
+
+		switch {
+		case section == "keyspace" && strings.HasPrefix(key, "db"):
+			n, err := strconv.Atoi(key[2:])
+			if err != nil {
+				continue
+			}
+			f := subfields(value)
+			info.Keyspace[n] = KeyspaceDB{Keys: atoi(f["keys"]), Expires: atoi(f["expires"]), AvgTTL: atoi(f["avg_ttl"])}
+		case section == "commandstats" && strings.HasPrefix(key, "cmdstat_"):
+			f := subfields(value)
+			usecPerCall, _ := strconv.ParseFloat(f["usec_per_call"], 64)
+			info.Commandstats[key[len("cmdstat_"):]] = CommandStat{
+				Calls:         atoi(f["calls"]),
+				Usec:          atoi(f["usec"]),
+				UsecPerCall:   usecPerCall,
+				RejectedCalls: atoi(f["rejected_calls"]),
+				FailedCalls:   atoi(f["failed_calls"]),
+			}
+		}
+	}
+
+	fill(&info.Server, info.Raw["server"])
+	fill(&info.Clients, info.Raw["clients"])
+	fill(&info.Memory, info.Raw["memory"])
+	fill(&info.Stats, info.Raw["stats"])
+	fill(&info.Persistence, info.Raw["persistence"])
+	fill(&info.Replication, info.Raw["replication"])
+	fill(&info.CPU, info.Raw["cpu"])
+	return info
+}
+
+// fill sets the fields of the struct pointed to by dst from their info tags
+func fill(dst interface{}, fields map[string]string) {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		raw, ok := fields[t.Field(i).Tag.Get("info")]
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(raw)
+		case reflect.Int64:
+			f.SetInt(atoi(raw))
+		case reflect.Float64:
+			n, _ := strconv.ParseFloat(raw, 64)
+			f.SetFloat(n)
+		}
+	}
+}
+
+// subfields splits "keys=1,expires=0" into a map
+func subfields(s string) map[string]string {
+	m := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func atoi(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LatencyEvent is one line of LATENCY LATEST
+type LatencyEvent struct {
+	Event  string
+	Time   time.Time
+	Latest time.Duration
+	Max    time.Duration
+}
+
+// LatencySample is one line of LATENCY HISTORY
+type LatencySample struct {
+	Time    time.Time
+	Latency time.Duration
+}
+
+// LatencyLatest runs LATENCY LATEST. It is empty unless the server has
+// latency-monitor-threshold set and an event crossed it.
+func LatencyLatest(ctx context.Context, rdb redis.UniversalClient) ([]LatencyEvent, error) {
+	rows, err := rdb.Do(ctx, "latency", "latest").Slice()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]LatencyEvent, 0, len(rows))
+	for _, row := range rows {
+		f, ok := row.([]interface{})
+		if !ok || len(f) < 4 {
+			return nil, fmt.Errorf("unexpected LATENCY LATEST row %v", row)
+		}
+		events = append(events, LatencyEvent{
+			Event:  fmt.Sprint(f[0]),
+			Time:   time.Unix(toInt(f[1]), 0),
+			Latest: time.Duration(toInt(f[2])) * time.Millisecond,
+			Max:    time.Duration(toInt(f[3])) * time.Millisecond,
+		})
+	}
+	return events, nil
+}
+
+// LatencyHistory runs LATENCY HISTORY for one event, oldest sample first
+func LatencyHistory(ctx context.Context, rdb redis.UniversalClient, event string) ([]LatencySample, error) {
+	rows, err := rdb.Do(ctx, "latency", "history", event).Slice()
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]LatencySample, 0, len(rows))
+	for _, row := range rows {
+		f, ok := row.([]interface{})
+		if !ok || len(f) < 2 {
+			return nil, fmt.Errorf("unexpected LATENCY HISTORY row %v", row)
+		}
+		samples = append(samples, LatencySample{
+			Time:    time.Unix(toInt(f[0]), 0),
+			Latency: time.Duration(toInt(f[1])) * time.Millisecond,
+		})
+	}
+	return samples, nil
+}
+
+func toInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case string:
+		return atoi(n)
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Options configures a Monitor
+type Options struct {
+	Sections      []string // INFO sections to request; empty means the default set
+	SlowlogCount  int64    // Entries fetched per SLOWLOG GET; default 128
+	LatencyEvents []string // Events to fetch LATENCY HISTORY for; empty means all in LATENCY LATEST
+}
+
+// Snapshot is the result of one poll. SLOWLOG and LATENCY are optional on
+// some servers; their errors are kept instead of failing the poll.
+type Snapshot struct {
+	Time       time.Time
+	Info       *Info
+	Rates      Rates
+	NewSlow    []redis.SlowLog // Entries first seen in this poll, oldest first
+	SlowGroups []SlowGroup     // Every entry seen so far, by total time
+	SlowlogErr error
+	Latency    []LatencyEvent
+	History    map[string][]LatencySample
+	LatencyErr error
+}
+
+// Monitor polls one server and keeps the state needed for rates and
+// slow log de-duplication
+type Monitor struct {
+	rdb      redis.UniversalClient
+	opts     Options
+	prev     *Info
+	prevTime time.Time
+	slowlog  *SlowlogTracker
+}
+
+// NewMonitor returns a monitor for rdb
+func NewMonitor(rdb redis.UniversalClient, opts Options) *Monitor {
+	if opts.SlowlogCount <= 0 {
+		opts.SlowlogCount = 128
+	}
+	return &Monitor{rdb: rdb, opts: opts, slowlog: NewSlowlogTracker()}
+}
+
+// Poll fetches INFO, SLOWLOG and LATENCY once
+func (m *Monitor) Poll(ctx context.Context) (*Snapshot, error) {
+	text, err := m.rdb.Info(ctx, m.opts.Sections...).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	snap := &Snapshot{Time: now, Info: ParseInfo(text)}
+
+	var interval time.Duration
+	if m.prev != nil {
+		interval = now.Sub(m.prevTime)
+	}
+	snap.Rates = ComputeRates(m.prev, snap.Info, interval)
+	m.prev, m.prevTime = snap.Info, now
+
+	entries, err := m.rdb.SlowLogGet(ctx, m.opts.SlowlogCount).Result()
+	if err != nil {
+		snap.SlowlogErr = err
+	} else {
+		snap.NewSlow = m.slowlog.Update(entries)
+	}
+	snap.SlowGroups = m.slowlog.Top(0)
+
+	snap.Latency, snap.LatencyErr = LatencyLatest(ctx, m.rdb)
+	if snap.LatencyErr == nil {
+		events := m.opts.LatencyEvents
+		if len(events) == 0 {
+			for _, e := range snap.Latency {
+				events = append(events, e.Event)
+			}
+		}
+		snap.History = make(map[string][]LatencySample, len(events))
+		for _, event := range events {
+			samples, err := LatencyHistory(ctx, m.rdb, event)
+			if err != nil {
+				snap.LatencyErr = err
+				break
+			}
+			snap.History[event] = samples
+		}
+	}
+	return snap, nil
+}
@@ -0,0 +1,62 @@
+package health
+
+import "time"
+
+// Rates are derived from two INFO snapshots
+type Rates struct {
+	Interval          time.Duration
+	OpsPerSec         float64
+	HitRatio          float64 // Keyspace hits / lookups over the interval, 0..1
+	Lookups           int64   // Lookups over the interval
+	EvictionsPerSec   float64
+	ExpirationsPerSec float64
+	ConnectionsPerSec float64
+	NetInKBps         float64
+	NetOutKBps        float64
+	CPUPercent        float64 // Server CPU time (sys+user) per wall time
+	Fragmentation     float64 // used_memory_rss / used_memory
+}
+
+// ComputeRates compares cur with the previous snapshot taken interval
+// earlier. With no previous snapshot the server's instantaneous figures and
+// lifetime hit ratio are used instead.
+func ComputeRates(prev, cur *Info, interval time.Duration) Rates {
+	r := Rates{Interval: interval, Fragmentation: cur.Memory.FragmentationRatio}
+	if r.Fragmentation == 0 && cur.Memory.Used > 0 {
+		r.Fragmentation = float64(cur.Memory.RSS) / float64(cur.Memory.Used)
+	}
+
+	if prev == nil || interval <= 0 {
+		r.OpsPerSec = float64(cur.Stats.InstantaneousOps)
+		r.NetInKBps = cur.Stats.InstantaneousInKbps
+		r.NetOutKBps = cur.Stats.InstantaneousOutKbps
+		r.Lookups = cur.Stats.KeyspaceHits + cur.Stats.KeyspaceMisses
+		if r.Lookups > 0 {
+			r.HitRatio = float64(cur.Stats.KeyspaceHits) / float64(r.Lookups)
+		}
+		return r
+	}
+
+	secs := interval.Seconds()
+	// Counters go backwards after CONFIG RESETSTAT or a restart
+	delta := func(a, b int64) float64 { return float64(max(0, b-a)) }
+
+	r.OpsPerSec = delta(prev.Stats.CommandsProcessed, cur.Stats.CommandsProcessed) / secs
+	r.EvictionsPerSec = delta(prev.Stats.EvictedKeys, cur.Stats.EvictedKeys) / secs
+	r.ExpirationsPerSec = delta(prev.Stats.ExpiredKeys, cur.Stats.ExpiredKeys) / secs
+	r.ConnectionsPerSec = delta(prev.Stats.ConnectionsReceived, cur.Stats.ConnectionsReceived) / secs
+	r.NetInKBps = delta(prev.Stats.NetInputBytes, cur.Stats.NetInputBytes) / 1024 / secs
+	r.NetOutKBps = delta(prev.Stats.NetOutputBytes, cur.Stats.NetOutputBytes) / 1024 / secs
+
+	hits := int64(delta(prev.Stats.KeyspaceHits, cur.Stats.KeyspaceHits))
+	r.Lookups = hits + int64(delta(prev.Stats.KeyspaceMisses, cur.Stats.KeyspaceMisses))
+	if r.Lookups > 0 {
+		r.HitRatio = float64(hits) / float64(r.Lookups)
+	}
+
+	cpu := (cur.CPU.Sys + cur.CPU.User) - (prev.CPU.Sys + prev.CPU.User)
+	if cpu > 0 {
+		r.CPUPercent = cpu / secs * 100
+	}
+	return r
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"Redis/tracing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SlowGroup aggregates slow log entries for the same command shape, with
+// values sanitized the way tracing.Sanitize does
+type SlowGroup struct {
+	Statement string
+	Count     int
+	Total     time.Duration
+	Max       time.Duration
+	Last      time.Time
+}
+
+// SlowlogTracker remembers which SLOWLOG entries it has seen, so repeated
+// polls of SLOWLOG GET only report new ones
+type SlowlogTracker struct {
+	lastID int64
+	seen   bool
+	groups map[string]*SlowGroup
+}
+
+// NewSlowlogTracker returns an empty tracker
+func NewSlowlogTracker() *SlowlogTracker {
+	return &SlowlogTracker{groups: make(map[string]*SlowGroup)}
+}
+
+// Update takes the reply of SLOWLOG GET (newest first) and returns the
+// entries not reported before, oldest first. When every ID is lower than the
+// last one seen the log was reset, so all entries count as new.
+func (t *SlowlogTracker) Update(entries []redis.SlowLog) []redis.SlowLog {
+	if len(entries) == 0 {
+		return nil
+	}
+	newest := entries[0].ID
+	for _, e := range entries {
+		newest = max(newest, e.ID)
+	}
+	if t.seen && newest < t.lastID {
+		t.lastID = -1
+	}
+
+	var fresh []redis.SlowLog
+	for _, e := range entries {
+		if !t.seen || e.ID > t.lastID {
+			fresh = append(fresh, e)
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].ID < fresh[j].ID })
+
+	for _, e := range fresh {
+		args := make([]interface{}, len(e.Args))
+		for i, a := range e.Args {
+			args[i] = a
+		}
+		statement, _ := tracing.Sanitize(args)
+		g := t.groups[statement]
+		if g == nil {
+			g = &SlowGroup{Statement: statement}
+			t.groups[statement] = g
+		}
+		g.Count++
+		g.Total += e.Duration
+		g.Max = max(g.Max, e.Duration)
+		if e.Time.After(g.Last) {
+			g.Last = e.Time
+		}
+	}
+
+	t.seen = true
+	t.lastID = max(t.lastID, newest)
+	return fresh
+}
+
+// Top returns up to n groups with the highest total time
+func (t *SlowlogTracker) Top(n int) []SlowGroup {
+	groups := make([]SlowGroup, 0, len(t.groups))
+	for _, g := range t.groups {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Total != groups[j].Total {
+			return groups[i].Total > groups[j].Total
+		}
+		return strings.Compare(groups[i].Statement, groups[j].Statement) < 0
+	})
+	if n > 0 && len(groups) > n {
+		groups = groups[:n]
+	}
+	return groups
+}
@@ -4,6 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Redis/namespace"
+	"Redis/tlsconf"
+	"Redis/tracing"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -12,10 +19,12 @@ import (
 func ListOperations() {
 	// Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 	defer rdb.Close()
 
 	ctx := context.Background()
@@ -308,4 +317,35 @@ func ListOperations() {
 		log.Fatalf("Error getting low priority tasks: %v", err)
 	}
 	fmt.Printf("Low priority tasks: %v\n", lowTasks)
+
+	// 15. Queue worker carrying trace context
+	fmt.Println("\n=== Traced Queue Worker ===")
+
+	traceFile := filepath.Join(os.TempDir(), "lists_trace.jsonl")
+	exporter, err := tracing.NewFileExporter(traceFile)
+	if err != nil {
+		log.Fatalf("Error opening trace file: %v", err)
+	}
+	defer exporter.Close()
+	tracer := tracing.NewTracer("lists-demo", exporter)
+
+	// List entries are plain strings, so the traceparent travels in an envelope
+	enqueueCtx, enqueue := tracer.Start(ctx, "enqueue job", tracing.KindProducer)
+	err = rdb.LPush(enqueueCtx, "traced_jobs", tracing.WrapMessage(enqueueCtx, "resize:image_42.png")).Err()
+	enqueue.End()
+	if err != nil {
+		log.Fatalf("Error enqueueing job: %v", err)
+	}
+
+	job, err := rdb.BRPop(ctx, time.Second, "traced_jobs").Result()
+	if err != nil {
+		log.Fatalf("Error dequeueing job: %v", err)
+	}
+	jobCtx, payload := tracing.UnwrapMessage(ctx, job[1])
+	_, work := tracer.Start(jobCtx, "process job", tracing.KindConsumer)
+	work.SetAttr("job.payload", payload)
+	work.End()
+	fmt.Printf("Worker processed %q in trace %s (enqueued in %s)\n",
+		payload, work.SpanContext().TraceID, enqueue.SpanContext().TraceID)
+	fmt.Printf("Spans written to %s\n", traceFile)
 }
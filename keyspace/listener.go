@@ -0,0 +1,248 @@
+// Package keyspace turns Redis keyspace notifications into typed events.
+//
+// A Listener enables the notify-keyspace-events flags its events need,
+// subscribes to the matching __keyevent@<db>__:<event> channels and hands each
+// event to the handlers whose key pattern and event types match.
+package keyspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EventType is the name of a keyevent channel, e.g. "expired"
+type EventType string
+
+// Common events. Any event name Redis publishes can be used.
+const (
+	Expired EventType = "expired"
+	Evicted EventType = "evicted"
+	Set     EventType = "set"
+	Del     EventType = "del"
+	Expire  EventType = "expire"
+	Rename  EventType = "rename_to"
+	HSet    EventType = "hset"
+	LPush   EventType = "lpush"
+	RPush   EventType = "rpush"
+	SAdd    EventType = "sadd"
+	ZAdd    EventType = "zadd"
+	XAdd    EventType = "xadd"
+	New     EventType = "new"
+)
+
+// eventClasses maps events to the notify-keyspace-events class that emits them
+var eventClasses = map[EventType]byte{
+	Expired: 'x',
+	Evicted: 'e',
+	Set:     '$',
+	Del:     'g',
+	Expire:  'g',
+	Rename:  'g',
+	HSet:    'h',
+	LPush:   'l',
+	RPush:   'l',
+	SAdd:    's',
+	ZAdd:    'z',
+	XAdd:    't',
+	New:     'n',
+}
+
+// allClasses is what the 'A' alias stands for
+const allClasses = "g$lshzxet"
+
+// Event is a single keyspace notification
+type Event struct {
+	DB       int
+	Type     EventType
+	Key      string
+	Received time.Time
+}
+
+// Handler processes an event. Handlers run one at a time on the listener's
+// goroutine, so slow work should be handed off.
+type Handler func(Event)
+
+// Config controls which events a Listener subscribes to
+type Config struct {
+	DB     int         // Database whose events are delivered
+	Events []EventType // Keyevent channels to subscribe to
+
+	// Configure merges the flags the events need into notify-keyspace-events.
+	// Disable it on managed services where CONFIG is not available.
+	Configure bool
+
+	// Flags, when set, is written to notify-keyspace-events as is instead
+	// of the flags derived from Events
+	Flags string
+}
+
+// DefaultConfig listens for expirations and evictions in database 0
+func DefaultConfig() Config {
+	return Config{
+		Events:    []EventType{Expired, Evicted},
+		Configure: true,
+	}
+}
+
+type route struct {
+	pattern string
+	types   map[EventType]bool
+	handler Handler
+}
+
+// Listener subscribes to keyevent channels and dispatches events
+type Listener struct {
+	rdb *redis.Client
+	cfg Config
+
+	mu     sync.RWMutex
+	routes []route
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// NewListener creates a listener; call Handle to register handlers and Start
+// to begin receiving events
+func NewListener(rdb *redis.Client, cfg Config) *Listener {
+	return &Listener{rdb: rdb, cfg: cfg}
+}
+
+// Handle registers h for keys matching the glob pattern. With no types the
+// handler receives every subscribed event.
+func (l *Listener) Handle(pattern string, h Handler, types ...EventType) {
+	r := route{pattern: pattern, handler: h}
+	if len(types) > 0 {
+		r.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			r.types[t] = true
+		}
+	}
+	l.mu.Lock()
+	l.routes = append(l.routes, r)
+	l.mu.Unlock()
+}
+
+// Start enables notifications if configured, subscribes and waits for Redis
+// to confirm every subscription before dispatching in the background
+func (l *Listener) Start(ctx context.Context) error {
+	if len(l.cfg.Events) == 0 {
+		return errors.New("keyspace: no events configured")
+	}
+	if l.cfg.Configure {
+		if err := l.enable(ctx); err != nil {
+			return err
+		}
+	}
+
+	channels := make([]string, len(l.cfg.Events))
+	for i, e := range l.cfg.Events {
+		channels[i] = fmt.Sprintf("__keyevent@%d__:%s", l.cfg.DB, e)
+	}
+
+	pubsub := l.rdb.Subscribe(ctx, channels...)
+	for range channels {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return fmt.Errorf("keyspace: subscribing: %w", err)
+		}
+	}
+
+	l.pubsub = pubsub
+	l.done = make(chan struct{})
+	go l.dispatch(pubsub.Channel())
+	return nil
+}
+
+// Close unsubscribes and waits for the dispatch goroutine to finish
+func (l *Listener) Close() error {
+	if l.pubsub == nil {
+		return nil
+	}
+	err := l.pubsub.Close()
+	<-l.done
+	return err
+}
+
+// enable adds the missing notification classes to the server configuration,
+// keeping whatever flags are already set for other consumers
+func (l *Listener) enable(ctx context.Context) error {
+	if l.cfg.Flags != "" {
+		return l.rdb.ConfigSet(ctx, "notify-keyspace-events", l.cfg.Flags).Err()
+	}
+
+	current, err := l.rdb.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("keyspace: reading notify-keyspace-events: %w", err)
+	}
+	flags := current["notify-keyspace-events"]
+	have := flags
+	if strings.ContainsRune(flags, 'A') {
+		have += allClasses
+	}
+
+	missing := ""
+	if !strings.ContainsRune(have, 'E') {
+		missing += "E"
+	}
+	for _, e := range l.cfg.Events {
+		class, ok := eventClasses[e]
+		if !ok {
+			class = 'A'
+		}
+		if !strings.ContainsRune(have+missing, rune(class)) {
+			missing += string(class)
+		}
+	}
+	if missing == "" {
+		return nil
+	}
+	return l.rdb.ConfigSet(ctx, "notify-keyspace-events", flags+missing).Err()
+}
+
+func (l *Listener) dispatch(ch <-chan *redis.Message) {
+	defer close(l.done)
+	for msg := range ch {
+		e, ok := parseEvent(msg)
+		if !ok {
+			continue
+		}
+		l.mu.RLock()
+		routes := l.routes
+		l.mu.RUnlock()
+
+		for _, r := range routes {
+			if r.types != nil && !r.types[e.Type] {
+				continue
+			}
+			if ok, _ := path.Match(r.pattern, e.Key); ok {
+				r.handler(e)
+			}
+		}
+	}
+}
+
+// parseEvent decodes a message from a __keyevent@<db>__:<event> channel
+func parseEvent(msg *redis.Message) (Event, bool) {
+	rest, ok := strings.CutPrefix(msg.Channel, "__keyevent@")
+	if !ok {
+		return Event{}, false
+	}
+	db, event, ok := strings.Cut(rest, "__:")
+	if !ok {
+		return Event{}, false
+	}
+	n, err := strconv.Atoi(db)
+	if err != nil {
+		return Event{}, false
+	}
+	return Event{DB: n, Type: EventType(event), Key: msg.Payload, Received: time.Now()}, true
+}
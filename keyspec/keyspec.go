@@ -0,0 +1,372 @@
+// Package keyspec finds which arguments of a command are keys, from the
+// key positions the server itself reports in the reply to COMMAND.
+//
+// Redis 7 describes each command's keys with key specs: where to start
+// looking (an index, or after a keyword such as STREAMS) and how to find
+// the keys from there (a range, or a count such as numkeys). Older servers
+// only report a first and last position and a step, and commands flagged
+// movablekeys (EVAL, ZUNIONSTORE, XREAD, SORT ... STORE) are handled here
+// case by case.
+package keyspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnknownCommand is returned by Keys for commands the server does not list
+var ErrUnknownCommand = errors.New("keyspec: unknown command")
+
+// Command is how one command, or container subcommand such as
+// "object|encoding", places its keys
+type Command struct {
+	Name    string
+	First   int // Position of the first key, 0 for none
+	Last    int // Position of the last key; negative counts from the end
+	Step    int
+	Movable bool // Keys depend on the arguments
+	Flags   []string
+	Specs   []Spec // Key specs, on Redis 7 and later
+}
+
+// Spec is a Redis 7 key spec. BeginSearch is "index" or "keyword" and
+// FindKeys is "range" or "keynum"; other types are not understood.
+type Spec struct {
+	Flags []string
+
+	BeginSearch string
+	Index       int    // index: position of the first key
+	Keyword     string // keyword: the keys follow it...
+	StartFrom   int    // ...searched for from here; negative searches backwards from the end
+
+	FindKeys string
+	LastKey  int // range: last key relative to the first; negative counts from the end
+	KeyStep  int // range and keynum: distance between keys
+	Limit    int // range with a negative LastKey: only 1/Limit of the remaining arguments are keys
+	KeyNum   int // keynum: position of the count, relative to the first key found
+	FirstKey int // keynum: position of the first key, relative to the same
+}
+
+// Table holds the commands of a server
+type Table struct {
+	commands map[string]*Command
+}
+
+// Load reads the command table of the server behind rdb
+func Load(ctx context.Context, rdb redis.UniversalClient) (*Table, error) {
+	reply, err := rdb.Do(ctx, "COMMAND").Slice()
+	if err != nil {
+		return nil, fmt.Errorf("keyspec: reading COMMAND: %w", err)
+	}
+	return Parse(reply)
+}
+
+// Parse builds a table from the reply to COMMAND or COMMAND INFO, as
+// returned by go-redis over RESP2 or RESP3
+func Parse(reply []interface{}) (*Table, error) {
+	t := &Table{commands: make(map[string]*Command, len(reply))}
+	for _, entry := range reply {
+		if entry == nil {
+			continue // COMMAND INFO of an unknown command
+		}
+		if err := t.add(entry); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// add parses one entry of the COMMAND reply: name, arity, flags, first
+// key, last key, step, then on Redis 7 ACL categories, tips, key specs and
+// subcommands in the same shape
+func (t *Table) add(entry interface{}) error {
+	fields, ok := entry.([]interface{})
+	if !ok || len(fields) < 6 {
+		return fmt.Errorf("keyspec: unexpected COMMAND entry %v", entry)
+	}
+	c := &Command{
+		Name:  strings.ToLower(fmt.Sprint(fields[0])),
+		First: toInt(fields[3]),
+		Last:  toInt(fields[4]),
+		Step:  toInt(fields[5]),
+	}
+	if flags, ok := fields[2].([]interface{}); ok {
+		for _, f := range flags {
+			c.Flags = append(c.Flags, fmt.Sprint(f))
+			c.Movable = c.Movable || f == "movablekeys"
+		}
+	}
+	if len(fields) >= 9 {
+		specs, _ := fields[8].([]interface{})
+		for _, spec := range specs {
+			c.Specs = append(c.Specs, parseSpec(spec))
+		}
+	}
+	t.commands[c.Name] = c
+	if len(fields) >= 10 {
+		subs, _ := fields[9].([]interface{})
+		for _, sub := range subs {
+			if err := t.add(sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSpec parses a key spec, a map in RESP3 and a flat list of names
+// and values in RESP2
+func parseSpec(v interface{}) Spec {
+	m := fieldMap(v)
+	s := Spec{}
+	if flags, ok := m["flags"].([]interface{}); ok {
+		for _, f := range flags {
+			s.Flags = append(s.Flags, fmt.Sprint(f))
+		}
+	}
+	begin := fieldMap(m["begin_search"])
+	s.BeginSearch = fmt.Sprint(begin["type"])
+	spec := fieldMap(begin["spec"])
+	s.Index = toInt(spec["index"])
+	s.Keyword = fmt.Sprint(spec["keyword"])
+	s.StartFrom = toInt(spec["startfrom"])
+
+	find := fieldMap(m["find_keys"])
+	s.FindKeys = fmt.Sprint(find["type"])
+	spec = fieldMap(find["spec"])
+	s.LastKey = toInt(spec["lastkey"])
+	s.KeyStep = toInt(spec["keystep"])
+	s.Limit = toInt(spec["limit"])
+	s.KeyNum = toInt(spec["keynumidx"])
+	s.FirstKey = toInt(spec["firstkey"])
+	return s
+}
+
+func fieldMap(v interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			m[fmt.Sprint(v[i])] = v[i+1]
+		}
+	}
+	return m
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+// Lookup returns the entry for args: its subcommand's when the server
+// lists one, such as "object|encoding", else the command's
+func (t *Table) Lookup(args []string) (*Command, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	name := strings.ToLower(args[0])
+	if len(args) > 1 {
+		if c, ok := t.commands[name+"|"+strings.ToLower(args[1])]; ok {
+			return c, true
+		}
+	}
+	c, ok := t.commands[name]
+	return c, ok
+}
+
+// Keys returns the positions of the keys in args. Commands the server does
+// not list return an error, as do movable-key commands not handled here.
+func (t *Table) Keys(args []string) ([]int, error) {
+	c, ok := t.Lookup(args)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommand, args[0])
+	}
+	// The key spec of MIGRATE also names the key position of its
+	// single-key form, which is empty in the KEYS form
+	if len(c.Specs) > 0 && c.Name != "migrate" {
+		if idx, ok := c.specKeys(args); ok {
+			return idx, nil
+		}
+	}
+	if c.Movable {
+		return movable(c.Name, args)
+	}
+	if c.First <= 0 {
+		return nil, nil
+	}
+	return span(args, c.First, c.Last, c.Step), nil
+}
+
+// specKeys applies the key specs of c to args. It reports false when a
+// spec is of a type it does not know.
+func (c *Command) specKeys(args []string) ([]int, bool) {
+	seen := map[int]bool{}
+	var idx []int
+	for _, s := range c.Specs {
+		keys, ok := s.keys(args)
+		if !ok {
+			return nil, false
+		}
+		for _, i := range keys {
+			if !seen[i] {
+				seen[i] = true
+				idx = append(idx, i)
+			}
+		}
+	}
+	sort.Ints(idx)
+	return idx, true
+}
+
+// keys returns the positions the spec finds in args
+func (s Spec) keys(args []string) ([]int, bool) {
+	start := -1
+	switch s.BeginSearch {
+	case "index":
+		start = s.Index
+	case "keyword":
+		if s.StartFrom >= 0 {
+			for i := s.StartFrom; i < len(args); i++ {
+				if strings.EqualFold(args[i], s.Keyword) {
+					start = i + 1
+					break
+				}
+			}
+		} else {
+			for i := len(args) + s.StartFrom; i > 0; i-- {
+				if strings.EqualFold(args[i], s.Keyword) {
+					start = i + 1
+					break
+				}
+			}
+		}
+		if start < 0 {
+			return nil, true // The keyword is optional and absent
+		}
+	default:
+		return nil, false
+	}
+	if start >= len(args) {
+		return nil, true
+	}
+
+	switch s.FindKeys {
+	case "range":
+		last := start + s.LastKey
+		if s.LastKey < 0 {
+			last = len(args) + s.LastKey
+			if s.Limit > 1 {
+				last = start + (last-start+1)/s.Limit - 1
+			}
+		}
+		return span(args, start, last, s.KeyStep), true
+	case "keynum":
+		pos := start + s.KeyNum
+		if pos >= len(args) {
+			return nil, true
+		}
+		n, err := strconv.Atoi(args[pos])
+		if err != nil || n <= 0 {
+			return nil, true
+		}
+		step := max(s.KeyStep, 1)
+		first := start + s.FirstKey
+		return span(args, first, first+(n-1)*step, step), true
+	}
+	return nil, false
+}
+
+func span(args []string, first, last, step int) []int {
+	if last < 0 {
+		last += len(args)
+	}
+	if step <= 0 {
+		step = 1
+	}
+	var idx []int
+	for i := first; i <= last && i < len(args); i += step {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// numkeys returns the keys counted by the argument at pos
+func numkeys(args []string, pos int) ([]int, error) {
+	if pos >= len(args) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(args[pos])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("keyspec: invalid numkeys %q", args[pos])
+	}
+	return span(args, pos+1, pos+n, 1), nil
+}
+
+// keyword returns the position of the argument after the first kw from pos on
+func keyword(args []string, pos int, kw string) int {
+	for i := pos; i < len(args)-1; i++ {
+		if strings.EqualFold(args[i], kw) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// movable locates the keys of movablekeys commands
+func movable(name string, args []string) ([]int, error) {
+	switch name {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro", "blmpop", "bzmpop":
+		return numkeys(args, 2)
+	case "zunion", "zinter", "zdiff", "zintercard", "sintercard", "lmpop", "zmpop":
+		return numkeys(args, 1)
+	case "zunionstore", "zinterstore", "zdiffstore":
+		keys, err := numkeys(args, 2)
+		return append([]int{1}, keys...), err
+	case "xread", "xreadgroup":
+		// STREAMS k1 k2 id1 id2; a group or consumer named "streams" comes
+		// after GROUP and so before the keyword
+		start := 1
+		if name == "xreadgroup" {
+			start = 4
+		}
+		first := keyword(args, start, "streams")
+		if first < 0 {
+			return nil, fmt.Errorf("keyspec: %s without STREAMS", name)
+		}
+		n := (len(args) - first) / 2
+		return span(args, first, first+n-1, 1), nil
+	case "sort", "sort_ro", "georadius", "georadiusbymember":
+		keys := []int{1}
+		for _, kw := range []string{"store", "storedist"} {
+			if i := keyword(args, 2, kw); i > 0 {
+				keys = append(keys, i)
+			}
+		}
+		return keys, nil
+	case "migrate":
+		if len(args) > 3 && args[3] != "" {
+			return []int{3}, nil
+		}
+		if i := keyword(args, 6, "keys"); i > 0 {
+			return span(args, i, -1, 1), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("keyspec: cannot locate the keys of %s", strings.ToUpper(name))
+}
@@ -0,0 +1,87 @@
+package keyspec
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// global are commands that reach every key of a database whatever their
+// arguments, so they cannot be confined to a prefix
+var global = map[string]bool{
+	"flushall": true, "flushdb": true, "randomkey": true, "swapdb": true,
+}
+
+// Prefix returns args with prefix added to every key. KEYS and SCAN get
+// their pattern narrowed to the prefix instead, a MATCH being added to SCAN
+// when it has none, and so do the BY and GET patterns of SORT, which read
+// keys the command table does not list. Commands the server does not list
+// are returned unchanged, as they name no keys Redis knows of; commands that
+// reach every key, such as FLUSHDB, return an error.
+func (t *Table) Prefix(args []string, prefix string) ([]string, error) {
+	name := strings.ToLower(args[0])
+	if global[name] {
+		return nil, fmt.Errorf("keyspec: %s would affect keys outside the prefix %q", strings.ToUpper(name), prefix)
+	}
+	out := slices.Clone(args)
+	switch name {
+	case "keys":
+		if len(out) > 1 {
+			out[1] = EscapeGlob(prefix) + out[1]
+		}
+		return out, nil
+	case "scan":
+		for i := 2; i < len(out)-1; i += 2 {
+			if strings.EqualFold(out[i], "match") {
+				out[i+1] = EscapeGlob(prefix) + out[i+1]
+				return out, nil
+			}
+		}
+		return append(out, "MATCH", EscapeGlob(prefix)+"*"), nil
+	}
+	idx, err := t.Keys(args)
+	if errors.Is(err, ErrUnknownCommand) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range idx {
+		out[i] = prefix + out[i]
+	}
+	if name == "sort" || name == "sort_ro" {
+		prefixSortPatterns(out, prefix)
+	}
+	return out, nil
+}
+
+// prefixSortPatterns prefixes the BY and GET patterns of a SORT in place.
+// GET # stands for the element itself and names no key.
+func prefixSortPatterns(args []string, prefix string) {
+	for i := 2; i < len(args)-1; i++ {
+		switch strings.ToLower(args[i]) {
+		case "limit":
+			i += 2
+		case "store":
+			i++
+		case "by", "get":
+			if args[i+1] != "#" {
+				args[i+1] = prefix + args[i+1]
+			}
+			i++
+		}
+	}
+}
+
+// EscapeGlob quotes the glob characters of s for KEYS and SCAN MATCH
+func EscapeGlob(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
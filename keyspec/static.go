@@ -0,0 +1,290 @@
+package keyspec
+
+import "sync"
+
+// Static returns a built-in table of the common commands, for when no
+// server is at hand: reading an AOF or a MONITOR capture, or sanitizing
+// every traced command without a round trip. It has the Redis 6 shape of
+// COMMAND, first and last key and step plus the write, readonly and
+// movablekeys flags, with no key specs. Prefer Load when connected, as
+// the server also knows its modules' commands and those added later.
+var Static = sync.OnceValue(func() *Table {
+	t := &Table{commands: make(map[string]*Command, len(static))}
+	for _, c := range static {
+		t.commands[c.Name] = c
+	}
+	return t
+})
+
+// Flags of the static entries
+const (
+	write       = "write"
+	readonly    = "readonly"
+	movablekeys = "movablekeys"
+)
+
+// cmd describes a command like its COMMAND entry: the positions of its
+// first and last key and the step between keys, then its flags
+func cmd(name string, first, last, step int, flags ...string) *Command {
+	c := &Command{Name: name, First: first, Last: last, Step: step, Flags: flags}
+	for _, f := range flags {
+		c.Movable = c.Movable || f == movablekeys
+	}
+	return c
+}
+
+// static lists the commands Static knows. Containers whose subcommands
+// take a key list those subcommands as "name|sub", like Redis 7 does.
+var static = []*Command{
+	// Strings
+	cmd("get", 1, 1, 1, readonly),
+	cmd("getrange", 1, 1, 1, readonly),
+	cmd("substr", 1, 1, 1, readonly),
+	cmd("strlen", 1, 1, 1, readonly),
+	cmd("mget", 1, -1, 1, readonly),
+	cmd("set", 1, 1, 1, write),
+	cmd("setnx", 1, 1, 1, write),
+	cmd("setex", 1, 1, 1, write),
+	cmd("psetex", 1, 1, 1, write),
+	cmd("mset", 1, -1, 2, write),
+	cmd("msetnx", 1, -1, 2, write),
+	cmd("append", 1, 1, 1, write),
+	cmd("setrange", 1, 1, 1, write),
+	cmd("getset", 1, 1, 1, write),
+	cmd("getdel", 1, 1, 1, write),
+	cmd("getex", 1, 1, 1, write),
+	cmd("incr", 1, 1, 1, write),
+	cmd("incrby", 1, 1, 1, write),
+	cmd("incrbyfloat", 1, 1, 1, write),
+	cmd("decr", 1, 1, 1, write),
+	cmd("decrby", 1, 1, 1, write),
+
+	// Keys
+	cmd("exists", 1, -1, 1, readonly),
+	cmd("touch", 1, -1, 1, readonly),
+	cmd("type", 1, 1, 1, readonly),
+	cmd("ttl", 1, 1, 1, readonly),
+	cmd("pttl", 1, 1, 1, readonly),
+	cmd("expiretime", 1, 1, 1, readonly),
+	cmd("pexpiretime", 1, 1, 1, readonly),
+	cmd("dump", 1, 1, 1, readonly),
+	cmd("object", 2, 2, 1, readonly),
+	cmd("keys", 0, 0, 0, readonly),
+	cmd("scan", 0, 0, 0, readonly),
+	cmd("dbsize", 0, 0, 0, readonly),
+	cmd("randomkey", 0, 0, 0, readonly),
+	cmd("sort_ro", 1, 1, 1, readonly),
+	cmd("del", 1, -1, 1, write),
+	cmd("unlink", 1, -1, 1, write),
+	cmd("expire", 1, 1, 1, write),
+	cmd("pexpire", 1, 1, 1, write),
+	cmd("expireat", 1, 1, 1, write),
+	cmd("pexpireat", 1, 1, 1, write),
+	cmd("persist", 1, 1, 1, write),
+	cmd("rename", 1, 2, 1, write),
+	cmd("renamenx", 1, 2, 1, write),
+	cmd("copy", 1, 2, 1, write),
+	cmd("move", 1, 1, 1, write),
+	cmd("restore", 1, 1, 1, write),
+	cmd("sort", 1, 1, 1, write, movablekeys),
+	cmd("migrate", 3, 3, 1, write, movablekeys),
+	cmd("flushdb", 0, 0, 0, write),
+	cmd("flushall", 0, 0, 0, write),
+	cmd("swapdb", 0, 0, 0, write),
+
+	// Hashes
+	cmd("hget", 1, 1, 1, readonly),
+	cmd("hmget", 1, 1, 1, readonly),
+	cmd("hgetall", 1, 1, 1, readonly),
+	cmd("hkeys", 1, 1, 1, readonly),
+	cmd("hvals", 1, 1, 1, readonly),
+	cmd("hlen", 1, 1, 1, readonly),
+	cmd("hexists", 1, 1, 1, readonly),
+	cmd("hstrlen", 1, 1, 1, readonly),
+	cmd("hrandfield", 1, 1, 1, readonly),
+	cmd("hscan", 1, 1, 1, readonly),
+	cmd("httl", 1, 1, 1, readonly),
+	cmd("hpttl", 1, 1, 1, readonly),
+	cmd("hset", 1, 1, 1, write),
+	cmd("hsetnx", 1, 1, 1, write),
+	cmd("hmset", 1, 1, 1, write),
+	cmd("hdel", 1, 1, 1, write),
+	cmd("hincrby", 1, 1, 1, write),
+	cmd("hincrbyfloat", 1, 1, 1, write),
+	cmd("hexpire", 1, 1, 1, write),
+	cmd("hpexpire", 1, 1, 1, write),
+	cmd("hexpireat", 1, 1, 1, write),
+	cmd("hpexpireat", 1, 1, 1, write),
+	cmd("hpersist", 1, 1, 1, write),
+	cmd("hgetdel", 1, 1, 1, write),
+
+	// Lists
+	cmd("lrange", 1, 1, 1, readonly),
+	cmd("lindex", 1, 1, 1, readonly),
+	cmd("llen", 1, 1, 1, readonly),
+	cmd("lpos", 1, 1, 1, readonly),
+	cmd("lpush", 1, 1, 1, write),
+	cmd("rpush", 1, 1, 1, write),
+	cmd("lpushx", 1, 1, 1, write),
+	cmd("rpushx", 1, 1, 1, write),
+	cmd("lpop", 1, 1, 1, write),
+	cmd("rpop", 1, 1, 1, write),
+	cmd("linsert", 1, 1, 1, write),
+	cmd("lset", 1, 1, 1, write),
+	cmd("lrem", 1, 1, 1, write),
+	cmd("ltrim", 1, 1, 1, write),
+	cmd("rpoplpush", 1, 2, 1, write),
+	cmd("brpoplpush", 1, 2, 1, write),
+	cmd("lmove", 1, 2, 1, write),
+	cmd("blmove", 1, 2, 1, write),
+	cmd("blpop", 1, -2, 1, write),
+	cmd("brpop", 1, -2, 1, write),
+	cmd("lmpop", 0, 0, 0, write, movablekeys),
+	cmd("blmpop", 0, 0, 0, write, movablekeys),
+
+	// Sets
+	cmd("smembers", 1, 1, 1, readonly),
+	cmd("sismember", 1, 1, 1, readonly),
+	cmd("smismember", 1, 1, 1, readonly),
+	cmd("scard", 1, 1, 1, readonly),
+	cmd("srandmember", 1, 1, 1, readonly),
+	cmd("sscan", 1, 1, 1, readonly),
+	cmd("sinter", 1, -1, 1, readonly),
+	cmd("sunion", 1, -1, 1, readonly),
+	cmd("sdiff", 1, -1, 1, readonly),
+	cmd("sintercard", 0, 0, 0, readonly, movablekeys),
+	cmd("sadd", 1, 1, 1, write),
+	cmd("srem", 1, 1, 1, write),
+	cmd("spop", 1, 1, 1, write),
+	cmd("smove", 1, 2, 1, write),
+	cmd("sinterstore", 1, -1, 1, write),
+	cmd("sunionstore", 1, -1, 1, write),
+	cmd("sdiffstore", 1, -1, 1, write),
+
+	// Sorted sets
+	cmd("zrange", 1, 1, 1, readonly),
+	cmd("zrangebyscore", 1, 1, 1, readonly),
+	cmd("zrevrange", 1, 1, 1, readonly),
+	cmd("zrevrangebyscore", 1, 1, 1, readonly),
+	cmd("zrangebylex", 1, 1, 1, readonly),
+	cmd("zrevrangebylex", 1, 1, 1, readonly),
+	cmd("zscore", 1, 1, 1, readonly),
+	cmd("zmscore", 1, 1, 1, readonly),
+	cmd("zrank", 1, 1, 1, readonly),
+	cmd("zrevrank", 1, 1, 1, readonly),
+	cmd("zcard", 1, 1, 1, readonly),
+	cmd("zcount", 1, 1, 1, readonly),
+	cmd("zlexcount", 1, 1, 1, readonly),
+	cmd("zrandmember", 1, 1, 1, readonly),
+	cmd("zscan", 1, 1, 1, readonly),
+	cmd("zunion", 0, 0, 0, readonly, movablekeys),
+	cmd("zinter", 0, 0, 0, readonly, movablekeys),
+	cmd("zdiff", 0, 0, 0, readonly, movablekeys),
+	cmd("zintercard", 0, 0, 0, readonly, movablekeys),
+	cmd("zadd", 1, 1, 1, write),
+	cmd("zincrby", 1, 1, 1, write),
+	cmd("zrem", 1, 1, 1, write),
+	cmd("zremrangebyscore", 1, 1, 1, write),
+	cmd("zremrangebyrank", 1, 1, 1, write),
+	cmd("zremrangebylex", 1, 1, 1, write),
+	cmd("zpopmin", 1, 1, 1, write),
+	cmd("zpopmax", 1, 1, 1, write),
+	cmd("bzpopmin", 1, -2, 1, write),
+	cmd("bzpopmax", 1, -2, 1, write),
+	cmd("zmpop", 0, 0, 0, write, movablekeys),
+	cmd("bzmpop", 0, 0, 0, write, movablekeys),
+	cmd("zrangestore", 1, 2, 1, write),
+	cmd("zunionstore", 1, 1, 1, write, movablekeys),
+	cmd("zinterstore", 1, 1, 1, write, movablekeys),
+	cmd("zdiffstore", 1, 1, 1, write, movablekeys),
+
+	// Streams
+	cmd("xrange", 1, 1, 1, readonly),
+	cmd("xrevrange", 1, 1, 1, readonly),
+	cmd("xlen", 1, 1, 1, readonly),
+	cmd("xpending", 1, 1, 1, readonly),
+	cmd("xinfo", 2, 2, 1, readonly),
+	cmd("xread", 0, 0, 0, readonly, movablekeys),
+	cmd("xadd", 1, 1, 1, write),
+	cmd("xdel", 1, 1, 1, write),
+	cmd("xtrim", 1, 1, 1, write),
+	cmd("xack", 1, 1, 1, write),
+	cmd("xclaim", 1, 1, 1, write),
+	cmd("xautoclaim", 1, 1, 1, write),
+	cmd("xsetid", 1, 1, 1, write),
+	cmd("xgroup", 2, 2, 1, write),
+	cmd("xreadgroup", 0, 0, 0, write, movablekeys),
+
+	// Bitmaps, HyperLogLogs and geo
+	cmd("getbit", 1, 1, 1, readonly),
+	cmd("bitcount", 1, 1, 1, readonly),
+	cmd("bitpos", 1, 1, 1, readonly),
+	cmd("bitfield_ro", 1, 1, 1, readonly),
+	cmd("pfcount", 1, -1, 1, readonly),
+	cmd("geopos", 1, 1, 1, readonly),
+	cmd("geodist", 1, 1, 1, readonly),
+	cmd("geohash", 1, 1, 1, readonly),
+	cmd("geosearch", 1, 1, 1, readonly),
+	cmd("georadius_ro", 1, 1, 1, readonly),
+	cmd("georadiusbymember_ro", 1, 1, 1, readonly),
+	cmd("setbit", 1, 1, 1, write),
+	cmd("bitfield", 1, 1, 1, write),
+	cmd("bitop", 2, -1, 1, write),
+	cmd("pfadd", 1, 1, 1, write),
+	cmd("pfmerge", 1, -1, 1, write),
+	cmd("geoadd", 1, 1, 1, write),
+	cmd("geosearchstore", 1, 2, 1, write),
+	cmd("georadius", 1, 1, 1, write, movablekeys),
+	cmd("georadiusbymember", 1, 1, 1, write, movablekeys),
+
+	// Scripts and functions
+	cmd("eval", 0, 0, 0, movablekeys),
+	cmd("evalsha", 0, 0, 0, movablekeys),
+	cmd("fcall", 0, 0, 0, movablekeys),
+	cmd("eval_ro", 0, 0, 0, readonly, movablekeys),
+	cmd("evalsha_ro", 0, 0, 0, readonly, movablekeys),
+	cmd("fcall_ro", 0, 0, 0, readonly, movablekeys),
+	cmd("script", 0, 0, 0),
+	cmd("function", 0, 0, 0),
+
+	// Transactions, connections, Pub/Sub and the server
+	cmd("watch", 1, -1, 1),
+	cmd("unwatch", 0, 0, 0),
+	cmd("multi", 0, 0, 0),
+	cmd("exec", 0, 0, 0),
+	cmd("discard", 0, 0, 0),
+	cmd("select", 0, 0, 0),
+	cmd("auth", 0, 0, 0),
+	cmd("hello", 0, 0, 0),
+	cmd("ping", 0, 0, 0),
+	cmd("echo", 0, 0, 0),
+	cmd("quit", 0, 0, 0),
+	cmd("reset", 0, 0, 0),
+	cmd("wait", 0, 0, 0),
+	cmd("publish", 0, 0, 0),
+	cmd("spublish", 0, 0, 0),
+	cmd("subscribe", 0, 0, 0),
+	cmd("unsubscribe", 0, 0, 0),
+	cmd("psubscribe", 0, 0, 0),
+	cmd("punsubscribe", 0, 0, 0),
+	cmd("ssubscribe", 0, 0, 0),
+	cmd("sunsubscribe", 0, 0, 0),
+	cmd("pubsub", 0, 0, 0),
+	cmd("memory", 0, 0, 0),
+	cmd("memory|usage", 2, 2, 1, readonly),
+	cmd("acl", 0, 0, 0),
+	cmd("client", 0, 0, 0),
+	cmd("cluster", 0, 0, 0),
+	cmd("command", 0, 0, 0),
+	cmd("config", 0, 0, 0),
+	cmd("info", 0, 0, 0),
+	cmd("latency", 0, 0, 0),
+	cmd("lastsave", 0, 0, 0),
+	cmd("module", 0, 0, 0),
+	cmd("monitor", 0, 0, 0),
+	cmd("save", 0, 0, 0),
+	cmd("bgsave", 0, 0, 0),
+	cmd("bgrewriteaof", 0, 0, 0),
+	cmd("slowlog", 0, 0, 0),
+	cmd("time", 0, 0, 0),
+}
@@ -0,0 +1,111 @@
+package loadgen
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Histogram layout: values below 2^subBits microseconds get one bucket each,
+// larger values keep subBits of precision (about 0.1% relative error), the
+// same log-linear scheme HdrHistogram uses with three significant figures.
+const (
+	subBits    = 11
+	subCount   = 1 << subBits
+	subHalf    = subCount / 2
+	maxTracked = int64(time.Minute / time.Microsecond)
+)
+
+var numBuckets = bucketIndex(maxTracked) + 1
+
+// Histogram records latencies in microseconds. Record is safe for concurrent
+// use; values above one minute are clamped.
+type Histogram struct {
+	counts []atomic.Int64
+	total  atomic.Int64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+// NewHistogram returns an empty histogram
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]atomic.Int64, numBuckets)}
+}
+
+func bucketIndex(v int64) int {
+	if v < subCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBits
+	return (shift+1)*subHalf + int(v>>shift) - subHalf
+}
+
+// bucketValue returns the highest value that maps to bucket i
+func bucketValue(i int) int64 {
+	if i < subCount {
+		return int64(i)
+	}
+	shift := i/subHalf - 1
+	sub := int64(i%subHalf + subHalf)
+	return (sub+1)<<shift - 1
+}
+
+// Record adds one latency
+func (h *Histogram) Record(d time.Duration) {
+	v := d.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+	if v > maxTracked {
+		v = maxTracked
+	}
+	h.counts[bucketIndex(v)].Add(1)
+	h.total.Add(1)
+	h.sum.Add(v)
+	for {
+		m := h.max.Load()
+		if v <= m || h.max.CompareAndSwap(m, v) {
+			break
+		}
+	}
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() int64 {
+	return h.total.Load()
+}
+
+// Mean returns the average latency
+func (h *Histogram) Mean() time.Duration {
+	n := h.total.Load()
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(h.sum.Load()/n) * time.Microsecond
+}
+
+// Max returns the largest recorded latency
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max.Load()) * time.Microsecond
+}
+
+// Percentile returns the latency below which p percent of values fall
+func (h *Histogram) Percentile(p float64) time.Duration {
+	n := h.total.Load()
+	if n == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100 * float64(n)))
+	if target < 1 {
+		target = 1
+	}
+	var seen int64
+	for i := range h.counts {
+		seen += h.counts[i].Load()
+		if seen >= target {
+			return time.Duration(min(bucketValue(i), h.max.Load())) * time.Microsecond
+		}
+	}
+	return h.Max()
+}
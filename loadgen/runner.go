@@ -0,0 +1,281 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Modes reported in Report.Mode
+const (
+	ClosedLoop = "closed-loop"
+	OpenLoop   = "open-loop"
+)
+
+// Progress is passed to the progress callback once per second
+type Progress struct {
+	Elapsed time.Duration
+	Ops     int64   // Commands completed so far
+	Errors  int64   // Failed commands so far
+	Rate    float64 // Ops/s over the last interval
+}
+
+// OpReport summarizes one operation, or the whole run in Report.Total
+type OpReport struct {
+	Name         string           `json:"name"`
+	Count        int64            `json:"count"`
+	Errors       int64            `json:"errors"`
+	Nil          int64            `json:"nil"` // Replies that were nil, e.g. GET on a missing key
+	Throughput   float64          `json:"throughput"`
+	Mean         time.Duration    `json:"mean"`
+	P50          time.Duration    `json:"p50"`
+	P90          time.Duration    `json:"p90"`
+	P99          time.Duration    `json:"p99"`
+	P999         time.Duration    `json:"p999"`
+	Max          time.Duration    `json:"max"`
+	ErrorClasses map[string]int64 `json:"error_classes,omitempty"`
+}
+
+// Report is the result of a run
+type Report struct {
+	Mode        string        `json:"mode"`
+	Concurrency int           `json:"concurrency"`
+	TargetRate  float64       `json:"target_rate,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Missed      int64         `json:"missed,omitempty"` // Open loop: scheduled ops never sent
+	Ops         []OpReport    `json:"ops"`
+	Total       OpReport      `json:"total"`
+}
+
+// opStats collects results for one op
+type opStats struct {
+	hist   *Histogram
+	errors atomic.Int64
+	nils   atomic.Int64
+
+	mu      sync.Mutex
+	classes map[string]int64
+}
+
+func newOpStats() *opStats {
+	return &opStats{hist: NewHistogram(), classes: make(map[string]int64)}
+}
+
+func (s *opStats) record(latency time.Duration, err error) {
+	s.hist.Record(latency)
+	switch {
+	case err == nil:
+	case err == redis.Nil:
+		s.nils.Add(1)
+	default:
+		s.errors.Add(1)
+		s.mu.Lock()
+		s.classes[errorClass(err)]++
+		s.mu.Unlock()
+	}
+}
+
+func (s *opStats) report(name string, elapsed time.Duration) OpReport {
+	s.mu.Lock()
+	classes := make(map[string]int64, len(s.classes))
+	for k, v := range s.classes {
+		classes[k] = v
+	}
+	s.mu.Unlock()
+
+	h := s.hist
+	return OpReport{
+		Name:         name,
+		Count:        h.Count(),
+		Errors:       s.errors.Load(),
+		Nil:          s.nils.Load(),
+		Throughput:   float64(h.Count()) / elapsed.Seconds(),
+		Mean:         h.Mean(),
+		P50:          h.Percentile(50),
+		P90:          h.Percentile(90),
+		P99:          h.Percentile(99),
+		P999:         h.Percentile(99.9),
+		Max:          h.Max(),
+		ErrorClasses: classes,
+	}
+}
+
+// errorClass groups errors by the server's error prefix (WRONGTYPE, ERR,
+// MOVED, ...), timeouts and connection failures
+func errorClass(err error) string {
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		class, _, _ := strings.Cut(rerr.Error(), " ")
+		return class
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		return "timeout"
+	}
+	return "connection"
+}
+
+// picker chooses key indexes in [1, n]
+type picker func() int
+
+func newPicker(op *Op, r *rand.Rand, zipfS float64, seq *atomic.Int64) picker {
+	n := op.Keyspace
+	switch op.Distribution {
+	case Zipf:
+		z := rand.NewZipf(r, zipfS, 1, uint64(n-1))
+		return func() int { return int(z.Uint64()) + 1 }
+	case Sequential:
+		return func() int { return int((seq.Add(1)-1)%int64(n)) + 1 }
+	default:
+		return func() int { return r.IntN(n) + 1 }
+	}
+}
+
+// Run executes the workload until spec.Duration elapses or ctx is cancelled.
+// With spec.Rate set, ops are scheduled at a constant rate and latency is
+// measured from each op's scheduled time, so a stalled server shows up in
+// the percentiles instead of silently slowing the load (coordinated omission).
+// progress may be nil.
+func Run(ctx context.Context, rdb *redis.Client, spec *Spec, progress func(Progress)) (*Report, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, spec.Duration)
+	defer cancel()
+	// In-flight commands are allowed to finish when the run ends
+	cmdCtx := context.WithoutCancel(ctx)
+
+	stats := make([]*opStats, len(spec.Ops))
+	seqs := make([]atomic.Int64, len(spec.Ops))
+	cumulative := make([]float64, len(spec.Ops))
+	total := newOpStats()
+	sum := 0.0
+	for i, op := range spec.Ops {
+		stats[i] = newOpStats()
+		sum += op.Ratio
+		cumulative[i] = sum
+	}
+	value := randomValue(spec.ValueSize)
+
+	// schedule carries intended start times in open-loop mode
+	var schedule chan time.Time
+	var scheduled atomic.Int64
+	start := time.Now()
+	if spec.Rate > 0 {
+		schedule = make(chan time.Time, spec.Concurrency)
+		go func() {
+			defer close(schedule)
+			interval := time.Duration(float64(time.Second) / spec.Rate)
+			for i := int64(0); ; i++ {
+				at := start.Add(time.Duration(i) * interval)
+				if wait := time.Until(at); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-runCtx.Done():
+						return
+					}
+				}
+				scheduled.Add(1)
+				select {
+				case schedule <- at:
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	var sent atomic.Int64
+	for w := 0; w < spec.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(spec.Seed, uint64(w)))
+			pickers := make([]picker, len(spec.Ops))
+			for i := range spec.Ops {
+				pickers[i] = newPicker(&spec.Ops[i], r, spec.ZipfS, &seqs[i])
+			}
+
+			do := func(intended time.Time) {
+				x := r.Float64() * sum
+				i := 0
+				for i < len(cumulative)-1 && x >= cumulative[i] {
+					i++
+				}
+				args := spec.Ops[i].args(pickers[i](), value)
+				if intended.IsZero() {
+					intended = time.Now()
+				}
+				err := rdb.Do(cmdCtx, args...).Err()
+				latency := time.Since(intended)
+				stats[i].record(latency, err)
+				total.record(latency, err)
+				sent.Add(1)
+			}
+
+			if schedule != nil {
+				for at := range schedule {
+					if runCtx.Err() != nil {
+						return
+					}
+					do(at)
+				}
+				return
+			}
+			for runCtx.Err() == nil {
+				do(time.Time{})
+			}
+		}(w)
+	}
+
+	if progress != nil {
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			var last int64
+			for {
+				select {
+				case <-ticker.C:
+					n := total.hist.Count()
+					progress(Progress{
+						Elapsed: time.Since(start).Round(time.Second),
+						Ops:     n,
+						Errors:  total.errors.Load(),
+						Rate:    float64(n - last),
+					})
+					last = n
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &Report{
+		Mode:        ClosedLoop,
+		Concurrency: spec.Concurrency,
+		Duration:    elapsed,
+		Total:       total.report("total", elapsed),
+	}
+	if spec.Rate > 0 {
+		report.Mode = OpenLoop
+		report.TargetRate = spec.Rate
+		report.Missed = max(0, scheduled.Load()-sent.Load())
+	}
+	for i, op := range spec.Ops {
+		report.Ops = append(report.Ops, stats[i].report(op.Name, elapsed))
+	}
+	return report, nil
+}
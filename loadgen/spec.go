@@ -0,0 +1,175 @@
+// Package loadgen drives weighted mixes of Redis commands against a server
+// and records latency histograms per operation, in the spirit of
+// redis-benchmark but using the key shapes of this project.
+package loadgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key distributions accepted by Spec.Distribution and Op.Distribution
+const (
+	Uniform    = "uniform"
+	Zipf       = "zipf"
+	Sequential = "sequential"
+)
+
+// Spec describes a workload. A token of an op's command containing * next to
+// other characters, such as user:*, has the * replaced by a key index in
+// [1, keyspace]; a bare * (an XADD ID) is sent as is. The token {value} is
+// replaced by a random payload of ValueSize bytes.
+type Spec struct {
+	Concurrency  int           `yaml:"concurrency"`  // Workers issuing commands; default 50
+	Duration     time.Duration `yaml:"duration"`     // How long to run; default 10s
+	Rate         float64       `yaml:"rate"`         // Total ops/s; > 0 enables open-loop mode
+	Keyspace     int           `yaml:"keyspace"`     // Default key index range; default 10000
+	Distribution string        `yaml:"distribution"` // Default key distribution; default uniform
+	ZipfS        float64       `yaml:"zipf_s"`       // Zipf exponent (> 1); default 1.1
+	ValueSize    int           `yaml:"value_size"`   // Bytes substituted for {value}; default 64
+	Seed         uint64        `yaml:"seed"`         // Seeds the op and key choices
+	Ops          []Op          `yaml:"ops"`
+}
+
+// Op is one entry of the workload mix
+type Op struct {
+	Name         string  `yaml:"name"`         // Label in the report; defaults to the command
+	Command      string  `yaml:"command"`      // e.g. "HGETALL product:*"
+	Ratio        float64 `yaml:"ratio"`        // Relative weight in the mix
+	Keyspace     int     `yaml:"keyspace"`     // Overrides Spec.Keyspace
+	Distribution string  `yaml:"distribution"` // Overrides Spec.Distribution
+
+	tokens []token
+}
+
+// token is one argument of a compiled command
+type token struct {
+	literal        string
+	prefix, suffix string // Set when keyed
+	keyed, value   bool
+}
+
+// LoadSpec reads a YAML workload file
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ParseSpec decodes a YAML workload, applies defaults and validates it
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate fills in defaults and compiles the op commands
+func (s *Spec) Validate() error {
+	if s.Concurrency <= 0 {
+		s.Concurrency = 50
+	}
+	if s.Duration <= 0 {
+		s.Duration = 10 * time.Second
+	}
+	if s.Keyspace <= 0 {
+		s.Keyspace = 10000
+	}
+	if s.Distribution == "" {
+		s.Distribution = Uniform
+	}
+	if s.ZipfS <= 1 {
+		s.ZipfS = 1.1
+	}
+	if s.ValueSize <= 0 {
+		s.ValueSize = 64
+	}
+	if s.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+	if len(s.Ops) == 0 {
+		return errors.New("workload has no ops")
+	}
+
+	for i := range s.Ops {
+		op := &s.Ops[i]
+		fields := strings.Fields(op.Command)
+		if len(fields) == 0 {
+			return fmt.Errorf("op %d: empty command", i+1)
+		}
+		if op.Ratio <= 0 {
+			return fmt.Errorf("op %q: ratio must be positive", op.Command)
+		}
+		if op.Name == "" {
+			op.Name = strings.Join(fields[:min(2, len(fields))], " ")
+		}
+		if op.Keyspace <= 0 {
+			op.Keyspace = s.Keyspace
+		}
+		if op.Distribution == "" {
+			op.Distribution = s.Distribution
+		}
+		switch op.Distribution {
+		case Uniform, Zipf, Sequential:
+		default:
+			return fmt.Errorf("op %q: unknown distribution %q", op.Name, op.Distribution)
+		}
+
+		op.tokens = make([]token, len(fields))
+		for j, f := range fields {
+			switch {
+			case f == "{value}":
+				op.tokens[j] = token{value: true}
+			case f != "*" && strings.Contains(f, "*"):
+				prefix, suffix, _ := strings.Cut(f, "*")
+				op.tokens[j] = token{keyed: true, prefix: prefix, suffix: suffix}
+			default:
+				op.tokens[j] = token{literal: f}
+			}
+		}
+	}
+	return nil
+}
+
+// args builds the command arguments for key index n
+func (op *Op) args(n int, value string) []interface{} {
+	args := make([]interface{}, len(op.tokens))
+	for i, t := range op.tokens {
+		switch {
+		case t.keyed:
+			args[i] = fmt.Sprintf("%s%d%s", t.prefix, n, t.suffix)
+		case t.value:
+			args[i] = value
+		default:
+			args[i] = t.literal
+		}
+	}
+	return args
+}
+
+// randomValue returns n printable random bytes
+func randomValue(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -6,6 +6,9 @@ import (
 	"log"
 	"time"
 
+	"Redis/namespace"
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,10 +18,12 @@ var ctx = context.Background()
 func main() {
 	// 1. Connect to Redis
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // Redis server address
-		Password: "",               // No password
-		DB:       0,                // Default DB
+		Addr:      "localhost:6379", // Redis server address
+		Password:  "",               // No password
+		DB:        0,                // Default DB
+		TLSConfig: tlsconf.FromEnv(),
 	})
+	namespace.FromEnv(rdb)
 
 	// 2. Check connection
 	pong, err := rdb.Ping(ctx).Result()
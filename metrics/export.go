@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// WriteTo writes every metric in the Prometheus text exposition format
+func (h *Hook) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	h.write(bw)
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (h *Hook) write(w *bufio.Writer) {
+	var names []string
+	h.commands.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	header(w, "redis_commands_total", "counter", "Commands sent, including those inside pipelines.")
+	for _, name := range names {
+		fmt.Fprintf(w, "redis_commands_total{cmd=%q} %d\n", name, h.stats(name).calls.Load())
+	}
+
+	header(w, "redis_command_errors_total", "counter", "Commands that returned an error, by class. Nil replies count as class nil.")
+	for _, name := range names {
+		s := h.stats(name)
+		s.mu.Lock()
+		classes := make([]string, 0, len(s.errors))
+		for c := range s.errors {
+			classes = append(classes, c)
+		}
+		sort.Strings(classes)
+		for _, c := range classes {
+			fmt.Fprintf(w, "redis_command_errors_total{cmd=%q,class=%q} %d\n", name, c, s.errors[c])
+		}
+		s.mu.Unlock()
+	}
+
+	header(w, "redis_command_duration_seconds", "histogram", "Latency of commands sent outside pipelines.")
+	for _, name := range names {
+		writeHistogram(w, "redis_command_duration_seconds", fmt.Sprintf("cmd=%q", name), h.stats(name).latency)
+	}
+
+	header(w, "redis_pipeline_size", "histogram", "Commands per pipeline, excluding MULTI and EXEC.")
+	writeHistogram(w, "redis_pipeline_size", `tx="false"`, h.pipelines[0])
+	writeHistogram(w, "redis_pipeline_size", `tx="true"`, h.pipelines[1])
+
+	header(w, "redis_pipeline_duration_seconds", "histogram", "Round-trip time of pipelines.")
+	writeHistogram(w, "redis_pipeline_duration_seconds", `tx="false"`, h.pipelineDur[0])
+	writeHistogram(w, "redis_pipeline_duration_seconds", `tx="true"`, h.pipelineDur[1])
+
+	header(w, "redis_dials_total", "counter", "Connections dialed.")
+	fmt.Fprintf(w, "redis_dials_total %d\n", h.dials.Load())
+	header(w, "redis_dial_errors_total", "counter", "Dials that failed.")
+	fmt.Fprintf(w, "redis_dial_errors_total %d\n", h.dialErrors.Load())
+
+	h.mu.Lock()
+	pools := make([]string, 0, len(h.pools))
+	stats := make(map[string]*poolStats, len(h.pools))
+	for name, p := range h.pools {
+		pools = append(pools, name)
+		s := p.PoolStats()
+		stats[name] = &poolStats{
+			hits: s.Hits, misses: s.Misses, timeouts: s.Timeouts, waits: s.WaitCount,
+			wait: time.Duration(s.WaitDurationNs), total: s.TotalConns, idle: s.IdleConns, stale: s.StaleConns,
+		}
+	}
+	h.mu.Unlock()
+	sort.Strings(pools)
+
+	for _, m := range poolMetrics {
+		header(w, m.name, m.kind, m.help)
+		for _, name := range pools {
+			fmt.Fprintf(w, "%s{pool=%q} %s\n", m.name, name, m.value(stats[name]))
+		}
+	}
+}
+
+// poolStats copies redis.PoolStats so poolMetrics can read it
+type poolStats struct {
+	hits, misses, timeouts, waits, total, idle, stale uint32
+	wait                                              time.Duration
+}
+
+var poolMetrics = []struct {
+	name, kind, help string
+	value            func(*poolStats) string
+}{
+	{"redis_pool_hits_total", "counter", "Times a free connection was found in the pool.", func(s *poolStats) string { return u(s.hits) }},
+	{"redis_pool_misses_total", "counter", "Times a new connection had to be created.", func(s *poolStats) string { return u(s.misses) }},
+	{"redis_pool_timeouts_total", "counter", "Times waiting for a connection timed out.", func(s *poolStats) string { return u(s.timeouts) }},
+	{"redis_pool_waits_total", "counter", "Times a caller waited for a connection.", func(s *poolStats) string { return u(s.waits) }},
+	{"redis_pool_wait_seconds_total", "counter", "Time spent waiting for connections.", func(s *poolStats) string { return f(s.wait.Seconds()) }},
+	{"redis_pool_connections", "gauge", "Connections currently open.", func(s *poolStats) string { return u(s.total) }},
+	{"redis_pool_idle_connections", "gauge", "Idle connections in the pool.", func(s *poolStats) string { return u(s.idle) }},
+	{"redis_pool_stale_connections_total", "counter", "Stale connections removed from the pool.", func(s *poolStats) string { return u(s.stale) }},
+}
+
+func u(v uint32) string  { return strconv.FormatUint(uint64(v), 10) }
+func f(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+func header(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w *bufio.Writer, name, labels string, h *histogram) {
+	cumulative, count, sum := h.snapshot()
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, labels, f(bound), cumulative[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, cumulative[len(cumulative)-1])
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, f(sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+// ServeHTTP serves the metrics, so a Hook can be mounted on any mux
+func (h *Hook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	h.WriteTo(w)
+}
+
+// Serve exposes the hook on addr at /metrics in the background. The
+// returned server's Addr holds the bound address, so addr may use port 0.
+func Serve(addr string, h *Hook) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h)
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
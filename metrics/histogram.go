@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Bucket upper bounds, in the unit of the observed values
+var (
+	LatencyBuckets  = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+	PipelineBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+)
+
+// histogram is a Prometheus-style histogram with fixed buckets. Observe is
+// safe for concurrent use.
+type histogram struct {
+	bounds []float64
+	counts []atomic.Int64 // Per bucket, not cumulative; the last is +Inf
+	count  atomic.Int64
+	sum    atomic.Uint64 // float64 bits
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]atomic.Int64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// snapshot returns cumulative bucket counts, the total count and the sum
+func (h *histogram) snapshot() ([]int64, int64, float64) {
+	cumulative := make([]int64, len(h.counts))
+	var n int64
+	for i := range h.counts {
+		n += h.counts[i].Load()
+		cumulative[i] = n
+	}
+	return cumulative, h.count.Load(), math.Float64frombits(h.sum.Load())
+}
@@ -0,0 +1,200 @@
+// Package metrics instruments go-redis clients with a Hook and exposes the
+// collected stats in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Error classes used for the class label of redis_command_errors_total
+const (
+	ClassNil        = "nil"
+	ClassTimeout    = "timeout"
+	ClassCanceled   = "canceled"
+	ClassConnection = "connection"
+	ClassMoved      = "moved"
+	ClassAsk        = "ask"
+	ClassTryAgain   = "tryagain"
+	ClassWrongType  = "wrongtype"
+	ClassOOM        = "oom"
+	ClassNoAuth     = "noauth"
+	ClassNoPerm     = "noperm"
+	ClassReadOnly   = "readonly"
+	ClassLoading    = "loading"
+	ClassBusy       = "busy"
+	ClassOther      = "err"
+)
+
+// ErrorClass maps an error returned by go-redis to one of the classes above
+func ErrorClass(err error) string {
+	if err == redis.Nil {
+		return ClassNil
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassCanceled
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		return ClassTimeout
+	}
+
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return ClassConnection
+	}
+	prefix, _, _ := strings.Cut(rerr.Error(), " ")
+	switch prefix {
+	case "MOVED":
+		return ClassMoved
+	case "ASK":
+		return ClassAsk
+	case "TRYAGAIN", "CLUSTERDOWN":
+		return ClassTryAgain
+	case "WRONGTYPE":
+		return ClassWrongType
+	case "OOM":
+		return ClassOOM
+	case "NOAUTH", "WRONGPASS":
+		return ClassNoAuth
+	case "NOPERM":
+		return ClassNoPerm
+	case "READONLY":
+		return ClassReadOnly
+	case "LOADING":
+		return ClassLoading
+	case "BUSY":
+		return ClassBusy
+	}
+	return ClassOther
+}
+
+// commandStats holds the metrics of one command name
+type commandStats struct {
+	calls   atomic.Int64
+	latency *histogram
+
+	mu     sync.Mutex
+	errors map[string]int64
+}
+
+// pooler is implemented by *redis.Client, *redis.ClusterClient and *redis.Ring
+type pooler interface {
+	PoolStats() *redis.PoolStats
+}
+
+// Hook records command, pipeline and connection metrics. Add it to one or
+// more clients with AddHook, or use Instrument.
+type Hook struct {
+	commands sync.Map // name -> *commandStats
+
+	pipelines   [2]*histogram // Sizes, indexed by tx
+	pipelineDur [2]*histogram
+	dials       atomic.Int64
+	dialErrors  atomic.Int64
+
+	mu    sync.Mutex
+	pools map[string]pooler
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a hook with no recorded metrics
+func NewHook() *Hook {
+	return &Hook{
+		pipelines:   [2]*histogram{newHistogram(PipelineBuckets), newHistogram(PipelineBuckets)},
+		pipelineDur: [2]*histogram{newHistogram(LatencyBuckets), newHistogram(LatencyBuckets)},
+		pools:       make(map[string]pooler),
+	}
+}
+
+// Instrument adds a new hook to rdb and reports its pool as "default"
+func Instrument(rdb *redis.Client) *Hook {
+	h := NewHook()
+	rdb.AddHook(h)
+	h.AddPool("default", rdb)
+	return h
+}
+
+// AddPool reports the connection pool of a client under the given label
+func (h *Hook) AddPool(name string, p pooler) {
+	h.mu.Lock()
+	h.pools[name] = p
+	h.mu.Unlock()
+}
+
+func (h *Hook) stats(name string) *commandStats {
+	if s, ok := h.commands.Load(name); ok {
+		return s.(*commandStats)
+	}
+	s, _ := h.commands.LoadOrStore(name, &commandStats{
+		latency: newHistogram(LatencyBuckets),
+		errors:  make(map[string]int64),
+	})
+	return s.(*commandStats)
+}
+
+// record counts one command and its error, if any
+func (h *Hook) record(cmd redis.Cmder, err error) *commandStats {
+	s := h.stats(cmd.Name())
+	s.calls.Add(1)
+	if err != nil {
+		s.mu.Lock()
+		s.errors[ErrorClass(err)]++
+		s.mu.Unlock()
+	}
+	return s
+}
+
+// DialHook counts new connections and failed dials
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		h.dials.Add(1)
+		if err != nil {
+			h.dialErrors.Add(1)
+		}
+		return conn, err
+	}
+}
+
+// ProcessHook records the count, latency and error class of each command
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		// The client sets cmd.Err only after the hooks return
+		err := next(ctx, cmd)
+		h.record(cmd, err).latency.observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ProcessPipelineHook records the size and duration of each pipeline and
+// counts the commands inside it. The MULTI/EXEC wrapping of transactions is
+// not counted as commands.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start).Seconds()
+
+		tx := 0
+		if len(cmds) >= 2 && cmds[0].Name() == "multi" && cmds[len(cmds)-1].Name() == "exec" {
+			tx = 1
+			cmds = cmds[1 : len(cmds)-1]
+		}
+		h.pipelines[tx].observe(float64(len(cmds)))
+		h.pipelineDur[tx].observe(elapsed)
+		for _, cmd := range cmds {
+			h.record(cmd, cmd.Err())
+		}
+		return err
+	}
+}
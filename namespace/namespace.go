@@ -0,0 +1,331 @@
+// Package namespace gives each developer or tenant sharing a Redis server
+// their own keys. A hook on the go-redis client prefixes every key a
+// command names, so code written against global names such as "leaderboard"
+// or "user:1001" runs unchanged side by side.
+//
+// Keys are located from the key specs the server reports in COMMAND, which
+// covers multi-key commands such as MSET, the destination of ZINTERSTORE,
+// the streams of XREAD and the KEYS of EVAL. KEYS and SCAN are narrowed to
+// the prefix, and the key names in their replies, and in the replies of
+// BLPOP, BZPOPMIN, LMPOP and XREAD, come back without it.
+//
+// Channels are not keys and are not prefixed, and neither are key names a
+// Lua script builds itself. FLUSHDB, FLUSHALL, RANDOMKEY and SWAPDB reach
+// beyond the prefix and fail.
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"Redis/keyspec"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EnvPrefix is the environment variable that puts the keys of the examples
+// under a prefix
+const EnvPrefix = "REDIS_NAMESPACE"
+
+// Hook prefixes the keys of every command and pipeline sent through a
+// go-redis client
+type Hook struct {
+	prefix string
+	load   func(context.Context) (*keyspec.Table, error)
+
+	mu   sync.Mutex
+	keys *keyspec.Table
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a hook adding prefix to the keys found with keys
+func NewHook(prefix string, keys *keyspec.Table) *Hook {
+	return &Hook{prefix: prefix, keys: keys}
+}
+
+// Wrap adds a hook to rdb that puts its keys under prefix. The command table
+// is read from the same server, over a separate connection, before the first
+// command.
+func Wrap(rdb *redis.Client, prefix string) *Hook {
+	opt := *rdb.Options()
+	h := &Hook{prefix: prefix, load: func(ctx context.Context) (*keyspec.Table, error) {
+		// A client without the hook, as reading the table through it would
+		// need the table
+		c := redis.NewClient(&opt)
+		defer c.Close()
+		return keyspec.Load(ctx, c)
+	}}
+	rdb.AddHook(h)
+	return h
+}
+
+// FromEnv wraps rdb with the prefix in $REDIS_NAMESPACE, and returns nil
+// leaving rdb alone when it is unset
+func FromEnv(rdb *redis.Client) *Hook {
+	prefix := os.Getenv(EnvPrefix)
+	if prefix == "" {
+		return nil
+	}
+	return Wrap(rdb, prefix)
+}
+
+// Prefix returns the prefix of the hook's keys
+func (h *Hook) Prefix() string {
+	return h.prefix
+}
+
+// Key returns the name of key in Redis
+func (h *Hook) Key(key string) string {
+	return h.prefix + key
+}
+
+// Strip returns the name of a key in Redis as the client sees it
+func (h *Hook) Strip(key string) string {
+	return strings.TrimPrefix(key, h.prefix)
+}
+
+// table returns the command table, reading it on first use
+func (h *Hook) table(ctx context.Context) (*keyspec.Table, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.keys == nil {
+		keys, err := h.load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("namespace: %w", err)
+		}
+		h.keys = keys
+	}
+	return h.keys, nil
+}
+
+// DialHook leaves connections alone
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook prefixes the keys of a command and strips the prefix from the
+// key names in its reply
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		keys, err := h.table(ctx)
+		if err != nil {
+			return err
+		}
+		p, err := h.prepare(keys, cmd)
+		if err != nil {
+			return err
+		}
+		err = next(ctx, p.send())
+		if p.swap != nil && err != nil {
+			// The client sets the error of the command it was given
+			p.swap.SetErr(err)
+		}
+		p.finish(h)
+		return err
+	}
+}
+
+// ProcessPipelineHook prefixes the keys of every command in a pipeline or
+// transaction. A pipeline with a command that cannot be prefixed is not sent.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		keys, err := h.table(ctx)
+		if err != nil {
+			return failAll(cmds, err)
+		}
+		preps := make([]prepared, 0, len(cmds))
+		send := cmds
+		for i, cmd := range cmds {
+			p, err := h.prepare(keys, cmd)
+			if err != nil {
+				for _, p := range preps {
+					p.finish(h)
+				}
+				return failAll(cmds, err)
+			}
+			if p.swap != nil {
+				if &send[0] == &cmds[0] {
+					send = slices.Clone(cmds)
+				}
+				send[i] = p.swap
+			}
+			preps = append(preps, p)
+		}
+		err = next(ctx, send)
+		for _, p := range preps {
+			p.finish(h)
+		}
+		return err
+	}
+}
+
+func failAll(cmds []redis.Cmder, err error) error {
+	for _, cmd := range cmds {
+		cmd.SetErr(err)
+	}
+	return err
+}
+
+// prepared is a command with its keys prefixed
+type prepared struct {
+	cmd  redis.Cmder
+	orig []interface{} // Arguments to put back once the reply is in, as SCAN iterators resend the command
+	swap *redis.Cmd    // Sent instead of cmd when arguments had to be added
+}
+
+func (p prepared) send() redis.Cmder {
+	if p.swap != nil {
+		return p.swap
+	}
+	return p.cmd
+}
+
+// prepare prefixes the keys of cmd in place. When the namespace needs more
+// arguments, as for SCAN without MATCH, a generic command is sent instead.
+func (h *Hook) prepare(keys *keyspec.Table, cmd redis.Cmder) (prepared, error) {
+	args := cmd.Args()
+	strs := make([]string, len(args))
+	for i, a := range args {
+		strs[i] = argString(a)
+	}
+	prefixed, err := keys.Prefix(strs, h.prefix)
+	if err != nil {
+		return prepared{}, fmt.Errorf("namespace: %s", strings.TrimPrefix(err.Error(), "keyspec: "))
+	}
+	p := prepared{cmd: cmd, orig: slices.Clone(args)}
+	if len(prefixed) != len(args) {
+		swapArgs := make([]interface{}, len(prefixed))
+		for i, a := range prefixed {
+			swapArgs[i] = a
+		}
+		p.swap = redis.NewCmd(context.Background(), swapArgs...)
+		return p, nil
+	}
+	for i := range args {
+		if prefixed[i] != strs[i] {
+			args[i] = prefixed[i]
+		}
+	}
+	return p, nil
+}
+
+func argString(a interface{}) string {
+	switch a := a.(type) {
+	case string:
+		return a
+	case []byte:
+		return string(a)
+	}
+	return fmt.Sprint(a)
+}
+
+// finish restores the arguments of the command, copies the reply of a
+// swapped command into it and strips the prefix from its key names
+func (p prepared) finish(h *Hook) {
+	copy(p.cmd.Args(), p.orig)
+	if p.swap != nil {
+		if err := p.swap.Err(); err != nil {
+			p.cmd.SetErr(err)
+			return
+		}
+		switch cmd := p.cmd.(type) {
+		case *redis.ScanCmd:
+			page, cursor := scanReply(p.swap.Val())
+			cmd.SetVal(page, cursor)
+		case *redis.Cmd:
+			cmd.SetVal(p.swap.Val())
+		default:
+			p.cmd.SetErr(fmt.Errorf("namespace: cannot return the reply of %s as %T", p.cmd.Name(), p.cmd))
+			return
+		}
+	}
+	h.strip(p.cmd)
+}
+
+// scanReply parses the [cursor, [keys...]] reply of SCAN
+func scanReply(v interface{}) ([]string, uint64) {
+	reply, _ := v.([]interface{})
+	if len(reply) != 2 {
+		return nil, 0
+	}
+	cursor, _ := strconv.ParseUint(argString(reply[0]), 10, 64)
+	list, _ := reply[1].([]interface{})
+	page := make([]string, len(list))
+	for i, k := range list {
+		page[i] = argString(k)
+	}
+	return page, cursor
+}
+
+// firstIsKey are commands whose replies start with the name of the key they
+// popped from
+var firstIsKey = map[string]bool{
+	"blpop": true, "brpop": true, "bzpopmin": true, "bzpopmax": true,
+	"lmpop": true, "blmpop": true, "zmpop": true, "bzmpop": true,
+}
+
+// strip removes the prefix from the key names in the reply of cmd
+func (h *Hook) strip(cmd redis.Cmder) {
+	name := cmd.Name()
+	switch cmd := cmd.(type) {
+	case *redis.StringSliceCmd:
+		val := cmd.Val()
+		switch {
+		case name == "keys":
+			for i := range val {
+				val[i] = h.Strip(val[i])
+			}
+		case firstIsKey[name] && len(val) > 0:
+			val[0] = h.Strip(val[0])
+		}
+	case *redis.ScanCmd:
+		page, cursor := cmd.Val()
+		for i := range page {
+			page[i] = h.Strip(page[i])
+		}
+		cmd.SetVal(page, cursor)
+	case *redis.ZWithKeyCmd:
+		if v := cmd.Val(); v != nil {
+			v.Key = h.Strip(v.Key)
+		}
+	case *redis.KeyValuesCmd:
+		key, vals := cmd.Val()
+		cmd.SetVal(h.Strip(key), vals)
+	case *redis.ZSliceWithKeyCmd:
+		key, z := cmd.Val()
+		cmd.SetVal(h.Strip(key), z)
+	case *redis.XStreamSliceCmd:
+		streams := cmd.Val()
+		for i := range streams {
+			streams[i].Stream = h.Strip(streams[i].Stream)
+		}
+	case *redis.Cmd:
+		// Replies to Do: strip what can be recognized
+		val, _ := cmd.Val().([]interface{})
+		switch {
+		case name == "keys":
+			for i, k := range val {
+				if s, ok := k.(string); ok {
+					val[i] = h.Strip(s)
+				}
+			}
+		case name == "scan" && len(val) == 2:
+			page, _ := val[1].([]interface{})
+			for i, k := range page {
+				if s, ok := k.(string); ok {
+					page[i] = h.Strip(s)
+				}
+			}
+		case firstIsKey[name] && len(val) > 0:
+			if s, ok := val[0].(string); ok {
+				val[0] = h.Strip(s)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Listener pushes a user's notifications to a handler while they are online
+type Listener struct {
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// Listen subscribes to a user's channel and waits for Redis to confirm the
+// subscription, so nothing sent after it returns is missed. Notifications
+// sent while offline are in the inbox.
+func (s *Service) Listen(ctx context.Context, userID string, h func(Notification)) (*Listener, error) {
+	pubsub := s.rdb.Subscribe(ctx, s.Channel(userID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("notify: subscribing: %w", err)
+	}
+	l := &Listener{pubsub: pubsub, done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		for msg := range pubsub.Channel() {
+			var n Notification
+			if err := json.Unmarshal([]byte(msg.Payload), &n); err == nil {
+				h(n)
+			}
+		}
+	}()
+	return l, nil
+}
+
+// Close unsubscribes and waits for the handler to return
+func (l *Listener) Close() error {
+	err := l.pubsub.Close()
+	<-l.done
+	return err
+}
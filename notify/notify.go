@@ -0,0 +1,322 @@
+// Package notify keeps a durable inbox of notifications per user, with
+// unread counts and live delivery over Pub/Sub to users who are online.
+//
+// Each user has two sorted sets scored by creation time: the inbox and the
+// subset still unread. Notification bodies are stored once, as JSON under
+// their ID, however many users receive them. Everything expires after the
+// retention period, so inactive users cost nothing.
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Notification is one message for users
+type Notification struct {
+	// ID identifies the notification. Sending the same ID to a user twice
+	// within the retention period delivers it once; leave it empty to
+	// generate a unique one.
+	ID      string            `json:"id"`
+	Type    string            `json:"type"`
+	Title   string            `json:"title"`
+	Body    string            `json:"body,omitempty"`
+	Data    map[string]string `json:"data,omitempty"`
+	Created time.Time         `json:"created"`
+
+	Read bool `json:"read,omitempty"` // Set by Inbox
+}
+
+// Config controls a Service
+type Config struct {
+	Prefix        string        // Key prefix; default "notify:"
+	ChannelPrefix string        // Live channel is ChannelPrefix + user ID; default "notifications:"
+	Retention     time.Duration // How long notifications are kept; default 30 days
+	MaxInbox      int64         // Newest notifications kept per user; default 1000
+	BatchSize     int           // Users per pipeline when fanning out; default 500
+}
+
+// Service sends and reads notifications
+type Service struct {
+	rdb redis.UniversalClient
+	cfg Config
+}
+
+// New creates a service
+func New(rdb redis.UniversalClient, cfg Config) *Service {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "notify:"
+	}
+	if cfg.ChannelPrefix == "" {
+		cfg.ChannelPrefix = "notifications:"
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.MaxInbox <= 0 {
+		cfg.MaxInbox = 1000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Service{rdb: rdb, cfg: cfg}
+}
+
+// Channel returns the Pub/Sub channel a user's notifications are pushed to
+func (s *Service) Channel(userID string) string {
+	return s.cfg.ChannelPrefix + userID
+}
+
+// userKeys returns a user's inbox and unread sets, in one cluster slot
+func (s *Service) userKeys(userID string) (inbox, unread string) {
+	base := s.cfg.Prefix + "{" + userID + "}"
+	return base + ":inbox", base + ":unread"
+}
+
+func (s *Service) bodyKey(id string) string {
+	return s.cfg.Prefix + "msg:" + id
+}
+
+// cutoff is the oldest creation time still within retention, in ms
+func (s *Service) cutoff() int64 {
+	return time.Now().Add(-s.cfg.Retention).UnixMilli()
+}
+
+// deliverScript adds a notification to one user's inbox unless it is
+// already there, trims what fell out of retention or over the cap, and
+// pushes it to the user's channel. Returns {added, receivers}.
+var deliverScript = redis.NewScript(`
+local inbox, unread = KEYS[1], KEYS[2]
+local id, score, cutoff, max, ttl = ARGV[1], tonumber(ARGV[2]), ARGV[3], tonumber(ARGV[4]), ARGV[5]
+redis.call('ZREMRANGEBYSCORE', inbox, '-inf', '(' .. cutoff)
+redis.call('ZREMRANGEBYSCORE', unread, '-inf', '(' .. cutoff)
+if score < tonumber(cutoff) or redis.call('ZSCORE', inbox, id) then
+	return {0, 0}
+end
+redis.call('ZADD', inbox, score, id)
+redis.call('ZADD', unread, score, id)
+local over = redis.call('ZCARD', inbox) - max
+if over > 0 then
+	local dropped = redis.call('ZRANGE', inbox, 0, over - 1)
+	redis.call('ZREMRANGEBYRANK', inbox, 0, over - 1)
+	redis.call('ZREM', unread, unpack(dropped))
+end
+redis.call('PEXPIRE', inbox, ttl)
+redis.call('PEXPIRE', unread, ttl)
+return {1, redis.call('PUBLISH', ARGV[6], ARGV[7])}
+`)
+
+var idSeq atomic.Uint32
+
+// prepare fills in the ID and creation time, stores the body and returns
+// the payload pushed to online users
+func (s *Service) prepare(ctx context.Context, n *Notification) (string, error) {
+	if n.ID == "" {
+		// Inboxes order notifications created in the same millisecond by
+		// ID, so generated IDs sort by creation within a process
+		b := make([]byte, 4)
+		rand.Read(b)
+		n.ID = fmt.Sprintf("%s-%06x-%s", strconv.FormatInt(time.Now().UnixMilli(), 36), idSeq.Add(1)&0xffffff, hex.EncodeToString(b))
+	}
+	if n.Created.IsZero() {
+		n.Created = time.Now()
+	}
+	n.Read = false
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return "", err
+	}
+	// The first body stored under an ID wins, like the first delivery
+	if err := s.rdb.SetNX(ctx, s.bodyKey(n.ID), payload, s.cfg.Retention).Err(); err != nil {
+		return "", fmt.Errorf("notify: storing %s: %w", n.ID, err)
+	}
+	return string(payload), nil
+}
+
+func (s *Service) deliverArgs(userID string, n *Notification, payload string) ([]string, []interface{}) {
+	inbox, unread := s.userKeys(userID)
+	return []string{inbox, unread}, []interface{}{
+		n.ID, n.Created.UnixMilli(), s.cutoff(), s.cfg.MaxInbox, s.cfg.Retention.Milliseconds(),
+		s.Channel(userID), payload,
+	}
+}
+
+// Result reports the outcome of a delivery
+type Result struct {
+	Recipients int // Users the notification was addressed to
+	Delivered  int // Users it was added to
+	Duplicates int // Users who already had it, or for whom it is older than the retention period
+	Online     int // Users with at least one live subscriber when it was pushed
+}
+
+// Send delivers n to one user. It fills in n.ID and n.Created when empty.
+func (s *Service) Send(ctx context.Context, userID string, n *Notification) (Result, error) {
+	payload, err := s.prepare(ctx, n)
+	if err != nil {
+		return Result{}, err
+	}
+	keys, args := s.deliverArgs(userID, n, payload)
+	reply, err := deliverScript.Run(ctx, s.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("notify: delivering to %s: %w", userID, err)
+	}
+	return tally(Result{Recipients: 1}, reply), nil
+}
+
+func tally(r Result, reply []int64) Result {
+	if reply[0] == 1 {
+		r.Delivered++
+	} else {
+		r.Duplicates++
+	}
+	if reply[1] > 0 {
+		r.Online++
+	}
+	return r
+}
+
+// FanOut delivers n to every user the recipients yield, one pipeline per
+// batch. Users listed twice, or in overlapping segments, get it once.
+func (s *Service) FanOut(ctx context.Context, n *Notification, recipients Recipients) (Result, error) {
+	payload, err := s.prepare(ctx, n)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := deliverScript.Load(ctx, s.rdb).Err(); err != nil {
+		return Result{}, fmt.Errorf("notify: loading script: %w", err)
+	}
+
+	var result Result
+	flush := func(users []string) error {
+		pipe := s.rdb.Pipeline()
+		cmds := make([]*redis.Cmd, len(users))
+		for i, u := range users {
+			keys, args := s.deliverArgs(u, n, payload)
+			cmds[i] = deliverScript.EvalSha(ctx, pipe, keys, args...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("notify: fan-out batch: %w", err)
+		}
+		for _, cmd := range cmds {
+			reply, err := cmd.Int64Slice()
+			if err != nil {
+				return err
+			}
+			result.Recipients++
+			result = tally(result, reply)
+		}
+		return nil
+	}
+
+	batch := make([]string, 0, s.cfg.BatchSize)
+	err = recipients(ctx, func(users []string) error {
+		for _, u := range users {
+			batch = append(batch, u)
+			if len(batch) == s.cfg.BatchSize {
+				if err := flush(batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush(batch)
+	}
+	return result, err
+}
+
+// Inbox returns a user's notifications, newest first, starting at offset
+func (s *Service) Inbox(ctx context.Context, userID string, offset, count int64) ([]Notification, error) {
+	inbox, unread := s.userKeys(userID)
+	ids, err := s.rdb.ZRevRangeByScore(ctx, inbox, &redis.ZRangeBy{
+		Min: strconv.FormatInt(s.cutoff(), 10), Max: "+inf", Offset: offset, Count: count,
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	pipe := s.rdb.Pipeline()
+	bodies := make([]*redis.StringCmd, len(ids))
+	scores := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		bodies[i] = pipe.Get(ctx, s.bodyKey(id))
+		scores[i] = pipe.ZScore(ctx, unread, id)
+	}
+	// Exec only reports the first failed command, and nil replies are
+	// expected, so each reply is checked below. Any other error, like a
+	// dropped connection, is not copied onto the commands.
+	var reply redis.Error
+	if _, err := pipe.Exec(ctx); err != nil && !errors.As(err, &reply) {
+		return nil, err
+	}
+
+	list := make([]Notification, 0, len(ids))
+	for i := range ids {
+		data, err := bodies[i].Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue // Body expired before the inbox entry was trimmed
+		}
+		if err != nil {
+			return nil, err
+		}
+		var n Notification
+		if err := json.Unmarshal(data, &n); err != nil {
+			return nil, fmt.Errorf("notify: decoding %s: %w", ids[i], err)
+		}
+		switch err := scores[i].Err(); {
+		case errors.Is(err, redis.Nil):
+			n.Read = true
+		case err != nil:
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// UnreadCount returns how many of a user's notifications are unread
+func (s *Service) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	_, unread := s.userKeys(userID)
+	return s.rdb.ZCount(ctx, unread, strconv.FormatInt(s.cutoff(), 10), "+inf").Result()
+}
+
+// MarkRead marks notifications read and returns how many were unread
+func (s *Service) MarkRead(ctx context.Context, userID string, ids ...string) (int64, error) {
+	_, unread := s.userKeys(userID)
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+	return s.rdb.ZRem(ctx, unread, members...).Result()
+}
+
+// MarkAllRead marks every notification of a user read
+func (s *Service) MarkAllRead(ctx context.Context, userID string) error {
+	_, unread := s.userKeys(userID)
+	return s.rdb.Del(ctx, unread).Err()
+}
+
+// Delete removes notifications from a user's inbox
+func (s *Service) Delete(ctx context.Context, userID string, ids ...string) error {
+	inbox, unread := s.userKeys(userID)
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.ZRem(ctx, inbox, members...)
+	pipe.ZRem(ctx, unread, members...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Recipients yields the user IDs of a fan-out, in batches, to yield
+type Recipients func(ctx context.Context, yield func(userIDs []string) error) error
+
+// Users addresses a fixed list of users
+func Users(ids ...string) Recipients {
+	return func(ctx context.Context, yield func([]string) error) error {
+		return yield(ids)
+	}
+}
+
+// Union addresses everyone in any of the segments. Users in several get
+// the notification once, since a user's inbox holds each ID once.
+func Union(segments ...Recipients) Recipients {
+	return func(ctx context.Context, yield func([]string) error) error {
+		for _, seg := range segments {
+			if err := seg(ctx, yield); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// SetMembers addresses the users in a set, such as segment:beta_testers
+func (s *Service) SetMembers(key string) Recipients {
+	return func(ctx context.Context, yield func([]string) error) error {
+		iter := s.rdb.SScan(ctx, key, 0, "", int64(s.cfg.BatchSize)).Iterator()
+		batch := make([]string, 0, s.cfg.BatchSize)
+		for iter.Next(ctx) {
+			batch = append(batch, iter.Val())
+			if len(batch) == s.cfg.BatchSize {
+				if err := yield(batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("notify: reading %s: %w", key, err)
+		}
+		return yield(batch)
+	}
+}
+
+// Interested addresses the users whose set under pattern contains
+// interest. The user ID is what the * stands for, so with the seed data
+// Interested("user_interests:*", "technology") yields IDs like user:42.
+func (s *Service) Interested(pattern, interest string) Recipients {
+	prefix, _, _ := strings.Cut(pattern, "*")
+	return func(ctx context.Context, yield func([]string) error) error {
+		var mu sync.Mutex // Cluster masters are scanned concurrently
+		scan := func(ctx context.Context, node redis.UniversalClient) error {
+			iter := node.ScanType(ctx, 0, pattern, int64(s.cfg.BatchSize), "set").Iterator()
+			keys := make([]string, 0, s.cfg.BatchSize)
+			check := func() error {
+				if len(keys) == 0 {
+					return nil
+				}
+				pipe := node.Pipeline()
+				cmds := make([]*redis.BoolCmd, len(keys))
+				for i, k := range keys {
+					cmds[i] = pipe.SIsMember(ctx, k, interest)
+				}
+				if _, err := pipe.Exec(ctx); err != nil {
+					return err
+				}
+				var users []string
+				for i, cmd := range cmds {
+					if cmd.Val() {
+						users = append(users, strings.TrimPrefix(keys[i], prefix))
+					}
+				}
+				keys = keys[:0]
+				mu.Lock()
+				defer mu.Unlock()
+				return yield(users)
+			}
+			for iter.Next(ctx) {
+				keys = append(keys, iter.Val())
+				if len(keys) == s.cfg.BatchSize {
+					if err := check(); err != nil {
+						return err
+					}
+				}
+			}
+			if err := iter.Err(); err != nil {
+				return fmt.Errorf("notify: scanning %s: %w", pattern, err)
+			}
+			return check()
+		}
+
+		if cluster, ok := s.rdb.(*redis.ClusterClient); ok {
+			return cluster.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+				return scan(ctx, master)
+			})
+		}
+		return scan(ctx, s.rdb)
+	}
+}
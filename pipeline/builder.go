@@ -0,0 +1,249 @@
+// Package pipeline wraps go-redis pipelines in a builder whose queued
+// commands return typed handles, so results are read by name after Exec
+// instead of by position and type assertion.
+//
+//	b := pipeline.New(ctx, rdb)
+//	name := b.HGet("user:1", "name")
+//	score := b.ZScore("game_leaderboard", "player_1")
+//	report, err := b.Exec()
+//	fmt.Println(name.Val(), score.Val())
+//
+// Exec collects per-command failures in a Report. A missing key (redis.Nil)
+// is not a failure; handles expose it through IsNil.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotExecuted is returned by handles read before Exec
+var ErrNotExecuted = errors.New("pipeline: not executed")
+
+// ErrExecuted is returned when Exec is called twice
+var ErrExecuted = errors.New("pipeline: already executed")
+
+// Handle is the typed result of one queued command
+type Handle[T any] struct {
+	cmd  resulter[T]
+	val  T
+	err  error
+	done bool
+}
+
+// resulter is implemented by every typed go-redis command
+type resulter[T any] interface {
+	redis.Cmder
+	Result() (T, error)
+}
+
+// Val returns the reply, or the zero value if the command failed or the key
+// was missing
+func (h *Handle[T]) Val() T {
+	return h.val
+}
+
+// Err returns the command's error: nil, redis.Nil for a missing key, a
+// server error, or ErrNotExecuted before Exec
+func (h *Handle[T]) Err() error {
+	if !h.done {
+		return ErrNotExecuted
+	}
+	return h.err
+}
+
+// Result returns the reply and error together
+func (h *Handle[T]) Result() (T, error) {
+	return h.val, h.Err()
+}
+
+// IsNil reports whether the command found no value
+func (h *Handle[T]) IsNil() bool {
+	return h.done && h.err == redis.Nil
+}
+
+// Failed reports whether the command returned an error other than redis.Nil
+func (h *Handle[T]) Failed() bool {
+	return h.done && h.err != nil && h.err != redis.Nil
+}
+
+func (h *Handle[T]) resolve() {
+	h.val, h.err = h.cmd.Result()
+	h.done = true
+}
+
+// Builder queues commands on a pipeline and hands out typed handles
+type Builder struct {
+	ctx      context.Context
+	pipe     redis.Pipeliner
+	handles  []interface{ resolve() }
+	cmds     []redis.Cmder
+	executed bool
+}
+
+// New starts a plain pipeline
+func New(ctx context.Context, rdb redis.Cmdable) *Builder {
+	return &Builder{ctx: ctx, pipe: rdb.Pipeline()}
+}
+
+// NewTx starts a pipeline wrapped in MULTI/EXEC
+func NewTx(ctx context.Context, rdb redis.Cmdable) *Builder {
+	return &Builder{ctx: ctx, pipe: rdb.TxPipeline()}
+}
+
+// Pipeliner exposes the underlying pipeline so that commands without a
+// builder method can be queued with Queue
+func (b *Builder) Pipeliner() redis.Pipeliner {
+	return b.pipe
+}
+
+// Context returns the context commands are queued with
+func (b *Builder) Context() context.Context {
+	return b.ctx
+}
+
+// Queue wraps a command created on b.Pipeliner() in a typed handle:
+//
+//	members := pipeline.Queue(b, b.Pipeliner().ZRangeWithScores(ctx, key, 0, 9))
+func Queue[T any](b *Builder, cmd resulter[T]) *Handle[T] {
+	h := &Handle[T]{cmd: cmd}
+	b.handles = append(b.handles, h)
+	b.cmds = append(b.cmds, cmd)
+	return h
+}
+
+// Len returns the number of queued commands
+func (b *Builder) Len() int {
+	return len(b.cmds)
+}
+
+// Exec sends the queued commands and populates every handle. The returned
+// error is a *PartialError listing every command that failed; the report is
+// returned either way.
+func (b *Builder) Exec() (*Report, error) {
+	if b.executed {
+		return nil, ErrExecuted
+	}
+	b.executed = true
+
+	// Exec's error is the first failed command's. A server reply such as
+	// WRONGTYPE belongs to that command; anything else, like an unreachable
+	// server, failed the round trip and go-redis leaves the commands alone.
+	if _, err := b.pipe.Exec(b.ctx); err != nil {
+		var reply redis.Error
+		if !errors.As(err, &reply) {
+			for _, cmd := range b.cmds {
+				if cmd.Err() == nil {
+					cmd.SetErr(err)
+				}
+			}
+		}
+	}
+
+	report := &Report{Total: len(b.cmds)}
+	for i, h := range b.handles {
+		h.resolve()
+		switch err := b.cmds[i].Err(); {
+		case err == nil:
+			report.Succeeded++
+		case err == redis.Nil:
+			report.Nil++
+		default:
+			report.Failures = append(report.Failures, Failure{
+				Index:   i,
+				Command: describe(b.cmds[i]),
+				Err:     err,
+			})
+		}
+	}
+	return report, report.Err()
+}
+
+// Failure records one command that returned an error
+type Failure struct {
+	Index   int    // Position in the pipeline, from 0
+	Command string // Command name and arguments, shortened
+	Err     error
+}
+
+// Report summarises the outcome of Exec
+type Report struct {
+	Total     int
+	Succeeded int
+	Nil       int // Commands that found no value
+	Failures  []Failure
+}
+
+// OK reports whether no command failed
+func (r *Report) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// Err returns a *PartialError describing the failures, or nil
+func (r *Report) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return &PartialError{Report: r}
+}
+
+// String summarises the report on one line per failure
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d commands: %d succeeded, %d nil, %d failed", r.Total, r.Succeeded, r.Nil, len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintf(&sb, "\n  #%d %s: %v", f.Index, f.Command, f.Err)
+	}
+	return sb.String()
+}
+
+// PartialError is returned by Exec when at least one command failed
+type PartialError struct {
+	Report *Report
+}
+
+func (e *PartialError) Error() string {
+	f := e.Report.Failures[0]
+	msg := fmt.Sprintf("pipeline: %d of %d commands failed: #%d %s: %v",
+		len(e.Report.Failures), e.Report.Total, f.Index, f.Command, f.Err)
+	if len(e.Report.Failures) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Report.Failures)-1)
+	}
+	return msg
+}
+
+// Unwrap exposes every failure's error to errors.Is and errors.As
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Report.Failures))
+	for i, f := range e.Report.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// describe renders a command for a report, shortening long arguments
+func describe(cmd redis.Cmder) string {
+	const maxArg, maxArgs = 32, 6
+
+	args := cmd.Args()
+	parts := make([]string, 0, min(len(args), maxArgs)+1)
+	for i, a := range args {
+		if i == maxArgs {
+			parts = append(parts, fmt.Sprintf("... (%d args)", len(args)))
+			break
+		}
+		s := fmt.Sprint(a)
+		if i == 0 {
+			s = strings.ToUpper(s)
+		}
+		if len(s) > maxArg {
+			s = s[:maxArg] + "..."
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Generic
+
+// Do queues an arbitrary command
+func (b *Builder) Do(args ...interface{}) *Handle[interface{}] {
+	return Queue(b, b.pipe.Do(b.ctx, args...))
+}
+
+// Del queues DEL and returns the number of keys removed
+func (b *Builder) Del(keys ...string) *Handle[int64] {
+	return Queue(b, b.pipe.Del(b.ctx, keys...))
+}
+
+// Exists queues EXISTS and returns how many of the keys exist
+func (b *Builder) Exists(keys ...string) *Handle[int64] {
+	return Queue(b, b.pipe.Exists(b.ctx, keys...))
+}
+
+// Expire queues EXPIRE
+func (b *Builder) Expire(key string, ttl time.Duration) *Handle[bool] {
+	return Queue(b, b.pipe.Expire(b.ctx, key, ttl))
+}
+
+// TTL queues TTL
+func (b *Builder) TTL(key string) *Handle[time.Duration] {
+	return Queue(b, b.pipe.TTL(b.ctx, key))
+}
+
+// Strings
+
+// Set queues SET; the handle holds "OK"
+func (b *Builder) Set(key string, value interface{}, ttl time.Duration) *Handle[string] {
+	return Queue(b, b.pipe.Set(b.ctx, key, value, ttl))
+}
+
+// SetNX queues SET NX and reports whether the key was set
+func (b *Builder) SetNX(key string, value interface{}, ttl time.Duration) *Handle[bool] {
+	return Queue(b, b.pipe.SetNX(b.ctx, key, value, ttl))
+}
+
+// Get queues GET
+func (b *Builder) Get(key string) *Handle[string] {
+	return Queue(b, b.pipe.Get(b.ctx, key))
+}
+
+// Incr queues INCR
+func (b *Builder) Incr(key string) *Handle[int64] {
+	return Queue(b, b.pipe.Incr(b.ctx, key))
+}
+
+// IncrBy queues INCRBY
+func (b *Builder) IncrBy(key string, n int64) *Handle[int64] {
+	return Queue(b, b.pipe.IncrBy(b.ctx, key, n))
+}
+
+// Hashes
+
+// HSet queues HSET and returns the number of new fields
+func (b *Builder) HSet(key string, values ...interface{}) *Handle[int64] {
+	return Queue(b, b.pipe.HSet(b.ctx, key, values...))
+}
+
+// HSetNX queues HSETNX
+func (b *Builder) HSetNX(key, field string, value interface{}) *Handle[bool] {
+	return Queue(b, b.pipe.HSetNX(b.ctx, key, field, value))
+}
+
+// HGet queues HGET
+func (b *Builder) HGet(key, field string) *Handle[string] {
+	return Queue(b, b.pipe.HGet(b.ctx, key, field))
+}
+
+// HGetAll queues HGETALL
+func (b *Builder) HGetAll(key string) *Handle[map[string]string] {
+	return Queue(b, b.pipe.HGetAll(b.ctx, key))
+}
+
+// HIncrBy queues HINCRBY
+func (b *Builder) HIncrBy(key, field string, n int64) *Handle[int64] {
+	return Queue(b, b.pipe.HIncrBy(b.ctx, key, field, n))
+}
+
+// Lists
+
+// LPush queues LPUSH and returns the new length
+func (b *Builder) LPush(key string, values ...interface{}) *Handle[int64] {
+	return Queue(b, b.pipe.LPush(b.ctx, key, values...))
+}
+
+// RPush queues RPUSH and returns the new length
+func (b *Builder) RPush(key string, values ...interface{}) *Handle[int64] {
+	return Queue(b, b.pipe.RPush(b.ctx, key, values...))
+}
+
+// LRange queues LRANGE
+func (b *Builder) LRange(key string, start, stop int64) *Handle[[]string] {
+	return Queue(b, b.pipe.LRange(b.ctx, key, start, stop))
+}
+
+// Sets
+
+// SAdd queues SADD and returns the number of new members
+func (b *Builder) SAdd(key string, members ...interface{}) *Handle[int64] {
+	return Queue(b, b.pipe.SAdd(b.ctx, key, members...))
+}
+
+// SMembers queues SMEMBERS
+func (b *Builder) SMembers(key string) *Handle[[]string] {
+	return Queue(b, b.pipe.SMembers(b.ctx, key))
+}
+
+// SIsMember queues SISMEMBER
+func (b *Builder) SIsMember(key string, member interface{}) *Handle[bool] {
+	return Queue(b, b.pipe.SIsMember(b.ctx, key, member))
+}
+
+// Sorted sets
+
+// ZAdd queues ZADD and returns the number of new members
+func (b *Builder) ZAdd(key string, members ...redis.Z) *Handle[int64] {
+	return Queue(b, b.pipe.ZAdd(b.ctx, key, members...))
+}
+
+// ZIncrBy queues ZINCRBY and returns the new score
+func (b *Builder) ZIncrBy(key string, n float64, member string) *Handle[float64] {
+	return Queue(b, b.pipe.ZIncrBy(b.ctx, key, n, member))
+}
+
+// ZScore queues ZSCORE
+func (b *Builder) ZScore(key, member string) *Handle[float64] {
+	return Queue(b, b.pipe.ZScore(b.ctx, key, member))
+}
+
+// ZRevRank queues ZREVRANK
+func (b *Builder) ZRevRank(key, member string) *Handle[int64] {
+	return Queue(b, b.pipe.ZRevRank(b.ctx, key, member))
+}
+
+// ZRangeWithScores queues ZRANGE ... WITHSCORES
+func (b *Builder) ZRangeWithScores(key string, start, stop int64) *Handle[[]redis.Z] {
+	return Queue(b, b.pipe.ZRangeWithScores(b.ctx, key, start, stop))
+}
+
+// ZRevRangeWithScores queues ZREVRANGE ... WITHSCORES
+func (b *Builder) ZRevRangeWithScores(key string, start, stop int64) *Handle[[]redis.Z] {
+	return Queue(b, b.pipe.ZRevRangeWithScores(b.ctx, key, start, stop))
+}
+
+// Streams
+
+// XAdd queues XADD and returns the entry ID
+func (b *Builder) XAdd(args *redis.XAddArgs) *Handle[string] {
+	return Queue(b, b.pipe.XAdd(b.ctx, args))
+}
+
+// XLen queues XLEN
+func (b *Builder) XLen(stream string) *Handle[int64] {
+	return Queue(b, b.pipe.XLen(b.ctx, stream))
+}
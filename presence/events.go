@@ -0,0 +1,78 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EventType says what happened in a room
+type EventType string
+
+const (
+	Join          EventType = "join"           // User came online in the room
+	Leave         EventType = "leave"          // User left the room
+	Timeout       EventType = "timeout"        // User stopped heartbeating and was swept
+	Typing        EventType = "typing"         // User started typing
+	StoppedTyping EventType = "stopped_typing" // User sent their message or cleared the input
+)
+
+// Event is published on a room's channel. Typing indicators that lapse
+// without a refresh produce no event; clients drop them after the TTL.
+type Event struct {
+	Type EventType `json:"type"`
+	Room string    `json:"room"`
+	User string    `json:"user"`
+	At   time.Time `json:"at"`
+}
+
+func newEvent(kind EventType, room, user string, at time.Time) (string, error) {
+	data, err := json.Marshal(Event{Type: kind, Room: room, User: user, At: at})
+	if err != nil {
+		return "", fmt.Errorf("presence: encoding event: %w", err)
+	}
+	return string(data), nil
+}
+
+// Watcher passes the events of some rooms to a handler
+type Watcher struct {
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// Watch subscribes to the events of rooms and waits for Redis to confirm
+// the subscriptions, so no event published after it returns is missed
+func (t *Tracker) Watch(ctx context.Context, h func(Event), rooms ...string) (*Watcher, error) {
+	channels := make([]string, len(rooms))
+	for i, room := range rooms {
+		channels[i] = t.Channel(room)
+	}
+	pubsub := t.rdb.Subscribe(ctx, channels...)
+	for range channels {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return nil, fmt.Errorf("presence: subscribing: %w", err)
+		}
+	}
+	w := &Watcher{pubsub: pubsub, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		for msg := range pubsub.Channel() {
+			var e Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err == nil {
+				h(e)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Close unsubscribes and waits for the handler to return
+func (w *Watcher) Close() error {
+	err := w.pubsub.Close()
+	<-w.done
+	return err
+}
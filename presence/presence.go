@@ -0,0 +1,300 @@
+// Package presence tracks which users are online, per chat room and
+// overall, from client heartbeats.
+//
+// Each room keeps a sorted set of its online users scored by the time they
+// were last seen. A user who stops heartbeating for longer than the timeout
+// is offline; Sweep removes them and announces it. Joins, leaves and typing
+// indicators are published as events on a channel per room.
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config controls a Tracker
+type Config struct {
+	Prefix        string        // Key and channel prefix; default "presence:"
+	MembersPrefix string        // Room member sets are MembersPrefix + room; default "chat_users:"
+	Timeout       time.Duration // Silence after which a user is offline; default 30s
+	TypingTTL     time.Duration // How long a typing indicator lasts without a refresh; default 5s
+
+	// LastSeenRetention is how long SweepAll keeps the last-seen time of
+	// users who stopped sending heartbeats; default 30 days, negative for ever
+	LastSeenRetention time.Duration
+}
+
+// Tracker records heartbeats and answers who is online
+type Tracker struct {
+	rdb redis.UniversalClient
+	cfg Config
+}
+
+// New creates a tracker. Clients should heartbeat well within the timeout,
+// e.g. every 10 seconds for the default of 30.
+func New(rdb redis.UniversalClient, cfg Config) *Tracker {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "presence:"
+	}
+	if cfg.MembersPrefix == "" {
+		cfg.MembersPrefix = "chat_users:"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.TypingTTL <= 0 {
+		cfg.TypingTTL = 5 * time.Second
+	}
+	if cfg.LastSeenRetention == 0 {
+		cfg.LastSeenRetention = 30 * 24 * time.Hour
+	}
+	return &Tracker{rdb: rdb, cfg: cfg}
+}
+
+// roomKey returns a key of room; the hash tag keeps a room's keys together
+func (t *Tracker) roomKey(room, name string) string {
+	return t.cfg.Prefix + "{" + room + "}:" + name
+}
+
+// Channel returns the channel a room's events are published on
+func (t *Tracker) Channel(room string) string {
+	return t.roomKey(room, "events")
+}
+
+func (t *Tracker) roomsKey() string    { return t.cfg.Prefix + "rooms" }
+func (t *Tracker) lastSeenKey() string { return t.cfg.Prefix + "last_seen" }
+
+func ms(tm time.Time) string {
+	return strconv.FormatInt(tm.UnixMilli(), 10)
+}
+
+// heartbeatScript marks a user seen in a room and announces the join when
+// they were not online there. The set outlives the timeout so Sweep can
+// still announce leaves, but disappears once a room has been idle for long.
+var heartbeatScript = redis.NewScript(`
+local prev = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+if not prev or tonumber(prev) < tonumber(ARGV[3]) then
+	redis.call('PUBLISH', ARGV[5], ARGV[6])
+	return 1
+end
+return 0
+`)
+
+// Heartbeat marks user as online in rooms and returns the rooms they joined
+// with this heartbeat, i.e. where they were not online before
+func (t *Tracker) Heartbeat(ctx context.Context, user string, rooms ...string) ([]string, error) {
+	now := time.Now()
+	if err := t.rdb.ZAdd(ctx, t.lastSeenKey(), redis.Z{Score: float64(now.UnixMilli()), Member: user}).Err(); err != nil {
+		return nil, fmt.Errorf("presence: heartbeat: %w", err)
+	}
+	var joined []string
+	for _, room := range rooms {
+		event, err := newEvent(Join, room, user, now)
+		if err != nil {
+			return nil, err
+		}
+		keys := []string{t.roomKey(room, "online")}
+		args := []interface{}{user, ms(now), ms(now.Add(-t.cfg.Timeout)), (10 * t.cfg.Timeout).Milliseconds(), t.Channel(room), event}
+		added, err := heartbeatScript.Run(ctx, t.rdb, keys, args...).Int()
+		if err != nil {
+			return nil, fmt.Errorf("presence: heartbeat in %s: %w", room, err)
+		}
+		if added == 1 {
+			joined = append(joined, room)
+			if err := t.rdb.SAdd(ctx, t.roomsKey(), room).Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return joined, nil
+}
+
+// removeScript takes a user out of a room, only if last seen before
+// ARGV[2] when given, and announces the leave
+var removeScript = redis.NewScript(`
+local seen = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not seen or (ARGV[2] ~= '' and tonumber(seen) >= tonumber(ARGV[2])) then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('PUBLISH', ARGV[3], ARGV[4])
+return 1
+`)
+
+func (t *Tracker) remove(ctx context.Context, user, room, before string, kind EventType) (bool, error) {
+	event, err := newEvent(kind, room, user, time.Now())
+	if err != nil {
+		return false, err
+	}
+	keys := []string{t.roomKey(room, "online"), t.roomKey(room, "typing")}
+	removed, err := removeScript.Run(ctx, t.rdb, keys, user, before, t.Channel(room), event).Int()
+	return removed == 1, err
+}
+
+// Leave takes user out of a room right away, e.g. on logout or closing the
+// room, and reports whether they were online there
+func (t *Tracker) Leave(ctx context.Context, user, room string) (bool, error) {
+	return t.remove(ctx, user, room, "", Leave)
+}
+
+// Online returns the users seen in room within the timeout, most recent first
+func (t *Tracker) Online(ctx context.Context, room string) ([]string, error) {
+	return t.rdb.ZRevRangeByScore(ctx, t.roomKey(room, "online"), &redis.ZRangeBy{
+		Min: ms(time.Now().Add(-t.cfg.Timeout)), Max: "+inf",
+	}).Result()
+}
+
+// CountOnline returns how many users are online in room
+func (t *Tracker) CountOnline(ctx context.Context, room string) (int64, error) {
+	return t.rdb.ZCount(ctx, t.roomKey(room, "online"), ms(time.Now().Add(-t.cfg.Timeout)), "+inf").Result()
+}
+
+// LastSeen returns when user last sent a heartbeat, and false if never
+func (t *Tracker) LastSeen(ctx context.Context, user string) (time.Time, bool, error) {
+	score, err := t.rdb.ZScore(ctx, t.lastSeenKey(), user).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(int64(score)), true, nil
+}
+
+// IsOnline reports whether user sent a heartbeat within the timeout
+func (t *Tracker) IsOnline(ctx context.Context, user string) (bool, error) {
+	seen, ok, err := t.LastSeen(ctx, user)
+	return ok && time.Since(seen) < t.cfg.Timeout, err
+}
+
+// Status is a room member's presence
+type Status struct {
+	User     string
+	Online   bool
+	LastSeen time.Time // Zero when never seen
+}
+
+// Members returns the presence of every member of a room, from the room's
+// member set such as chat_users:general, online users first
+func (t *Tracker) Members(ctx context.Context, room string) ([]Status, error) {
+	members, err := t.rdb.SMembers(ctx, t.cfg.MembersPrefix+room).Result()
+	if err != nil {
+		return nil, err
+	}
+	pipe := t.rdb.Pipeline()
+	online := make([]*redis.FloatCmd, len(members))
+	seen := make([]*redis.FloatCmd, len(members))
+	for i, m := range members {
+		online[i] = pipe.ZScore(ctx, t.roomKey(room, "online"), m)
+		seen[i] = pipe.ZScore(ctx, t.lastSeenKey(), m)
+	}
+	// Exec only reports the first failed command, and members who are
+	// offline or never seen reply nil, so each reply is checked below. Any
+	// other error, like a dropped connection, is not copied onto the commands.
+	var reply redis.Error
+	if _, err := pipe.Exec(ctx); err != nil && !errors.As(err, &reply) {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-t.cfg.Timeout).UnixMilli()
+	var first, rest []Status
+	for i, m := range members {
+		s := Status{User: m}
+		score, err := online[i].Result()
+		switch {
+		case err == nil:
+			s.Online = int64(score) >= cutoff
+		case !errors.Is(err, redis.Nil):
+			return nil, err
+		}
+		score, err = seen[i].Result()
+		switch {
+		case err == nil:
+			s.LastSeen = time.UnixMilli(int64(score))
+		case !errors.Is(err, redis.Nil):
+			return nil, err
+		}
+		if s.Online {
+			first = append(first, s)
+		} else {
+			rest = append(rest, s)
+		}
+	}
+	return append(first, rest...), nil
+}
+
+// Sweep removes users who timed out from room, announcing each leave, and
+// returns them. A heartbeat racing with the sweep keeps the user online.
+func (t *Tracker) Sweep(ctx context.Context, room string) ([]string, error) {
+	cutoff := ms(time.Now().Add(-t.cfg.Timeout))
+	stale, err := t.rdb.ZRangeByScore(ctx, t.roomKey(room, "online"), &redis.ZRangeBy{
+		Min: "-inf", Max: "(" + cutoff,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, user := range stale {
+		ok, err := t.remove(ctx, user, room, cutoff, Timeout)
+		if err != nil {
+			return removed, fmt.Errorf("presence: sweeping %s: %w", room, err)
+		}
+		if ok {
+			removed = append(removed, user)
+		}
+	}
+	return removed, nil
+}
+
+// SweepAll sweeps every room anyone joined, forgetting rooms left empty
+// and last-seen times older than the retention, and returns the number of
+// users removed from rooms
+func (t *Tracker) SweepAll(ctx context.Context) (int, error) {
+	if t.cfg.LastSeenRetention > 0 {
+		cutoff := time.Now().Add(-t.cfg.LastSeenRetention).UnixMilli()
+		if err := t.rdb.ZRemRangeByScore(ctx, t.lastSeenKey(), "-inf", "("+strconv.FormatInt(cutoff, 10)).Err(); err != nil {
+			return 0, fmt.Errorf("presence: trimming last seen: %w", err)
+		}
+	}
+	rooms, err := t.rdb.SMembers(ctx, t.roomsKey()).Result()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, room := range rooms {
+		removed, err := t.Sweep(ctx, room)
+		total += len(removed)
+		if err != nil {
+			return total, err
+		}
+		if n, err := t.rdb.ZCard(ctx, t.roomKey(room, "online")).Result(); err == nil && n == 0 {
+			t.rdb.SRem(ctx, t.roomsKey(), room)
+		}
+	}
+	return total, nil
+}
+
+// Run sweeps all rooms every interval until ctx is done. One instance is
+// enough; running several only duplicates work, not leave events.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := t.SweepAll(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
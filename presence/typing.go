@@ -0,0 +1,70 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// typingScript marks a user typing until ARGV[3] and announces it unless
+// they already were, so clients can call it on every keystroke. Lapsed
+// entries are trimmed, and the set goes away with the last typist.
+var typingScript = redis.NewScript(`
+local prev = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+if not prev or tonumber(prev) < tonumber(ARGV[2]) then
+	redis.call('PUBLISH', ARGV[5], ARGV[6])
+	return 1
+end
+return 0
+`)
+
+// stopTypingScript clears a user's indicator, announcing it if still active
+var stopTypingScript = redis.NewScript(`
+local prev = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+if prev and tonumber(prev) >= tonumber(ARGV[2]) then
+	redis.call('PUBLISH', ARGV[3], ARGV[4])
+	return 1
+end
+return 0
+`)
+
+// Typing shows user as typing in room for the typing TTL, and reports
+// whether that started an indicator rather than refreshing one
+func (t *Tracker) Typing(ctx context.Context, user, room string) (bool, error) {
+	now := time.Now()
+	event, err := newEvent(Typing, room, user, now)
+	if err != nil {
+		return false, err
+	}
+	keys := []string{t.roomKey(room, "typing")}
+	args := []interface{}{user, ms(now), ms(now.Add(t.cfg.TypingTTL)), t.cfg.TypingTTL.Milliseconds(), t.Channel(room), event}
+	started, err := typingScript.Run(ctx, t.rdb, keys, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("presence: typing in %s: %w", room, err)
+	}
+	return started == 1, nil
+}
+
+// StopTyping clears user's typing indicator in room, e.g. once they send
+func (t *Tracker) StopTyping(ctx context.Context, user, room string) error {
+	now := time.Now()
+	event, err := newEvent(StoppedTyping, room, user, now)
+	if err != nil {
+		return err
+	}
+	keys := []string{t.roomKey(room, "typing")}
+	return stopTypingScript.Run(ctx, t.rdb, keys, user, ms(now), t.Channel(room), event).Err()
+}
+
+// Typers returns the users typing in room
+func (t *Tracker) Typers(ctx context.Context, room string) ([]string, error) {
+	return t.rdb.ZRangeByScore(ctx, t.roomKey(room, "typing"), &redis.ZRangeBy{
+		Min: ms(time.Now()), Max: "+inf",
+	}).Result()
+}
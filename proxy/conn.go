@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// call is one command on its way through the proxy. The reader queues calls
+// in the order clients send them and the writer answers them in that order.
+type call struct {
+	name     string
+	args     []string // As sent to Redis
+	start    time.Time
+	local    []byte // Reply of the proxy when the command does not reach Redis
+	refused  bool
+	override []byte        // Sent in place of the reply of Redis
+	prefix   string        // Removed from the keys in the reply
+	raw      bool          // Replies are copied as they arrive from this call on
+	update   func(*Client) // Applied once Redis accepts the command
+}
+
+// conn relays one client over its own connection to Redis
+type conn struct {
+	p        *Proxy
+	client   net.Conn
+	upstream net.Conn
+	calls    chan *call
+	done     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+
+	mu   sync.Mutex
+	info Client
+
+	wmu sync.Mutex // Serializes writes to client once replies are copied as they arrive
+}
+
+func newConn(p *Proxy, client, upstream net.Conn) *conn {
+	return &conn{
+		p:        p,
+		client:   client,
+		upstream: upstream,
+		calls:    make(chan *call, 1024),
+		done:     make(chan struct{}),
+		info:     Client{ID: p.ids.Add(1), Addr: client.RemoteAddr().String(), User: "default"},
+	}
+}
+
+// run relays commands until either side disconnects
+func (c *conn) run() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.readLoop()
+	}()
+	c.writeLoop()
+	c.close()
+	c.wg.Wait()
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.client.Close()
+		c.upstream.Close()
+	})
+}
+
+func (c *conn) snapshot() Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info
+}
+
+// readLoop reads commands from the client, decides what to do with each and
+// forwards the ones that go to Redis
+func (c *conn) readLoop() {
+	defer c.close()
+	defer close(c.calls)
+	br := bufio.NewReader(c.client)
+	bw := bufio.NewWriter(c.upstream)
+	var multi, dirty bool
+	for {
+		args, err := readCommand(br)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		ca := c.prepare(args)
+		if ca.local != nil {
+			// Like Redis, a transaction with a refused command fails at EXEC
+			dirty = dirty || multi
+		} else {
+			switch ca.name {
+			case "multi":
+				multi, dirty = true, false
+			case "exec":
+				if dirty {
+					ca.args = []string{"DISCARD"}
+					ca.override = errorReply("EXECABORT Transaction discarded because of previous errors.")
+				}
+				multi, dirty = false, false
+			case "discard", "reset":
+				multi, dirty = false, false
+			case "subscribe", "psubscribe", "ssubscribe", "monitor":
+				ca.raw = true
+			}
+		}
+
+		select {
+		case c.calls <- ca:
+		case <-c.done:
+			return
+		}
+		if ca.local == nil {
+			bw.Write(appendCommand(nil, ca.args))
+		}
+		// Pipelined commands go out together
+		if br.Buffered() == 0 && bw.Buffered() > 0 {
+			if bw.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// prepare checks args against the allow-list and prefixes their keys
+func (c *conn) prepare(args []string) *call {
+	ca := &call{name: strings.ToLower(args[0]), args: args, start: time.Now()}
+	if msg := c.p.refused(args); msg != "" {
+		ca.local, ca.refused = errorReply(msg), true
+		return ca
+	}
+	if c.p.cfg.Prefix != nil {
+		if ca.prefix = c.p.cfg.Prefix(c.snapshot()); ca.prefix != "" {
+			prefixed, err := c.p.cfg.Keys.Prefix(args, ca.prefix)
+			if err != nil {
+				ca.local, ca.refused = errorReply("ERR "+strings.TrimPrefix(err.Error(), "keyspec: ")), true
+				return ca
+			}
+			ca.args = prefixed
+		}
+	}
+	ca.update = stateUpdate(args)
+	return ca
+}
+
+// stateUpdate returns how a successful args changes the client's state
+func stateUpdate(args []string) func(*Client) {
+	switch strings.ToLower(args[0]) {
+	case "auth":
+		user := "default"
+		if len(args) > 2 {
+			user = args[1]
+		}
+		return func(cl *Client) { cl.User = user }
+	case "hello":
+		var user, name string
+		for i := 2; i < len(args); i++ {
+			switch {
+			case strings.EqualFold(args[i], "auth") && i+2 < len(args):
+				user = args[i+1]
+				i += 2
+			case strings.EqualFold(args[i], "setname") && i+1 < len(args):
+				name = args[i+1]
+				i++
+			}
+		}
+		return func(cl *Client) {
+			if user != "" {
+				cl.User = user
+			}
+			if name != "" {
+				cl.Name = name
+			}
+		}
+	case "client":
+		if len(args) > 2 && strings.EqualFold(args[1], "setname") {
+			name := args[2]
+			return func(cl *Client) { cl.Name = name }
+		}
+	case "select":
+		if db, err := strconv.Atoi(args[len(args)-1]); err == nil && len(args) == 2 {
+			return func(cl *Client) { cl.DB = db }
+		}
+	case "reset":
+		return func(cl *Client) { cl.User, cl.Name, cl.DB = "default", "", 0 }
+	}
+	return nil
+}
+
+// writeLoop answers the queued calls in order, reading one reply from Redis
+// for each call that went there
+func (c *conn) writeLoop() {
+	br := bufio.NewReader(c.upstream)
+	bw := bufio.NewWriter(c.client)
+	defer bw.Flush()
+	raw := false
+	for ca := range c.calls {
+		e := Entry{Time: ca.start, Args: redact(ca.args), Refused: ca.refused}
+		switch {
+		case raw:
+			// Redis replies in its own order now; only log the commands
+			if ca.local != nil {
+				c.writeRaw(ca.local)
+			}
+		case ca.local != nil:
+			bw.Write(ca.local)
+		case ca.raw:
+			// Subscribed and monitoring clients get messages without sending
+			// commands, so from here on replies are copied as they arrive
+			if bw.Flush() != nil {
+				return
+			}
+			raw = true
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				io.Copy(writerFunc(c.writeRaw), br)
+				c.close()
+			}()
+		default:
+			v, err := c.readReply(br, bw)
+			if err != nil {
+				return
+			}
+			e.Duration = time.Since(ca.start)
+			if r := v.reply(); r.isError() {
+				e.Err = r.str
+			} else {
+				if ca.update != nil {
+					c.mu.Lock()
+					ca.update(&c.info)
+					c.mu.Unlock()
+				}
+				if ca.prefix != "" {
+					stripReply(ca.name, r, ca.prefix)
+				}
+			}
+			if ca.override != nil {
+				bw.Write(ca.override)
+			} else {
+				bw.Write(appendValue(nil, v))
+			}
+		}
+		if ca.local != nil {
+			e.Err = string(ca.local[1 : len(ca.local)-2])
+		} else if ca.override != nil {
+			e.Err = string(ca.override[1 : len(ca.override)-2])
+		}
+		e.Client = c.snapshot()
+		c.p.record(ca.name, e)
+
+		if len(c.calls) == 0 && bw.Buffered() > 0 {
+			if bw.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// readReply reads the reply to a command, passing on the RESP3 push
+// messages, such as client-side caching invalidations, that come before it
+func (c *conn) readReply(br *bufio.Reader, bw *bufio.Writer) (value, error) {
+	for {
+		v, err := readValue(br)
+		if err != nil || v.kind != '>' {
+			return v, err
+		}
+		bw.Write(appendValue(nil, v))
+	}
+}
+
+func (c *conn) writeRaw(b []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.client.Write(b)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) { return f(b) }
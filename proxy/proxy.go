@@ -0,0 +1,242 @@
+// Package proxy is a TCP proxy that speaks RESP2 and RESP3 between clients
+// and Redis. It logs every command, refuses commands outside an allow-list,
+// prefixes the keys of each client for multi-tenant isolation and measures
+// the latency of every command.
+//
+// Each client gets its own connection to Redis, so transactions, blocking
+// commands, Pub/Sub and HELLO 3 behave as with a direct connection, and
+// existing clients work unchanged when pointed at the proxy.
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Redis/keyspec"
+	"Redis/loadgen"
+)
+
+// Client describes a client connection, as far as the proxy has seen
+type Client struct {
+	ID   int64
+	Addr string
+	User string // Set by a successful AUTH or HELLO AUTH; "default" until then
+	Name string // Set by CLIENT SETNAME or HELLO SETNAME
+	DB   int
+}
+
+// Entry is one command handled by the proxy
+type Entry struct {
+	Time     time.Time
+	Client   Client
+	Args     []string      // As sent to Redis, prefixed, with passwords redacted
+	Duration time.Duration // Until the reply arrived; 0 for refused commands, and once a client subscribed or ran MONITOR
+	Err      string        // Error reply
+	Refused  bool          // Answered by the proxy without reaching Redis
+}
+
+// Config controls a Proxy
+type Config struct {
+	Upstream  string      // Redis address
+	TLSConfig *tls.Config // Connect to Redis over TLS when set
+
+	// Allow lists the commands clients may run, by name or as
+	// "container|subcommand" such as "config|get"; empty allows all. Deny
+	// refuses commands even when allowed. AUTH, HELLO, PING, QUIT, RESET and
+	// the CLIENT subcommands clients send on connect are always allowed.
+	Allow []string
+	Deny  []string
+
+	// Prefix returns the key prefix of a client's commands, e.g. by user or
+	// client name; nil or "" leaves keys alone. Keys are located with Keys.
+	Prefix func(Client) string
+	Keys   *keyspec.Table
+
+	Log func(Entry) // Called for every command, from the client's goroutine
+}
+
+// alwaysAllowed are needed by clients to connect
+var alwaysAllowed = map[string]bool{
+	"auth": true, "hello": true, "ping": true, "quit": true, "reset": true,
+	"client|setinfo": true, "client|setname": true, "client|getname": true, "client|id": true,
+}
+
+// Proxy accepts clients and relays their commands to Redis
+type Proxy struct {
+	cfg   Config
+	allow map[string]bool
+	deny  map[string]bool
+
+	ids   atomic.Int64
+	mu    sync.Mutex
+	ls    []net.Listener
+	conns map[*conn]struct{}
+	stats map[string]*commandStats
+	wg    sync.WaitGroup
+}
+
+// New creates a proxy
+func New(cfg Config) (*Proxy, error) {
+	if cfg.Upstream == "" {
+		return nil, errors.New("proxy: no upstream address")
+	}
+	if cfg.Prefix != nil && cfg.Keys == nil {
+		return nil, errors.New("proxy: key prefixes need the command table in Config.Keys")
+	}
+	p := &Proxy{cfg: cfg, conns: map[*conn]struct{}{}, stats: map[string]*commandStats{}}
+	p.allow = lowerSet(cfg.Allow)
+	p.deny = lowerSet(cfg.Deny)
+	return p, nil
+}
+
+func lowerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[strings.ToLower(strings.TrimSpace(n))] = true
+	}
+	return set
+}
+
+// refused returns why args may not run, or "" when they may
+func (p *Proxy) refused(args []string) string {
+	name := strings.ToLower(args[0])
+	full := name
+	if len(args) > 1 {
+		full = name + "|" + strings.ToLower(args[1])
+	}
+	if alwaysAllowed[name] || alwaysAllowed[full] {
+		return ""
+	}
+	if p.deny[name] || p.deny[full] || (len(p.allow) > 0 && !p.allow[name] && !p.allow[full]) {
+		return fmt.Sprintf("NOPERM this proxy does not allow the '%s' command", name)
+	}
+	return ""
+}
+
+// Serve accepts clients on l until Close is called
+func (p *Proxy) Serve(l net.Listener) error {
+	p.mu.Lock()
+	p.ls = append(p.ls, l)
+	p.mu.Unlock()
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(nc)
+		}()
+	}
+}
+
+func (p *Proxy) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 5 * time.Minute}
+	if p.cfg.TLSConfig != nil {
+		return (&tls.Dialer{NetDialer: d, Config: p.cfg.TLSConfig}).DialContext(context.Background(), "tcp", p.cfg.Upstream)
+	}
+	return d.Dial("tcp", p.cfg.Upstream)
+}
+
+func (p *Proxy) handle(client net.Conn) {
+	upstream, err := p.dial()
+	if err != nil {
+		client.Write(errorReply("ERR proxy cannot reach Redis: " + err.Error()))
+		client.Close()
+		return
+	}
+	c := newConn(p, client, upstream)
+	p.mu.Lock()
+	p.conns[c] = struct{}{}
+	p.mu.Unlock()
+
+	c.run()
+
+	p.mu.Lock()
+	delete(p.conns, c)
+	p.mu.Unlock()
+}
+
+// Close stops accepting clients, disconnects the connected ones and waits
+// for their goroutines to finish
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	for _, l := range p.ls {
+		l.Close()
+	}
+	for c := range p.conns {
+		c.close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return nil
+}
+
+// record updates the statistics and logs e
+func (p *Proxy) record(name string, e Entry) {
+	p.mu.Lock()
+	s, ok := p.stats[name]
+	if !ok {
+		s = &commandStats{latency: loadgen.NewHistogram()}
+		p.stats[name] = s
+	}
+	p.mu.Unlock()
+
+	s.calls.Add(1)
+	switch {
+	case e.Refused:
+		s.refused.Add(1)
+	case e.Err != "":
+		s.errors.Add(1)
+	}
+	if e.Duration > 0 {
+		s.latency.Record(e.Duration)
+	}
+	if p.cfg.Log != nil {
+		p.cfg.Log(e)
+	}
+}
+
+type commandStats struct {
+	calls, errors, refused atomic.Int64
+	latency                *loadgen.Histogram
+}
+
+// CommandStats summarizes the calls of one command
+type CommandStats struct {
+	Name                   string
+	Calls, Errors, Refused int64
+	Mean, P50, P99, Max    time.Duration
+}
+
+// Stats returns per-command statistics since the proxy started, busiest first
+func (p *Proxy) Stats() []CommandStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]CommandStats, 0, len(p.stats))
+	for name, s := range p.stats {
+		list = append(list, CommandStats{
+			Name: name, Calls: s.calls.Load(), Errors: s.errors.Load(), Refused: s.refused.Load(),
+			Mean: s.latency.Mean(), P50: s.latency.Percentile(50), P99: s.latency.Percentile(99), Max: s.latency.Max(),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Calls != list[j].Calls {
+			return list[i].Calls > list[j].Calls
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits on what a client may send, as in Redis
+const (
+	maxArgs = 1024 * 1024
+	maxBulk = 512 * 1024 * 1024
+)
+
+// readLine reads a CRLF-terminated line without the terminator
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readCommand reads a command as an array of bulk strings, or inline as
+// typed into telnet
+func readCommand(br *bufio.Reader) ([]string, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArgs {
+		return nil, fmt.Errorf("invalid multibulk length %q", line)
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(br)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulk {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// appendCommand encodes args as an array of bulk strings
+func appendCommand(b []byte, args []string) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, a := range args {
+		b = appendBulk(b, a)
+	}
+	return b
+}
+
+func appendBulk(b []byte, s string) []byte {
+	b = append(b, '$')
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, '\r', '\n')
+	b = append(b, s...)
+	return append(b, '\r', '\n')
+}
+
+func errorReply(msg string) []byte {
+	return []byte("-" + msg + "\r\n")
+}
+
+// value is a decoded RESP2 or RESP3 reply
+type value struct {
+	kind  byte   // The type byte: + - : $ * _ , # ! = ( % ~ > |
+	str   string // Simple and bulk strings, numbers, errors
+	null  bool   // $-1 or *-1
+	elems []value
+}
+
+// isError reports whether v is a simple or bulk error
+func (v value) isError() bool { return v.kind == '-' || v.kind == '!' }
+
+// readValue reads one reply. An attribute (|) is kept as the first element
+// of a value that wraps it and the reply it annotates.
+func readValue(br *bufio.Reader) (value, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return value{}, err
+	}
+	if line == "" {
+		return value{}, errors.New("empty reply line")
+	}
+	v := value{kind: line[0], str: line[1:]}
+	switch v.kind {
+	case '+', '-', ':', '_', ',', '#', '(':
+		return v, nil
+	case '$', '!', '=':
+		size, err := strconv.Atoi(v.str)
+		if err != nil {
+			return v, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if size < 0 {
+			v.null = true
+			return v, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return v, err
+		}
+		v.str = string(buf[:size])
+		return v, nil
+	case '*', '~', '>', '%', '|':
+		n, err := strconv.Atoi(v.str)
+		if err != nil {
+			return v, fmt.Errorf("invalid aggregate length %q", line)
+		}
+		if n < 0 {
+			v.null = true
+			return v, nil
+		}
+		if v.kind == '%' || v.kind == '|' {
+			n *= 2
+		}
+		v.elems = make([]value, n)
+		for i := range v.elems {
+			if v.elems[i], err = readValue(br); err != nil {
+				return v, err
+			}
+		}
+		if v.kind == '|' {
+			next, err := readValue(br)
+			if err != nil {
+				return v, err
+			}
+			return value{kind: 'a', elems: []value{v, next}}, nil
+		}
+		return v, nil
+	}
+	return v, fmt.Errorf("unknown reply type %q", line)
+}
+
+// reply returns the annotated reply of an attribute wrapper, else v
+func (v *value) reply() *value {
+	if v.kind == 'a' {
+		return &v.elems[1]
+	}
+	return v
+}
+
+// appendValue encodes v as it was read
+func appendValue(b []byte, v value) []byte {
+	if v.kind == 'a' {
+		return appendValue(appendValue(b, v.elems[0]), v.elems[1])
+	}
+	b = append(b, v.kind)
+	switch v.kind {
+	case '$', '!', '=':
+		if v.null {
+			return append(b, "-1\r\n"...)
+		}
+		b = strconv.AppendInt(b, int64(len(v.str)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, v.str...)
+	case '*', '~', '>', '%', '|':
+		if v.null {
+			return append(b, "-1\r\n"...)
+		}
+		n := len(v.elems)
+		if v.kind == '%' || v.kind == '|' {
+			n /= 2
+		}
+		b = strconv.AppendInt(b, int64(n), 10)
+		b = append(b, '\r', '\n')
+		for _, e := range v.elems {
+			b = appendValue(b, e)
+		}
+		return b
+	default:
+		b = append(b, v.str...)
+	}
+	return append(b, '\r', '\n')
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"slices"
+	"strings"
+)
+
+// stripReply removes prefix from the key names in the reply to a command
+func stripReply(name string, v *value, prefix string) {
+	if v.null {
+		return
+	}
+	trim := func(e *value) { e.str = strings.TrimPrefix(e.str, prefix) }
+	switch name {
+	case "keys":
+		for i := range v.elems {
+			trim(&v.elems[i])
+		}
+	case "scan":
+		if len(v.elems) == 2 {
+			for i := range v.elems[1].elems {
+				trim(&v.elems[1].elems[i])
+			}
+		}
+	case "blpop", "brpop", "bzpopmin", "bzpopmax", "lmpop", "blmpop", "zmpop", "bzmpop":
+		// The key comes first: [key, value...]
+		if len(v.elems) > 0 {
+			trim(&v.elems[0])
+		}
+	case "xread", "xreadgroup":
+		// A map of key to entries in RESP3, else [[key, entries]...]
+		if v.kind == '%' {
+			for i := 0; i < len(v.elems); i += 2 {
+				trim(&v.elems[i])
+			}
+			return
+		}
+		for i := range v.elems {
+			if len(v.elems[i].elems) > 0 {
+				trim(&v.elems[i].elems[0])
+			}
+		}
+	}
+}
+
+// redact hides the passwords of AUTH and HELLO ... AUTH in logged commands
+func redact(args []string) []string {
+	switch strings.ToLower(args[0]) {
+	case "auth":
+		if len(args) > 1 {
+			args = slices.Clone(args)
+			args[len(args)-1] = "(redacted)"
+		}
+	case "hello":
+		for i := 2; i < len(args)-2; i++ {
+			if strings.EqualFold(args[i], "auth") {
+				args = slices.Clone(args)
+				args[i+2] = "(redacted)"
+				break
+			}
+		}
+	}
+	return args
+}
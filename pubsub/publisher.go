@@ -0,0 +1,156 @@
+// Package pubsub adds delivery guarantees on top of Redis Pub/Sub.
+//
+// A Publisher numbers the messages of each channel and appends them to a
+// capped stream before publishing. A Subscriber waits for Redis to confirm
+// its subscriptions, reconnects when the connection drops, and backfills
+// from the stream the messages it missed in between, so handlers see every
+// message once and in order while it is still in the stream.
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPrefix is where sequence counters and message logs are kept
+const DefaultPrefix = "pubsub:"
+
+// Message is a numbered message on a channel
+type Message struct {
+	Channel string
+	Seq     int64 // Position on the channel; 0 for messages sent with a plain PUBLISH
+	Payload string
+
+	Backfilled bool  // Read from the stream rather than received live
+	Missed     int64 // Messages before this one that were trimmed from the stream and are lost
+}
+
+// keys returns the sequence counter and log stream of a channel. Their hash
+// tag is the part of the channel name that decides its slot, so on a
+// cluster the publish script and a sharded channel live on the same node.
+func keys(prefix, channel string) (seq, log string) {
+	base := prefix + "{" + channel + "}"
+	if tag := hashTag(channel); tag != channel {
+		base = prefix + "{" + tag + "}:" + channel
+	}
+	return base + ":seq", base + ":log"
+}
+
+// hashTag returns what Redis hashes to find the slot of name: the text
+// between the first { and the following }, when not empty, else all of it
+func hashTag(name string) string {
+	if start := strings.IndexByte(name, '{'); start >= 0 {
+		if end := strings.IndexByte(name[start+1:], '}'); end > 0 {
+			return name[start+1 : start+1+end]
+		}
+	}
+	return name
+}
+
+// decode parses the payload the publish script sends: "<seq>:<payload>"
+func decode(channel, data string) Message {
+	s, payload, ok := strings.Cut(data, ":")
+	if seq, err := strconv.ParseInt(s, 10, 64); ok && err == nil && seq > 0 {
+		return Message{Channel: channel, Seq: seq, Payload: payload}
+	}
+	return Message{Channel: channel, Payload: data}
+}
+
+// publishScript numbers a message, logs it under stream ID 0-<seq> and
+// publishes it with PUBLISH or SPUBLISH, atomically so the log and live
+// messages never disagree
+var publishScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('XADD', KEYS[2], 'MAXLEN', ARGV[3], '0-' .. seq, 'payload', ARGV[2])
+redis.call(ARGV[4], ARGV[1], seq .. ':' .. ARGV[2])
+return seq
+`)
+
+// PublisherConfig controls a Publisher
+type PublisherConfig struct {
+	Prefix string // Key prefix; default DefaultPrefix
+	MaxLen int64  // Messages kept per channel for backfill; default 10000
+
+	// Sharded publishes with SPUBLISH (Redis 7), which a cluster only sends
+	// to the shard owning the channel's slot instead of every node. Only
+	// sharded subscribers (Config.Sharded) receive these messages.
+	Sharded bool
+}
+
+// Publisher publishes numbered messages
+type Publisher struct {
+	rdb redis.UniversalClient
+	cfg PublisherConfig
+}
+
+// NewPublisher creates a publisher
+func NewPublisher(rdb redis.UniversalClient, cfg PublisherConfig) *Publisher {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.MaxLen <= 0 {
+		cfg.MaxLen = 10000
+	}
+	return &Publisher{rdb: rdb, cfg: cfg}
+}
+
+// Publish logs and publishes payload on channel and returns its sequence number
+func (p *Publisher) Publish(ctx context.Context, channel, payload string) (int64, error) {
+	seqKey, logKey := keys(p.cfg.Prefix, channel)
+	command := "PUBLISH"
+	if p.cfg.Sharded {
+		command = "SPUBLISH"
+	}
+	seq, err := publishScript.Run(ctx, p.rdb, []string{seqKey, logKey}, channel, payload, p.cfg.MaxLen, command).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("pubsub: publishing to %s: %w", channel, err)
+	}
+	return seq, nil
+}
+
+// LastSeq returns the sequence number of the latest message on channel,
+// 0 if nothing was published
+func LastSeq(ctx context.Context, rdb redis.UniversalClient, prefix, channel string) (int64, error) {
+	seqKey, _ := keys(prefix, channel)
+	seq, err := rdb.Get(ctx, seqKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return seq, err
+}
+
+// History returns the logged messages of channel with sequence numbers
+// after seq, at most count of them (0 for all). Messages already trimmed
+// from the log are reported in the Missed field of the first one returned.
+func History(ctx context.Context, rdb redis.UniversalClient, prefix, channel string, after, count int64) ([]Message, error) {
+	_, logKey := keys(prefix, channel)
+	var entries []redis.XMessage
+	var err error
+	start := "0-" + strconv.FormatInt(after+1, 10)
+	if count > 0 {
+		entries, err = rdb.XRangeN(ctx, logKey, start, "+", count).Result()
+	} else {
+		entries, err = rdb.XRange(ctx, logKey, start, "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(entries))
+	next := after + 1
+	for _, e := range entries {
+		_, s, _ := strings.Cut(e.ID, "-")
+		seq, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("pubsub: unexpected log entry %s in %s", e.ID, logKey)
+		}
+		payload, _ := e.Values["payload"].(string)
+		msgs = append(msgs, Message{Channel: channel, Seq: seq, Payload: payload, Backfilled: true, Missed: seq - next})
+		next = seq + 1
+	}
+	return msgs, nil
+}
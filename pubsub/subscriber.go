@@ -0,0 +1,421 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler processes a message. Handlers run one at a time on the
+// subscriber's goroutine, in sequence order per channel.
+type Handler func(Message)
+
+// Config controls a Subscriber
+type Config struct {
+	Channels []string
+	Prefix   string // Key prefix the publishers use; default DefaultPrefix
+
+	// Sharded subscribes with SSUBSCRIBE, which only receives messages sent
+	// with SPUBLISH (PublisherConfig.Sharded). On a cluster the channels are
+	// grouped by the master serving their slot, one connection per shard.
+	Sharded bool
+
+	// After resumes channels after these sequence numbers, e.g. positions
+	// saved from Position by a previous run. Other channels start with the
+	// next message published.
+	After map[string]int64
+
+	PingInterval time.Duration // Idle time before a connection is checked with PING; default 5s
+	MaxBackoff   time.Duration // Longest wait between reconnection attempts; default 5s
+
+	// OnError is called with the error that dropped the subscription, and
+	// with every failed reconnection attempt
+	OnError func(error)
+}
+
+// Subscriber receives numbered messages and backfills gaps from the log
+type Subscriber struct {
+	rdb redis.UniversalClient
+	cfg Config
+	h   Handler
+
+	mu       sync.Mutex
+	position map[string]int64
+
+	reconnects atomic.Int64
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewSubscriber creates a subscriber that hands messages to h; call Start
+// to begin receiving
+func NewSubscriber(rdb redis.UniversalClient, cfg Config, h Handler) *Subscriber {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 5 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Second
+	}
+	return &Subscriber{rdb: rdb, cfg: cfg, h: h, position: make(map[string]int64)}
+}
+
+// Start subscribes, waits for Redis to confirm every subscription and
+// backfills from the positions in After, then receives in the background
+// until Close. Messages published after Start returns are not lost.
+func (s *Subscriber) Start(ctx context.Context) error {
+	if len(s.cfg.Channels) == 0 {
+		return errors.New("pubsub: no channels configured")
+	}
+	// Read positions before subscribing: anything published in between is
+	// in the log and gets backfilled
+	for _, ch := range s.cfg.Channels {
+		seq, ok := s.cfg.After[ch]
+		if !ok {
+			var err error
+			if seq, err = LastSeq(ctx, s.rdb, s.cfg.Prefix, ch); err != nil {
+				return fmt.Errorf("pubsub: reading position of %s: %w", ch, err)
+			}
+		}
+		s.position[ch] = seq
+	}
+
+	sess, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(runCtx, sess)
+	return nil
+}
+
+// Close stops receiving and waits for the running handler to return
+func (s *Subscriber) Close() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Position returns the sequence number of the last message handled on channel
+func (s *Subscriber) Position(channel string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.position[channel]
+}
+
+// Reconnects returns how many times the subscription was re-established
+func (s *Subscriber) Reconnects() int64 {
+	return s.reconnects.Load()
+}
+
+// event is a reply or error read from one of the session's connections
+type event struct {
+	msg interface{}
+	err error
+}
+
+// session is one set of subscription connections, each with a reader
+// goroutine feeding events
+type session struct {
+	conns  []*redis.PubSub
+	events chan event
+	stop   chan struct{}
+}
+
+func (sess *session) close() {
+	close(sess.stop)
+	for _, ps := range sess.conns {
+		ps.Close()
+	}
+}
+
+// connect subscribes every channel group, then handles what was logged
+// since each channel's position. Messages published during the backfill
+// arrive live as well and are dropped as duplicates.
+func (s *Subscriber) connect(ctx context.Context) (*session, error) {
+	groups, err := s.groups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: locating shards: %w", err)
+	}
+	sess := &session{events: make(chan event), stop: make(chan struct{})}
+	for _, channels := range groups {
+		ps, err := s.subscribe(ctx, channels)
+		if err != nil {
+			sess.close()
+			return nil, err
+		}
+		sess.conns = append(sess.conns, ps)
+		go s.read(ps, sess)
+	}
+	for _, ch := range s.cfg.Channels {
+		if err := s.backfill(ctx, ch); err != nil {
+			sess.close()
+			return nil, err
+		}
+	}
+	return sess, nil
+}
+
+// groups splits the channels by connection: all on one, unless sharded on a
+// cluster, where each group holds the channels of one master
+func (s *Subscriber) groups(ctx context.Context) ([][]string, error) {
+	cluster, ok := s.rdb.(*redis.ClusterClient)
+	if !s.cfg.Sharded || !ok {
+		return [][]string{s.cfg.Channels}, nil
+	}
+	var groups [][]string
+	index := make(map[string]int)
+	for _, ch := range s.cfg.Channels {
+		master, err := cluster.MasterForKey(ctx, ch)
+		if err != nil {
+			return nil, err
+		}
+		addr := master.Options().Addr
+		i, ok := index[addr]
+		if !ok {
+			i = len(groups)
+			index[addr] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], ch)
+	}
+	return groups, nil
+}
+
+// subscribe opens a connection and waits for one confirmation per channel.
+// A cluster client routes the connection by the slot of the first channel.
+// Shard channels are subscribed one slot at a time on that connection, as
+// a cluster refuses an SSUBSCRIBE whose channels span several slots.
+func (s *Subscriber) subscribe(ctx context.Context, channels []string) (*redis.PubSub, error) {
+	batches := [][]string{channels}
+	if s.cfg.Sharded {
+		batches = bySlot(channels)
+	}
+	var ps *redis.PubSub
+	for i, batch := range batches {
+		var err error
+		switch {
+		case !s.cfg.Sharded:
+			ps = s.rdb.Subscribe(ctx, batch...)
+		case i == 0:
+			ps = s.rdb.SSubscribe(ctx, batch...)
+		default:
+			err = ps.SSubscribe(ctx, batch...)
+		}
+		if err == nil {
+			err = confirm(ctx, ps, len(batch))
+		}
+		if err != nil {
+			ps.Close()
+			return nil, fmt.Errorf("pubsub: subscribing to %v: %w", batch, err)
+		}
+	}
+	return ps, nil
+}
+
+// confirm waits for n subscription confirmations on ps
+func confirm(ctx context.Context, ps *redis.PubSub, n int) error {
+	for range n {
+		msg, err := ps.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*redis.Subscription); !ok {
+			return fmt.Errorf("unexpected %T before subscription confirmation", msg)
+		}
+	}
+	return nil
+}
+
+// bySlot groups channels by cluster hash slot, in order of first appearance
+func bySlot(channels []string) [][]string {
+	var batches [][]string
+	index := make(map[int]int)
+	for _, ch := range channels {
+		i, ok := index[slot(ch)]
+		if !ok {
+			i = len(batches)
+			index[slot(ch)] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], ch)
+	}
+	return batches
+}
+
+// slot returns the cluster hash slot of key: the CRC16 (XMODEM) of its
+// {hash tag}, or of the whole key without one, modulo 16384
+func slot(key string) int {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+// read forwards everything ps receives until it fails or the session
+// stops. A PING unanswered for a whole interval counts as failed.
+func (s *Subscriber) read(ps *redis.PubSub, sess *session) {
+	ctx := context.Background()
+	pinged := false
+	for {
+		msg, err := ps.ReceiveTimeout(ctx, s.cfg.PingInterval)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			if pinged {
+				err = errors.New("pubsub: no reply to PING")
+			} else if err = ps.Ping(ctx); err == nil {
+				pinged = true
+				continue
+			}
+		} else if err == nil {
+			pinged = false
+		}
+
+		select {
+		case sess.events <- event{msg: msg, err: err}:
+		case <-sess.stop:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Subscriber) run(ctx context.Context, sess *session) {
+	defer close(s.done)
+	for {
+		err := s.receive(ctx, sess)
+		sess.close()
+		if ctx.Err() != nil {
+			return
+		}
+		s.report(err)
+		if cluster, ok := s.rdb.(*redis.ClusterClient); ok {
+			cluster.ReloadState(ctx)
+		}
+
+		backoff := 50 * time.Millisecond
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if sess, err = s.connect(ctx); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.report(err)
+			backoff = min(backoff*2, s.cfg.MaxBackoff)
+		}
+		s.reconnects.Add(1)
+	}
+}
+
+func (s *Subscriber) report(err error) {
+	if s.cfg.OnError != nil {
+		s.cfg.OnError(err)
+	}
+}
+
+// receive handles live messages until a connection fails or the server
+// drops a subscription, which it does to shard channels when their slot
+// migrates to another node. Either way the whole session is rebuilt.
+func (s *Subscriber) receive(ctx context.Context, sess *session) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e := <-sess.events:
+			if e.err != nil {
+				return e.err
+			}
+			switch m := e.msg.(type) {
+			case *redis.Message:
+				if err := s.deliver(ctx, decode(m.Channel, m.Payload)); err != nil {
+					return err
+				}
+			case *redis.Subscription:
+				if m.Kind == "sunsubscribe" || m.Kind == "unsubscribe" {
+					return fmt.Errorf("pubsub: server ended the subscription to %s", m.Channel)
+				}
+			}
+		}
+	}
+}
+
+// deliver hands a live message to the handler, dropping duplicates of
+// backfilled ones and filling any gap before it from the log
+func (s *Subscriber) deliver(ctx context.Context, m Message) error {
+	if m.Seq == 0 {
+		s.h(m)
+		return nil
+	}
+	last := s.Position(m.Channel)
+	if m.Seq > last+1 {
+		if err := s.backfill(ctx, m.Channel); err != nil {
+			return err
+		}
+		last = s.Position(m.Channel)
+	}
+	if m.Seq <= last {
+		return nil
+	}
+	m.Missed = m.Seq - last - 1
+	s.handle(m)
+	return nil
+}
+
+// backfill handles the logged messages after the channel's position
+func (s *Subscriber) backfill(ctx context.Context, channel string) error {
+	const page = 1000
+	for {
+		msgs, err := History(ctx, s.rdb, s.cfg.Prefix, channel, s.Position(channel), page)
+		if err != nil {
+			return fmt.Errorf("pubsub: backfilling %s: %w", channel, err)
+		}
+		for _, m := range msgs {
+			s.handle(m)
+		}
+		if len(msgs) < page {
+			return nil
+		}
+	}
+}
+
+func (s *Subscriber) handle(m Message) {
+	s.mu.Lock()
+	s.position[m.Channel] = m.Seq
+	s.mu.Unlock()
+	s.h(m)
+}
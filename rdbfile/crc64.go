@@ -0,0 +1,30 @@
+package rdbfile
+
+// Redis checksums RDB files with CRC-64/Jones: reflected input and output,
+// zero initial value and no final XOR. hash/crc64 inverts the register
+// before and after, so it can't be used directly.
+const jonesPoly = 0x95ac9329ac4bc9b5 // 0xad93d23594c935a9 reflected
+
+var crcTable = func() [256]uint64 {
+	var t [256]uint64
+	for i := range t {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ jonesPoly
+			} else {
+				crc >>= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+// crc64 updates crc with p
+func crc64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crcTable[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
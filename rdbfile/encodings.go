@@ -0,0 +1,308 @@
+package rdbfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// blob walks a serialized compact encoding with bounds checking
+type blob struct {
+	name string
+	b    []byte
+	pos  int
+}
+
+func (z *blob) need(n int) error {
+	if n < 0 || z.pos+n > len(z.b) {
+		return fmt.Errorf("%s: truncated at byte %d", z.name, z.pos)
+	}
+	return nil
+}
+
+func (z *blob) take(n int) ([]byte, error) {
+	if err := z.need(n); err != nil {
+		return nil, err
+	}
+	p := z.b[z.pos : z.pos+n]
+	z.pos += n
+	return p, nil
+}
+
+// signed sign-extends the low bits of a little-endian integer
+func signed(p []byte) int64 {
+	var v uint64
+	for i := len(p) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(p[i])
+	}
+	shift := 64 - 8*uint(len(p))
+	return int64(v<<shift) >> shift
+}
+
+// parseZiplist decodes a ziplist (Redis < 7) into its elements
+func parseZiplist(b []byte) ([]string, error) {
+	z := &blob{name: "ziplist", b: b, pos: 10}
+	if err := z.need(1); err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint16(b[8:10]))
+	out := make([]string, 0, count)
+
+	for {
+		if err := z.need(1); err != nil {
+			return nil, err
+		}
+		if z.b[z.pos] == 0xff {
+			return out, nil
+		}
+
+		// Skip the previous entry length
+		prev := 1
+		if z.b[z.pos] == 0xfe {
+			prev = 5
+		}
+		if _, err := z.take(prev); err != nil {
+			return nil, err
+		}
+
+		head, err := z.take(1)
+		if err != nil {
+			return nil, err
+		}
+		enc := head[0]
+
+		var strLen int
+		switch {
+		case enc>>6 == 0:
+			strLen = int(enc & 0x3f)
+		case enc>>6 == 1:
+			p, err := z.take(1)
+			if err != nil {
+				return nil, err
+			}
+			strLen = int(enc&0x3f)<<8 | int(p[0])
+		case enc>>6 == 2:
+			p, err := z.take(4)
+			if err != nil {
+				return nil, err
+			}
+			strLen = int(binary.BigEndian.Uint32(p))
+		default:
+			v, err := z.ziplistInt(enc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, strconv.FormatInt(v, 10))
+			continue
+		}
+
+		p, err := z.take(strLen)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, string(p))
+	}
+}
+
+func (z *blob) ziplistInt(enc byte) (int64, error) {
+	size := 0
+	switch enc {
+	case 0xc0:
+		size = 2
+	case 0xd0:
+		size = 4
+	case 0xe0:
+		size = 8
+	case 0xf0:
+		size = 3
+	case 0xfe:
+		size = 1
+	default:
+		if enc >= 0xf1 && enc <= 0xfd {
+			return int64(enc&0x0f) - 1, nil
+		}
+		return 0, fmt.Errorf("ziplist: unknown encoding 0x%02x", enc)
+	}
+	p, err := z.take(size)
+	if err != nil {
+		return 0, err
+	}
+	return signed(p), nil
+}
+
+// parseListpack decodes a listpack (Redis 7 compact encoding) into its elements
+func parseListpack(b []byte) ([]string, error) {
+	z := &blob{name: "listpack", b: b, pos: 6}
+	if err := z.need(1); err != nil {
+		return nil, err
+	}
+	count := int(binary.LittleEndian.Uint16(b[4:6]))
+	out := make([]string, 0, count)
+
+	for {
+		head, err := z.take(1)
+		if err != nil {
+			return nil, err
+		}
+		enc := head[0]
+		if enc == 0xff {
+			return out, nil
+		}
+
+		var (
+			value   string
+			encSize int // encoding header plus data, used to size the back-length
+		)
+		switch {
+		case enc&0x80 == 0: // 7-bit unsigned int
+			value = strconv.Itoa(int(enc & 0x7f))
+			encSize = 1
+		case enc&0xc0 == 0x80: // 6-bit length string
+			n := int(enc & 0x3f)
+			p, err := z.take(n)
+			if err != nil {
+				return nil, err
+			}
+			value = string(p)
+			encSize = 1 + n
+		case enc&0xe0 == 0xc0: // 13-bit signed int
+			p, err := z.take(1)
+			if err != nil {
+				return nil, err
+			}
+			v := int(enc&0x1f)<<8 | int(p[0])
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+			value = strconv.Itoa(v)
+			encSize = 2
+		case enc&0xf0 == 0xe0: // 12-bit length string
+			p, err := z.take(1)
+			if err != nil {
+				return nil, err
+			}
+			n := int(enc&0x0f)<<8 | int(p[0])
+			if p, err = z.take(n); err != nil {
+				return nil, err
+			}
+			value = string(p)
+			encSize = 2 + n
+		case enc == 0xf0: // 32-bit length string
+			p, err := z.take(4)
+			if err != nil {
+				return nil, err
+			}
+			n := int(binary.LittleEndian.Uint32(p))
+			if p, err = z.take(n); err != nil {
+				return nil, err
+			}
+			value = string(p)
+			encSize = 5 + n
+		case enc >= 0xf1 && enc <= 0xf4: // 16, 24, 32 and 64-bit ints
+			size := map[byte]int{0xf1: 2, 0xf2: 3, 0xf3: 4, 0xf4: 8}[enc]
+			p, err := z.take(size)
+			if err != nil {
+				return nil, err
+			}
+			value = strconv.FormatInt(signed(p), 10)
+			encSize = 1 + size
+		default:
+			return nil, fmt.Errorf("listpack: unknown encoding 0x%02x", enc)
+		}
+
+		if _, err := z.take(backlenSize(encSize)); err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+	}
+}
+
+// backlenSize returns how many bytes the reverse-traversal length of an entry takes
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	}
+	return 5
+}
+
+// parseIntset decodes an intset into decimal strings
+func parseIntset(b []byte) ([]string, error) {
+	z := &blob{name: "intset", b: b}
+	hdr, err := z.take(8)
+	if err != nil {
+		return nil, err
+	}
+	width := int(binary.LittleEndian.Uint32(hdr[0:4]))
+	count := int(binary.LittleEndian.Uint32(hdr[4:8]))
+	if width != 2 && width != 4 && width != 8 {
+		return nil, fmt.Errorf("intset: invalid encoding %d", width)
+	}
+
+	out := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		p, err := z.take(width)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, strconv.FormatInt(signed(p), 10))
+	}
+	return out, nil
+}
+
+// parseZipmap decodes a zipmap (hashes written by Redis < 2.6) into field/value pairs
+func parseZipmap(b []byte) ([]string, error) {
+	z := &blob{name: "zipmap", b: b, pos: 1}
+	var out []string
+
+	readLen := func() (int, bool, error) {
+		p, err := z.take(1)
+		if err != nil {
+			return 0, false, err
+		}
+		switch {
+		case p[0] == 0xff:
+			return 0, true, nil
+		case p[0] < 254:
+			return int(p[0]), false, nil
+		}
+		p, err = z.take(4)
+		if err != nil {
+			return 0, false, err
+		}
+		return int(binary.LittleEndian.Uint32(p)), false, nil
+	}
+
+	for {
+		klen, end, err := readLen()
+		if err != nil || end {
+			return out, err
+		}
+		key, err := z.take(klen)
+		if err != nil {
+			return nil, err
+		}
+		vlen, _, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		free, err := z.take(1)
+		if err != nil {
+			return nil, err
+		}
+		val, err := z.take(vlen)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := z.take(int(free[0])); err != nil {
+			return nil, err
+		}
+		out = append(out, string(key), string(val))
+	}
+}
@@ -0,0 +1,570 @@
+// Package rdbfile parses Redis RDB snapshot files without a running server.
+//
+// It understands RDB versions 1 through 12 (Redis 7.4), including the compact
+// ziplist, listpack, intset, zipmap and quicklist encodings and stream records.
+package rdbfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"Redis/dataset"
+)
+
+// MaxVersion is the newest RDB format version the parser accepts
+const MaxVersion = 12
+
+// Opcodes that precede keys or carry file metadata
+const (
+	opSlotInfo      = 0xf4
+	opFunction2     = 0xf5
+	opFunctionPreGA = 0xf6
+	opModuleAux     = 0xf7
+	opIdle          = 0xf8
+	opFreq          = 0xf9
+	opAux           = 0xfa
+	opResizeDB      = 0xfb
+	opExpireTimeMS  = 0xfc
+	opExpireTime    = 0xfd
+	opSelectDB      = 0xfe
+	opEOF           = 0xff
+)
+
+// Value types as stored in the file
+const (
+	typeString              = 0
+	typeList                = 1
+	typeSet                 = 2
+	typeZSet                = 3
+	typeHash                = 4
+	typeZSet2               = 5
+	typeModule              = 6
+	typeModule2             = 7
+	typeHashZipmap          = 9
+	typeListZiplist         = 10
+	typeSetIntset           = 11
+	typeZSetZiplist         = 12
+	typeHashZiplist         = 13
+	typeListQuicklist       = 14
+	typeStreamListpacks     = 15
+	typeHashListpack        = 16
+	typeZSetListpack        = 17
+	typeListQuicklist2      = 18
+	typeStreamListpacks2    = 19
+	typeSetListpack         = 20
+	typeStreamListpacks3    = 21
+	typeHashMetadataPreGA   = 22
+	typeHashListpackExPreGA = 23
+	typeHashMetadata        = 24
+	typeHashListpackEx      = 25
+)
+
+// TypeModule is the Entry type of values owned by Redis modules
+const TypeModule = "module"
+
+// ErrChecksum is returned when the trailing CRC64 does not match the content
+var ErrChecksum = errors.New("rdb checksum mismatch")
+
+// Entry is one key read from the snapshot
+type Entry struct {
+	DB       int
+	Key      string
+	Type     string // One of the dataset.Type* constants or TypeModule
+	Encoding string // On-disk encoding, e.g. "listpack", "quicklist", "intset"
+	ExpireAt int64  // Unix milliseconds, 0 when the key does not expire
+	Size     int64  // Bytes the key and value occupy in the file
+	Module   string // Module type name for TypeModule values
+
+	// Value uses the same representation as dataset.Record; nil for modules
+	Value interface{}
+
+	// FieldExpires holds per-field expiries (unix ms) of Redis 7.4 hashes
+	FieldExpires map[string]int64
+}
+
+// Record converts the entry to the JSON Lines export format
+func (e *Entry) Record() *dataset.Record {
+	return &dataset.Record{Key: e.Key, Type: e.Type, ExpireAt: e.ExpireAt, Value: e.Value}
+}
+
+// Info describes the file as a whole
+type Info struct {
+	Version  int
+	Aux      map[string]string // Metadata such as redis-ver, ctime and used-mem
+	Keys     int
+	Checksum uint64 // Stored CRC64; 0 when the server had rdbchecksum disabled
+}
+
+// Parse reads an RDB file and calls fn for every key in order.
+//
+// When r is a *bufio.Reader of at least BufferSize bytes it is used directly,
+// so nothing past the end of the snapshot is consumed. This lets callers read
+// data that follows an embedded snapshot, such as an AOF with an RDB preamble.
+func Parse(r io.Reader, fn func(*Entry) error) (*Info, error) {
+	rd := newReader(r)
+
+	header := make([]byte, 9)
+	if err := rd.read(header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:5]) != "REDIS" {
+		return nil, fmt.Errorf("not an RDB file: bad magic %q", header[:5])
+	}
+	version, err := strconv.Atoi(string(header[5:]))
+	if err != nil || version < 1 || version > MaxVersion {
+		return nil, fmt.Errorf("unsupported RDB version %q", header[5:])
+	}
+
+	info := &Info{Version: version, Aux: make(map[string]string)}
+	db := 0
+	var expireAt int64
+
+	for {
+		op, err := rd.readByte()
+		if err != nil {
+			return info, err
+		}
+
+		switch op {
+		case opEOF:
+			if version < 5 {
+				return info, nil
+			}
+			computed := rd.crc
+			stored, err := rd.readUint64LE()
+			if err != nil {
+				return info, fmt.Errorf("reading checksum: %w", err)
+			}
+			info.Checksum = stored
+			if stored != 0 && stored != computed {
+				return info, fmt.Errorf("%w: stored %016x, computed %016x", ErrChecksum, stored, computed)
+			}
+			return info, nil
+
+		case opSelectDB:
+			n, err := rd.readLength()
+			if err != nil {
+				return info, err
+			}
+			db = int(n)
+
+		case opResizeDB:
+			if err := rd.skipLengths(2); err != nil {
+				return info, err
+			}
+
+		case opSlotInfo:
+			if err := rd.skipLengths(3); err != nil {
+				return info, err
+			}
+
+		case opAux:
+			key, err := rd.readString()
+			if err != nil {
+				return info, err
+			}
+			val, err := rd.readString()
+			if err != nil {
+				return info, err
+			}
+			info.Aux[key] = val
+
+		case opExpireTime:
+			sec, err := rd.readUint32LE()
+			if err != nil {
+				return info, err
+			}
+			expireAt = int64(sec) * 1000
+
+		case opExpireTimeMS:
+			ms, err := rd.readUint64LE()
+			if err != nil {
+				return info, err
+			}
+			expireAt = int64(ms)
+
+		case opFreq:
+			if _, err := rd.readByte(); err != nil {
+				return info, err
+			}
+
+		case opIdle:
+			if err := rd.skipLengths(1); err != nil {
+				return info, err
+			}
+
+		case opModuleAux:
+			// module id, when-opcode and when, then a self-describing payload
+			if err := rd.skipLengths(3); err != nil {
+				return info, err
+			}
+			if err := rd.skipModuleValue(); err != nil {
+				return info, err
+			}
+
+		case opFunction2:
+			if _, err := rd.readRaw(); err != nil {
+				return info, err
+			}
+
+		case opFunctionPreGA:
+			return info, fmt.Errorf("offset %d: pre-release function records are not supported", rd.n)
+
+		default:
+			start := rd.n - 1
+			key, err := rd.readString()
+			if err != nil {
+				return info, err
+			}
+			e := &Entry{DB: db, Key: key, ExpireAt: expireAt}
+			expireAt = 0
+
+			if err := rd.readValue(op, e); err != nil {
+				return info, fmt.Errorf("key %q: %w", key, err)
+			}
+			e.Size = rd.n - start
+			info.Keys++
+
+			if err := fn(e); err != nil {
+				return info, err
+			}
+		}
+	}
+}
+
+func (r *reader) skipLengths(n int) error {
+	for i := 0; i < n; i++ {
+		if _, _, err := r.readLen(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Module value opcodes (RDB_MODULE_OPCODE_*)
+const (
+	moduleOpEOF    = 0
+	moduleOpSInt   = 1
+	moduleOpUInt   = 2
+	moduleOpFloat  = 3
+	moduleOpDouble = 4
+	moduleOpString = 5
+)
+
+// skipModuleValue skips a self-describing module payload up to its EOF opcode
+func (r *reader) skipModuleValue() error {
+	for {
+		op, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case moduleOpEOF:
+			return nil
+		case moduleOpSInt, moduleOpUInt:
+			err = r.skipLengths(1)
+		case moduleOpFloat:
+			_, err = r.readUint32LE()
+		case moduleOpDouble:
+			_, err = r.readUint64LE()
+		case moduleOpString:
+			_, err = r.readRaw()
+		default:
+			err = fmt.Errorf("offset %d: unknown module opcode %d", r.n, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// moduleName decodes the 9-character type name packed into a module id
+func moduleName(id uint64) string {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	name := make([]byte, 9)
+	id >>= 10
+	for i := 8; i >= 0; i-- {
+		name[i] = charset[id&63]
+		id >>= 6
+	}
+	return string(name)
+}
+
+// readValue decodes the value of a key of the given on-disk type
+func (r *reader) readValue(t byte, e *Entry) error {
+	switch t {
+	case typeString:
+		s, err := r.readString()
+		e.Type, e.Encoding, e.Value = dataset.TypeString, "string", s
+		return err
+
+	case typeList, typeSet:
+		items, err := r.readStrings(1)
+		if t == typeList {
+			e.Type, e.Encoding = dataset.TypeList, "linkedlist"
+		} else {
+			e.Type, e.Encoding = dataset.TypeSet, "hashtable"
+		}
+		e.Value = items
+		return err
+
+	case typeHash:
+		pairs, err := r.readStrings(2)
+		e.Type, e.Encoding, e.Value = dataset.TypeHash, "hashtable", toHash(pairs)
+		return err
+
+	case typeZSet, typeZSet2:
+		n, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		members := make([]dataset.ZMember, 0, min(n, 1<<16))
+		for i := uint64(0); i < n; i++ {
+			m, err := r.readString()
+			if err != nil {
+				return err
+			}
+			var score float64
+			if t == typeZSet {
+				score, err = r.readDouble()
+			} else {
+				score, err = r.readBinaryDouble()
+			}
+			if err != nil {
+				return err
+			}
+			members = append(members, dataset.ZMember{Member: m, Score: score})
+		}
+		e.Type, e.Encoding, e.Value = dataset.TypeZSet, "skiplist", members
+		return nil
+
+	case typeHashZipmap, typeHashZiplist, typeHashListpack:
+		items, enc, err := r.readCompact(t)
+		if err != nil {
+			return err
+		}
+		e.Type, e.Encoding, e.Value = dataset.TypeHash, enc, toHash(items)
+		return nil
+
+	case typeListZiplist:
+		items, enc, err := r.readCompact(t)
+		e.Type, e.Encoding, e.Value = dataset.TypeList, enc, items
+		return err
+
+	case typeSetIntset, typeSetListpack:
+		items, enc, err := r.readCompact(t)
+		e.Type, e.Encoding, e.Value = dataset.TypeSet, enc, items
+		return err
+
+	case typeZSetZiplist, typeZSetListpack:
+		items, enc, err := r.readCompact(t)
+		if err != nil {
+			return err
+		}
+		members, err := toZSet(items)
+		e.Type, e.Encoding, e.Value = dataset.TypeZSet, enc, members
+		return err
+
+	case typeListQuicklist, typeListQuicklist2:
+		items, err := r.readQuicklist(t)
+		e.Type, e.Encoding, e.Value = dataset.TypeList, "quicklist", items
+		return err
+
+	case typeHashMetadata, typeHashMetadataPreGA:
+		return r.readHashMetadata(t, e)
+
+	case typeHashListpackEx, typeHashListpackExPreGA:
+		return r.readHashListpackEx(t, e)
+
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		stream, err := r.readStream(t)
+		e.Type, e.Encoding, e.Value = dataset.TypeStream, "stream", stream
+		return err
+
+	case typeModule2:
+		id, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		e.Type, e.Encoding, e.Module = TypeModule, "module", moduleName(id)
+		return r.skipModuleValue()
+
+	case typeModule:
+		return errors.New("module values in the pre-release format cannot be skipped")
+	}
+	return fmt.Errorf("offset %d: unknown value type %d", r.n, t)
+}
+
+// readStrings reads a length followed by length*per strings
+func (r *reader) readStrings(per uint64) ([]string, error) {
+	n, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, min(n*per, 1<<16))
+	for i := uint64(0); i < n*per; i++ {
+		s, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// readCompact reads a value serialized as a single compact blob
+func (r *reader) readCompact(t byte) ([]string, string, error) {
+	b, err := r.readRaw()
+	if err != nil {
+		return nil, "", err
+	}
+	switch t {
+	case typeHashZipmap:
+		items, err := parseZipmap(b)
+		return items, "zipmap", err
+	case typeSetIntset:
+		items, err := parseIntset(b)
+		return items, "intset", err
+	case typeListZiplist, typeZSetZiplist, typeHashZiplist:
+		items, err := parseZiplist(b)
+		return items, "ziplist", err
+	}
+	items, err := parseListpack(b)
+	return items, "listpack", err
+}
+
+// readQuicklist reads a list of ziplist (v1) or listpack/plain (v2) nodes
+func (r *reader) readQuicklist(t byte) ([]string, error) {
+	const (
+		containerPlain  = 1
+		containerPacked = 2
+	)
+
+	nodes, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for i := uint64(0); i < nodes; i++ {
+		container := uint64(containerPacked)
+		if t == typeListQuicklist2 {
+			if container, err = r.readLength(); err != nil {
+				return nil, err
+			}
+		}
+		b, err := r.readRaw()
+		if err != nil {
+			return nil, err
+		}
+
+		var node []string
+		switch {
+		case container == containerPlain:
+			node = []string{string(b)}
+		case t == typeListQuicklist:
+			node, err = parseZiplist(b)
+		default:
+			node, err = parseListpack(b)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, node...)
+	}
+	return items, nil
+}
+
+// readHashMetadata reads a Redis 7.4 hash with field expiries in hashtable form
+func (r *reader) readHashMetadata(t byte, e *Entry) error {
+	var minExpire uint64
+	var err error
+	if t == typeHashMetadata {
+		if minExpire, err = r.readUint64LE(); err != nil {
+			return err
+		}
+	}
+	n, err := r.readLength()
+	if err != nil {
+		return err
+	}
+
+	hash := make(map[string]string, min(n, 1<<16))
+	expires := make(map[string]int64)
+	for i := uint64(0); i < n; i++ {
+		ttl, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		field, err := r.readString()
+		if err != nil {
+			return err
+		}
+		val, err := r.readString()
+		if err != nil {
+			return err
+		}
+		hash[field] = val
+		if ttl != 0 {
+			// GA files store expiries relative to the minimum, offset by one
+			if t == typeHashMetadata {
+				ttl += minExpire - 1
+			}
+			expires[field] = int64(ttl)
+		}
+	}
+	e.Type, e.Encoding, e.Value, e.FieldExpires = dataset.TypeHash, "hashtable", hash, expires
+	return nil
+}
+
+// readHashListpackEx reads a Redis 7.4 listpack hash of field, value, expiry triplets
+func (r *reader) readHashListpackEx(t byte, e *Entry) error {
+	if t == typeHashListpackEx {
+		if _, err := r.readUint64LE(); err != nil {
+			return err
+		}
+	}
+	b, err := r.readRaw()
+	if err != nil {
+		return err
+	}
+	items, err := parseListpack(b)
+	if err != nil {
+		return err
+	}
+	if len(items)%3 != 0 {
+		return fmt.Errorf("listpack hash has %d elements, expected triplets", len(items))
+	}
+
+	hash := make(map[string]string, len(items)/3)
+	expires := make(map[string]int64)
+	for i := 0; i < len(items); i += 3 {
+		hash[items[i]] = items[i+1]
+		if ttl, _ := strconv.ParseInt(items[i+2], 10, 64); ttl > 0 {
+			expires[items[i]] = ttl
+		}
+	}
+	e.Type, e.Encoding, e.Value, e.FieldExpires = dataset.TypeHash, "listpack", hash, expires
+	return nil
+}
+
+func toHash(pairs []string) map[string]string {
+	hash := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		hash[pairs[i]] = pairs[i+1]
+	}
+	return hash
+}
+
+func toZSet(pairs []string) ([]dataset.ZMember, error) {
+	members := make([]dataset.ZMember, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		score, err := strconv.ParseFloat(pairs[i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score %q: %w", pairs[i+1], err)
+		}
+		members = append(members, dataset.ZMember{Member: pairs[i], Score: score})
+	}
+	return members, nil
+}
@@ -0,0 +1,245 @@
+package rdbfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Length encoding types (the top two bits of the first byte)
+const (
+	len6Bit   = 0
+	len14Bit  = 1
+	len32Or64 = 2
+	lenEncVal = 3
+
+	len32Bit = 0x80
+	len64Bit = 0x81
+)
+
+// Special string encodings used when lenEncVal is set
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// reader consumes the RDB byte stream, tracking the offset and running checksum
+type reader struct {
+	br  *bufio.Reader
+	crc uint64
+	n   int64
+	buf [8]byte
+}
+
+// BufferSize is the read buffer size Parse uses
+const BufferSize = 64 * 1024
+
+func newReader(r io.Reader) *reader {
+	return &reader{br: bufio.NewReaderSize(r, BufferSize)}
+}
+
+func (r *reader) read(p []byte) error {
+	if _, err := io.ReadFull(r.br, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("offset %d: %w", r.n, err)
+	}
+	r.crc = crc64(r.crc, p)
+	r.n += int64(len(p))
+	return nil
+}
+
+func (r *reader) readByte() (byte, error) {
+	if err := r.read(r.buf[:1]); err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
+
+func (r *reader) readBytes(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, fmt.Errorf("offset %d: length %d too large", r.n, n)
+	}
+	p := make([]byte, n)
+	return p, r.read(p)
+}
+
+func (r *reader) readUint32LE() (uint32, error) {
+	if err := r.read(r.buf[:4]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(r.buf[:4]), nil
+}
+
+func (r *reader) readUint64LE() (uint64, error) {
+	if err := r.read(r.buf[:8]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(r.buf[:8]), nil
+}
+
+// readLen reads a length. When encoded is true the value is one of the
+// special string encodings rather than a length.
+func (r *reader) readLen() (length uint64, encoded bool, err error) {
+	b, err := r.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case len6Bit:
+		return uint64(b & 0x3f), false, nil
+	case len14Bit:
+		next, err := r.readByte()
+		return uint64(b&0x3f)<<8 | uint64(next), false, err
+	case lenEncVal:
+		return uint64(b & 0x3f), true, nil
+	}
+	switch b {
+	case len32Bit:
+		if err := r.read(r.buf[:4]); err != nil {
+			return 0, false, err
+		}
+		return uint64(binary.BigEndian.Uint32(r.buf[:4])), false, nil
+	case len64Bit:
+		if err := r.read(r.buf[:8]); err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(r.buf[:8]), false, nil
+	}
+	return 0, false, fmt.Errorf("offset %d: unknown length encoding 0x%02x", r.n, b)
+}
+
+// readLength reads a plain length, rejecting special encodings
+func (r *reader) readLength() (uint64, error) {
+	n, encoded, err := r.readLen()
+	if err == nil && encoded {
+		err = fmt.Errorf("offset %d: unexpected encoded value where a length was expected", r.n)
+	}
+	return n, err
+}
+
+// readString reads a length-prefixed, integer-encoded or LZF-compressed string
+func (r *reader) readString() (string, error) {
+	b, err := r.readRaw()
+	return string(b), err
+}
+
+func (r *reader) readRaw() ([]byte, error) {
+	n, encoded, err := r.readLen()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return r.readBytes(n)
+	}
+
+	switch n {
+	case encInt8:
+		b, err := r.readByte()
+		return []byte(strconv.Itoa(int(int8(b)))), err
+	case encInt16:
+		if err := r.read(r.buf[:2]); err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(int(int16(binary.LittleEndian.Uint16(r.buf[:2]))))), nil
+	case encInt32:
+		v, err := r.readUint32LE()
+		return []byte(strconv.Itoa(int(int32(v)))), err
+	case encLZF:
+		clen, err := r.readLength()
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := r.readLength()
+		if err != nil {
+			return nil, err
+		}
+		compressed, err := r.readBytes(clen)
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(compressed, int(ulen))
+	}
+	return nil, fmt.Errorf("offset %d: unknown string encoding %d", r.n, n)
+}
+
+// readDouble reads the textual double used by RDB_TYPE_ZSET
+func (r *reader) readDouble() (float64, error) {
+	n, err := r.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	p, err := r.readBytes(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(p), 64)
+}
+
+// readBinaryDouble reads a little-endian IEEE 754 double
+func (r *reader) readBinaryDouble() (float64, error) {
+	v, err := r.readUint64LE()
+	return math.Float64frombits(v), err
+}
+
+var errLZF = errors.New("corrupt LZF data")
+
+// lzfDecompress expands LZF-compressed data to exactly outLen bytes
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < 1<<5 {
+			// Literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, errLZF
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// Back reference
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, errLZF
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errLZF
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, errLZF
+		}
+		for j := 0; j < length+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != outLen {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", errLZF, outLen, len(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,218 @@
+package rdbfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"Redis/dataset"
+)
+
+// Stream entry flags stored in listpack nodes
+const (
+	streamItemDeleted    = 1
+	streamItemSameFields = 2
+)
+
+// readStream reads the listpack nodes, metadata and consumer groups of a stream
+func (r *reader) readStream(t byte) (*dataset.Stream, error) {
+	nodes, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &dataset.Stream{Entries: []dataset.StreamEntry{}}
+	for i := uint64(0); i < nodes; i++ {
+		master, err := r.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		if len(master) != 16 {
+			return nil, fmt.Errorf("stream node key has %d bytes, expected 16", len(master))
+		}
+		lp, err := r.readRaw()
+		if err != nil {
+			return nil, err
+		}
+		items, err := parseListpack(lp)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := streamNodeEntries(binary.BigEndian.Uint64(master[:8]), binary.BigEndian.Uint64(master[8:]), items)
+		if err != nil {
+			return nil, err
+		}
+		stream.Entries = append(stream.Entries, entries...)
+	}
+
+	// Length, then the last generated ID
+	if err := r.skipLengths(1); err != nil {
+		return nil, err
+	}
+	lastID, err := r.readStreamID()
+	if err != nil {
+		return nil, err
+	}
+	stream.LastID = lastID
+
+	// Version 2+ adds first ID, max deleted ID and entries-added counter
+	if t >= typeStreamListpacks2 {
+		if err := r.skipLengths(5); err != nil {
+			return nil, err
+		}
+	}
+
+	groups, err := r.readLength()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		name, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		lastDelivered, err := r.readStreamID()
+		if err != nil {
+			return nil, err
+		}
+		if t >= typeStreamListpacks2 {
+			if err := r.skipLengths(1); err != nil { // entries read
+				return nil, err
+			}
+		}
+		if err := r.skipConsumerGroupState(t); err != nil {
+			return nil, err
+		}
+		stream.Groups = append(stream.Groups, dataset.StreamGroup{Name: name, LastDelivered: lastDelivered})
+	}
+	return stream, nil
+}
+
+func (r *reader) readStreamID() (string, error) {
+	ms, err := r.readLength()
+	if err != nil {
+		return "", err
+	}
+	seq, err := r.readLength()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", ms, seq), nil
+}
+
+// skipConsumerGroupState skips the pending entries list and consumers of a group
+func (r *reader) skipConsumerGroupState(t byte) error {
+	var rawID [16]byte
+
+	pending, err := r.readLength()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < pending; i++ {
+		if err := r.read(rawID[:]); err != nil {
+			return err
+		}
+		if _, err := r.readUint64LE(); err != nil { // delivery time
+			return err
+		}
+		if err := r.skipLengths(1); err != nil { // delivery count
+			return err
+		}
+	}
+
+	consumers, err := r.readLength()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < consumers; i++ {
+		if _, err := r.readRaw(); err != nil { // name
+			return err
+		}
+		if _, err := r.readUint64LE(); err != nil { // seen time
+			return err
+		}
+		if t >= typeStreamListpacks3 {
+			if _, err := r.readUint64LE(); err != nil { // active time
+				return err
+			}
+		}
+		owned, err := r.readLength()
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < owned; j++ {
+			if err := r.read(rawID[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// streamNodeEntries decodes the entries of one listpack node. The node starts
+// with a master entry (count, deleted, field names, 0) and every following
+// entry stores its ID as a delta from the node's master ID.
+func streamNodeEntries(msBase, seqBase uint64, items []string) ([]dataset.StreamEntry, error) {
+	bad := func(what string) error {
+		return fmt.Errorf("stream node %d-%d: %s", msBase, seqBase, what)
+	}
+	num := func(i int) (int64, error) {
+		if i >= len(items) {
+			return 0, bad("truncated")
+		}
+		return strconv.ParseInt(items[i], 10, 64)
+	}
+
+	masterFields, err := num(2)
+	if err != nil {
+		return nil, bad("invalid master entry")
+	}
+	pos := 3 + int(masterFields) + 1 // skip field names and the master terminator
+	if pos > len(items) {
+		return nil, bad("truncated master entry")
+	}
+	fieldNames := items[3 : 3+masterFields]
+
+	var entries []dataset.StreamEntry
+	for pos < len(items) {
+		flags, err := num(pos)
+		if err != nil {
+			return nil, bad("invalid flags")
+		}
+		msDiff, err := num(pos + 1)
+		if err != nil {
+			return nil, bad("invalid ID")
+		}
+		seqDiff, err := num(pos + 2)
+		if err != nil {
+			return nil, bad("invalid ID")
+		}
+		pos += 3
+
+		entry := dataset.StreamEntry{
+			ID: fmt.Sprintf("%d-%d", msBase+uint64(msDiff), seqBase+uint64(seqDiff)),
+		}
+		if flags&streamItemSameFields != 0 {
+			if pos+len(fieldNames) > len(items) {
+				return nil, bad("truncated entry")
+			}
+			for i, name := range fieldNames {
+				entry.Fields = append(entry.Fields, name, items[pos+i])
+			}
+			pos += len(fieldNames)
+		} else {
+			n, err := num(pos)
+			if err != nil || pos+1+2*int(n) > len(items) {
+				return nil, bad("truncated entry")
+			}
+			entry.Fields = append(entry.Fields, items[pos+1:pos+1+2*int(n)]...)
+			pos += 1 + 2*int(n)
+		}
+		pos++ // lp-count, used for backwards iteration
+
+		if flags&streamItemDeleted == 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
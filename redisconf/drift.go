@@ -0,0 +1,211 @@
+package redisconf
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DriftKind says how a setting differs
+type DriftKind int
+
+const (
+	// Changed means the server runs with a different value, usually after
+	// CONFIG SET without CONFIG REWRITE
+	Changed DriftKind = iota
+	// Unknown means the server does not report the setting: a typo, a
+	// removed option, or one the server was built without
+	Unknown
+)
+
+func (k DriftKind) String() string {
+	if k == Unknown {
+		return "unknown"
+	}
+	return "changed"
+}
+
+// Drift is one setting whose file value does not match the server
+type Drift struct {
+	Name   string
+	Line   int
+	Kind   DriftKind
+	File   string // Normalised file value
+	Server string // Value from CONFIG GET
+}
+
+// notInConfigGet are directives CONFIG GET does not report
+var notInConfigGet = map[string]bool{
+	"include": true, "rename-command": true, "loadmodule": true, "user": true,
+	"replicaof": true, "slaveof": true,
+}
+
+// sensitive settings are compared but never printed
+var sensitive = map[string]bool{
+	"requirepass": true, "masterauth": true, "masteruser": true,
+}
+
+// ServerConfig returns CONFIG GET * from a running server
+func ServerConfig(ctx context.Context, rdb redis.UniversalClient) (map[string]string, error) {
+	return rdb.ConfigGet(ctx, "*").Result()
+}
+
+// Compare reports every setting in f that the server runs with differently.
+// Values are normalised first: memory sizes to bytes, enum case, the order
+// of notify-keyspace-events flags, and the renamed slave/ziplist options.
+// Relative paths such as dir ./ are skipped since the server reports them
+// resolved.
+func Compare(f *File, server map[string]string) []Drift {
+	var out []Drift
+	seen := make(map[string]bool)
+	for i := len(f.Directives) - 1; i >= 0; i-- {
+		d := f.Directives[i]
+		if seen[d.Name] || notInConfigGet[d.Name] {
+			continue
+		}
+		seen[d.Name] = true
+
+		name, have, ok := lookupServer(server, d.Name)
+		if !ok {
+			out = append(out, Drift{Name: d.Name, Line: d.Line, Kind: Unknown, File: display(d.Name, fileValue(f, d))})
+			continue
+		}
+		want := fileValue(f, d)
+		if isRelativePath(name, want) || equalValues(name, want, have) {
+			continue
+		}
+		out = append(out, Drift{Name: d.Name, Line: d.Line, Kind: Changed, File: display(name, want), Server: display(name, have)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Line < out[j].Line })
+	return out
+}
+
+// fileValue returns the setting as CONFIG GET would print it. save and
+// client-output-buffer-limit accumulate across lines.
+func fileValue(f *File, d Directive) string {
+	switch d.Name {
+	case "save":
+		var parts []string
+		for _, s := range f.All("save") {
+			if s.Value() == "" {
+				parts = nil
+				continue
+			}
+			parts = append(parts, s.Args...)
+		}
+		return strings.Join(parts, " ")
+	case "client-output-buffer-limit":
+		var parts []string
+		for _, s := range f.All(d.Name) {
+			parts = append(parts, s.Args...)
+		}
+		return strings.Join(parts, " ")
+	}
+	return d.Value()
+}
+
+// lookupServer finds name in the server's config, trying the current name of
+// renamed options as well
+func lookupServer(server map[string]string, name string) (string, string, bool) {
+	candidates := []string{
+		name,
+		strings.ReplaceAll(name, "slave", "replica"),
+		strings.ReplaceAll(name, "replica", "slave"),
+		strings.ReplaceAll(name, "ziplist", "listpack"),
+		strings.ReplaceAll(name, "listpack", "ziplist"),
+	}
+	for _, c := range candidates {
+		if v, ok := server[c]; ok {
+			return c, v, true
+		}
+	}
+	return name, "", false
+}
+
+func equalValues(name, file, server string) bool {
+	switch name {
+	case "notify-keyspace-events":
+		return keyspaceFlags(file) == keyspaceFlags(server)
+	case "client-output-buffer-limit":
+		return outputBuffersMatch(file, server)
+	}
+	a, b := strings.Fields(file), strings.Fields(server)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalToken(a[i], b[i], sensitive[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalToken(a, b string, exact bool) bool {
+	if a == b || (!exact && strings.EqualFold(a, b)) {
+		return true
+	}
+	x, ok1 := ParseMemory(a)
+	y, ok2 := ParseMemory(b)
+	return ok1 && ok2 && x == y
+}
+
+// keyspaceFlags returns the notify-keyspace-events flags as a sorted set,
+// with A expanded to the classes it stands for
+func keyspaceFlags(s string) string {
+	s = strings.ReplaceAll(s, "A", "g$lshzxetd")
+	set := make(map[rune]bool)
+	for _, r := range s {
+		set[r] = true
+	}
+	flags := make([]string, 0, len(set))
+	for r := range set {
+		flags = append(flags, string(r))
+	}
+	sort.Strings(flags)
+	return strings.Join(flags, "")
+}
+
+// outputBuffersMatch compares the classes set in the file with the server's,
+// which always lists all three
+func outputBuffersMatch(file, server string) bool {
+	limits := func(s string) map[string][]string {
+		m := make(map[string][]string)
+		f := strings.Fields(s)
+		for i := 0; i+3 < len(f); i += 4 {
+			class := strings.ToLower(f[i])
+			if class == "slave" {
+				class = "replica"
+			}
+			m[class] = f[i+1 : i+4]
+		}
+		return m
+	}
+	have := limits(server)
+	for class, want := range limits(file) {
+		got, ok := have[class]
+		if !ok {
+			return false
+		}
+		for i := range want {
+			if !equalToken(want[i], got[i], false) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isRelativePath(name, value string) bool {
+	return (name == "dir" || name == "aclfile") && value != "" && !filepath.IsAbs(value)
+}
+
+func display(name, value string) string {
+	if sensitive[name] && value != "" {
+		return "(hidden)"
+	}
+	return value
+}
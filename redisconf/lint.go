@@ -0,0 +1,259 @@
+package redisconf
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Severity ranks lint findings
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Critical:
+		return "critical"
+	case Warning:
+		return "warning"
+	}
+	return "info"
+}
+
+// ParseSeverity parses "info", "warning" or "critical"
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range []Severity{Info, Warning, Critical} {
+		if strings.EqualFold(s, sev.String()) {
+			return sev, nil
+		}
+	}
+	return Info, fmt.Errorf("unknown severity %q", s)
+}
+
+// Finding is one lint result
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Line     int // Line of the directive at fault; 0 when the problem is a missing directive
+	Message  string
+}
+
+// rule inspects a file and reports what it finds
+type rule func(f *File) []Finding
+
+var rules = []rule{
+	lintAuth,
+	lintEviction,
+	lintAOF,
+	lintPersistence,
+	lintOutputBuffers,
+	lintTimeouts,
+	lintDuplicates,
+	lintIncludes,
+}
+
+// Lint checks a file against every rule and returns the findings, most
+// severe first
+func Lint(f *File) []Finding {
+	var out []Finding
+	for _, r := range rules {
+		out = append(out, r(f)...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Severity != out[j].Severity {
+			return out[i].Severity > out[j].Severity
+		}
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+// hasAuth reports whether clients must authenticate: requirepass, an ACL
+// file, or a default user that is disabled or has a password
+func hasAuth(f *File) bool {
+	if f.Get("requirepass") != "" || f.Get("aclfile") != "" {
+		return true
+	}
+	for _, u := range f.All("user") {
+		if len(u.Args) > 0 && u.Args[0] == "default" {
+			return containsArg(u.Args, "off") || !containsArg(u.Args, "nopass")
+		}
+	}
+	return false
+}
+
+// openBind returns the first address in bind that is reachable from other
+// hosts. A missing bind listens on every interface.
+func openBind(f *File) (string, bool) {
+	d, ok := f.Lookup("bind")
+	if !ok {
+		return "*", true
+	}
+	for _, addr := range d.Args {
+		addr = strings.TrimPrefix(addr, "-")
+		if addr == "*" || addr == "::*" {
+			return addr, true
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil || !ip.IsLoopback() {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+func lintAuth(f *File) []Finding {
+	var out []Finding
+	protected, _ := f.Lookup("protected-mode")
+	addr, open := openBind(f)
+	switch {
+	case hasAuth(f):
+		if pass, ok := f.Lookup("requirepass"); ok && len(pass.Value()) < 16 {
+			out = append(out, Finding{Warning, "weak-password", pass.Line,
+				"requirepass is shorter than 16 characters; the server can check passwords very quickly"})
+		}
+	case open && strings.EqualFold(protected.Value(), "no"):
+		d, _ := f.Lookup("bind")
+		out = append(out, Finding{Critical, "no-auth-open-bind", d.Line,
+			fmt.Sprintf("bind %s with protected-mode no and no requirepass: anyone who can reach the port can read, write and CONFIG SET the server", addr)})
+	case open:
+		d, _ := f.Lookup("bind")
+		out = append(out, Finding{Warning, "no-auth-open-bind", d.Line,
+			fmt.Sprintf("bind %s without requirepass: remote clients are refused only because protected-mode is on", addr)})
+	case strings.EqualFold(protected.Value(), "no"):
+		out = append(out, Finding{Info, "protected-mode-off", protected.Line,
+			"protected-mode no without a password; safe only while bind stays on loopback"})
+	}
+	return out
+}
+
+func lintEviction(f *File) []Finding {
+	var out []Finding
+	maxmem, hasMax := f.Lookup("maxmemory")
+	limit, _ := ParseMemory(maxmem.Value())
+	policy, hasPolicy := f.Lookup("maxmemory-policy")
+	name := strings.ToLower(policy.Value())
+
+	switch {
+	case limit > 0 && (!hasPolicy || name == "noeviction"):
+		out = append(out, Finding{Warning, "maxmemory-noeviction", maxmem.Line,
+			fmt.Sprintf("maxmemory %s with policy noeviction: writes fail with OOM errors once memory is full; set maxmemory-policy if this is a cache", maxmem.Value())})
+	case hasPolicy && name != "noeviction" && (!hasMax || limit == 0):
+		out = append(out, Finding{Info, "policy-without-maxmemory", policy.Line,
+			fmt.Sprintf("maxmemory-policy %s has no effect without maxmemory", policy.Value())})
+	}
+	if limit > 0 && strings.HasPrefix(name, "volatile-") {
+		out = append(out, Finding{Info, "volatile-policy", policy.Line,
+			fmt.Sprintf("%s only evicts keys with a TTL; without enough of them writes still fail with OOM", name)})
+	}
+	return out
+}
+
+func lintAOF(f *File) []Finding {
+	if !strings.EqualFold(f.Get("appendonly"), "yes") {
+		return nil
+	}
+	var out []Finding
+	aof, _ := f.Lookup("appendonly")
+	pct, hasPct := f.Lookup("auto-aof-rewrite-percentage")
+	minSize, hasMin := f.Lookup("auto-aof-rewrite-min-size")
+	switch {
+	case hasPct && pct.Value() == "0":
+		out = append(out, Finding{Warning, "aof-no-rewrite", pct.Line,
+			"auto-aof-rewrite-percentage 0 disables automatic rewrites; the AOF grows until BGREWRITEAOF is run by hand"})
+	case !hasPct || !hasMin:
+		out = append(out, Finding{Info, "aof-rewrite-defaults", aof.Line,
+			"appendonly yes without auto-aof-rewrite-percentage and auto-aof-rewrite-min-size; the defaults (100, 64mb) apply"})
+	}
+	if hasMin {
+		if n, ok := ParseMemory(minSize.Value()); ok && n < 1<<20 {
+			out = append(out, Finding{Warning, "aof-rewrite-min-size", minSize.Line,
+				"auto-aof-rewrite-min-size below 1mb makes small datasets rewrite constantly"})
+		}
+	}
+	if fsync, ok := f.Lookup("appendfsync"); ok && strings.EqualFold(fsync.Value(), "no") {
+		out = append(out, Finding{Warning, "appendfsync-no", fsync.Line,
+			"appendfsync no leaves flushing to the OS; a crash can lose around 30 seconds of writes"})
+	}
+	return out
+}
+
+func lintPersistence(f *File) []Finding {
+	saves := f.All("save")
+	noSnapshots := len(saves) > 0 && saves[len(saves)-1].Value() == ""
+	if noSnapshots && !strings.EqualFold(f.Get("appendonly"), "yes") {
+		return []Finding{{Warning, "no-persistence", saves[len(saves)-1].Line,
+			"save \"\" with appendonly no: every key is lost on restart"}}
+	}
+	return nil
+}
+
+func lintOutputBuffers(f *File) []Finding {
+	var out []Finding
+	for _, d := range f.All("client-output-buffer-limit") {
+		if len(d.Args) != 4 {
+			out = append(out, Finding{Warning, "output-buffer-syntax", d.Line,
+				"client-output-buffer-limit takes a class, hard limit, soft limit and soft seconds"})
+			continue
+		}
+		class := strings.ToLower(d.Args[0])
+		hard, _ := ParseMemory(d.Args[1])
+		soft, _ := ParseMemory(d.Args[2])
+		switch {
+		case (class == "pubsub" || class == "replica" || class == "slave") && hard == 0 && soft == 0:
+			out = append(out, Finding{Warning, "unbounded-output-buffer", d.Line,
+				fmt.Sprintf("no %s output buffer limit: a slow consumer can grow the server's memory without bound", class)})
+		case hard > 0 && soft > hard:
+			out = append(out, Finding{Info, "soft-above-hard", d.Line,
+				fmt.Sprintf("%s soft limit is above the hard limit, so it never applies", class)})
+		}
+	}
+	return out
+}
+
+func lintTimeouts(f *File) []Finding {
+	timeout, _ := f.Lookup("timeout")
+	keepalive, hasKeepalive := f.Lookup("tcp-keepalive")
+	if (timeout.Value() == "" || timeout.Value() == "0") && hasKeepalive && keepalive.Value() == "0" {
+		return []Finding{{Info, "no-dead-peer-detection", keepalive.Line,
+			"timeout 0 and tcp-keepalive 0: connections from crashed clients are never closed"}}
+	}
+	return nil
+}
+
+func lintDuplicates(f *File) []Finding {
+	var out []Finding
+	last := make(map[string]Directive)
+	for _, d := range f.Directives {
+		if prev, ok := last[d.Name]; ok && !multiValued[d.Name] {
+			out = append(out, Finding{Warning, "duplicate", prev.Line,
+				fmt.Sprintf("%s is set again on line %d, which wins", d.Name, d.Line)})
+		}
+		last[d.Name] = d
+	}
+	return out
+}
+
+func lintIncludes(f *File) []Finding {
+	var out []Finding
+	for _, d := range f.All("include") {
+		out = append(out, Finding{Info, "include", d.Line,
+			fmt.Sprintf("include %s is not followed; lint that file separately", d.Value())})
+	}
+	return out
+}
+
+func containsArg(args []string, want string) bool {
+	for _, a := range args {
+		if strings.EqualFold(a, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+// Package redisconf parses redis.conf, lints it for risky combinations of
+// settings and compares it with the configuration of a running server.
+package redisconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Directive is one configuration line
+type Directive struct {
+	Name string // Lowercased
+	Args []string
+	Line int
+}
+
+// Value returns the arguments joined by spaces
+func (d Directive) Value() string {
+	return strings.Join(d.Args, " ")
+}
+
+// File is a parsed redis.conf
+type File struct {
+	Path       string
+	Directives []Directive
+}
+
+// multiValued directives may appear several times, each line adding to the
+// setting rather than replacing it
+var multiValued = map[string]bool{
+	"save": true, "client-output-buffer-limit": true, "rename-command": true,
+	"include": true, "loadmodule": true, "user": true,
+}
+
+// ParseFile reads and parses a redis.conf. Include directives are recorded
+// but not followed.
+func ParseFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	conf, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	conf.Path = path
+	return conf, nil
+}
+
+// Parse parses redis.conf syntax: one directive per line, arguments split
+// on spaces with double-quoted (escapes allowed) and single-quoted strings
+func Parse(r io.Reader) (*File, error) {
+	conf := &File{}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		conf.Directives = append(conf.Directives, Directive{Name: strings.ToLower(args[0]), Args: args[1:], Line: n})
+	}
+	return conf, sc.Err()
+}
+
+// Lookup returns the directive that takes effect for name: the last one, as
+// the server applies lines in order
+func (f *File) Lookup(name string) (Directive, bool) {
+	for i := len(f.Directives) - 1; i >= 0; i-- {
+		if f.Directives[i].Name == name {
+			return f.Directives[i], true
+		}
+	}
+	return Directive{}, false
+}
+
+// Get returns the effective value of name, or "" when it is not set
+func (f *File) Get(name string) string {
+	d, _ := f.Lookup(name)
+	return d.Value()
+}
+
+// All returns every directive named name, in file order
+func (f *File) All(name string) []Directive {
+	var out []Directive
+	for _, d := range f.Directives {
+		if d.Name == name {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// splitArgs splits a line the way the server's sdssplitargs does
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		var sb strings.Builder
+		switch line[i] {
+		case '"':
+			i++
+			for {
+				if i >= len(line) {
+					return nil, fmt.Errorf("unbalanced quotes")
+				}
+				c := line[i]
+				if c == '"' {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(line) {
+					switch e := line[i+1]; e {
+					case 'n':
+						sb.WriteByte('\n')
+					case 'r':
+						sb.WriteByte('\r')
+					case 't':
+						sb.WriteByte('\t')
+					case 'a':
+						sb.WriteByte('\a')
+					case 'b':
+						sb.WriteByte('\b')
+					case 'x':
+						if i+3 < len(line) {
+							if b, err := strconv.ParseUint(line[i+2:i+4], 16, 8); err == nil {
+								sb.WriteByte(byte(b))
+								i += 4
+								continue
+							}
+						}
+						sb.WriteByte(e)
+					default:
+						sb.WriteByte(e)
+					}
+					i += 2
+					continue
+				}
+				sb.WriteByte(c)
+				i++
+			}
+		case '\'':
+			i++
+			for {
+				if i >= len(line) {
+					return nil, fmt.Errorf("unbalanced quotes")
+				}
+				c := line[i]
+				if c == '\'' {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i += 2
+					continue
+				}
+				sb.WriteByte(c)
+				i++
+			}
+		default:
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				sb.WriteByte(line[i])
+				i++
+			}
+			args = append(args, sb.String())
+			continue
+		}
+		// A closing quote must end the argument
+		if i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			return nil, fmt.Errorf("closing quote must be followed by a space")
+		}
+		args = append(args, sb.String())
+	}
+	return args, nil
+}
+
+// ParseMemory converts a size such as 256mb or 1g to bytes using the
+// server's rules: k, m and g are powers of 1000, kb, mb and gb powers of 1024
+func ParseMemory(s string) (int64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+		{"g", 1000 * 1000 * 1000}, {"m", 1000 * 1000}, {"k", 1000}, {"b", 1},
+	}
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mul = strings.TrimSuffix(s, u.suffix), u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * mul, true
+}
@@ -0,0 +1,107 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Dataset names accepted by Config.Datasets
+const (
+	DatasetUsers       = "users"
+	DatasetProducts    = "products"
+	DatasetLeaderboard = "leaderboard"
+	DatasetSessions    = "sessions"
+	DatasetChat        = "chat"
+	DatasetEvents      = "events"
+	DatasetRateLimits  = "ratelimits"
+)
+
+// AllDatasets lists every dataset in the order they are generated
+var AllDatasets = []string{
+	DatasetUsers,
+	DatasetProducts,
+	DatasetLeaderboard,
+	DatasetSessions,
+	DatasetChat,
+	DatasetEvents,
+	DatasetRateLimits,
+}
+
+// Base sizes at scale 1.0; every count is multiplied by Config.Scale
+const (
+	baseUsers        = 10000
+	baseProducts     = 1000
+	basePlayers      = 2000
+	baseSessions     = 2000
+	baseRooms        = 20
+	baseChatMessages = 20000
+	baseEvents       = 50000
+	baseRateLimited  = 1000
+)
+
+// Config controls how much data is generated and how it is written
+type Config struct {
+	Scale       float64   // Multiplier applied to the base dataset sizes
+	Seed        int64     // Equal seeds produce identical datasets
+	Concurrency int       // Number of pipelines in flight at once
+	BatchSize   int       // Commands per pipeline
+	Epoch       time.Time // Reference time for generated timestamps and stream IDs
+	Datasets    []string  // Datasets to generate; empty means AllDatasets
+	Flush       bool      // FLUSHDB before seeding; otherwise only the datasets' own keys are deleted
+}
+
+// DefaultConfig returns the configuration used by scripts/seed_data.go
+func DefaultConfig() Config {
+	return Config{
+		Scale:       1,
+		Seed:        1,
+		Concurrency: 8,
+		BatchSize:   500,
+		Epoch:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Flush:       true,
+	}
+}
+
+// Validate checks the configuration and fills in defaults for zero values
+func (c *Config) Validate() error {
+	if c.Scale <= 0 {
+		return fmt.Errorf("scale must be positive, got %v", c.Scale)
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1
+	}
+	if c.Epoch.IsZero() {
+		c.Epoch = DefaultConfig().Epoch
+	}
+	if len(c.Datasets) == 0 {
+		c.Datasets = AllDatasets
+	}
+	for _, name := range c.Datasets {
+		if !isDataset(name) {
+			return fmt.Errorf("unknown dataset %q", name)
+		}
+	}
+	return nil
+}
+
+// count scales a base size, never returning less than one
+func (c *Config) count(base int) int {
+	n := int(math.Round(float64(base) * c.Scale))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func isDataset(name string) bool {
+	for _, d := range AllDatasets {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	firstNames = []string{"Alice", "Bob", "Charlie", "Diana", "Eve", "Frank", "Grace", "Henry", "Ivy", "Jack", "Karen", "Leo", "Mia", "Noah", "Olivia", "Paul", "Quinn", "Rosa", "Sam", "Tina"}
+	lastNames  = []string{"Johnson", "Smith", "Brown", "Prince", "Wilson", "Miller", "Lee", "Davis", "Garcia", "Martinez", "Taylor", "Anderson", "Thomas", "Moore", "Martin", "Clark"}
+	locations  = []struct {
+		City    string
+		Country string
+		Weight  float64
+	}{
+		{"New York", "USA", 12}, {"San Francisco", "USA", 8}, {"London", "UK", 10}, {"Paris", "France", 7},
+		{"Berlin", "Germany", 6}, {"Tokyo", "Japan", 9}, {"Seoul", "South Korea", 5}, {"Sydney", "Australia", 4},
+		{"Toronto", "Canada", 4}, {"Bangalore", "India", 8}, {"Sao Paulo", "Brazil", 5}, {"Amsterdam", "Netherlands", 3},
+	}
+	roles = []struct {
+		Name   string
+		Salary float64
+		Weight float64
+	}{
+		{"Developer", 70000, 50}, {"Designer", 62000, 15}, {"Manager", 85000, 12}, {"Architect", 95000, 5},
+		{"Analyst", 58000, 10}, {"Support", 45000, 8},
+	}
+	categories = []struct {
+		Name   string
+		Price  float64
+		Weight float64
+	}{
+		{"Electronics", 300, 25}, {"Clothing", 45, 20}, {"Books", 20, 15}, {"Sports", 60, 10},
+		{"Furniture", 180, 8}, {"Appliances", 120, 7}, {"Gadgets", 35, 10}, {"Accessories", 25, 5},
+	}
+	productAdjectives = []string{"Pro", "Lite", "Max", "Mini", "Classic", "Smart", "Ultra", "Eco"}
+	interests         = []string{"programming", "gaming", "music", "sports", "travel", "cooking", "photography", "reading", "movies", "fitness", "art", "finance"}
+	playerClasses     = []string{"Warrior", "Mage", "Rogue", "Paladin"}
+	defaultRooms      = []string{"general", "gaming", "tech", "random"}
+	chatPhrases       = []string{"Hello everyone!", "How is everyone doing?", "Great to be here!", "Looking forward to the discussion", "Anyone up for a game?", "Check out this link", "brb", "That's awesome", "Good morning!", "See you later"}
+	eventTypes        = []string{"page_view", "click", "search", "user_login", "user_logout", "purchase"}
+	eventWeights      = []float64{45, 25, 12, 8, 7, 3}
+	userAgents        = []string{"Mozilla/5.0 (compatible; RedisPractice/1.0)", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0)", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"}
+)
+
+// Zipf exponent used for popularity skew across users, products and rooms
+const popularitySkew = 1.1
+
+// userID formats the key of the n-th user (1-based, like the original seed data)
+func userID(n int) string {
+	return "user:" + strconv.Itoa(n+1)
+}
+
+func (g *Generator) users(r *rand.Rand, w *batchWriter) error {
+	n := g.cfg.count(baseUsers)
+	locationWeights := make([]float64, len(locations))
+	for i, l := range locations {
+		locationWeights[i] = l.Weight
+	}
+	roleWeights := make([]float64, len(roles))
+	for i, role := range roles {
+		roleWeights[i] = role.Weight
+	}
+	interestPopularity := newZipf(r, popularitySkew, len(interests))
+
+	for i := 0; i < n; i++ {
+		first := firstNames[r.IntN(len(firstNames))]
+		last := lastNames[r.IntN(len(lastNames))]
+		loc := locations[weighted(r, locationWeights)]
+		role := roles[weighted(r, roleWeights)]
+		age := int(clamp(math.Round(r.NormFloat64()*8+32), 18, 70))
+		salary := int(math.Round(logNormal(r, role.Salary, 0.25)/100) * 100)
+		joined := g.cfg.Epoch.AddDate(0, 0, -r.IntN(5*365))
+
+		id := userID(i)
+		w.Key()
+		if err := w.Add("HSET", id,
+			"name", first+" "+last,
+			"email", fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			"age", age,
+			"city", loc.City,
+			"country", loc.Country,
+			"role", role.Name,
+			"salary", salary,
+			"join_date", joined.Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+
+		// 2-5 interests, biased towards the popular ones
+		args := []interface{}{"SADD", "user_interests:" + id}
+		for j := r.IntN(4) + 2; j > 0; j-- {
+			args = append(args, interests[interestPopularity.Next()])
+		}
+		w.Key()
+		if err := w.Add(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) products(r *rand.Rand, w *batchWriter) error {
+	n := g.cfg.count(baseProducts)
+	weights := make([]float64, len(categories))
+	for i, c := range categories {
+		weights[i] = c.Weight
+	}
+
+	tags := []interface{}{"SADD", "product_tags"}
+	for _, c := range categories {
+		tags = append(tags, strings.ToLower(c.Name))
+	}
+	w.Key()
+	if err := w.Add(tags...); err != nil {
+		return err
+	}
+
+	// Views follow Zipf's law: the product at rank k gets ~1/k^s of the top views
+	topViews := 100000 * g.cfg.Scale
+	w.Key()
+	for i := 0; i < n; i++ {
+		cat := categories[weighted(r, weights)]
+		id := fmt.Sprintf("product:%d", i+1)
+		price := math.Round(logNormal(r, cat.Price, 0.6)*100)/100 - 0.01
+		rating := clamp(5-math.Abs(r.NormFloat64())*0.6, 1, 5)
+		views := int64(topViews / math.Pow(float64(i+1), popularitySkew))
+
+		w.Key()
+		if err := w.Add("HSET", id,
+			"name", fmt.Sprintf("%s %s %d", cat.Name, productAdjectives[r.IntN(len(productAdjectives))], i+1),
+			"category", cat.Name,
+			"price", strconv.FormatFloat(math.Max(price, 0.99), 'f', 2, 64),
+			"stock", r.IntN(500),
+			"rating", strconv.FormatFloat(rating, 'f', 1, 64),
+			"views", views,
+			"description", fmt.Sprintf("Synthetic %s product", strings.ToLower(cat.Name)),
+		); err != nil {
+			return err
+		}
+		if err := w.Add("ZADD", "product_popularity", views, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) leaderboard(r *rand.Rand, w *batchWriter) error {
+	n := g.cfg.count(basePlayers)
+	w.Key()
+	for i := 1; i <= n; i++ {
+		playerID := fmt.Sprintf("player_%d", i)
+		// Power-law scores: most players are casual, a few are very strong
+		score := math.Round(clamp(pareto(r, 100, 1.5), 0, 1e7))
+
+		if err := w.Add("ZADD", "game_leaderboard", score, playerID); err != nil {
+			return err
+		}
+		w.Key()
+		if err := w.Add("HSET", "player:"+playerID,
+			"name", fmt.Sprintf("Player%d", i),
+			"level", int(clamp(math.Log(score)*10, 1, 100)),
+			"class", playerClasses[r.IntN(len(playerClasses))],
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) sessions(r *rand.Rand, w *batchWriter) error {
+	n := g.cfg.count(baseSessions)
+	users := g.cfg.count(baseUsers)
+	activity := newZipf(r, popularitySkew, users)
+
+	for i := 1; i <= n; i++ {
+		user := activity.Next()
+		created := g.cfg.Epoch.Add(-time.Duration(r.IntN(3600)) * time.Second)
+		ttl := time.Duration(5+r.IntN(24*60-5)) * time.Minute
+		data := fmt.Sprintf(`{"user_id":"%s","created_at":%d,"expires_at":%d,"data":{}}`,
+			userID(user), created.Unix(), created.Add(ttl).Unix())
+
+		w.Key()
+		if err := w.Add("SET", fmt.Sprintf("session:%d", i), data, "PX", ttl.Milliseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) chat(r *rand.Rand, w *batchWriter) error {
+	users := g.cfg.count(baseUsers)
+	rooms := make([]string, g.cfg.count(baseRooms))
+	for i := range rooms {
+		if i < len(defaultRooms) {
+			rooms[i] = defaultRooms[i]
+		} else {
+			rooms[i] = fmt.Sprintf("room_%d", i+1)
+		}
+	}
+	roomPopularity := newZipf(r, popularitySkew, len(rooms))
+	activity := newZipf(r, popularitySkew, users)
+
+	// Popular rooms have more members
+	for rank, room := range rooms {
+		members := int(math.Max(5, float64(users)/20/math.Pow(float64(rank+1), popularitySkew)))
+		args := []interface{}{"SADD", "chat_users:" + room}
+		for j := 0; j < members; j++ {
+			args = append(args, userID(activity.Next()))
+		}
+		w.Key()
+		if err := w.Add(args...); err != nil {
+			return err
+		}
+	}
+
+	ts := g.cfg.Epoch.Add(-24 * time.Hour)
+	seen := make(map[string]bool, len(rooms))
+	for i := 0; i < g.cfg.count(baseChatMessages); i++ {
+		room := rooms[roomPopularity.Next()]
+		ts = ts.Add(time.Duration(r.ExpFloat64()*float64(2*time.Second)) + time.Millisecond)
+		message := fmt.Sprintf("%d|%s|%s|%s|%d",
+			ts.UnixNano(), room, userID(activity.Next()), chatPhrases[r.IntN(len(chatPhrases))], ts.Unix())
+
+		if !seen[room] {
+			seen[room] = true
+			w.Key()
+		}
+		if err := w.Add("LPUSH", "chat_history:"+room, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) events(r *rand.Rand, w *batchWriter) error {
+	users := g.cfg.count(baseUsers)
+	activity := newZipf(r, popularitySkew, users)
+
+	// Explicit, strictly increasing IDs keep the stream reproducible
+	ms := g.cfg.Epoch.Add(-24 * time.Hour).UnixMilli()
+	seq := 0
+	w.Key()
+	for i := 0; i < g.cfg.count(baseEvents); i++ {
+		gap := int64(r.ExpFloat64() * 500)
+		if gap == 0 {
+			seq++
+		} else {
+			ms += gap
+			seq = 0
+		}
+		if err := w.Add("XADD", "events", fmt.Sprintf("%d-%d", ms, seq),
+			"event_type", eventTypes[weighted(r, eventWeights)],
+			"user_id", userID(activity.Next()),
+			"timestamp", ms/1000,
+			"ip_address", fmt.Sprintf("192.168.%d.%d", r.IntN(256), r.IntN(254)+1),
+			"user_agent", userAgents[r.IntN(len(userAgents))],
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) rateLimits(r *rand.Rand, w *batchWriter) error {
+	n := g.cfg.count(baseRateLimited)
+	now := g.cfg.Epoch.UnixMilli()
+
+	for i := 0; i < n+1; i++ {
+		key := "rate_limit:api:global"
+		if i < n {
+			key = "rate_limit:api:" + userID(i)
+		}
+		// Request counts in the window are heavy-tailed: a few clients hammer the API
+		requests := int(clamp(pareto(r, 1, 1.2), 1, 1000))
+		args := []interface{}{"ZADD", key}
+		for j := 0; j < requests; j++ {
+			at := now - int64(r.IntN(60000))
+			args = append(args, at, fmt.Sprintf("%d-%d", at, j))
+		}
+		w.Key()
+		if err := w.Add(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
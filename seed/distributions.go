@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+)
+
+// newRand returns a generator seeded from the config seed and a stream name,
+// so each dataset draws from its own reproducible sequence
+func newRand(seed int64, stream string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(stream))
+	return rand.New(rand.NewPCG(uint64(seed), h.Sum64()))
+}
+
+// zipf picks ranks in [0, n) where low ranks are far more popular
+type zipf struct {
+	z *rand.Zipf
+}
+
+// newZipf creates a Zipfian picker with exponent s (> 1) over n items
+func newZipf(r *rand.Rand, s float64, n int) *zipf {
+	return &zipf{z: rand.NewZipf(r, s, 1, uint64(n-1))}
+}
+
+// Next returns the next rank
+func (z *zipf) Next() int {
+	return int(z.z.Uint64())
+}
+
+// pareto draws from a power-law distribution with minimum xm and shape alpha
+func pareto(r *rand.Rand, xm, alpha float64) float64 {
+	return xm / math.Pow(1-r.Float64(), 1/alpha)
+}
+
+// logNormal draws a value whose logarithm is normally distributed around ln(median)
+func logNormal(r *rand.Rand, median, sigma float64) float64 {
+	return median * math.Exp(r.NormFloat64()*sigma)
+}
+
+// weighted picks an index according to the given relative weights
+func weighted(r *rand.Rand, weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return len(weights) - 1
+}
+
+// clamp limits v to the range [lo, hi]
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
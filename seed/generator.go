@@ -0,0 +1,307 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DatasetStats describes what was written for one dataset
+type DatasetStats struct {
+	Name     string
+	Keys     int64
+	Commands int64
+	Duration time.Duration
+}
+
+// OpsPerSec returns the command throughput for the dataset
+func (s DatasetStats) OpsPerSec() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Commands) / s.Duration.Seconds()
+}
+
+// Report summarises a seeding run
+type Report struct {
+	Datasets []DatasetStats
+	Duration time.Duration
+}
+
+// Commands returns the total number of commands sent
+func (r *Report) Commands() int64 {
+	var total int64
+	for _, d := range r.Datasets {
+		total += d.Commands
+	}
+	return total
+}
+
+// Keys returns the total number of keys written
+func (r *Report) Keys() int64 {
+	var total int64
+	for _, d := range r.Datasets {
+		total += d.Keys
+	}
+	return total
+}
+
+// OpsPerSec returns the overall command throughput
+func (r *Report) OpsPerSec() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Commands()) / r.Duration.Seconds()
+}
+
+// batch is a group of commands sent to Redis in a single pipeline
+type batch struct {
+	stats *datasetStats
+	keys  int
+	cmds  [][]interface{}
+}
+
+// datasetStats accumulates counters while a dataset is being written
+type datasetStats struct {
+	mu       sync.Mutex
+	name     string
+	keys     int64
+	commands int64
+	finished time.Time
+}
+
+func (s *datasetStats) record(keys, commands int) {
+	s.mu.Lock()
+	s.keys += int64(keys)
+	s.commands += int64(commands)
+	s.finished = time.Now()
+	s.mu.Unlock()
+}
+
+// dataset describes how to produce one family of keys
+type dataset struct {
+	// ordered datasets must be written in generation order (streams with
+	// explicit IDs, lists built with pushes) and bypass the worker pool
+	ordered  bool
+	generate func(g *Generator, r *rand.Rand, w *batchWriter) error
+	// keys are the names and glob patterns of the keys the dataset writes,
+	// deleted before it is regenerated without a flush
+	keys []string
+}
+
+var datasets = map[string]dataset{
+	DatasetUsers:       {generate: (*Generator).users, keys: []string{"user:*", "user_interests:user:*"}},
+	DatasetProducts:    {generate: (*Generator).products, keys: []string{"product:*", "product_tags", "product_popularity"}},
+	DatasetLeaderboard: {generate: (*Generator).leaderboard, keys: []string{"game_leaderboard", "player:*"}},
+	DatasetSessions:    {generate: (*Generator).sessions, keys: []string{"session:*"}},
+	DatasetChat:        {ordered: true, generate: (*Generator).chat, keys: []string{"chat_users:*", "chat_history:*"}},
+	DatasetEvents:      {ordered: true, generate: (*Generator).events, keys: []string{"events"}},
+	DatasetRateLimits:  {generate: (*Generator).rateLimits, keys: []string{"rate_limit:api:*"}},
+}
+
+// Generator writes a synthetic, reproducible dataset into Redis
+type Generator struct {
+	rdb redis.Cmdable
+	cfg Config
+}
+
+// NewGenerator creates a generator for the given client and configuration
+func NewGenerator(rdb redis.Cmdable, cfg Config) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{rdb: rdb, cfg: cfg}, nil
+}
+
+// Config returns the validated configuration
+func (g *Generator) Config() Config {
+	return g.cfg
+}
+
+// Run generates every configured dataset and writes it through pipelines
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	if g.cfg.Flush {
+		if err := g.rdb.FlushDB(ctx).Err(); err != nil {
+			return nil, fmt.Errorf("flushing database: %w", err)
+		}
+	} else {
+		// Streams with explicit IDs and pushed lists cannot be written over
+		for _, name := range g.cfg.Datasets {
+			if err := g.clear(ctx, datasets[name].keys); err != nil {
+				return nil, fmt.Errorf("clearing %s: %w", name, err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	start := time.Now()
+	work := make(chan *batch, g.cfg.Concurrency)
+
+	// Workers drain batches from unordered datasets
+	var workers sync.WaitGroup
+	for i := 0; i < g.cfg.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range work {
+				if err := g.exec(ctx, b); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	stats := make([]*datasetStats, len(g.cfg.Datasets))
+	var producers sync.WaitGroup
+	for i, name := range g.cfg.Datasets {
+		ds := datasets[name]
+		stats[i] = &datasetStats{name: name}
+
+		w := &batchWriter{ctx: ctx, size: g.cfg.BatchSize, stats: stats[i]}
+		if ds.ordered {
+			w.send = func(b *batch) error { return g.exec(ctx, b) }
+		} else {
+			w.send = func(b *batch) error {
+				select {
+				case work <- b:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		producers.Add(1)
+		go func(name string, ds dataset, w *batchWriter) {
+			defer producers.Done()
+			r := newRand(g.cfg.Seed, name)
+			if err := ds.generate(g, r, w); err != nil {
+				fail(fmt.Errorf("generating %s: %w", name, err))
+				return
+			}
+			if err := w.Flush(); err != nil {
+				fail(fmt.Errorf("generating %s: %w", name, err))
+			}
+		}(name, ds, w)
+	}
+
+	producers.Wait()
+	close(work)
+	workers.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	report := &Report{Duration: time.Since(start)}
+	for _, s := range stats {
+		report.Datasets = append(report.Datasets, DatasetStats{
+			Name:     s.name,
+			Keys:     s.keys,
+			Commands: s.commands,
+			Duration: s.finished.Sub(start),
+		})
+	}
+	return report, nil
+}
+
+// clear deletes the keys matching patterns, in batches
+func (g *Generator) clear(ctx context.Context, patterns []string) error {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "*") {
+			if err := g.rdb.Del(ctx, pattern).Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		var cursor uint64
+		for {
+			keys, next, err := g.rdb.Scan(ctx, cursor, pattern, int64(g.cfg.BatchSize)).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := g.rdb.Del(ctx, keys...).Err(); err != nil {
+					return err
+				}
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// exec sends one batch as a pipeline
+func (g *Generator) exec(ctx context.Context, b *batch) error {
+	pipe := g.rdb.Pipeline()
+	for _, args := range b.cmds {
+		pipe.Do(ctx, args...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("writing %s batch: %w", b.stats.name, err)
+	}
+	b.stats.record(b.keys, len(b.cmds))
+	return nil
+}
+
+// batchWriter groups generated commands into pipeline-sized batches
+type batchWriter struct {
+	ctx   context.Context
+	size  int
+	stats *datasetStats
+	send  func(*batch) error
+	cur   *batch
+}
+
+// Add queues a command, sending the current batch once it is full
+func (w *batchWriter) Add(args ...interface{}) error {
+	if w.cur == nil {
+		w.cur = &batch{stats: w.stats, cmds: make([][]interface{}, 0, w.size)}
+	}
+	w.cur.cmds = append(w.cur.cmds, args)
+	if len(w.cur.cmds) >= w.size {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Key records that a new key was created
+func (w *batchWriter) Key() {
+	if w.cur == nil {
+		w.cur = &batch{stats: w.stats, cmds: make([][]interface{}, 0, w.size)}
+	}
+	w.cur.keys++
+}
+
+// Flush sends any queued commands
+func (w *batchWriter) Flush() error {
+	if w.cur == nil || len(w.cur.cmds) == 0 {
+		return nil
+	}
+	b := w.cur
+	w.cur = nil
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	return w.send(b)
+}
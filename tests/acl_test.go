@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"Redis/acl"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setACLPasswords sets the passwords scripts/acl.yaml reads from the environment
+func setACLPasswords(t *testing.T) {
+	t.Setenv("ANALYTICS_PASSWORD", "analytics-test-password")
+	t.Setenv("QUEUE_WORKER_PASSWORD", "queue-worker-test-password")
+	t.Setenv("CHAT_PASSWORD", "chat-test-password")
+}
+
+// TestACLSpec tests loading the example spec and turning users into ACL SETUSER rules
+func TestACLSpec(t *testing.T) {
+	setACLPasswords(t)
+	spec, err := acl.LoadSpec("../scripts/acl.yaml")
+	if err != nil {
+		t.Fatalf("Error loading spec: %v", err)
+	}
+	if len(spec.Users) != 3 {
+		t.Fatalf("Expected 3 users, got %d", len(spec.Users))
+	}
+
+	worker, _ := spec.Lookup("queue_worker")
+	rules, err := worker.Rules()
+	if err != nil {
+		t.Fatalf("Error building rules: %v", err)
+	}
+	want := "reset on >queue-worker-test-password ~queue:* +@list +@stream +@connection -@dangerous"
+	if got := strings.Join(rules, " "); got != want {
+		t.Errorf("Expected rules %q, got %q", want, got)
+	}
+	if got := strings.Join(acl.Redact(rules), " "); strings.Contains(got, "test-password") {
+		t.Errorf("Expected passwords to be redacted, got %q", got)
+	}
+
+	analytics, _ := spec.Lookup("analytics")
+	rules, _ = analytics.Rules()
+	if rules[3] != "%R~*" {
+		t.Errorf("Expected read-only key access, got %v", rules)
+	}
+	chat, _ := spec.Lookup("chat")
+	rules, _ = chat.Rules()
+	if rules[3] != "&chat:*" {
+		t.Errorf("Expected channel access, got %v", rules)
+	}
+
+	t.Setenv("CHAT_PASSWORD", "")
+	if _, err := chat.Rules(); err == nil {
+		t.Errorf("Expected an error for an unset password variable")
+	}
+
+	for _, bad := range []string{
+		"users: [{name: a}]",
+		"users: [{name: a, nopass: true, passwords: [x]}]",
+		"users: [{name: a, nopass: true}, {name: a, nopass: true}]",
+		"users: [{name: a, nopass: true, commands: ['@read']}]",
+	} {
+		if _, err := acl.ParseSpec([]byte(bad)); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestACLRoles tests that each example role can and cannot run specific commands and keys
+func TestACLRoles(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	setACLPasswords(t)
+	spec, err := acl.LoadSpec("../scripts/acl.yaml")
+	if err != nil {
+		t.Fatalf("Error loading spec: %v", err)
+	}
+	changes, err := acl.Apply(ctx, rdb, spec, acl.ApplyOptions{})
+	if err != nil && strings.Contains(err.Error(), "unknown command") {
+		t.Skipf("Server does not support ACL: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Error applying ACL spec: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected 3 users applied, got %+v", changes)
+	}
+
+	// Every can/cannot in the spec, checked with ACL DRYRUN
+	results, err := acl.Verify(ctx, rdb, spec)
+	if err != nil {
+		t.Fatalf("Error verifying: %v", err)
+	}
+	for _, r := range results {
+		if !r.OK() {
+			t.Errorf("%s: expected %q allowed=%v, server says allowed=%v (%s)", r.User, r.Command, r.Want, r.Allowed, r.Reason)
+		}
+	}
+
+	// The same rules on real connections
+	login := func(user string) *redis.Client {
+		u, _ := spec.Lookup(user)
+		rules, _ := u.Rules()
+		return redis.NewClient(&redis.Options{Addr: "localhost:6379", Username: user, Password: rules[2][1:], TLSConfig: tlsconf.FromEnv()})
+	}
+	denied := func(err error) bool {
+		return err != nil && strings.HasPrefix(err.Error(), "NOPERM")
+	}
+
+	rdb.Set(ctx, "acl:test:page_views", "10", 0)
+	analytics := login("analytics")
+	defer analytics.Close()
+	if v, err := analytics.Get(ctx, "acl:test:page_views").Result(); err != nil || v != "10" {
+		t.Errorf("Expected analytics to read, got %q: %v", v, err)
+	}
+	if err := analytics.Set(ctx, "acl:test:page_views", "0", 0).Err(); !denied(err) {
+		t.Errorf("Expected analytics writes to be denied, got %v", err)
+	}
+
+	worker := login("queue_worker")
+	defer worker.Close()
+	if err := worker.LPush(ctx, "queue:acl_test", "job-1").Err(); err != nil {
+		t.Errorf("Expected queue_worker to push to queue:*, got %v", err)
+	}
+	if v, err := worker.RPop(ctx, "queue:acl_test").Result(); err != nil || v != "job-1" {
+		t.Errorf("Expected queue_worker to pop job-1, got %q: %v", v, err)
+	}
+	if err := worker.LPush(ctx, "acl:test:tasks", "job-1").Err(); !denied(err) {
+		t.Errorf("Expected queue_worker to be denied keys outside queue:*, got %v", err)
+	}
+
+	chat := login("chat")
+	defer chat.Close()
+	if err := chat.Publish(ctx, "chat:acl_test", "hello").Err(); err != nil {
+		t.Errorf("Expected chat to publish on chat:*, got %v", err)
+	}
+	if err := chat.Publish(ctx, "notifications:acl_test", "hello").Err(); !denied(err) {
+		t.Errorf("Expected chat to be denied other channels, got %v", err)
+	}
+	if err := chat.Get(ctx, "acl:test:page_views").Err(); !denied(err) {
+		t.Errorf("Expected chat to be denied keys, got %v", err)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "acl:test:page_views", "queue:acl_test")
+	for _, u := range spec.Users {
+		rdb.ACLDelUser(ctx, u.Name)
+	}
+}
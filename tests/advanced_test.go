@@ -6,15 +6,18 @@ import (
 	"testing"
 	"time"
 
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
 // TestHashOperations tests Redis hash operations
 func TestHashOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -67,9 +70,10 @@ func TestHashOperations(t *testing.T) {
 // TestListOperations tests Redis list operations
 func TestListOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -112,9 +116,10 @@ func TestListOperations(t *testing.T) {
 // TestSetOperations tests Redis set operations
 func TestSetOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -157,9 +162,10 @@ func TestSetOperations(t *testing.T) {
 // TestSortedSetOperations tests Redis sorted set operations
 func TestSortedSetOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -202,9 +208,10 @@ func TestSortedSetOperations(t *testing.T) {
 // TestPipelineOperations tests Redis pipeline operations
 func TestPipelineOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -245,9 +252,10 @@ func TestPipelineOperations(t *testing.T) {
 // TestTransactionOperations tests Redis transaction operations
 func TestTransactionOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -287,9 +295,10 @@ func TestTransactionOperations(t *testing.T) {
 // TestPubSubOperations tests Redis Pub/Sub operations
 func TestPubSubOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -329,9 +338,10 @@ func TestPubSubOperations(t *testing.T) {
 // TestStreamOperations tests Redis stream operations
 func TestStreamOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -377,9 +387,10 @@ func TestStreamOperations(t *testing.T) {
 // TestErrorHandling tests error handling
 func TestErrorHandling(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -407,9 +418,10 @@ func TestErrorHandling(t *testing.T) {
 // TestConcurrentOperations tests concurrent operations
 func TestConcurrentOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -458,9 +470,10 @@ func TestConcurrentOperations(t *testing.T) {
 // TestPerformance tests basic performance
 func TestPerformance(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
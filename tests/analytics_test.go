@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"Redis/analytics"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cleanupAnalytics deletes every key under prefix
+func cleanupAnalytics(ctx context.Context, rdb *redis.Client, prefix string) {
+	keys, _ := rdb.Keys(ctx, prefix+"*").Result()
+	if len(keys) > 0 {
+		rdb.Del(ctx, keys...)
+	}
+}
+
+// TestAnalyticsActiveUsers tests unique counts and daily, weekly and monthly active users
+func TestAnalyticsActiveUsers(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	const prefix = "analytics:test:"
+	cleanupAnalytics(ctx, rdb, prefix)
+
+	a := analytics.New(rdb, analytics.Config{Prefix: prefix})
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+
+	// 10 users on day, 5 of them twice in the same hour, 20 more over the previous 10 days
+	var events []analytics.Event
+	for i := 0; i < 10; i++ {
+		user := fmt.Sprintf("user:%d", i)
+		events = append(events, analytics.Event{UserID: user, Type: "page_view", Time: day.Add(9 * time.Hour)})
+		if i < 5 {
+			events = append(events, analytics.Event{UserID: user, Type: "page_view", Time: day.Add(9*time.Hour + time.Minute)})
+		}
+	}
+	for i := 10; i < 30; i++ {
+		events = append(events, analytics.Event{UserID: fmt.Sprintf("user:%d", i), Type: "click", Time: day.AddDate(0, 0, -(i-10)/2)})
+	}
+	if err := a.Record(ctx, events...); err != nil {
+		t.Fatalf("Error recording events: %v", err)
+	}
+
+	hours, err := a.Uniques(ctx, "page_view", analytics.Hour, day.Add(8*time.Hour), day.Add(10*time.Hour))
+	if err != nil {
+		t.Fatalf("Error counting uniques: %v", err)
+	}
+	if len(hours) != 3 || hours[0].Users != 0 || hours[1].Users != 10 || hours[2].Users != 0 {
+		t.Errorf("Expected 10 unique page viewers at 9:00 only, got %+v", hours)
+	}
+	if n, _ := a.UniqueUsers(ctx, analytics.AllEvents, analytics.Day, day.AddDate(0, 0, -9), day); n != 30 {
+		t.Errorf("Expected 30 unique users over 10 days, got %d", n)
+	}
+
+	// Day has the 10 page viewers and 2 clickers, and each earlier day 2 more clickers
+	for name, want := range map[string]int64{"DAU": 12, "WAU": 24, "MAU": 30} {
+		var got int64
+		switch name {
+		case "DAU":
+			got, err = a.DAU(ctx, day)
+		case "WAU":
+			got, err = a.WAU(ctx, day)
+		case "MAU":
+			got, err = a.MAU(ctx, day)
+		}
+		if err != nil {
+			t.Fatalf("Error counting %s: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("Expected %s of %d, got %d", name, want, got)
+		}
+	}
+
+	// Recording the same events again changes nothing
+	a.Record(ctx, events...)
+	if dau, _ := a.DAU(ctx, day); dau != 12 {
+		t.Errorf("Expected replayed events to leave DAU at 12, got %d", dau)
+	}
+
+	// Cleanup
+	cleanupAnalytics(ctx, rdb, prefix)
+}
+
+// TestAnalyticsRetentionAndFunnel tests cohort retention and funnel conversion
+func TestAnalyticsRetentionAndFunnel(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	const prefix = "analytics:test:"
+	cleanupAnalytics(ctx, rdb, prefix)
+
+	a := analytics.New(rdb, analytics.Config{Prefix: prefix})
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -3)
+
+	// 8 new users on day: 4 come back the next day, 2 of those the day after
+	var events []analytics.Event
+	for i := 0; i < 8; i++ {
+		user := fmt.Sprintf("user:%d", i)
+		events = append(events, analytics.Event{UserID: user, Type: "page_view", Time: day.Add(time.Hour)})
+		if i < 4 {
+			events = append(events, analytics.Event{UserID: user, Type: "search", Time: day.Add(25 * time.Hour)})
+		}
+		if i < 2 {
+			events = append(events, analytics.Event{UserID: user, Type: "purchase", Time: day.Add(49 * time.Hour)})
+		}
+	}
+	// A purchase without a search does not count towards the funnel
+	events = append(events, analytics.Event{UserID: "user:6", Type: "purchase", Time: day.Add(2 * time.Hour)})
+	if err := a.Record(ctx, events...); err != nil {
+		t.Fatalf("Error recording events: %v", err)
+	}
+
+	cohort, err := a.Retention(ctx, day, 3)
+	if err != nil {
+		t.Fatalf("Error computing retention: %v", err)
+	}
+	if cohort.Users != 8 || cohort.Retained[0] != 4 || cohort.Retained[1] != 2 || cohort.Retained[2] != 0 {
+		t.Errorf("Expected a cohort of 8 retaining 4, 2 and 0, got %d and %v", cohort.Users, cohort.Retained)
+	}
+	if rate := cohort.Rate(1); rate != 0.5 {
+		t.Errorf("Expected day-1 retention of 0.5, got %.2f", rate)
+	}
+	if next, _ := a.Retention(ctx, day.AddDate(0, 0, 1), 1); next.Users != 0 {
+		t.Errorf("Expected returning users not to join the next cohort, got %d", next.Users)
+	}
+
+	steps, err := a.Funnel(ctx, day, day.AddDate(0, 0, 2), "page_view", "search", "purchase")
+	if err != nil {
+		t.Fatalf("Error computing funnel: %v", err)
+	}
+	if len(steps) != 3 || steps[0].Users != 8 || steps[1].Users != 4 || steps[2].Users != 2 {
+		t.Fatalf("Expected a funnel of 8, 4 and 2 users, got %+v", steps)
+	}
+	if steps[1].StepConversion != 0.5 || steps[2].Conversion != 0.25 {
+		t.Errorf("Expected conversions of 0.5 and 0.25, got %.2f and %.2f", steps[1].StepConversion, steps[2].Conversion)
+	}
+	if keys, _ := rdb.Keys(ctx, prefix+"tmp:*").Result(); len(keys) != 0 {
+		t.Errorf("Expected temporary keys to be deleted, got %v", keys)
+	}
+
+	// Empty ranges are errors rather than BITOP without source keys
+	if _, err := a.Funnel(ctx, day.AddDate(0, 0, 2), day, "page_view", "search"); err == nil {
+		t.Error("Expected an error for a funnel ending before it starts")
+	}
+	if _, err := a.Active(ctx, day, 0); err == nil {
+		t.Error("Expected an error for active users over 0 days")
+	}
+	if _, err := a.Retention(ctx, day, -1); err == nil {
+		t.Error("Expected an error for retention over -1 days")
+	}
+
+	// Cleanup
+	cleanupAnalytics(ctx, rdb, prefix)
+}
+
+// TestAnalyticsRun tests consuming a stream through a consumer group
+func TestAnalyticsRun(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	const prefix = "analytics:test:"
+	cleanupAnalytics(ctx, rdb, prefix)
+
+	stream := prefix + "events"
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i < 20; i++ {
+		rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{
+			"event_type": "click",
+			"user_id":    fmt.Sprintf("user:%d", i%5),
+			"timestamp":  day.Add(time.Duration(i) * time.Minute).Unix(),
+		}})
+	}
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"note": "no user"}})
+
+	a := analytics.New(rdb, analytics.Config{Prefix: prefix, Stream: stream, Block: 100 * time.Millisecond})
+	runCtx, cancel := context.WithCancel(ctx)
+	recorded := 0
+	err := a.Run(runCtx, func(total int) {
+		recorded = total
+		if total >= 20 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("Error running consumer: %v", err)
+	}
+	if recorded != 20 {
+		t.Errorf("Expected 20 events recorded, got %d", recorded)
+	}
+	if dau, _ := a.DAU(ctx, day); dau != 5 {
+		t.Errorf("Expected 5 daily active users, got %d", dau)
+	}
+	if pending, _ := rdb.XPending(ctx, stream, "analytics").Result(); pending.Count != 0 {
+		t.Errorf("Expected every entry acknowledged, %d pending", pending.Count)
+	}
+
+	// Cleanup
+	cleanupAnalytics(ctx, rdb, prefix)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/aof"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// respCommand encodes a command as it appears in an AOF
+func respCommand(args ...string) string {
+	s := fmt.Sprintf("*%d\r\n", len(args))
+	for _, a := range args {
+		s += fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)
+	}
+	return s
+}
+
+// writeAOFDir creates a multi-part AOF: an RDB base plus one incr file with
+// timestamp annotations, a transaction and a truncated final command
+func writeAOFDir(t *testing.T) (dir string, incr string) {
+	dir = t.TempDir()
+	manifest := "file appendonly.aof.1.base.rdb seq 1 type b\n" +
+		"file appendonly.aof.1.incr.aof seq 1 type h\n" +
+		"file \"appendonly.aof.2.incr.aof\" seq 2 type i\n"
+
+	incr = "#TS:1000\r\n" +
+		respCommand("SELECT", "14") +
+		respCommand("SET", "aoftest:a", "1") +
+		respCommand("SET", "other", "x") +
+		"#TS:2000\r\n" +
+		respCommand("DEL", "aoftest:b", "other") +
+		respCommand("SET", "aoftest:b", "2") +
+		"#TS:3000\r\n" +
+		respCommand("MULTI") +
+		respCommand("INCR", "aoftest:a") +
+		respCommand("EXEC") +
+		"*3\r\n$3\r\nSET\r\n$9\r\naoftest:c"
+
+	files := map[string]string{
+		"appendonly.aof.manifest":   manifest,
+		"appendonly.aof.1.base.rdb": string(buildRDB(0)),
+		"appendonly.aof.2.incr.aof": incr,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Error writing %s: %v", name, err)
+		}
+	}
+	return dir, incr
+}
+
+// TestAOFRead tests manifest resolution, base conversion, timestamps and truncation
+func TestAOFRead(t *testing.T) {
+	dir, incr := writeAOFDir(t)
+
+	files, err := aof.Files(dir)
+	if err != nil {
+		t.Fatalf("Error resolving manifest: %v", err)
+	}
+	if len(files) != 2 || !strings.HasSuffix(files[0], ".base.rdb") {
+		t.Fatalf("Unexpected files %v", files)
+	}
+
+	var cmds []*aof.Command
+	err = aof.Read(files, func(c *aof.Command) error {
+		cmds = append(cmds, c)
+		return nil
+	})
+	var te *aof.TruncatedError
+	if !errors.As(err, &te) {
+		t.Fatalf("Expected truncated tail, got %v", err)
+	}
+	if want := int64(strings.LastIndex(incr, "*3\r\n$3\r\nSET")); te.Offset != want {
+		t.Errorf("Expected truncation at %d, got %d", want, te.Offset)
+	}
+
+	var base, tail []string
+	for _, c := range cmds {
+		if strings.HasSuffix(c.File, ".rdb") {
+			base = append(base, c.Name())
+		} else {
+			tail = append(tail, c.Name())
+		}
+	}
+	if len(base) == 0 || base[0] != "SELECT" {
+		t.Errorf("Expected base commands starting with SELECT, got %v", base)
+	}
+	if got := strings.Join(tail, " "); got != "SELECT SET SET DEL SET MULTI INCR EXEC" {
+		t.Errorf("Unexpected incr commands %s", got)
+	}
+
+	last := cmds[len(cmds)-1]
+	if last.DB != 14 || !last.Time.Equal(time.Unix(3000, 0)) {
+		t.Errorf("Expected db 14 at time 3000, got db %d at %v", last.DB, last.Time)
+	}
+}
+
+// TestAOFCheckAndFix tests that check finds the damaged tail and fix removes it
+func TestAOFCheckAndFix(t *testing.T) {
+	dir, _ := writeAOFDir(t)
+	files, err := aof.Files(dir)
+	if err != nil {
+		t.Fatalf("Error resolving manifest: %v", err)
+	}
+
+	res, err := aof.Check(files)
+	if err != nil {
+		t.Fatalf("Error checking AOF: %v", err)
+	}
+	if res.Truncated == nil {
+		t.Fatalf("Expected truncated tail to be reported")
+	}
+	if err := aof.Fix(res.Truncated); err != nil {
+		t.Fatalf("Error fixing AOF: %v", err)
+	}
+
+	res, err = aof.Check(files)
+	if err != nil || res.Truncated != nil {
+		t.Errorf("Expected clean log after fix, got %v, %v", res.Truncated, err)
+	}
+}
+
+// TestAOFReplayPointInTime tests replaying up to a timestamp with a key filter
+func TestAOFReplayPointInTime(t *testing.T) {
+	ctx := context.Background()
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 14, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+
+	dir, _ := writeAOFDir(t)
+	files, err := aof.Files(dir)
+	if err != nil {
+		t.Fatalf("Error resolving manifest: %v", err)
+	}
+
+	rdb.FlushDB(ctx)
+	filter := aof.Filter{Until: time.Unix(2000, 0), Patterns: []string{"aoftest:*"}}
+	stats, err := aof.Replay(ctx, rdb, files, aof.ReplayOptions{Filter: filter})
+	if err != nil {
+		t.Fatalf("Error replaying AOF: %v", err)
+	}
+	if stats.Truncated != nil {
+		t.Errorf("Expected replay to stop before the truncated tail")
+	}
+
+	a, _ := rdb.Get(ctx, "aoftest:a").Result()
+	b, _ := rdb.Get(ctx, "aoftest:b").Result()
+	if a != "1" || b != "2" {
+		t.Errorf("Expected a=1 b=2 at time 2000, got a=%q b=%q", a, b)
+	}
+	if n, _ := rdb.Exists(ctx, "other").Result(); n != 0 {
+		t.Errorf("Expected keys outside the pattern to be skipped")
+	}
+
+	// Replaying everything applies the transaction and drops the partial command
+	rdb.FlushDB(ctx)
+	stats, err = aof.Replay(ctx, rdb, files, aof.ReplayOptions{Filter: aof.Filter{Patterns: []string{"aoftest:*"}}})
+	if err != nil {
+		t.Fatalf("Error replaying AOF: %v", err)
+	}
+	if stats.Truncated == nil {
+		t.Errorf("Expected truncated tail to be reported")
+	}
+	if a, _ := rdb.Get(ctx, "aoftest:a").Result(); a != "2" {
+		t.Errorf("Expected a=2 after the transaction, got %q", a)
+	}
+	if n, _ := rdb.Exists(ctx, "aoftest:c").Result(); n != 0 {
+		t.Errorf("Expected the truncated command not to be applied")
+	}
+
+	rdb.FlushDB(ctx)
+}
+
+// TestAOFReplayUnreachable tests that a replay into an unreachable server fails instead of advancing
+func TestAOFReplayUnreachable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: closedAddr(t), MaxRetries: -1})
+	defer rdb.Close()
+
+	dir, _ := writeAOFDir(t)
+	files, err := aof.Files(dir)
+	if err != nil {
+		t.Fatalf("Error resolving manifest: %v", err)
+	}
+	stats, err := aof.Replay(context.Background(), rdb, files, aof.ReplayOptions{})
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("Expected the connection error, got %v", err)
+	}
+	if stats.Commands != 0 || stats.LastIndex >= 0 {
+		t.Errorf("Expected nothing counted as replayed, got %+v", stats)
+	}
+}
+
+// TestAOFFilterKeys tests key matching for multi-key and subcommand forms
+func TestAOFFilterKeys(t *testing.T) {
+	filter := aof.Filter{Patterns: []string{"events*"}}
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"XGROUP", "CREATE", "events", "analytics", "0"}, []string{"XGROUP", "CREATE", "events", "analytics", "0"}},
+		{[]string{"XGROUP", "CREATE", "orders", "events", "0"}, nil},
+		{[]string{"XGROUP", "SETID", "events:2", "analytics", "5-0"}, []string{"XGROUP", "SETID", "events:2", "analytics", "5-0"}},
+		{[]string{"XADD", "events", "1-1", "a", "1"}, []string{"XADD", "events", "1-1", "a", "1"}},
+		{[]string{"DEL", "orders", "events"}, []string{"DEL", "events"}},
+		{[]string{"MSET", "events:n", "1", "orders:n", "2"}, []string{"MSET", "events:n", "1"}},
+		{[]string{"FLUSHALL"}, nil},
+	}
+	for _, c := range cases {
+		got := filter.Apply(&aof.Command{Args: c.args})
+		if strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Errorf("Expected %v to filter to %v, got %v", c.args, c.want, got)
+		}
+	}
+}
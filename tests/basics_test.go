@@ -5,15 +5,18 @@ import (
 	"testing"
 	"time"
 
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
 // TestRedisConnection tests basic Redis connection
 func TestRedisConnection(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -32,9 +35,10 @@ func TestRedisConnection(t *testing.T) {
 // TestBasicSetGet tests basic SET and GET operations
 func TestBasicSetGet(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -63,9 +67,10 @@ func TestBasicSetGet(t *testing.T) {
 // TestExpiration tests key expiration
 func TestExpiration(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -104,9 +109,10 @@ func TestExpiration(t *testing.T) {
 // TestDeleteKey tests key deletion
 func TestDeleteKey(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -142,9 +148,10 @@ func TestDeleteKey(t *testing.T) {
 // TestTTL tests TTL operations
 func TestTTL(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -193,9 +200,10 @@ func TestTTL(t *testing.T) {
 // TestRenameKey tests key renaming
 func TestRenameKey(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -240,9 +248,10 @@ func TestRenameKey(t *testing.T) {
 // TestMultipleKeys tests operations with multiple keys
 func TestMultipleKeys(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -293,9 +302,10 @@ func TestMultipleKeys(t *testing.T) {
 // TestNonExistentKey tests operations on non-existent keys
 func TestNonExistentKey(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -331,9 +341,10 @@ func TestNonExistentKey(t *testing.T) {
 // TestPersistKey tests PERSIST operation
 func TestPersistKey(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -382,9 +393,10 @@ func TestPersistKey(t *testing.T) {
 // TestRenameNX tests RENAMENX operation
 func TestRenameNX(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
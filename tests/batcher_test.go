@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Redis/batcher"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestBatcherConcurrentWriters tests that concurrent calls are coalesced and each gets its own result
+func TestBatcherConcurrentWriters(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	var maxSize atomic.Int64
+	b := batcher.New(rdb, batcher.Options{
+		MaxBatch:   50,
+		MaxDelay:   2 * time.Millisecond,
+		MaxPending: 200,
+		OnFlush: func(info batcher.FlushInfo) {
+			if int64(info.Size) > maxSize.Load() {
+				maxSize.Store(int64(info.Size))
+			}
+		},
+	})
+
+	const writers, perWriter = 20, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				key := fmt.Sprintf("batch_key_%d_%d", w, i)
+				if err := b.Do(ctx, "SET", key, i).Err(); err != nil {
+					errs <- err
+					return
+				}
+				got, err := b.Do(ctx, "GET", key).Text()
+				if err != nil || got != fmt.Sprint(i) {
+					errs <- fmt.Errorf("GET %s = %q, %v", key, got, err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Error in batched writer: %v", err)
+	}
+
+	// A missing key is a per-command nil, not a failed flush
+	if _, err := b.Do(ctx, "GET", "batch_missing_key").Text(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for missing key, got %v", err)
+	}
+
+	b.Close()
+	stats := b.Stats()
+	if stats.Commands != 2*writers*perWriter+1 {
+		t.Errorf("Expected %d commands, got %d", 2*writers*perWriter+1, stats.Commands)
+	}
+	if stats.AvgBatch() <= 1 || maxSize.Load() > 50 {
+		t.Errorf("Expected batches between 1 and 50 commands, got avg %.1f max %d", stats.AvgBatch(), maxSize.Load())
+	}
+	if stats.Pending != 0 || stats.FailedFlushes != 0 {
+		t.Errorf("Unexpected stats after close: %+v", stats)
+	}
+	if err := b.Do(ctx, "PING").Err(); err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+
+	// Cleanup
+	for w := 0; w < writers; w++ {
+		keys := make([]string, perWriter)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("batch_key_%d_%d", w, i)
+		}
+		rdb.Del(ctx, keys...)
+	}
+}
+
+// closedAddr returns a local address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// TestBatcherUnreachable tests that a failed round trip fails every future with the connection error
+func TestBatcherUnreachable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: closedAddr(t), MaxRetries: -1})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	b := batcher.New(rdb, batcher.Options{MaxBatch: 10, MaxDelay: time.Millisecond})
+	set := b.Do(ctx, "SET", "batch_unreachable", 1)
+	get := b.Do(ctx, "GET", "batch_unreachable")
+	for _, f := range []*batcher.Future{set, get} {
+		var opErr *net.OpError
+		if err := f.Err(); !errors.As(err, &opErr) {
+			t.Errorf("Expected the connection error, got %v", err)
+		}
+	}
+	b.Close()
+	if stats := b.Stats(); stats.FailedFlushes == 0 {
+		t.Errorf("Expected a failed flush, got %+v", stats)
+	}
+}
+
+// BenchmarkSetNaiveLoop issues one SET per round trip, like TestPerformance
+func BenchmarkSetNaiveLoop(b *testing.B) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := rdb.Set(ctx, fmt.Sprintf("perf_key_%d", i%1000), i, 0).Err(); err != nil {
+			b.Fatalf("Error setting key: %v", err)
+		}
+	}
+}
+
+// BenchmarkSetParallelNaive issues SETs from many goroutines without batching
+func BenchmarkSetParallelNaive(b *testing.B) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if err := rdb.Set(ctx, fmt.Sprintf("perf_key_%d", i%1000), i, 0).Err(); err != nil {
+				b.Errorf("Error setting key: %v", err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkSetParallelBatched issues SETs from many goroutines through the batcher
+func BenchmarkSetParallelBatched(b *testing.B) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	bt := batcher.New(rdb, batcher.Options{})
+	defer bt.Close()
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if err := bt.Do(ctx, "SET", fmt.Sprintf("perf_key_%d", i%1000), i).Err(); err != nil {
+				b.Errorf("Error setting key: %v", err)
+				return
+			}
+			i++
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(bt.Stats().AvgBatch(), "cmds/flush")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Benchmarks comparing access patterns. Run them with
+//
+//	go test ./tests -run '^$' -bench . -benchmem
+//
+// or through `redisctl bench` to store results as JSON and compare them with
+// a baseline. Every benchmark reports ops/s, where an op is one key, field,
+// member or entry written.
+
+// benchClient connects to the local server, failing the benchmark if it is down
+func benchClient(b *testing.B) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		b.Fatalf("Could not connect to Redis: %v", err)
+	}
+	b.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// reportOps records throughput given the number of ops each iteration performs
+func reportOps(b *testing.B, perIteration int) {
+	if s := b.Elapsed().Seconds(); s > 0 {
+		b.ReportMetric(float64(b.N*perIteration)/s, "ops/s")
+	}
+}
+
+// benchKeys returns n distinct key names under prefix
+func benchKeys(prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bench:%s:%d", prefix, i)
+	}
+	return keys
+}
+
+// BenchmarkWrites compares 100 SETs sent one by one, pipelined and in MULTI/EXEC
+func BenchmarkWrites(b *testing.B) {
+	rdb := benchClient(b)
+	ctx := context.Background()
+	keys := benchKeys("writes", 100)
+	defer rdb.Del(ctx, keys...)
+
+	b.Run("single", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				if err := rdb.Set(ctx, key, i, 0).Err(); err != nil {
+					b.Fatalf("Error setting key: %v", err)
+				}
+			}
+		}
+		reportOps(b, len(keys))
+	})
+
+	b.Run("pipelined", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Set(ctx, key, i, 0)
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatalf("Error executing pipeline: %v", err)
+			}
+		}
+		reportOps(b, len(keys))
+	})
+
+	b.Run("txpipelined", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Set(ctx, key, i, 0)
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatalf("Error executing transaction: %v", err)
+			}
+		}
+		reportOps(b, len(keys))
+	})
+}
+
+// BenchmarkMSet compares one MSET with a loop of SETs for growing key counts
+func BenchmarkMSet(b *testing.B) {
+	rdb := benchClient(b)
+	ctx := context.Background()
+
+	for _, n := range []int{10, 100, 1000} {
+		keys := benchKeys("mset", n)
+		pairs := make([]interface{}, 0, 2*n)
+		for _, key := range keys {
+			pairs = append(pairs, key, "value")
+		}
+
+		b.Run(fmt.Sprintf("mset/keys=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := rdb.MSet(ctx, pairs...).Err(); err != nil {
+					b.Fatalf("Error in MSET: %v", err)
+				}
+			}
+			reportOps(b, n)
+		})
+
+		b.Run(fmt.Sprintf("loop/keys=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, key := range keys {
+					if err := rdb.Set(ctx, key, "value", 0).Err(); err != nil {
+						b.Fatalf("Error setting key: %v", err)
+					}
+				}
+			}
+			reportOps(b, n)
+		})
+
+		rdb.Del(ctx, keys...)
+	}
+}
+
+// BenchmarkHSetFields measures HSET as the number of fields per call grows
+func BenchmarkHSetFields(b *testing.B) {
+	rdb := benchClient(b)
+	ctx := context.Background()
+	defer rdb.Del(ctx, "bench:hash")
+
+	for _, n := range []int{1, 10, 100, 1000} {
+		fields := make([]interface{}, 0, 2*n)
+		for i := 0; i < n; i++ {
+			fields = append(fields, fmt.Sprintf("field_%d", i), i)
+		}
+
+		b.Run(fmt.Sprintf("fields=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := rdb.HSet(ctx, "bench:hash", fields...).Err(); err != nil {
+					b.Fatalf("Error in HSET: %v", err)
+				}
+			}
+			reportOps(b, n)
+		})
+		rdb.Del(ctx, "bench:hash")
+	}
+}
+
+// BenchmarkZAddBulk measures ZADD with growing numbers of members per call
+func BenchmarkZAddBulk(b *testing.B) {
+	rdb := benchClient(b)
+	ctx := context.Background()
+	defer rdb.Del(ctx, "bench:zset")
+
+	for _, n := range []int{1, 10, 100, 1000} {
+		members := make([]redis.Z, n)
+		for i := range members {
+			members[i] = redis.Z{Score: float64(i), Member: fmt.Sprintf("player_%d", i)}
+		}
+
+		b.Run(fmt.Sprintf("members=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := rdb.ZAdd(ctx, "bench:zset", members...).Err(); err != nil {
+					b.Fatalf("Error in ZADD: %v", err)
+				}
+			}
+			reportOps(b, n)
+		})
+		rdb.Del(ctx, "bench:zset")
+	}
+}
+
+// BenchmarkXAdd compares unbounded XADD with exact and approximate MAXLEN trimming
+func BenchmarkXAdd(b *testing.B) {
+	rdb := benchClient(b)
+	ctx := context.Background()
+	defer rdb.Del(ctx, "bench:stream")
+
+	cases := []struct {
+		name   string
+		maxLen int64
+		approx bool
+	}{
+		{"unbounded", 0, false},
+		{"maxlen=1000", 1000, false},
+		{"maxlen~1000", 1000, true},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				err := rdb.XAdd(ctx, &redis.XAddArgs{
+					Stream: "bench:stream",
+					MaxLen: c.maxLen,
+					Approx: c.approx,
+					Values: []interface{}{"type", "click", "user", i},
+				}).Err()
+				if err != nil {
+					b.Fatalf("Error in XADD: %v", err)
+				}
+			}
+			reportOps(b, 1)
+		})
+		rdb.Del(ctx, "bench:stream")
+	}
+}
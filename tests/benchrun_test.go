@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Redis/benchrun"
+)
+
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: Redis/tests
+cpu: Example CPU @ 3.00GHz
+BenchmarkWrites/single-8         	     200	   3000000 ns/op	     33000 ops/s	   25000 B/op	     700 allocs/op
+BenchmarkWrites/single-8         	     200	   5000000 ns/op	     19000 ops/s	   25000 B/op	     700 allocs/op
+BenchmarkWrites/pipelined-8      	    2000	    400000 ns/op	    250000 ops/s	   30000 B/op	     900 allocs/op
+PASS
+ok  	Redis/tests	4.512s
+`
+
+// TestBenchRunParse tests parsing go test -bench output and averaging repeated runs
+func TestBenchRunParse(t *testing.T) {
+	run, err := benchrun.Parse(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("Error parsing benchmark output: %v", err)
+	}
+	if run.Env["pkg"] != "Redis/tests" || run.Env["cpu"] != "Example CPU @ 3.00GHz" {
+		t.Errorf("Unexpected env %v", run.Env)
+	}
+	if len(run.Benchmarks) != 2 {
+		t.Fatalf("Expected 2 benchmarks, got %d", len(run.Benchmarks))
+	}
+
+	single, ok := run.Get("BenchmarkWrites/single")
+	if !ok {
+		t.Fatalf("Expected BenchmarkWrites/single without the GOMAXPROCS suffix")
+	}
+	if single.Runs != 2 || single.Metrics[benchrun.NsPerOp] != 4000000 || single.Metrics[benchrun.OpsPerSec] != 26000 {
+		t.Errorf("Unexpected averaged result %+v", single)
+	}
+
+	path := filepath.Join(t.TempDir(), "bench.json")
+	if err := run.Save(path); err != nil {
+		t.Fatalf("Error saving results: %v", err)
+	}
+	loaded, err := benchrun.Load(path)
+	if err != nil {
+		t.Fatalf("Error loading results: %v", err)
+	}
+	if b, _ := loaded.Get("BenchmarkWrites/pipelined"); b.Metrics[benchrun.AllocsPerOp] != 900 {
+		t.Errorf("Unexpected loaded result %+v", b)
+	}
+}
+
+// TestBenchRunCompare tests regression detection against a baseline
+func TestBenchRunCompare(t *testing.T) {
+	base, err := benchrun.Parse(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("Error parsing benchmark output: %v", err)
+	}
+	// -count=3 averages single to 700.33 allocs/op, which is noise rather than a regression
+	cur, err := benchrun.Parse(strings.NewReader(`BenchmarkWrites/single-8 200 4200000 ns/op 25000 ops/s 25000 B/op 700 allocs/op
+BenchmarkWrites/single-8 200 4200000 ns/op 25000 ops/s 25000 B/op 701 allocs/op
+BenchmarkWrites/single-8 200 4200000 ns/op 25000 ops/s 25000 B/op 700 allocs/op
+BenchmarkWrites/pipelined-8 1000 600000 ns/op 166000 ops/s 30000 B/op 900 allocs/op
+BenchmarkMSet/mset/keys=10-8 5000 200000 ns/op 50000 ops/s 2000 B/op 50 allocs/op
+`))
+	if err != nil {
+		t.Fatalf("Error parsing benchmark output: %v", err)
+	}
+
+	deltas := benchrun.Compare(base, cur, 10)
+	if len(deltas) != 3 {
+		t.Fatalf("Expected 3 deltas, got %d", len(deltas))
+	}
+	if deltas[0].Regressed {
+		t.Errorf("Expected a 5%% slowdown and a fraction of an alloc to stay under the threshold")
+	}
+	if !deltas[1].Regressed || deltas[1].Change[benchrun.NsPerOp] != 50 {
+		t.Errorf("Expected pipelined to regress by 50%%, got %+v", deltas[1].Change)
+	}
+	if !deltas[2].Added {
+		t.Errorf("Expected the MSET benchmark to be reported as new")
+	}
+	if n := benchrun.Regressions(deltas); n != 1 {
+		t.Errorf("Expected 1 regression, got %d", n)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"Redis/bloom"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bloomItems returns n distinct item names
+func bloomItems(prefix string, n int) []string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf("%s:%d", prefix, i)
+	}
+	return items
+}
+
+// countTrue counts the true values in results
+func countTrue(results []bool) int {
+	n := 0
+	for _, ok := range results {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// TestBloomFalsePositiveRate tests that a filter finds every added item and stays near its target false-positive rate
+func TestBloomFalsePositiveRate(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Del(ctx, "bloom:test", "bloom:test:meta")
+
+	const n, rate = 10000, 0.01
+	f, err := bloom.New(ctx, rdb, "bloom:test", bloom.Config{Capacity: n, ErrorRate: rate})
+	if err != nil {
+		t.Fatalf("Error creating filter: %v", err)
+	}
+	if bits, hashes := bloom.Optimal(n, rate); f.Bits() != bits || f.Hashes() != hashes || hashes != 7 {
+		t.Errorf("Expected %d bits and 7 hashes, got %d and %d", bits, f.Bits(), f.Hashes())
+	}
+
+	added := bloomItems("user", n)
+	isNew, err := f.Add(ctx, added...)
+	if err != nil {
+		t.Fatalf("Error adding items: %v", err)
+	}
+	if fresh := countTrue(isNew); fresh < n*99/100 {
+		t.Errorf("Expected nearly all items to be new, got %d of %d", fresh, n)
+	}
+	if again, _ := f.Add(ctx, added[:100]...); countTrue(again) != 0 {
+		t.Error("Expected re-added items to be reported as seen")
+	}
+
+	found, err := f.Exists(ctx, added...)
+	if err != nil {
+		t.Fatalf("Error checking items: %v", err)
+	}
+	if countTrue(found) != n {
+		t.Errorf("Expected no false negatives, found %d of %d", countTrue(found), n)
+	}
+
+	found, _ = f.Exists(ctx, bloomItems("stranger", n)...)
+	fp := float64(countTrue(found)) / n
+	t.Logf("False-positive rate %.4f for a target of %.2f", fp, rate)
+	if fp > 2*rate {
+		t.Errorf("Expected a false-positive rate near %.2f, got %.4f", rate, fp)
+	}
+
+	if count, _ := f.Count(ctx); count < n*95/100 || count > n*105/100 {
+		t.Errorf("Expected about %d items counted, got %d", n, count)
+	}
+
+	// Another process opening the key gets the stored size, whatever it asks for
+	other, err := bloom.New(ctx, rdb, "bloom:test", bloom.Config{Capacity: 10})
+	if err != nil || other.Bits() != f.Bits() {
+		t.Errorf("Expected the existing filter's size, got %d bits (%v)", other.Bits(), err)
+	}
+	if _, err := bloom.NewCounting(ctx, rdb, "bloom:test", bloom.Config{}); err == nil {
+		t.Error("Expected opening a Bloom filter as a counting filter to fail")
+	}
+
+	// Cleanup
+	f.Delete(ctx)
+}
+
+// TestScalableBloom tests a scalable filter growing past its initial capacity while keeping its error rate
+func TestScalableBloom(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	const rate = 0.01
+	s, err := bloom.NewScalable(ctx, rdb, "bloom:scalable", bloom.ScalableConfig{Config: bloom.Config{Capacity: 1000, ErrorRate: rate}})
+	if err != nil {
+		t.Fatalf("Error creating scalable filter: %v", err)
+	}
+	s.Delete(ctx)
+	s, _ = bloom.NewScalable(ctx, rdb, "bloom:scalable", bloom.ScalableConfig{Config: bloom.Config{Capacity: 1000, ErrorRate: rate}})
+
+	added := bloomItems("visitor", 10000)
+	for start := 0; start < len(added); start += 2500 {
+		if _, err := s.Add(ctx, added[start:start+2500]...); err != nil {
+			t.Fatalf("Error adding items: %v", err)
+		}
+	}
+	// Capacities 1000, 2000, 4000, 8000
+	if layers, _ := s.Layers(ctx); layers != 4 {
+		t.Errorf("Expected 4 layers, got %d", layers)
+	}
+	if count, _ := s.Count(ctx); count < 9900 || count > 10000 {
+		t.Errorf("Expected about 10000 items, got %d", count)
+	}
+
+	found, _ := s.Exists(ctx, added...)
+	if countTrue(found) != len(added) {
+		t.Errorf("Expected no false negatives, found %d of %d", countTrue(found), len(added))
+	}
+	found, _ = s.Exists(ctx, bloomItems("stranger", 10000)...)
+	fp := float64(countTrue(found)) / 10000
+	t.Logf("False-positive rate %.4f across layers for a target of %.2f", fp, rate)
+	if fp > 2*rate {
+		t.Errorf("Expected a false-positive rate near %.2f, got %.4f", rate, fp)
+	}
+
+	// Another process sees the same layers
+	other, err := bloom.NewScalable(ctx, rdb, "bloom:scalable", bloom.ScalableConfig{Config: bloom.Config{Capacity: 1000, ErrorRate: rate}})
+	if err != nil {
+		t.Fatalf("Error opening scalable filter: %v", err)
+	}
+	if found, _ := other.Exists(ctx, "visitor:9999"); !found[0] {
+		t.Error("Expected the newest layer to be opened by another process")
+	}
+
+	// Cleanup
+	s.Delete(ctx)
+}
+
+// TestCountingBloom tests adding, checking and removing items in a counting filter
+func TestCountingBloom(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Del(ctx, "bloom:counting", "bloom:counting:meta")
+
+	const n, rate = 5000, 0.01
+	c, err := bloom.NewCounting(ctx, rdb, "bloom:counting", bloom.Config{Capacity: n, ErrorRate: rate})
+	if err != nil {
+		t.Fatalf("Error creating counting filter: %v", err)
+	}
+	added := bloomItems("session", n)
+	if _, err := c.Add(ctx, added...); err != nil {
+		t.Fatalf("Error adding items: %v", err)
+	}
+	found, _ := c.Exists(ctx, added...)
+	if countTrue(found) != n {
+		t.Errorf("Expected no false negatives, found %d of %d", countTrue(found), n)
+	}
+	found, _ = c.Exists(ctx, bloomItems("stranger", n)...)
+	fp := float64(countTrue(found)) / n
+	t.Logf("False-positive rate %.4f for a target of %.2f", fp, rate)
+	if fp > 2*rate {
+		t.Errorf("Expected a false-positive rate near %.2f, got %.4f", rate, fp)
+	}
+
+	// Removing half leaves the other half findable
+	removed, err := c.Remove(ctx, added[:n/2]...)
+	if err != nil {
+		t.Fatalf("Error removing items: %v", err)
+	}
+	if countTrue(removed) != n/2 {
+		t.Errorf("Expected %d items removed, got %d", n/2, countTrue(removed))
+	}
+	found, _ = c.Exists(ctx, added[:n/2]...)
+	if gone := n/2 - countTrue(found); gone < n/2*95/100 {
+		t.Errorf("Expected removed items to be gone, %d of %d still found", countTrue(found), n/2)
+	}
+	found, _ = c.Exists(ctx, added[n/2:]...)
+	if countTrue(found) != n/2 {
+		t.Errorf("Expected the remaining items to stay, found %d of %d", countTrue(found), n/2)
+	}
+	if removed, _ := c.Remove(ctx, "never-added"); removed[0] {
+		t.Error("Expected removing an item that was never added to report false")
+	}
+
+	// Cleanup
+	c.Delete(ctx)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/capture"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const sampleCapture = `OK
+1700000000.000000 [0 127.0.0.1:50001] "set" "capture:user:1" "Alice"
+1700000000.250000 [0 127.0.0.1:50001] "get" "capture:user:1"
+1700000000.500000 [0 127.0.0.1:50002] "hset" "capture:cart:1" "item" "say \"hi\"\n\xff"
+1700000000.600000 [0 127.0.0.1:50002] "multi"
+1700000000.650000 [0 127.0.0.1:50001] "get" "capture:user:1"
+1700000000.700000 [0 127.0.0.1:50002] "incr" "capture:counter"
+1700000000.700000 [0 127.0.0.1:50002] "incr" "capture:counter"
+1700000000.750000 [0 127.0.0.1:50002] "exec"
+1700000000.800000 [0 lua] "get" "capture:user:1"
+1700000001.000000 [0 127.0.0.1:50003] "blpop" "capture:queue" "0"
+`
+
+// TestCaptureParse tests parsing MONITOR lines and formatting them back
+func TestCaptureParse(t *testing.T) {
+	var entries []capture.Entry
+	err := capture.Read(strings.NewReader(sampleCapture), func(e capture.Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error reading capture: %v", err)
+	}
+	if len(entries) != 10 {
+		t.Fatalf("Expected 10 entries, got %d", len(entries))
+	}
+
+	hset := entries[2]
+	if hset.Client != "127.0.0.1:50002" || hset.DB != 0 || hset.Name() != "hset" {
+		t.Errorf("Unexpected entry %+v", hset)
+	}
+	if got := hset.Args[3]; got != "say \"hi\"\n\xff" {
+		t.Errorf("Expected escapes to be decoded, got %q", got)
+	}
+	if got := hset.Time.Sub(entries[0].Time); got != 500*time.Millisecond {
+		t.Errorf("Expected 500ms between entries, got %v", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(sampleCapture), "\n")[1:]
+	for i, e := range entries {
+		if e.String() != lines[i] {
+			t.Errorf("Expected %s to round-trip, got %s", lines[i], e.String())
+		}
+	}
+
+	ipv6, err := capture.ParseLine(`1700000000.1 [3 [::1]:6000] "ping"`)
+	if err != nil || ipv6.DB != 3 || ipv6.Client != "[::1]:6000" || ipv6.Time.Nanosecond() != 100000000 {
+		t.Errorf("Unexpected IPv6 entry %+v: %v", ipv6, err)
+	}
+}
+
+// TestCaptureAnalyze tests top commands, key prefixes, client rates and the read/write ratio
+func TestCaptureAnalyze(t *testing.T) {
+	analyzer := capture.NewAnalyzer(":")
+	capture.Read(strings.NewReader(sampleCapture), func(e capture.Entry) error {
+		analyzer.Add(e)
+		return nil
+	})
+	r := analyzer.Report(3)
+
+	if r.Total != 10 || r.Duration != time.Second || r.PerSec != 10 {
+		t.Errorf("Expected 10 commands over 1s, got %d over %v (%.1f/s)", r.Total, r.Duration, r.PerSec)
+	}
+	if r.Reads != 3 || r.Writes != 5 || r.Other != 2 || r.ReadWriteRatio != 0.6 {
+		t.Errorf("Expected 3 reads, 5 writes, 2 other, got %d/%d/%d ratio %.2f", r.Reads, r.Writes, r.Other, r.ReadWriteRatio)
+	}
+	if len(r.Commands) != 3 || r.Commands[0].Name != "get" || r.Commands[0].Count != 3 {
+		t.Errorf("Expected get to be the top command, got %+v", r.Commands)
+	}
+	if len(r.Prefixes) != 1 || r.Prefixes[0].Name != "capture:*" || r.Prefixes[0].Count != 8 {
+		t.Errorf("Expected 8 accesses under capture:*, got %+v", r.Prefixes)
+	}
+	if capture.Prefix("user:1001:cart", ":") != "user:*" || capture.Prefix("game_leaderboard", ":") != "game_leaderboard" {
+		t.Errorf("Unexpected prefixes")
+	}
+	kinds := map[string]capture.Kind{
+		"SET": capture.WriteCommand, "xgroup": capture.WriteCommand, "ZRANGE": capture.ReadCommand,
+		"xread": capture.ReadCommand, "PING": capture.OtherCommand, "NOSUCH": capture.OtherCommand,
+	}
+	for name, want := range kinds {
+		if got := capture.Classify(name); got != want {
+			t.Errorf("Expected %s to be classified %v, got %v", name, want, got)
+		}
+	}
+
+	client := r.Clients[0]
+	if client.Client != "127.0.0.1:50002" || client.Commands != 5 || client.PerSec != 5 || client.Top != "incr" {
+		t.Errorf("Expected 127.0.0.1:50002 to be the busiest client, got %+v", client)
+	}
+}
+
+// TestCaptureReplay tests replaying a capture, including a transaction and skipped commands
+func TestCaptureReplay(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Del(ctx, "capture:user:1", "capture:cart:1", "capture:counter")
+
+	start := time.Now()
+	stats, err := capture.Replay(ctx, rdb, strings.NewReader(sampleCapture), capture.ReplayOptions{Speed: 4})
+	if err != nil {
+		t.Fatalf("Error replaying: %v", err)
+	}
+	// The last replayed command (EXEC) comes 750ms in, so 4x takes about 190ms
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("Expected the replay to keep the scaled pace, took %v", elapsed)
+	}
+	if stats.Commands != 6 || stats.Transactions != 1 || stats.Skipped != 2 || stats.Errors != 0 {
+		t.Errorf("Expected 6 commands, 1 transaction and 2 skipped, got %+v", stats)
+	}
+
+	if v, _ := rdb.Get(ctx, "capture:user:1").Result(); v != "Alice" {
+		t.Errorf("Expected Alice, got %q", v)
+	}
+	if v, _ := rdb.Get(ctx, "capture:counter").Int(); v != 2 {
+		t.Errorf("Expected counter 2, got %d", v)
+	}
+	if v, _ := rdb.HGet(ctx, "capture:cart:1", "item").Result(); v != "say \"hi\"\n\xff" {
+		t.Errorf("Expected binary-safe value, got %q", v)
+	}
+
+	// An unfinished transaction is not replayed
+	open := "1700000000.0 [0 127.0.0.1:50009] \"multi\"\n1700000000.1 [0 127.0.0.1:50009] \"incr\" \"capture:counter\"\n"
+	stats, err = capture.Replay(ctx, rdb, strings.NewReader(open), capture.ReplayOptions{})
+	if err != nil || stats.Discarded != 1 || stats.Commands != 0 {
+		t.Errorf("Expected 1 discarded command, got %+v: %v", stats, err)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "capture:user:1", "capture:cart:1", "capture:counter")
+}
+
+// TestCaptureReplayUnreachable tests that a replay into an unreachable server fails instead of counting commands as sent
+func TestCaptureReplayUnreachable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: closedAddr(t), MaxRetries: -1})
+	defer rdb.Close()
+
+	stats, err := capture.Replay(context.Background(), rdb, strings.NewReader(sampleCapture), capture.ReplayOptions{})
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("Expected the connection error, got %v", err)
+	}
+	if stats.Commands != 0 {
+		t.Errorf("Expected nothing counted as sent, got %+v", stats)
+	}
+}
+
+// TestCaptureRecord tests recording live MONITOR output
+func TestCaptureRecord(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan capture.Entry, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- capture.Record(ctx, rdb, func(e capture.Entry) error {
+			if e.Name() == "set" && len(e.Args) == 3 && e.Args[1] == "capture:record" {
+				select {
+				case entries <- e:
+				default:
+				}
+			}
+			return nil
+		})
+	}()
+
+	// MONITOR only sees commands issued after it starts; keep writing until
+	// the first one arrives
+	deadline := time.After(5 * time.Second)
+	for {
+		rdb.Set(ctx, "capture:record", "value", 0)
+		select {
+		case err := <-done:
+			if err != nil && strings.Contains(err.Error(), "unknown command") {
+				t.Skipf("Server does not support MONITOR: %v", err)
+			}
+			t.Fatalf("Error recording: %v", err)
+		case e := <-entries:
+			if e.Args[2] != "value" || e.Client == "" || e.Time.IsZero() {
+				t.Errorf("Expected a complete entry for the SET, got %v", e)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Expected a clean stop, got %v", err)
+			}
+			rdb.Del(context.Background(), "capture:record")
+			return
+		case <-deadline:
+			t.Fatalf("No command captured within 5s")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
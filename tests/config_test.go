@@ -0,0 +1,170 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"Redis/redisconf"
+)
+
+// findingRules returns the rules that fired, by name
+func findingRules(findings []redisconf.Finding) map[string]redisconf.Severity {
+	rules := make(map[string]redisconf.Severity)
+	for _, f := range findings {
+		rules[f.Rule] = f.Severity
+	}
+	return rules
+}
+
+// TestConfigParse tests directives, quoting and memory units
+func TestConfigParse(t *testing.T) {
+	conf, err := redisconf.Parse(strings.NewReader(`
+# comment
+logfile ""
+requirepass "pa ss\"word"
+rename-command CONFIG 'cfg\'x'
+save 900 1
+save 300 10
+MaxMemory 256mb
+`))
+	if err != nil {
+		t.Fatalf("Error parsing config: %v", err)
+	}
+	if len(conf.Directives) != 6 {
+		t.Fatalf("Expected 6 directives, got %d", len(conf.Directives))
+	}
+	if d, ok := conf.Lookup("logfile"); !ok || len(d.Args) != 1 || d.Args[0] != "" || d.Line != 3 {
+		t.Errorf("Expected an empty logfile on line 3, got %+v", d)
+	}
+	if got := conf.Get("requirepass"); got != `pa ss"word` {
+		t.Errorf("Expected the quoted password to be unescaped, got %q", got)
+	}
+	if got := conf.Get("rename-command"); got != "CONFIG cfg'x" {
+		t.Errorf("Expected single-quoted argument, got %q", got)
+	}
+	if got := conf.Get("maxmemory"); got != "256mb" {
+		t.Errorf("Expected names to be lowercased, got %q", got)
+	}
+	if len(conf.All("save")) != 2 {
+		t.Errorf("Expected 2 save lines")
+	}
+
+	for in, want := range map[string]int64{"256mb": 256 << 20, "1k": 1000, "1KB": 1024, "2g": 2e9, "100": 100} {
+		if got, ok := redisconf.ParseMemory(in); !ok || got != want {
+			t.Errorf("Expected %s to be %d bytes, got %d", in, want, got)
+		}
+	}
+
+	if _, err := redisconf.Parse(strings.NewReader(`requirepass "unterminated`)); err == nil {
+		t.Errorf("Expected an error for unbalanced quotes")
+	}
+}
+
+// TestConfigLint tests the risky combinations in the project's redis.conf and a crafted one
+func TestConfigLint(t *testing.T) {
+	conf, err := redisconf.ParseFile("../scripts/redis.conf")
+	if err != nil {
+		t.Fatalf("Error parsing redis.conf: %v", err)
+	}
+	rules := findingRules(redisconf.Lint(conf))
+	if rules["no-auth-open-bind"] != redisconf.Critical {
+		t.Errorf("Expected bind 0.0.0.0 with protected-mode no and no password to be critical, got %v", rules)
+	}
+	if _, ok := rules["maxmemory-noeviction"]; ok {
+		t.Errorf("Expected allkeys-lru to satisfy the eviction rule")
+	}
+
+	conf, _ = redisconf.Parse(strings.NewReader(`
+bind 127.0.0.1 -::1
+requirepass short
+maxmemory 1gb
+appendonly yes
+auto-aof-rewrite-percentage 0
+appendfsync no
+client-output-buffer-limit pubsub 0 0 0
+timeout 0
+timeout 300
+`))
+	findings := redisconf.Lint(conf)
+	rules = findingRules(findings)
+	for rule, sev := range map[string]redisconf.Severity{
+		"weak-password":           redisconf.Warning,
+		"maxmemory-noeviction":    redisconf.Warning,
+		"aof-no-rewrite":          redisconf.Warning,
+		"appendfsync-no":          redisconf.Warning,
+		"unbounded-output-buffer": redisconf.Warning,
+		"duplicate":               redisconf.Warning,
+	} {
+		if got, ok := rules[rule]; !ok || got != sev {
+			t.Errorf("Expected %s at %v, got %v", rule, sev, rules)
+		}
+	}
+	if _, ok := rules["no-auth-open-bind"]; ok {
+		t.Errorf("Expected loopback bind with a password not to be flagged")
+	}
+	for i := 1; i < len(findings); i++ {
+		if findings[i].Severity > findings[i-1].Severity {
+			t.Errorf("Expected findings sorted by severity")
+		}
+	}
+
+	// ACL users count as auth; no bind means every interface
+	conf, _ = redisconf.Parse(strings.NewReader("user default on nopass ~* +@all\n"))
+	if rules := findingRules(redisconf.Lint(conf)); rules["no-auth-open-bind"] != redisconf.Warning {
+		t.Errorf("Expected a passwordless default user on all interfaces to be flagged, got %v", rules)
+	}
+	conf, _ = redisconf.Parse(strings.NewReader("user default on >0123456789abcdef0123 ~* +@all\n"))
+	if rules := findingRules(redisconf.Lint(conf)); len(rules) != 0 {
+		t.Errorf("Expected a default user with a password to pass, got %v", rules)
+	}
+}
+
+// TestConfigDrift tests comparing a file with CONFIG GET output
+func TestConfigDrift(t *testing.T) {
+	conf, err := redisconf.ParseFile("../scripts/redis.conf")
+	if err != nil {
+		t.Fatalf("Error parsing redis.conf: %v", err)
+	}
+
+	// What CONFIG GET * returns for the file, normalised the server's way
+	server := map[string]string{
+		"bind": "0.0.0.0", "port": "6379", "protected-mode": "no", "daemonize": "no",
+		"supervised": "no", "pidfile": "/var/run/redis_6379.pid", "loglevel": "notice", "logfile": "",
+		"save": "900 1 300 10 60 10000", "maxmemory": "268435456", "maxmemory-policy": "allkeys-lru",
+		"appendonly": "yes", "appendfilename": "appendonly.aof", "appendfsync": "everysec",
+		"slowlog-log-slower-than": "10000", "slowlog-max-len": "128", "latency-monitor-threshold": "100",
+		"notify-keyspace-events": "xeE", "tcp-keepalive": "300", "timeout": "0", "tcp-backlog": "511",
+		"client-output-buffer-limit": "normal 0 0 0 slave 268435456 67108864 60 pubsub 33554432 8388608 60",
+	}
+	if drift := redisconf.Compare(conf, server); len(drift) != 0 {
+		t.Fatalf("Expected no drift, got %+v", drift)
+	}
+
+	server["maxmemory-policy"] = "volatile-lru"
+	server["client-output-buffer-limit"] = "normal 0 0 0 slave 268435456 67108864 60 pubsub 0 0 0"
+	delete(server, "latency-monitor-threshold")
+	drift := redisconf.Compare(conf, server)
+	if len(drift) != 3 {
+		t.Fatalf("Expected 3 drifted settings, got %+v", drift)
+	}
+	got := make(map[string]redisconf.Drift)
+	for _, d := range drift {
+		got[d.Name] = d
+	}
+	if d := got["maxmemory-policy"]; d.Kind != redisconf.Changed || d.File != "allkeys-lru" || d.Server != "volatile-lru" {
+		t.Errorf("Unexpected maxmemory-policy drift %+v", d)
+	}
+	if d := got["client-output-buffer-limit"]; d.Kind != redisconf.Changed {
+		t.Errorf("Expected the pubsub limit change to be reported, got %+v", d)
+	}
+	if d := got["latency-monitor-threshold"]; d.Kind != redisconf.Unknown {
+		t.Errorf("Expected a setting the server does not report to be unknown, got %+v", d)
+	}
+
+	// Renamed options and secrets
+	conf, _ = redisconf.Parse(strings.NewReader("slave-read-only yes\nrequirepass secret\n"))
+	drift = redisconf.Compare(conf, map[string]string{"replica-read-only": "yes", "requirepass": "other"})
+	if len(drift) != 1 || drift[0].File != "(hidden)" || drift[0].Server != "(hidden)" {
+		t.Errorf("Expected only a hidden requirepass drift, got %+v", drift)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/dataset"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestDatasetRoundTrip tests export followed by import under a new prefix
+func TestDatasetRoundTrip(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
+	})
+	defer rdb.Close()
+
+	ctx := context.Background()
+
+	// Setup one key of every supported type
+	rdb.Set(ctx, "dump:string", "hello", time.Minute)
+	rdb.Set(ctx, "dump:binary", "\xff\xfe\x00raw", 0)
+	rdb.HSet(ctx, "dump:hash", "name", "Alice", "city", "Paris")
+	rdb.RPush(ctx, "dump:list", "a", "b", "c")
+	rdb.SAdd(ctx, "dump:set", "x", "y")
+	rdb.ZAdd(ctx, "dump:zset", redis.Z{Score: 1.5, Member: "one"}, redis.Z{Score: 2, Member: "two"})
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "dump:stream", ID: "1-1", Values: []interface{}{"b", "2", "a", "1"}})
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "dump:stream", ID: "2-0", Values: []interface{}{"c", "3"}})
+
+	var buf bytes.Buffer
+	stats, err := dataset.Export(ctx, rdb, &buf, dataset.ExportOptions{Patterns: []string{"dump:*"}})
+	if err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	if stats.Keys != 7 {
+		t.Errorf("Expected 7 exported keys, got %d", stats.Keys)
+	}
+
+	_, err = dataset.Import(ctx, rdb, bytes.NewReader(buf.Bytes()), dataset.ImportOptions{
+		Prefixes: []dataset.PrefixRewrite{{From: "dump:", To: "restored:"}},
+		Rename:   map[string]string{"dump:list": "renamed:list"},
+	})
+	if err != nil {
+		t.Fatalf("Error importing: %v", err)
+	}
+
+	// Verify restored values
+	if val, _ := rdb.Get(ctx, "restored:binary").Result(); val != "\xff\xfe\x00raw" {
+		t.Errorf("Expected binary value to survive, got %q", val)
+	}
+	if ttl, _ := rdb.TTL(ctx, "restored:string").Result(); ttl <= 0 {
+		t.Errorf("Expected TTL to be preserved, got %v", ttl)
+	}
+	if list, _ := rdb.LRange(ctx, "renamed:list", 0, -1).Result(); !reflect.DeepEqual(list, []string{"a", "b", "c"}) {
+		t.Errorf("Expected renamed list [a b c], got %v", list)
+	}
+	if score, _ := rdb.ZScore(ctx, "restored:zset", "one").Result(); score != 1.5 {
+		t.Errorf("Expected score 1.5, got %v", score)
+	}
+	entries, _ := rdb.XRange(ctx, "restored:stream", "-", "+").Result()
+	if len(entries) != 2 || entries[0].ID != "1-1" || entries[0].Values["a"] != "1" {
+		t.Errorf("Expected stream entries with original IDs, got %v", entries)
+	}
+
+	// Without -replace, existing keys are skipped
+	stats, err = dataset.Import(ctx, rdb, bytes.NewReader(buf.Bytes()), dataset.ImportOptions{
+		Prefixes: []dataset.PrefixRewrite{{From: "dump:", To: "restored:"}},
+	})
+	if err != nil {
+		t.Fatalf("Error re-importing: %v", err)
+	}
+	if stats.Skipped != 6 {
+		t.Errorf("Expected 6 skipped keys, got %d", stats.Skipped)
+	}
+
+	// Cleanup
+	for _, pattern := range []string{"dump:*", "restored:*", "renamed:*"} {
+		keys, _ := rdb.Keys(ctx, pattern).Result()
+		if len(keys) > 0 {
+			rdb.Del(ctx, keys...)
+		}
+	}
+}
+
+// changeBeforeRead runs change just before the first pipeline that reads values, after key types are known
+type changeBeforeRead struct {
+	change func()
+	done   bool
+}
+
+func (h *changeBeforeRead) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *changeBeforeRead) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *changeBeforeRead) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.done && len(cmds) > 0 && cmds[0].Name() != "type" {
+			h.done = true
+			h.change()
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// TestDatasetExportVanishedKeys tests that keys deleted or retyped while exporting are dropped rather than failing the export
+func TestDatasetExportVanishedKeys(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	other := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer other.Close()
+	ctx := context.Background()
+
+	rdb.Set(ctx, "vanish:keep", "stays", 0)
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "vanish:retyped", Values: []interface{}{"a", "1"}})
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "vanish:deleted", Values: []interface{}{"a", "1"}})
+	rdb.HSet(ctx, "vanish:hash", "name", "Alice")
+	rdb.AddHook(&changeBeforeRead{change: func() {
+		other.Del(ctx, "vanish:retyped", "vanish:deleted", "vanish:hash")
+		other.Set(ctx, "vanish:retyped", "now a string", 0)
+	}})
+
+	var buf bytes.Buffer
+	stats, err := dataset.Export(ctx, rdb, &buf, dataset.ExportOptions{Patterns: []string{"vanish:*"}})
+	if err != nil {
+		t.Fatalf("Error exporting: %v", err)
+	}
+	// SCAN may find vanish:retyped again, as a string, in a later batch
+	out := buf.String()
+	if !strings.Contains(out, "vanish:keep") || stats.Keys > 2 {
+		t.Errorf("Expected vanish:keep exported, got %d keys: %s", stats.Keys, out)
+	}
+	for _, key := range []string{"vanish:deleted", "vanish:hash"} {
+		if strings.Contains(out, key) {
+			t.Errorf("Expected %s to be skipped once deleted, got: %s", key, out)
+		}
+	}
+
+	// Cleanup
+	other.Del(ctx, "vanish:keep", "vanish:retyped")
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/gateway"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gatewayCall sends a JSON request and decodes the JSON reply
+func gatewayCall(t *testing.T, srv *httptest.Server, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req, _ := http.NewRequest(method, srv.URL+path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error calling %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	var reply map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil && err != io.EOF {
+		t.Fatalf("Error decoding %s %s reply (status %d): %v", method, path, resp.StatusCode, err)
+	}
+	return resp.StatusCode, reply
+}
+
+// jsonPath walks decoded JSON by object keys and array indexes, returning
+// nil when any step is missing rather than panicking
+func jsonPath(v any, path ...any) any {
+	for _, step := range path {
+		switch step := step.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil
+			}
+			v = obj[step]
+		case int:
+			arr, ok := v.([]any)
+			if !ok || step >= len(arr) {
+				return nil
+			}
+			v = arr[step]
+		}
+	}
+	return v
+}
+
+// TestGatewayDataStructures tests the JSON endpoints for each data type, with TTLs
+func TestGatewayDataStructures(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	srv := httptest.NewServer(gateway.New(rdb, gateway.Config{}))
+	defer srv.Close()
+	keys := []string{"gateway:string", "gateway:counter", "gateway:hash", "gateway:list", "gateway:set", "gateway:zset", "gateway:stream"}
+	rdb.Del(ctx, keys...)
+
+	// Strings
+	if status, reply := gatewayCall(t, srv, "PUT", "/string/gateway:string", map[string]any{"value": "hello", "ttl_seconds": 60}); status != 200 || reply["updated"] != true {
+		t.Errorf("Expected the string set, got %d %v", status, reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/string/gateway:string", nil); reply["value"] != "hello" {
+		t.Errorf("Expected hello, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "PUT", "/string/gateway:string", map[string]any{"value": "again", "only_if": "absent"}); reply["updated"] != false {
+		t.Errorf("Expected NX not to overwrite, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/keys/gateway:string", nil); reply["type"] != "string" || reply["ttl_seconds"] != 60.0 {
+		t.Errorf("Expected a string with a 60s TTL, got %v", reply)
+	}
+	gatewayCall(t, srv, "POST", "/string/gateway:counter/incr", nil)
+	if _, reply := gatewayCall(t, srv, "POST", "/string/gateway:counter/incr", map[string]any{"by": 5}); reply["value"] != 6.0 {
+		t.Errorf("Expected the counter at 6, got %v", reply)
+	}
+
+	// Hashes
+	gatewayCall(t, srv, "PUT", "/hash/gateway:hash", map[string]any{"fields": map[string]string{"name": "Alice", "visits": "1"}})
+	if _, reply := gatewayCall(t, srv, "POST", "/hash/gateway:hash/incr", map[string]any{"field": "visits"}); reply["value"] != 2.0 {
+		t.Errorf("Expected visits at 2, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/hash/gateway:hash", nil); jsonPath(reply, "fields", "name") != "Alice" {
+		t.Errorf("Expected the hash fields, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/hash/gateway:hash/visits", nil); reply["value"] != "2" {
+		t.Errorf("Expected the visits field, got %v", reply)
+	}
+
+	// Lists
+	gatewayCall(t, srv, "POST", "/list/gateway:list/push", map[string]any{"values": []string{"a", "b", "c"}})
+	if _, reply := gatewayCall(t, srv, "GET", "/list/gateway:list?start=1", nil); jsonPath(reply, "items", 1) == nil || jsonPath(reply, "items", 2) != nil {
+		t.Errorf("Expected 2 items from index 1, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "POST", "/list/gateway:list/pop", map[string]any{"side": "right"}); jsonPath(reply, "items", 0) != "c" {
+		t.Errorf("Expected c popped from the right, got %v", reply)
+	}
+
+	// Sets
+	if _, reply := gatewayCall(t, srv, "POST", "/set/gateway:set", map[string]any{"members": []string{"x", "y", "x"}}); reply["added"] != 2.0 {
+		t.Errorf("Expected 2 members added, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/set/gateway:set/y", nil); reply["is_member"] != true {
+		t.Errorf("Expected y to be a member, got %v", reply)
+	}
+
+	// Sorted sets
+	gatewayCall(t, srv, "POST", "/zset/gateway:zset", map[string]any{"members": []map[string]any{{"member": "alice", "score": 10}, {"member": "bob", "score": 20}}})
+	if _, reply := gatewayCall(t, srv, "POST", "/zset/gateway:zset/incr", map[string]any{"member": "alice", "by": 15}); reply["score"] != 25.0 {
+		t.Errorf("Expected alice at 25, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/zset/gateway:zset?rev=true&stop=0", nil); jsonPath(reply, "members", 0, "member") != "alice" {
+		t.Errorf("Expected alice on top, got %v", reply)
+	}
+	if _, reply := gatewayCall(t, srv, "GET", "/zset/gateway:zset/bob", nil); reply["rank"] != 0.0 {
+		t.Errorf("Expected bob ranked 0 from the bottom, got %v", reply)
+	}
+
+	// Streams
+	_, added := gatewayCall(t, srv, "POST", "/stream/gateway:stream", map[string]any{"values": map[string]string{"event": "login"}})
+	if _, reply := gatewayCall(t, srv, "GET", "/stream/gateway:stream", nil); jsonPath(reply, "entries", 0, "id") != added["id"] {
+		t.Errorf("Expected the added entry, got %v", reply)
+	}
+
+	// TTLs and deletion
+	gatewayCall(t, srv, "PUT", "/keys/gateway:hash/ttl", map[string]any{"ttl_seconds": 30})
+	if ttl := rdb.TTL(ctx, "gateway:hash").Val(); ttl <= 0 || ttl > 30*time.Second {
+		t.Errorf("Expected a 30s TTL on the hash, got %v", ttl)
+	}
+	gatewayCall(t, srv, "DELETE", "/keys/gateway:hash/ttl", nil)
+	if ttl := rdb.TTL(ctx, "gateway:hash").Val(); ttl != -1 {
+		t.Errorf("Expected the TTL removed, got %v", ttl)
+	}
+	if _, reply := gatewayCall(t, srv, "DELETE", "/keys/gateway:hash", nil); reply["deleted"] != true {
+		t.Errorf("Expected the hash deleted, got %v", reply)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, keys...)
+}
+
+// TestGatewayValidation tests error statuses for invalid requests, missing keys and wrong types
+func TestGatewayValidation(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	srv := httptest.NewServer(gateway.New(rdb, gateway.Config{MaxBody: 1024}))
+	defer srv.Close()
+	rdb.Set(ctx, "gateway:text", "not a number", 0)
+
+	for _, tc := range []struct {
+		method, path string
+		body         any
+		status       int
+		message      string
+	}{
+		{"POST", "/zset/gateway:zset/incr", map[string]any{"by": 1}, 400, "member is required"},
+		{"POST", "/zset/gateway:zset", map[string]any{"members": []map[string]any{{"score": 1}}}, 400, "members[0].member is required"},
+		{"POST", "/list/gateway:list/push", map[string]any{"values": []string{"a"}, "side": "middle"}, 400, "side must be one of left, right"},
+		{"POST", "/list/gateway:list/pop", map[string]any{"count": 5000}, 400, "count must be at most 1000"},
+		{"PUT", "/string/gateway:text", map[string]any{"value": "x", "ttl": 5}, 400, "unknown field"},
+		{"GET", "/list/gateway:list?start=first", nil, 400, "not an integer"},
+		{"PUT", "/string/gateway:text", map[string]any{"value": strings.Repeat("x", 2048)}, 413, "larger than"},
+		{"GET", "/hash/gateway:missing", nil, 404, "gateway:missing not found"},
+		{"GET", "/list/gateway:missing", nil, 404, "not found"},
+		{"GET", "/hash/gateway:text", nil, 409, "WRONGTYPE"},
+		{"POST", "/string/gateway:text/incr", nil, 400, "not an integer"},
+	} {
+		status, reply := gatewayCall(t, srv, tc.method, tc.path, tc.body)
+		msg, _ := reply["error"].(string)
+		if status != tc.status || !strings.Contains(msg, tc.message) {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tc.method, tc.path, tc.status, tc.message, status, msg)
+		}
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "gateway:text", "gateway:zset", "gateway:list")
+}
+
+// readEvent reads Server-Sent Events until one named name and returns its data
+func readEvent(t *testing.T, r *bufio.Reader, name string) map[string]any {
+	t.Helper()
+	event := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading events: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == name:
+			var data map[string]any
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("Error decoding %s event: %v", name, err)
+			}
+			return data
+		}
+	}
+}
+
+// TestGatewayEvents tests following a stream and a Pub/Sub channel over Server-Sent Events
+func TestGatewayEvents(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv := httptest.NewServer(gateway.New(rdb, gateway.Config{KeepAlive: 200 * time.Millisecond}))
+	defer srv.Close()
+	rdb.Del(ctx, "gateway:events")
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "gateway:events", Values: map[string]any{"n": "old"}})
+
+	// The response arrives once the stream is open, so the entry added next is new
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/stream/gateway:events/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error following the stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", ct)
+	}
+	rdb.XAdd(ctx, &redis.XAddArgs{Stream: "gateway:events", Values: map[string]any{"n": "new"}})
+	entry := readEvent(t, bufio.NewReader(resp.Body), "entry")
+	if jsonPath(entry, "values", "n") != "new" {
+		t.Errorf("Expected only the new entry, got %v", entry)
+	}
+
+	req, _ = http.NewRequestWithContext(ctx, "GET", srv.URL+"/pubsub/gateway:chat:*/events?pattern=true", nil)
+	sub, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error subscribing: %v", err)
+	}
+	defer sub.Body.Close()
+	if _, reply := gatewayCall(t, srv, "POST", "/pubsub/gateway:chat:lobby", map[string]any{"message": "hi"}); reply["receivers"] != 1.0 {
+		t.Errorf("Expected the subscriber to receive the message, got %v", reply)
+	}
+	msg := readEvent(t, bufio.NewReader(sub.Body), "message")
+	if msg["channel"] != "gateway:chat:lobby" || msg["message"] != "hi" || msg["pattern"] != "gateway:chat:*" {
+		t.Errorf("Expected the published message, got %v", msg)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "gateway:events")
+}
+
+// TestGatewayOpenAPI tests that the OpenAPI document describes the handlers
+func TestGatewayOpenAPI(t *testing.T) {
+	srv := httptest.NewServer(gateway.New(nil, gateway.Config{AllowOrigins: []string{"http://localhost:3000"}}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("Error fetching the document: %v", err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("Error decoding the document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got %s", doc.OpenAPI)
+	}
+	if op := doc.Paths["/hash/{key}"]["get"]; op["operationId"] != "getHash" {
+		t.Errorf("Expected GET /hash/{key} to be getHash, got %v", op)
+	}
+	incr := doc.Paths["/zset/{key}/incr"]["post"]
+	schema := jsonPath(incr, "requestBody", "content", "application/json", "schema")
+	if jsonPath(schema, "required", 0) != "member" || jsonPath(schema, "required", 1) != nil {
+		t.Errorf("Expected member to be required, got %v", schema)
+	}
+	if jsonPath(doc.Paths["/stream/{key}/events"]["get"], "responses", "200", "content", "text/event-stream") == nil {
+		t.Errorf("Expected the stream events endpoint to respond with an event stream")
+	}
+
+	// CORS preflight from an allowed origin
+	req, _ := http.NewRequest("OPTIONS", srv.URL+"/hash/user:1", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	pre, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending preflight: %v", err)
+	}
+	pre.Body.Close()
+	if pre.StatusCode != http.StatusNoContent || pre.Header.Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("Expected the preflight allowed, got %d %v", pre.StatusCode, pre.Header)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Redis/health"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const sampleInfo = `# Server
+redis_version:7.2.4
+redis_mode:standalone
+uptime_in_seconds:3600
+
+# Clients
+connected_clients:12
+blocked_clients:1
+
+# Memory
+used_memory:1048576
+used_memory_rss:2097152
+maxmemory:0
+maxmemory_policy:noeviction
+mem_fragmentation_ratio:2.00
+
+# Stats
+total_connections_received:100
+total_commands_processed:5000
+instantaneous_ops_per_sec:250
+keyspace_hits:900
+keyspace_misses:100
+evicted_keys:0
+expired_keys:20
+
+# Keyspace
+db0:keys=42,expires=7,avg_ttl=1500
+
+# Commandstats
+cmdstat_get:calls=900,usec=1800,usec_per_call=2.00,rejected_calls=0,failed_calls=1
+`
+
+// TestHealthParseInfo tests parsing INFO text into typed sections
+func TestHealthParseInfo(t *testing.T) {
+	info := health.ParseInfo(sampleInfo)
+
+	if info.Server.Version != "7.2.4" || info.Server.UptimeSeconds != 3600 {
+		t.Errorf("Expected server 7.2.4 up 3600s, got %+v", info.Server)
+	}
+	if info.Clients.Connected != 12 || info.Clients.Blocked != 1 {
+		t.Errorf("Expected 12 clients with 1 blocked, got %+v", info.Clients)
+	}
+	if info.Memory.Used != 1048576 || info.Memory.MaxPolicy != "noeviction" || info.Memory.FragmentationRatio != 2 {
+		t.Errorf("Unexpected memory section %+v", info.Memory)
+	}
+	if db := info.Keyspace[0]; db.Keys != 42 || db.Expires != 7 || db.AvgTTL != 1500 {
+		t.Errorf("Expected db0 keys=42 expires=7 avg_ttl=1500, got %+v", db)
+	}
+	if get := info.Commandstats["get"]; get.Calls != 900 || get.UsecPerCall != 2 || get.FailedCalls != 1 {
+		t.Errorf("Unexpected cmdstat_get %+v", get)
+	}
+	if !info.Has("stats") || info.Has("replication") {
+		t.Errorf("Expected stats section only, got sections %v", info.Raw)
+	}
+}
+
+// TestHealthRates tests rates derived from two INFO samples
+func TestHealthRates(t *testing.T) {
+	prev := health.ParseInfo(sampleInfo)
+
+	prev.Stats.InstantaneousInKbps, prev.Stats.InstantaneousOutKbps = 12.5, 40
+	first := health.ComputeRates(nil, prev, 0)
+	if first.OpsPerSec != 250 || first.HitRatio != 0.9 {
+		t.Errorf("Expected instantaneous 250 ops/s and 0.9 hit ratio, got %+v", first)
+	}
+	// instantaneous_input_kbps is already in kilobytes, like the later deltas
+	if first.NetInKBps != 12.5 || first.NetOutKBps != 40 {
+		t.Errorf("Expected 12.5 KB/s in and 40 KB/s out, got %.2f and %.2f", first.NetInKBps, first.NetOutKBps)
+	}
+
+	cur := health.ParseInfo(sampleInfo)
+	cur.Stats.CommandsProcessed += 1000
+	cur.Stats.KeyspaceHits += 50
+	cur.Stats.KeyspaceMisses += 50
+	cur.Stats.EvictedKeys += 10
+
+	r := health.ComputeRates(prev, cur, 2*time.Second)
+	if r.OpsPerSec != 500 {
+		t.Errorf("Expected 500 ops/s, got %v", r.OpsPerSec)
+	}
+	if r.HitRatio != 0.5 || r.Lookups != 100 {
+		t.Errorf("Expected hit ratio 0.5 over 100 lookups, got %v over %d", r.HitRatio, r.Lookups)
+	}
+	if r.EvictionsPerSec != 5 {
+		t.Errorf("Expected 5 evictions/s, got %v", r.EvictionsPerSec)
+	}
+	if r.Fragmentation != 2 {
+		t.Errorf("Expected fragmentation 2, got %v", r.Fragmentation)
+	}
+
+	// A restart resets counters; rates must not go negative
+	restarted := health.ParseInfo(sampleInfo)
+	restarted.Stats.CommandsProcessed = 10
+	if r := health.ComputeRates(cur, restarted, time.Second); r.OpsPerSec < 0 {
+		t.Errorf("Expected non-negative ops/s after restart, got %v", r.OpsPerSec)
+	}
+}
+
+// TestHealthSlowlogDedupe tests that repeated SLOWLOG GET replies only report new entries
+func TestHealthSlowlogDedupe(t *testing.T) {
+	entry := func(id int64, d time.Duration, args ...string) redis.SlowLog {
+		return redis.SlowLog{ID: id, Time: time.Unix(1700000000+id, 0), Duration: d, Args: args}
+	}
+	tracker := health.NewSlowlogTracker()
+
+	// SLOWLOG GET returns newest first
+	fresh := tracker.Update([]redis.SlowLog{
+		entry(2, 30*time.Millisecond, "HSET", "user:1", "name", "Bob"),
+		entry(1, 10*time.Millisecond, "HSET", "user:1", "name", "Alice"),
+	})
+	if len(fresh) != 2 || fresh[0].ID != 1 {
+		t.Fatalf("Expected 2 new entries oldest first, got %v", fresh)
+	}
+
+	fresh = tracker.Update([]redis.SlowLog{
+		entry(3, 5*time.Millisecond, "KEYS", "*"),
+		entry(2, 30*time.Millisecond, "HSET", "user:1", "name", "Bob"),
+		entry(1, 10*time.Millisecond, "HSET", "user:1", "name", "Alice"),
+	})
+	if len(fresh) != 1 || fresh[0].ID != 3 {
+		t.Fatalf("Expected only entry 3 to be new, got %v", fresh)
+	}
+
+	top := tracker.Top(1)
+	if len(top) != 1 || top[0].Statement != "HSET user:1 ? ?" {
+		t.Fatalf("Expected HSET to be the top group, got %+v", top)
+	}
+	if top[0].Count != 2 || top[0].Total != 40*time.Millisecond || top[0].Max != 30*time.Millisecond {
+		t.Errorf("Expected 2 HSET entries totalling 40ms, got %+v", top[0])
+	}
+
+	// SLOWLOG RESET restarts IDs at 0
+	fresh = tracker.Update([]redis.SlowLog{entry(0, time.Millisecond, "GET", "x")})
+	if len(fresh) != 1 {
+		t.Errorf("Expected the entry after a reset to be new, got %v", fresh)
+	}
+}
+
+// TestHealthPoll tests polling a live server
+func TestHealthPoll(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	mon := health.NewMonitor(rdb, health.Options{})
+	first, err := mon.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Error polling: %v", err)
+	}
+	if first.Info.Clients.Connected < 1 {
+		t.Errorf("Expected at least one connected client, got %d", first.Info.Clients.Connected)
+	}
+
+	for i := 0; i < 20; i++ {
+		rdb.Get(ctx, "health:missing")
+	}
+	second, err := mon.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Error polling: %v", err)
+	}
+	if second.Rates.Interval <= 0 {
+		t.Errorf("Expected the second poll to have an interval, got %v", second.Rates.Interval)
+	}
+	if second.Info.Stats.CommandsProcessed > 0 && second.Rates.OpsPerSec <= 0 {
+		t.Errorf("Expected a positive ops/s rate, got %v", second.Rates.OpsPerSec)
+	}
+	// SLOWLOG and LATENCY may be disabled or renamed; the poll still succeeds
+	if second.SlowlogErr != nil {
+		t.Logf("SLOWLOG unavailable: %v", second.SlowlogErr)
+	}
+	if second.LatencyErr != nil {
+		t.Logf("LATENCY unavailable: %v", second.LatencyErr)
+	}
+}
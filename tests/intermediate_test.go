@@ -6,15 +6,18 @@ import (
 	"testing"
 	"time"
 
+	"Redis/tlsconf"
+
 	"github.com/redis/go-redis/v9"
 )
 
 // TestIntermediateHashOperations tests Redis hash operations
 func TestIntermediateHashOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -171,9 +174,10 @@ func TestIntermediateHashOperations(t *testing.T) {
 // TestIntermediateListOperations tests Redis list operations
 func TestIntermediateListOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -289,9 +293,10 @@ func TestIntermediateListOperations(t *testing.T) {
 // TestIntermediateSetOperations tests Redis set operations
 func TestIntermediateSetOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -406,9 +411,10 @@ func TestIntermediateSetOperations(t *testing.T) {
 // TestIntermediateSortedSetOperations tests Redis sorted set operations
 func TestIntermediateSortedSetOperations(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -573,9 +579,10 @@ func TestIntermediateSortedSetOperations(t *testing.T) {
 // TestHashOperationsConcurrency tests concurrent hash operations
 func TestHashOperationsConcurrency(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -624,9 +631,10 @@ func TestHashOperationsConcurrency(t *testing.T) {
 // TestListOperationsConcurrency tests concurrent list operations
 func TestListOperationsConcurrency(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -674,9 +682,10 @@ func TestListOperationsConcurrency(t *testing.T) {
 // TestSetOperationsConcurrency tests concurrent set operations
 func TestSetOperationsConcurrency(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -724,9 +733,10 @@ func TestSetOperationsConcurrency(t *testing.T) {
 // TestSortedSetOperationsConcurrency tests concurrent sorted set operations
 func TestSortedSetOperationsConcurrency(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
@@ -774,9 +784,10 @@ func TestSortedSetOperationsConcurrency(t *testing.T) {
 // TestIntermediatePerformance tests performance of intermediate operations
 func TestIntermediatePerformance(t *testing.T) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        0,
+		TLSConfig: tlsconf.FromEnv(),
 	})
 	defer rdb.Close()
 
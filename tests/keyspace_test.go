@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Redis/keyspace"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestKeyspaceDispatch tests that events reach only the handlers whose pattern and type match
+func TestKeyspaceDispatch(t *testing.T) {
+	ctx := context.Background()
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+
+	cfg := keyspace.Config{DB: 0, Events: []keyspace.EventType{keyspace.Expired, keyspace.Evicted}}
+	listener := keyspace.NewListener(rdb, cfg)
+
+	sessions := make(chan keyspace.Event, 10)
+	all := make(chan keyspace.Event, 10)
+	listener.Handle("session:*", func(e keyspace.Event) { sessions <- e }, keyspace.Expired)
+	listener.Handle("*", func(e keyspace.Event) { all <- e })
+
+	if err := listener.Start(ctx); err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	defer listener.Close()
+
+	// Publish the notifications Redis would send
+	rdb.Publish(ctx, "__keyevent@0__:expired", "session:42")
+	rdb.Publish(ctx, "__keyevent@0__:evicted", "session:43")
+	rdb.Publish(ctx, "__keyevent@0__:expired", "cache:1")
+
+	var got []keyspace.Event
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-all:
+			got = append(got, e)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	if got[1].Type != keyspace.Evicted || got[1].Key != "session:43" || got[1].DB != 0 {
+		t.Errorf("Unexpected evicted event %+v", got[1])
+	}
+
+	select {
+	case e := <-sessions:
+		if e.Key != "session:42" || e.Type != keyspace.Expired {
+			t.Errorf("Unexpected session event %+v", e)
+		}
+	default:
+		t.Errorf("Expected the expired session event")
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected evictions and other keys to be filtered out, got %d extra", len(sessions))
+	}
+}
+
+// TestKeyspaceExpiry tests that a real expiry is delivered without polling
+func TestKeyspaceExpiry(t *testing.T) {
+	ctx := context.Background()
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+
+	listener := keyspace.NewListener(rdb, keyspace.DefaultConfig())
+	expired := make(chan string, 1)
+	listener.Handle("keyspace_test:*", func(e keyspace.Event) { expired <- e.Key }, keyspace.Expired)
+	if err := listener.Start(ctx); err != nil {
+		t.Fatalf("Error starting listener: %v", err)
+	}
+	defer listener.Close()
+
+	if err := rdb.Set(ctx, "keyspace_test:short", "v", 100*time.Millisecond).Err(); err != nil {
+		t.Fatalf("Error setting key: %v", err)
+	}
+
+	select {
+	case key := <-expired:
+		if key != "keyspace_test:short" {
+			t.Errorf("Expected keyspace_test:short to expire, got %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Timed out waiting for expired event")
+	}
+}
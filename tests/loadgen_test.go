@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Redis/loadgen"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestLoadgenSpec tests workload defaults, validation and key substitution
+func TestLoadgenSpec(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	if _, err := loadgen.ParseSpec([]byte("ops:\n  - command: GET x\n    ratio: 0\n")); err == nil {
+		t.Errorf("Expected an error for a zero ratio")
+	}
+
+	spec, err := loadgen.ParseSpec([]byte(`
+concurrency: 1
+duration: 300ms
+keyspace: 5
+distribution: sequential
+ops:
+  - command: XADD loadgen:stream * key loadgen:key:*
+    ratio: 1
+`))
+	if err != nil {
+		t.Fatalf("Error parsing spec: %v", err)
+	}
+	if spec.Ops[0].Name != "XADD loadgen:stream" || spec.ValueSize != 64 {
+		t.Errorf("Unexpected defaults: %+v", spec)
+	}
+
+	if _, err := loadgen.Run(ctx, rdb, spec, nil); err != nil {
+		t.Fatalf("Error running workload: %v", err)
+	}
+	entries, err := rdb.XRangeN(ctx, "loadgen:stream", "-", "+", 6).Result()
+	if err != nil {
+		t.Fatalf("Error reading stream: %v", err)
+	}
+	if len(entries) != 6 || entries[0].Values["key"] != "loadgen:key:1" || entries[5].Values["key"] != "loadgen:key:1" {
+		t.Errorf("Expected sequential keys wrapping after 5, got %v", entries)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "loadgen:stream")
+}
+
+// TestLoadgenClosedLoop tests that every op of the mix runs and is reported
+func TestLoadgenClosedLoop(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	spec := &loadgen.Spec{
+		Concurrency: 4,
+		Duration:    500 * time.Millisecond,
+		Keyspace:    100,
+		Ops: []loadgen.Op{
+			{Name: "set", Command: "SET loadgen:* {value}", Ratio: 3},
+			{Name: "get", Command: "GET loadgen:*", Ratio: 6},
+			{Name: "wrongtype", Command: "HGET loadgen:* field", Ratio: 1},
+		},
+	}
+	report, err := loadgen.Run(ctx, rdb, spec, nil)
+	if err != nil {
+		t.Fatalf("Error running workload: %v", err)
+	}
+
+	if report.Mode != loadgen.ClosedLoop || report.Total.Count == 0 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	for _, op := range report.Ops {
+		if op.Count == 0 || op.P99 < op.P50 || op.Max < op.P99 {
+			t.Errorf("Unexpected stats for %s: %+v", op.Name, op)
+		}
+	}
+	if report.Ops[0].Errors != 0 || report.Ops[2].ErrorClasses["WRONGTYPE"] == 0 {
+		t.Errorf("Expected only HGET on string keys to fail, got %+v", report.Ops)
+	}
+
+	// Cleanup
+	keys, _ := rdb.Keys(ctx, "loadgen:*").Result()
+	if len(keys) > 0 {
+		rdb.Del(ctx, keys...)
+	}
+}
+
+// TestLoadgenOpenLoop tests that open-loop mode holds the target rate
+func TestLoadgenOpenLoop(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	spec := &loadgen.Spec{
+		Concurrency: 4,
+		Duration:    time.Second,
+		Rate:        200,
+		Ops:         []loadgen.Op{{Command: "INCR loadgen:counter", Ratio: 1}},
+	}
+	report, err := loadgen.Run(ctx, rdb, spec, nil)
+	if err != nil {
+		t.Fatalf("Error running workload: %v", err)
+	}
+	if report.Mode != loadgen.OpenLoop {
+		t.Errorf("Expected open-loop mode, got %s", report.Mode)
+	}
+	if report.Total.Count < 180 || report.Total.Count > 201 {
+		t.Errorf("Expected about 200 ops at 200 ops/s for 1s, got %d", report.Total.Count)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "loadgen:counter")
+}
+
+// TestLoadgenHistogram tests percentiles from the latency histogram
+func TestLoadgenHistogram(t *testing.T) {
+	h := loadgen.NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	checks := map[float64]time.Duration{50: 500 * time.Millisecond, 99: 990 * time.Millisecond, 100: time.Second}
+	for p, want := range checks {
+		got := h.Percentile(p)
+		if diff := got - want; diff < 0 || diff > want/1000 {
+			t.Errorf("p%v = %v, expected %v within 0.1%%", p, got, want)
+		}
+	}
+	if h.Count() != 1000 || h.Max() != time.Second {
+		t.Errorf("Unexpected count %d or max %v", h.Count(), h.Max())
+	}
+}
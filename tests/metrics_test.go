@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"Redis/metrics"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestMetricsHook tests command counts, error classes and pipeline sizes
+func TestMetricsHook(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	hook := metrics.Instrument(rdb)
+
+	rdb.Set(ctx, "metrics:string", "value", 0)
+	rdb.Get(ctx, "metrics:string")
+	rdb.Get(ctx, "metrics:missing")
+	rdb.HGet(ctx, "metrics:string", "field")
+	rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < 3; i++ {
+			pipe.Incr(ctx, "metrics:counter")
+		}
+		return nil
+	})
+	rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, "metrics:counter")
+		pipe.Incr(ctx, "metrics:counter")
+		return nil
+	})
+
+	var out strings.Builder
+	if _, err := hook.WriteTo(&out); err != nil {
+		t.Fatalf("Error writing metrics: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		`redis_commands_total{cmd="get"} 2`,
+		`redis_commands_total{cmd="incr"} 5`,
+		`redis_command_errors_total{cmd="get",class="nil"} 1`,
+		`redis_command_errors_total{cmd="hget",class="wrongtype"} 1`,
+		`redis_command_duration_seconds_count{cmd="set"} 1`,
+		`redis_pipeline_size_bucket{tx="true",le="2"} 1`,
+		`redis_pipeline_size_sum{tx="true"} 2`,
+		`redis_pool_connections{pool="default"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "metrics:string", "metrics:counter")
+}
+
+// TestMetricsEndpoint tests serving metrics over HTTP
+func TestMetricsEndpoint(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	srv, err := metrics.Serve("localhost:0", metrics.Instrument(rdb))
+	if err != nil {
+		t.Fatalf("Error serving metrics: %v", err)
+	}
+	defer srv.Close()
+	rdb.Ping(ctx)
+
+	resp, err := http.Get("http://" + srv.Addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Error fetching metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") || !strings.Contains(string(body), `redis_commands_total{cmd="ping"} 1`) {
+		t.Errorf("Unexpected response %q", body)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/keyspec"
+	"Redis/namespace"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestNamespaceKeys tests prefixing the keys of commands, pipelines and scripts per tenant
+func TestNamespaceKeys(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	alice := redis.NewClient(&redis.Options{Addr: "localhost:6379", TLSConfig: tlsconf.FromEnv()})
+	defer alice.Close()
+	namespace.Wrap(alice, "ns:alice:")
+	bob := redis.NewClient(&redis.Options{Addr: "localhost:6379", TLSConfig: tlsconf.FromEnv()})
+	defer bob.Close()
+	ns := namespace.Wrap(bob, "ns:bob:")
+
+	// The same names do not collide
+	alice.Set(ctx, "counter", 1, 0)
+	bob.Set(ctx, "counter", 2, 0)
+	if v, _ := alice.Get(ctx, "counter").Int(); v != 1 {
+		t.Errorf("Expected alice's counter to be 1, got %d", v)
+	}
+	if v, _ := rdb.Get(ctx, ns.Key("counter")).Int(); v != 2 {
+		t.Errorf("Expected bob's counter under his prefix, got %d", v)
+	}
+
+	// Multi-key commands, destinations and scripts
+	alice.MSet(ctx, "user:1001", "Alice", "user:1002", "Bob")
+	if vals, _ := alice.MGet(ctx, "user:1001", "user:1002").Result(); len(vals) != 2 || vals[1] != "Bob" {
+		t.Errorf("Expected MGET to read the prefixed keys, got %v", vals)
+	}
+	alice.ZAdd(ctx, "scores:a", redis.Z{Score: 1, Member: "x"}, redis.Z{Score: 5, Member: "y"})
+	alice.ZAdd(ctx, "scores:b", redis.Z{Score: 2, Member: "x"})
+	alice.ZInterStore(ctx, "leaderboard", &redis.ZStore{Keys: []string{"scores:a", "scores:b"}})
+	if score, _ := rdb.ZScore(ctx, "ns:alice:leaderboard", "x").Result(); score != 3 {
+		t.Errorf("Expected ZINTERSTORE to write ns:alice:leaderboard, got %v", score)
+	}
+	script := redis.NewScript("return redis.call('INCRBY', KEYS[1], ARGV[1])")
+	if v, err := script.Run(ctx, bob, []string{"counter"}, 5).Int(); err != nil || v != 7 {
+		t.Errorf("Expected the script to increment bob's counter to 7, got %d (%v)", v, err)
+	}
+
+	// Streams and blocking pops reply with the names the client used
+	alice.XAdd(ctx, &redis.XAddArgs{Stream: "events", Values: map[string]any{"type": "login"}})
+	streams, err := alice.XRead(ctx, &redis.XReadArgs{Streams: []string{"events", "0"}, Count: 10}).Result()
+	if err != nil || len(streams) != 1 || streams[0].Stream != "events" || len(streams[0].Messages) != 1 {
+		t.Errorf("Expected one message from events, got %v (%v)", streams, err)
+	}
+	bob.RPush(ctx, "queue", "job")
+	if kv, _ := bob.BLPop(ctx, time.Second, "queue").Result(); !slices.Equal(kv, []string{"queue", "job"}) {
+		t.Errorf("Expected [queue job], got %v", kv)
+	}
+	bob.ZAdd(ctx, "ranks", redis.Z{Score: 1, Member: "a"})
+	if z, err := bob.BZPopMin(ctx, time.Second, "ranks").Result(); err != nil || z.Key != "ranks" {
+		t.Errorf("Expected BZPOPMIN to name ranks, got %v (%v)", z, err)
+	}
+
+	// Pipelines and transactions
+	cmds, err := bob.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, "counter")
+		pipe.HSet(ctx, "user:1001", "name", "Bob")
+		return nil
+	})
+	if err != nil || cmds[0].(*redis.IntCmd).Val() != 8 {
+		t.Errorf("Expected the transaction to increment bob's counter, got %v (%v)", cmds, err)
+	}
+	if name, _ := rdb.HGet(ctx, "ns:bob:user:1001", "name").Result(); name != "Bob" {
+		t.Errorf("Expected the transaction to write ns:bob:user:1001, got %q", name)
+	}
+
+	// KEYS and SCAN see only the tenant's keys, without the prefix
+	keys, _ := alice.Keys(ctx, "*").Result()
+	slices.Sort(keys)
+	want := []string{"counter", "events", "leaderboard", "scores:a", "scores:b", "user:1001", "user:1002"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+	var scanned []string
+	iter := alice.Scan(ctx, 0, "user:*", 1).Iterator()
+	for iter.Next(ctx) {
+		scanned = append(scanned, iter.Val())
+	}
+	slices.Sort(scanned)
+	if !slices.Equal(scanned, []string{"user:1001", "user:1002"}) {
+		t.Errorf("Expected SCAN MATCH to find alice's users, got %v", scanned)
+	}
+	scanned = nil
+	iter = bob.Scan(ctx, 0, "", 0).Iterator()
+	for iter.Next(ctx) {
+		scanned = append(scanned, iter.Val())
+	}
+	slices.Sort(scanned)
+	if !slices.Equal(scanned, []string{"counter", "user:1001"}) {
+		t.Errorf("Expected SCAN to find bob's keys, got %v", scanned)
+	}
+	if v, _ := bob.Do(ctx, "KEYS", "count*").StringSlice(); !slices.Equal(v, []string{"counter"}) {
+		t.Errorf("Expected KEYS through Do to strip the prefix, got %v", v)
+	}
+
+	if err := alice.FlushDB(ctx).Err(); err == nil || !strings.Contains(err.Error(), "outside the prefix") {
+		t.Errorf("Expected FLUSHDB to be refused, got %v", err)
+	}
+
+	// Cleanup
+	for _, prefix := range []string{"ns:alice:*", "ns:bob:*"} {
+		keys, _ := rdb.Keys(ctx, prefix).Result()
+		if len(keys) > 0 {
+			rdb.Del(ctx, keys...)
+		}
+	}
+}
+
+// TestKeyspecSpecs tests locating keys from Redis 7 key specs, in both the RESP2 and RESP3 reply shapes
+func TestKeyspecSpecs(t *testing.T) {
+	index := func(i int) []any { return []any{"type", "index", "spec", []any{"index", int64(i)}} }
+	keyword := func(kw string, from int) []any {
+		return []any{"type", "keyword", "spec", []any{"keyword", kw, "startfrom", int64(from)}}
+	}
+	rng := func(last, step, limit int) []any {
+		return []any{"type", "range", "spec", []any{"lastkey", int64(last), "keystep", int64(step), "limit", int64(limit)}}
+	}
+	keynum := func(idx, first, step int) []any {
+		return []any{"type", "keynum", "spec", []any{"keynumidx", int64(idx), "firstkey", int64(first), "keystep", int64(step)}}
+	}
+	spec := func(begin, find []any) any {
+		return []any{"flags", []any{"RW"}, "begin_search", begin, "find_keys", find}
+	}
+	entry := func(name string, first, last, step int, flags []any, specs ...any) any {
+		return []any{name, int64(-1), flags, int64(first), int64(last), int64(step), []any{}, []any{}, specs, []any{}}
+	}
+	movable := []any{"movablekeys"}
+	reply := []any{
+		entry("mset", 1, -1, 2, nil, spec(index(1), rng(-1, 2, 0))),
+		entry("xread", 0, 0, 0, movable, spec(keyword("STREAMS", 1), rng(-1, 1, 2))),
+		entry("zinterstore", 1, 1, 1, movable, spec(index(1), rng(0, 1, 0)), spec(index(2), keynum(0, 1, 1))),
+		entry("eval", 0, 0, 0, movable, spec(index(2), keynum(0, 1, 1))),
+		entry("sort", 1, 1, 1, movable, spec(index(1), rng(0, 1, 0)), spec(keyword("STORE", 1), rng(0, 1, 0))),
+		// RESP3: maps instead of flat lists
+		[]any{"get", int64(2), []any{}, int64(1), int64(1), int64(1), []any{}, []any{}, []any{map[any]any{
+			"flags":        []any{"RO"},
+			"begin_search": map[any]any{"type": "index", "spec": map[any]any{"index": int64(1)}},
+			"find_keys":    map[any]any{"type": "range", "spec": map[any]any{"lastkey": int64(0), "keystep": int64(1), "limit": int64(0)}},
+		}}, []any{}},
+	}
+	table, err := keyspec.Parse(reply)
+	if err != nil {
+		t.Fatalf("Error parsing COMMAND: %v", err)
+	}
+
+	cases := []struct {
+		args []string
+		want []int
+	}{
+		{[]string{"MSET", "a", "1", "b", "2"}, []int{1, 3}},
+		{[]string{"XREAD", "COUNT", "10", "STREAMS", "s1", "s2", "0", "$"}, []int{4, 5}},
+		{[]string{"ZINTERSTORE", "dest", "2", "k1", "k2", "WEIGHTS", "1", "2"}, []int{1, 3, 4}},
+		{[]string{"EVAL", "return 1", "2", "k1", "k2", "argv"}, []int{3, 4}},
+		{[]string{"EVAL", "return 1", "0"}, nil},
+		{[]string{"SORT", "list", "BY", "w_*", "STORE", "out"}, []int{1, 5}},
+		{[]string{"SORT", "list"}, []int{1}},
+		{[]string{"GET", "k"}, []int{1}},
+	}
+	for _, c := range cases {
+		got, err := table.Keys(c.args)
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("%v: expected keys at %v, got %v (%v)", c.args, c.want, got, err)
+		}
+	}
+
+	prefixed, _ := table.Prefix([]string{"XREAD", "STREAMS", "events", "0"}, "t:")
+	if !slices.Equal(prefixed, []string{"XREAD", "STREAMS", "t:events", "0"}) {
+		t.Errorf("Expected the stream prefixed, got %v", prefixed)
+	}
+	if _, err := table.Keys([]string{"NOSUCH", "k"}); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+// TestKeyspecStatic tests the built-in command table against the server's for the commands both know
+func TestKeyspecStatic(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	server, err := keyspec.Load(context.Background(), rdb)
+	if err != nil {
+		t.Fatalf("Error loading the command table: %v", err)
+	}
+
+	static := keyspec.Static()
+	for _, args := range [][]string{
+		{"GET", "k"},
+		{"MSET", "a", "1", "b", "2"},
+		{"DEL", "a", "b", "c"},
+		{"BLPOP", "a", "b", "0"},
+		{"LMOVE", "src", "dst", "LEFT", "RIGHT"},
+		{"BITOP", "AND", "dest", "a", "b"},
+		{"ZUNIONSTORE", "dest", "2", "a", "b", "WEIGHTS", "1", "2"},
+		{"EVAL", "return 1", "1", "k", "argv"},
+		{"XREADGROUP", "GROUP", "g", "c", "STREAMS", "s1", "s2", ">", ">"},
+		{"XGROUP", "CREATE", "stream", "g", "$"},
+		{"OBJECT", "ENCODING", "k"},
+		{"PUBLISH", "channel", "message"},
+		{"FLUSHDB"},
+	} {
+		want, err := server.Keys(args)
+		if err != nil {
+			t.Logf("Skipping %s: %v", args[0], err)
+			continue
+		}
+		if got, err := static.Keys(args); err != nil || !slices.Equal(got, want) {
+			t.Errorf("%v: expected keys at %v like the server, got %v (%v)", args, want, got, err)
+		}
+	}
+
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"Redis/notify"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestNotifyInbox tests sending, deduplication, unread counts, mark-read and live push
+func TestNotifyInbox(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	svc := notify.New(rdb, notify.Config{Prefix: "notify_test:", ChannelPrefix: "notify_test:live:", MaxInbox: 3})
+	rdb.Del(ctx, "notify_test:{user:1}:inbox", "notify_test:{user:1}:unread")
+
+	live := make(chan notify.Notification, 10)
+	listener, err := svc.Listen(ctx, "user:1", func(n notify.Notification) { live <- n })
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	defer listener.Close()
+
+	base := time.Now().Add(-time.Hour)
+	for i, title := range []string{"Welcome", "Order shipped", "New follower"} {
+		n := &notify.Notification{ID: "test-" + title, Type: "info", Title: title, Created: base.Add(time.Duration(i) * time.Minute)}
+		res, err := svc.Send(ctx, "user:1", n)
+		if err != nil {
+			t.Fatalf("Error sending: %v", err)
+		}
+		if res.Delivered != 1 || res.Online != 1 {
+			t.Errorf("Expected delivery to an online user, got %+v", res)
+		}
+	}
+	select {
+	case n := <-live:
+		if n.Title != "Welcome" {
+			t.Errorf("Expected the first notification pushed live, got %+v", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a live notification")
+	}
+
+	// Same ID again is a duplicate
+	res, _ := svc.Send(ctx, "user:1", &notify.Notification{ID: "test-Welcome", Title: "Welcome"})
+	if res.Delivered != 0 || res.Duplicates != 1 {
+		t.Errorf("Expected a duplicate, got %+v", res)
+	}
+
+	if n, err := svc.UnreadCount(ctx, "user:1"); err != nil || n != 3 {
+		t.Errorf("Expected 3 unread, got %d: %v", n, err)
+	}
+	if n, _ := svc.MarkRead(ctx, "user:1", "test-Order shipped", "test-unknown"); n != 1 {
+		t.Errorf("Expected 1 notification marked read, got %d", n)
+	}
+	inbox, err := svc.Inbox(ctx, "user:1", 0, 10)
+	if err != nil {
+		t.Fatalf("Error reading inbox: %v", err)
+	}
+	if len(inbox) != 3 || inbox[0].Title != "New follower" || inbox[2].Title != "Welcome" {
+		t.Fatalf("Expected newest first, got %+v", inbox)
+	}
+	if inbox[0].Read || !inbox[1].Read {
+		t.Errorf("Expected only the shipped notification read, got %+v", inbox)
+	}
+
+	// The cap drops the oldest, read or not
+	svc.Send(ctx, "user:1", &notify.Notification{Title: "Price drop"})
+	inbox, _ = svc.Inbox(ctx, "user:1", 0, 10)
+	if len(inbox) != 3 || inbox[0].Title != "Price drop" || inbox[2].Title != "Order shipped" {
+		t.Errorf("Expected the oldest dropped, got %+v", inbox)
+	}
+	if n, _ := svc.UnreadCount(ctx, "user:1"); n != 2 {
+		t.Errorf("Expected 2 unread after the cap, got %d", n)
+	}
+
+	svc.MarkAllRead(ctx, "user:1")
+	if n, _ := svc.UnreadCount(ctx, "user:1"); n != 0 {
+		t.Errorf("Expected nothing unread, got %d", n)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "notify_test:{user:1}:inbox", "notify_test:{user:1}:unread")
+	for _, n := range inbox {
+		rdb.Del(ctx, "notify_test:msg:"+n.ID)
+	}
+	rdb.Del(ctx, "notify_test:msg:test-Welcome")
+}
+
+// TestNotifyRetention tests that notifications older than the retention period are not kept
+func TestNotifyRetention(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	svc := notify.New(rdb, notify.Config{Prefix: "notify_test:", Retention: 24 * time.Hour})
+	rdb.Del(ctx, "notify_test:{user:2}:inbox", "notify_test:{user:2}:unread")
+
+	old := &notify.Notification{Title: "Stale", Created: time.Now().Add(-48 * time.Hour)}
+	if res, _ := svc.Send(ctx, "user:2", old); res.Delivered != 0 {
+		t.Errorf("Expected a notification past retention to be dropped, got %+v", res)
+	}
+	svc.Send(ctx, "user:2", &notify.Notification{Title: "Fresh"})
+
+	// An entry that aged out since it was delivered
+	rdb.ZAdd(ctx, "notify_test:{user:2}:inbox", redis.Z{Score: float64(time.Now().Add(-25 * time.Hour).UnixMilli()), Member: "aged"})
+	rdb.ZAdd(ctx, "notify_test:{user:2}:unread", redis.Z{Score: float64(time.Now().Add(-25 * time.Hour).UnixMilli()), Member: "aged"})
+
+	inbox, err := svc.Inbox(ctx, "user:2", 0, 10)
+	if err != nil {
+		t.Fatalf("Error reading inbox: %v", err)
+	}
+	if len(inbox) != 1 || inbox[0].Title != "Fresh" {
+		t.Errorf("Expected only the fresh notification, got %+v", inbox)
+	}
+	if n, _ := svc.UnreadCount(ctx, "user:2"); n != 1 {
+		t.Errorf("Expected 1 unread, got %d", n)
+	}
+	if ttl := rdb.TTL(ctx, "notify_test:{user:2}:inbox").Val(); ttl <= 0 || ttl > 24*time.Hour {
+		t.Errorf("Expected the inbox to expire with the retention period, got %v", ttl)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "notify_test:{user:2}:inbox", "notify_test:{user:2}:unread", "notify_test:msg:"+inbox[0].ID, "notify_test:msg:"+old.ID)
+}
+
+// dropPipelines fails every pipeline the way a dropped connection does, leaving its commands untouched
+type dropPipelines struct{ err error }
+
+func (h dropPipelines) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h dropPipelines) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h dropPipelines) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(context.Context, []redis.Cmder) error { return h.err }
+}
+
+// TestNotifyInboxDropped tests that a connection lost while reading the inbox is returned as such
+func TestNotifyInboxDropped(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	cfg := notify.Config{Prefix: "notify_test:"}
+	rdb.Del(ctx, "notify_test:{user:3}:inbox", "notify_test:{user:3}:unread")
+	n := &notify.Notification{Title: "Hello"}
+	if _, err := notify.New(rdb, cfg).Send(ctx, "user:3", n); err != nil {
+		t.Fatalf("Error sending: %v", err)
+	}
+
+	dropped := &net.OpError{Op: "read", Net: "tcp", Err: io.ErrUnexpectedEOF}
+	// DisableIdentity keeps the hook off the CLIENT SETINFO pipeline of the handshake
+	broken := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv(), DisableIdentity: true})
+	defer broken.Close()
+	broken.AddHook(dropPipelines{err: dropped})
+	if _, err := notify.New(broken, cfg).Inbox(ctx, "user:3", 0, 10); !errors.Is(err, dropped) {
+		t.Errorf("Expected the connection error, got %v", err)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "notify_test:{user:3}:inbox", "notify_test:{user:3}:unread", "notify_test:msg:"+n.ID)
+}
+
+// TestNotifyFanOut tests bulk delivery to interest segments with overlapping users
+func TestNotifyFanOut(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	interests := map[string][]string{
+		"user:10": {"sports", "technology"},
+		"user:11": {"technology"},
+		"user:12": {"music"},
+		"user:13": {"technology", "music"},
+	}
+	for user, list := range interests {
+		rdb.SAdd(ctx, "notify_test_interests:"+user, list)
+	}
+	rdb.SAdd(ctx, "notify_test_segment:beta", "user:12", "user:13", "user:14")
+
+	svc := notify.New(rdb, notify.Config{Prefix: "notify_test:", BatchSize: 2})
+	n := &notify.Notification{ID: "launch-2026", Type: "promo", Title: "New gadgets"}
+	segment := notify.Union(
+		svc.Interested("notify_test_interests:*", "technology"),
+		svc.SetMembers("notify_test_segment:beta"),
+	)
+	res, err := svc.FanOut(ctx, n, segment)
+	if err != nil {
+		t.Fatalf("Error fanning out: %v", err)
+	}
+	// user:13 is in both segments
+	if res.Recipients != 6 || res.Delivered != 5 || res.Duplicates != 1 {
+		t.Errorf("Expected 5 users reached out of 6 addressed, got %+v", res)
+	}
+
+	var reached []string
+	for _, user := range []string{"user:10", "user:11", "user:12", "user:13", "user:14"} {
+		if c, _ := svc.UnreadCount(ctx, user); c == 1 {
+			reached = append(reached, user)
+		}
+	}
+	sort.Strings(reached)
+	if len(reached) != 5 {
+		t.Errorf("Expected every user to have one unread, got %v", reached)
+	}
+
+	// Running the campaign again reaches no one
+	res, _ = svc.FanOut(ctx, n, segment)
+	if res.Delivered != 0 || res.Duplicates != 6 {
+		t.Errorf("Expected a repeated fan-out to be deduplicated, got %+v", res)
+	}
+
+	// Cleanup
+	for user := range interests {
+		rdb.Del(ctx, "notify_test_interests:"+user)
+	}
+	for _, user := range []string{"user:10", "user:11", "user:12", "user:13", "user:14"} {
+		rdb.Del(ctx, "notify_test:{"+user+"}:inbox", "notify_test:{"+user+"}:unread")
+	}
+	rdb.Del(ctx, "notify_test_segment:beta", "notify_test:msg:launch-2026")
+}
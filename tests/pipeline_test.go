@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"Redis/pipeline"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestPipelineTypedHandles tests that handles are populated by name after Exec
+func TestPipelineTypedHandles(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	b := pipeline.New(ctx, rdb)
+	b.HSet("typed:user", "name", "Alice", "age", 30)
+	b.ZAdd("typed:scores", redis.Z{Score: 100, Member: "math"}, redis.Z{Score: 95, Member: "science"})
+	count := b.Incr("typed:counter")
+	name := b.HGet("typed:user", "name")
+	profile := b.HGetAll("typed:user")
+	top := b.ZRevRangeWithScores("typed:scores", 0, 0)
+	missing := b.Get("typed:missing")
+
+	if _, err := name.Result(); err != pipeline.ErrNotExecuted {
+		t.Errorf("Expected ErrNotExecuted before Exec, got %v", err)
+	}
+
+	report, err := b.Exec()
+	if err != nil {
+		t.Fatalf("Error executing pipeline: %v", err)
+	}
+	if report.Total != 7 || report.Succeeded != 6 || report.Nil != 1 {
+		t.Errorf("Unexpected report: %s", report)
+	}
+
+	if count.Val() != 1 || name.Val() != "Alice" || profile.Val()["age"] != "30" {
+		t.Errorf("Unexpected values: count=%d name=%q profile=%v", count.Val(), name.Val(), profile.Val())
+	}
+	if z := top.Val(); len(z) != 1 || z[0].Member != "math" || z[0].Score != 100 {
+		t.Errorf("Unexpected top score %v", z)
+	}
+	if !missing.IsNil() || missing.Failed() {
+		t.Errorf("Expected missing key to be nil, not a failure")
+	}
+	if _, err := b.Exec(); err != pipeline.ErrExecuted {
+		t.Errorf("Expected ErrExecuted on second Exec, got %v", err)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "typed:user", "typed:scores", "typed:counter")
+}
+
+// TestPipelinePartialFailure tests that failures are reported per command
+func TestPipelinePartialFailure(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	b := pipeline.New(ctx, rdb)
+	b.Set("typed:string", "not a hash", 0)
+	wrongType := b.HGet("typed:string", "field")
+	ok := b.Get("typed:string")
+	unknown := b.Do("NOSUCHCOMMAND", "x")
+
+	report, err := b.Exec()
+	var partial *pipeline.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected PartialError, got %v", err)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].Index != 1 || report.Failures[1].Index != 3 {
+		t.Fatalf("Unexpected failures: %s", report)
+	}
+	if !strings.HasPrefix(report.Failures[0].Command, "HGET typed:string") {
+		t.Errorf("Unexpected failure description %q", report.Failures[0].Command)
+	}
+	if !wrongType.Failed() || !unknown.Failed() || ok.Val() != "not a hash" {
+		t.Errorf("Expected only the bad commands to fail")
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "typed:string")
+}
+
+// TestPipelineUnreachable tests that a failed round trip fails every handle and the report
+func TestPipelineUnreachable(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: closedAddr(t), MaxRetries: -1})
+	defer rdb.Close()
+
+	b := pipeline.New(context.Background(), rdb)
+	set := b.Set("typed:unreachable", "value", 0)
+	get := b.Get("typed:unreachable")
+
+	report, err := b.Exec()
+	var partial *pipeline.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected PartialError, got %v", err)
+	}
+	if report.Succeeded != 0 || len(report.Failures) != 2 {
+		t.Errorf("Expected both commands to fail, got %s", report)
+	}
+	var opErr *net.OpError
+	if !set.Failed() || !get.Failed() || !errors.As(get.Err(), &opErr) {
+		t.Errorf("Expected the connection error on every handle, got %v and %v", set.Err(), get.Err())
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/presence"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestPresenceOnline tests heartbeats, who's online, member status, leave and join/leave events
+func TestPresenceOnline(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	tracker := presence.New(rdb, presence.Config{Prefix: "presence_test:", MembersPrefix: "presence_test_members:"})
+	rdb.SAdd(ctx, "presence_test_members:lobby", "user:1", "user:2", "user:3")
+
+	events := make(chan presence.Event, 10)
+	watcher, err := tracker.Watch(ctx, func(e presence.Event) { events <- e }, "lobby")
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+	defer watcher.Close()
+
+	joined, err := tracker.Heartbeat(ctx, "user:1", "lobby", "support")
+	if err != nil {
+		t.Fatalf("Error sending heartbeat: %v", err)
+	}
+	if len(joined) != 2 {
+		t.Errorf("Expected the first heartbeat to join both rooms, got %v", joined)
+	}
+	if joined, _ := tracker.Heartbeat(ctx, "user:1", "lobby"); len(joined) != 0 {
+		t.Errorf("Expected a repeated heartbeat to join nothing, got %v", joined)
+	}
+	tracker.Heartbeat(ctx, "user:2", "lobby")
+
+	online, err := tracker.Online(ctx, "lobby")
+	if err != nil {
+		t.Fatalf("Error listing online users: %v", err)
+	}
+	if len(online) != 2 || online[0] != "user:2" {
+		t.Errorf("Expected user:2 and user:1 online, most recent first, got %v", online)
+	}
+	if ok, _ := tracker.IsOnline(ctx, "user:3"); ok {
+		t.Errorf("Expected user:3 offline")
+	}
+
+	members, err := tracker.Members(ctx, "lobby")
+	if err != nil {
+		t.Fatalf("Error reading members: %v", err)
+	}
+	if len(members) != 3 || !members[0].Online || !members[1].Online || members[2].User != "user:3" || !members[2].LastSeen.IsZero() {
+		t.Errorf("Expected two online members then user:3 never seen, got %+v", members)
+	}
+
+	if left, _ := tracker.Leave(ctx, "user:2", "lobby"); !left {
+		t.Errorf("Expected user:2 to leave")
+	}
+	if n, _ := tracker.CountOnline(ctx, "lobby"); n != 1 {
+		t.Errorf("Expected 1 user online after the leave, got %d", n)
+	}
+
+	want := []presence.EventType{presence.Join, presence.Join, presence.Leave}
+	for i, kind := range want {
+		select {
+		case e := <-events:
+			if e.Type != kind || e.Room != "lobby" {
+				t.Errorf("Expected event %d to be %s in lobby, got %+v", i, kind, e)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a %s event", kind)
+		}
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "presence_test_members:lobby", "presence_test:{lobby}:online", "presence_test:{support}:online",
+		"presence_test:rooms", "presence_test:last_seen")
+}
+
+// TestPresenceMembersError tests that an error reading a member is returned even after offline members' nil replies
+func TestPresenceMembersError(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	tracker := presence.New(rdb, presence.Config{Prefix: "presence_broken:", MembersPrefix: "presence_broken_members:"})
+	rdb.SAdd(ctx, "presence_broken_members:lobby", "user:1")
+	rdb.Set(ctx, "presence_broken:last_seen", "not a sorted set", 0)
+
+	if members, err := tracker.Members(ctx, "lobby"); err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE from last_seen, got %+v, %v", members, err)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "presence_broken_members:lobby", "presence_broken:last_seen")
+}
+
+// TestPresenceExpiry tests that users who stop heartbeating drop out and are swept with a timeout event
+func TestPresenceExpiry(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	tracker := presence.New(rdb, presence.Config{Prefix: "presence_test:", Timeout: 200 * time.Millisecond, LastSeenRetention: 250 * time.Millisecond})
+
+	events := make(chan presence.Event, 10)
+	watcher, err := tracker.Watch(ctx, func(e presence.Event) { events <- e }, "general")
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+	defer watcher.Close()
+
+	tracker.Heartbeat(ctx, "user:1", "general")
+	tracker.Heartbeat(ctx, "user:2", "general")
+	time.Sleep(300 * time.Millisecond)
+	tracker.Heartbeat(ctx, "user:2", "general") // Rejoins, user:1 went quiet
+
+	online, _ := tracker.Online(ctx, "general")
+	if len(online) != 1 || online[0] != "user:2" {
+		t.Errorf("Expected only user:2 online, got %v", online)
+	}
+
+	swept, err := tracker.SweepAll(ctx)
+	if err != nil {
+		t.Fatalf("Error sweeping: %v", err)
+	}
+	if swept != 1 {
+		t.Errorf("Expected 1 user swept, got %d", swept)
+	}
+	if n := rdb.ZCard(ctx, "presence_test:{general}:online").Val(); n != 1 {
+		t.Errorf("Expected the stale user removed from the set, got %d members", n)
+	}
+	if _, seen, _ := tracker.LastSeen(ctx, "user:1"); seen {
+		t.Errorf("Expected user:1's last-seen time to be trimmed after the retention")
+	}
+	if _, seen, _ := tracker.LastSeen(ctx, "user:2"); !seen {
+		t.Errorf("Expected user:2's last-seen time to be kept")
+	}
+
+	var timedOut []string
+	for i := 0; i < 4; i++ {
+		select {
+		case e := <-events:
+			if e.Type == presence.Timeout {
+				timedOut = append(timedOut, e.User)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected 4 events, got %d", i)
+		}
+	}
+	if len(timedOut) != 1 || timedOut[0] != "user:1" {
+		t.Errorf("Expected a timeout event for user:1, got %v", timedOut)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "presence_test:{general}:online", "presence_test:rooms", "presence_test:last_seen")
+}
+
+// TestPresenceTyping tests typing indicators that lapse after their TTL
+func TestPresenceTyping(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	tracker := presence.New(rdb, presence.Config{Prefix: "presence_test:", TypingTTL: 200 * time.Millisecond})
+
+	events := make(chan presence.Event, 10)
+	watcher, err := tracker.Watch(ctx, func(e presence.Event) { events <- e }, "random")
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+	defer watcher.Close()
+
+	if started, _ := tracker.Typing(ctx, "user:1", "random"); !started {
+		t.Errorf("Expected the first keystroke to start an indicator")
+	}
+	if started, _ := tracker.Typing(ctx, "user:1", "random"); started {
+		t.Errorf("Expected the next keystroke only to refresh it")
+	}
+	tracker.Typing(ctx, "user:2", "random")
+
+	typers, err := tracker.Typers(ctx, "random")
+	if err != nil {
+		t.Fatalf("Error listing typers: %v", err)
+	}
+	if len(typers) != 2 {
+		t.Errorf("Expected 2 users typing, got %v", typers)
+	}
+
+	tracker.StopTyping(ctx, "user:2", "random")
+	time.Sleep(300 * time.Millisecond)
+	if typers, _ := tracker.Typers(ctx, "random"); len(typers) != 0 {
+		t.Errorf("Expected indicators to lapse after the TTL, got %v", typers)
+	}
+
+	want := []presence.EventType{presence.Typing, presence.Typing, presence.StoppedTyping}
+	for i, kind := range want {
+		select {
+		case e := <-events:
+			if e.Type != kind {
+				t.Errorf("Expected event %d to be %s, got %+v", i, kind, e)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a %s event", kind)
+		}
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "presence_test:{random}:typing")
+}
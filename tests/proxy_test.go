@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Redis/keyspec"
+	"Redis/proxy"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// startProxy serves a proxy to the test server on a free local port
+func startProxy(t *testing.T, cfg proxy.Config) (*proxy.Proxy, string) {
+	t.Helper()
+	cfg.Upstream = "localhost:6379"
+	cfg.TLSConfig = tlsconf.FromEnv()
+	p, err := proxy.New(cfg)
+	if err != nil {
+		t.Fatalf("Error creating proxy: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	go p.Serve(l)
+	return p, l.Addr().String()
+}
+
+// TestProxyCommands tests relaying commands, pipelines and transactions over RESP2 and RESP3, with logging and stats
+func TestProxyCommands(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var logged []proxy.Entry
+	p, addr := startProxy(t, proxy.Config{Log: func(e proxy.Entry) {
+		mu.Lock()
+		logged = append(logged, e)
+		mu.Unlock()
+	}})
+	defer p.Close()
+
+	for _, protocol := range []int{2, 3} {
+		client := redis.NewClient(&redis.Options{Addr: addr, Protocol: protocol})
+		client.Del(ctx, "proxy:string", "proxy:hash", "proxy:list")
+
+		if err := client.Set(ctx, "proxy:string", "hello", 0).Err(); err != nil {
+			t.Fatalf("Error setting through the proxy: %v", err)
+		}
+		if v, _ := client.Get(ctx, "proxy:string").Result(); v != "hello" {
+			t.Errorf("RESP%d: expected hello, got %q", protocol, v)
+		}
+		if _, err := client.Get(ctx, "proxy:missing").Result(); err != redis.Nil {
+			t.Errorf("RESP%d: expected redis.Nil for a missing key, got %v", protocol, err)
+		}
+		client.HSet(ctx, "proxy:hash", "a", "1", "b", "2")
+		if m, _ := client.HGetAll(ctx, "proxy:hash").Result(); len(m) != 2 || m["b"] != "2" {
+			t.Errorf("RESP%d: expected the hash back, got %v", protocol, m)
+		}
+		if err := client.HIncrBy(ctx, "proxy:string", "a", 1).Err(); err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+			t.Errorf("RESP%d: expected WRONGTYPE, got %v", protocol, err)
+		}
+
+		cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := 0; i < 100; i++ {
+				pipe.RPush(ctx, "proxy:list", i)
+			}
+			return nil
+		})
+		if err != nil || len(cmds) != 100 || cmds[99].(*redis.IntCmd).Val() != 100 {
+			t.Errorf("RESP%d: expected 100 pipelined pushes, got %d (%v)", protocol, len(cmds), err)
+		}
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Incr(ctx, "proxy:counter")
+			pipe.Incr(ctx, "proxy:counter")
+			return nil
+		})
+		if err != nil {
+			t.Errorf("RESP%d: error running a transaction: %v", protocol, err)
+		}
+		client.Close()
+	}
+
+	mu.Lock()
+	var sets []proxy.Entry
+	for _, e := range logged {
+		if e.Args[0] == "set" {
+			sets = append(sets, e)
+		}
+	}
+	mu.Unlock()
+	if len(sets) != 2 || !slices.Equal(sets[0].Args, []string{"set", "proxy:string", "hello"}) || sets[0].Duration <= 0 {
+		t.Errorf("Expected both SETs logged with their latency, got %+v", sets)
+	}
+
+	stats := map[string]proxy.CommandStats{}
+	for _, s := range p.Stats() {
+		stats[s.Name] = s
+	}
+	if s := stats["rpush"]; s.Calls != 200 || s.Max <= 0 || s.P50 > s.Max {
+		t.Errorf("Expected 200 RPUSH calls with latencies, got %+v", s)
+	}
+	if s := stats["hincrby"]; s.Errors != 2 {
+		t.Errorf("Expected 2 HINCRBY errors, got %+v", s)
+	}
+
+	// Cleanup
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	rdb.Del(ctx, "proxy:string", "proxy:hash", "proxy:list", "proxy:counter")
+}
+
+// TestProxyAllowList tests refusing denied commands, alone and inside transactions
+func TestProxyAllowList(t *testing.T) {
+	ctx := context.Background()
+	p, addr := startProxy(t, proxy.Config{Deny: []string{"FLUSHALL", "keys", "config|set"}})
+	defer p.Close()
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	if err := client.FlushAll(ctx).Err(); err == nil || !strings.HasPrefix(err.Error(), "NOPERM") {
+		t.Errorf("Expected FLUSHALL to be refused, got %v", err)
+	}
+	if err := client.Keys(ctx, "*").Err(); err == nil {
+		t.Error("Expected KEYS to be refused")
+	}
+	if err := client.Do(ctx, "CONFIG", "SET", "maxmemory", "1").Err(); err == nil || !strings.Contains(err.Error(), "not allow") {
+		t.Errorf("Expected CONFIG SET to be refused, got %v", err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Errorf("Expected the connection to stay usable, got %v", err)
+	}
+
+	// A refused command inside MULTI aborts the transaction, as in Redis
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "proxy:tx", "1", 0)
+		pipe.Keys(ctx, "*")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "EXECABORT") {
+		t.Errorf("Expected EXECABORT, got %v", err)
+	}
+	if n, _ := client.Exists(ctx, "proxy:tx").Result(); n != 0 {
+		t.Error("Expected the aborted transaction not to run")
+	}
+
+	// Only allowed commands run
+	allowOnly, addr2 := startProxy(t, proxy.Config{Allow: []string{"get", "set"}})
+	defer allowOnly.Close()
+	limited := redis.NewClient(&redis.Options{Addr: addr2})
+	defer limited.Close()
+	if err := limited.Set(ctx, "proxy:tx", "1", 0).Err(); err != nil {
+		t.Errorf("Expected SET to be allowed, got %v", err)
+	}
+	if err := limited.Del(ctx, "proxy:tx").Err(); err == nil {
+		t.Error("Expected DEL to be refused")
+	}
+
+	stats := map[string]proxy.CommandStats{}
+	for _, s := range p.Stats() {
+		stats[s.Name] = s
+	}
+	if stats["keys"].Refused != 2 || stats["flushall"].Refused != 1 {
+		t.Errorf("Expected refused commands counted, got %+v", p.Stats())
+	}
+
+	// Cleanup
+	client.Del(ctx, "proxy:tx")
+}
+
+// TestProxyKeyPrefix tests isolating tenants by prefixing their keys
+func TestProxyKeyPrefix(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	table, err := keyspec.Load(ctx, rdb)
+	if err != nil {
+		t.Fatalf("Error loading the command table: %v", err)
+	}
+	p, addr := startProxy(t, proxy.Config{Keys: table, Prefix: func(c proxy.Client) string {
+		if c.Name == "" {
+			return ""
+		}
+		return "tenant:" + c.Name + ":"
+	}})
+	defer p.Close()
+	alice := redis.NewClient(&redis.Options{Addr: addr, ClientName: "alice"})
+	defer alice.Close()
+	bob := redis.NewClient(&redis.Options{Addr: addr, ClientName: "bob", Protocol: 2})
+	defer bob.Close()
+
+	alice.Set(ctx, "greeting", "hello alice", 0)
+	bob.Set(ctx, "greeting", "hello bob", 0)
+	if v, _ := rdb.Get(ctx, "tenant:alice:greeting").Result(); v != "hello alice" {
+		t.Errorf("Expected alice's key under her prefix, got %q", v)
+	}
+	if v, _ := bob.Get(ctx, "greeting").Result(); v != "hello bob" {
+		t.Errorf("Expected bob to read his own key, got %q", v)
+	}
+
+	// Multi-key commands, scripts and streams
+	alice.MSet(ctx, "a", "1", "b", "2")
+	if vals, _ := alice.MGet(ctx, "a", "b", "greeting").Result(); len(vals) != 3 || vals[1] != "2" {
+		t.Errorf("Expected MGET to see alice's keys, got %v", vals)
+	}
+	if v, _ := rdb.Get(ctx, "tenant:alice:b").Result(); v != "2" {
+		t.Errorf("Expected MSET to prefix every key, got %q", v)
+	}
+	script := "return redis.call('GET', KEYS[1])"
+	if v, _ := alice.Eval(ctx, script, []string{"greeting"}).Result(); v != "hello alice" {
+		t.Errorf("Expected EVAL to prefix KEYS, got %v", v)
+	}
+	alice.ZAdd(ctx, "z1", redis.Z{Score: 1, Member: "x"})
+	alice.ZAdd(ctx, "z2", redis.Z{Score: 2, Member: "x"})
+	alice.ZUnionStore(ctx, "zu", &redis.ZStore{Keys: []string{"z1", "z2"}})
+	if score, _ := rdb.ZScore(ctx, "tenant:alice:zu", "x").Result(); score != 3 {
+		t.Errorf("Expected ZUNIONSTORE to use prefixed keys, got %v", score)
+	}
+	alice.XAdd(ctx, &redis.XAddArgs{Stream: "events", Values: map[string]any{"n": "1"}})
+	streams, err := alice.XRead(ctx, &redis.XReadArgs{Streams: []string{"events", "0"}, Count: 10}).Result()
+	if err != nil || len(streams) != 1 || streams[0].Stream != "events" {
+		t.Errorf("Expected XREAD to name the stream without the prefix, got %v (%v)", streams, err)
+	}
+	bob.RPush(ctx, "jobs", "j1")
+	if kv, _ := bob.BLPop(ctx, time.Second, "jobs").Result(); len(kv) != 2 || kv[0] != "jobs" {
+		t.Errorf("Expected BLPOP to name the list without the prefix, got %v", kv)
+	}
+
+	// KEYS and SCAN only see the tenant's keys, without the prefix
+	keys, _ := alice.Keys(ctx, "*").Result()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b", "events", "greeting", "z1", "z2", "zu"}) {
+		t.Errorf("Expected only alice's keys, got %v", keys)
+	}
+	var scanned []string
+	iter := bob.Scan(ctx, 0, "", 100).Iterator()
+	for iter.Next(ctx) {
+		scanned = append(scanned, iter.Val())
+	}
+	if !slices.Equal(scanned, []string{"greeting"}) {
+		t.Errorf("Expected SCAN to return only bob's keys, got %v", scanned)
+	}
+	if err := alice.FlushDB(ctx).Err(); err == nil {
+		t.Error("Expected FLUSHDB to be refused for a prefixed client")
+	}
+
+	// SORT patterns look up keys too, so they stay within the prefix
+	sortArgs := []string{"SORT", "ids", "LIMIT", "0", "10", "BY", "weight_*", "GET", "#", "GET", "tenant:bob:*", "STORE", "out"}
+	rewritten, err := table.Prefix(sortArgs, "tenant:alice:")
+	want := []string{"SORT", "tenant:alice:ids", "LIMIT", "0", "10", "BY", "tenant:alice:weight_*", "GET", "#", "GET", "tenant:alice:tenant:bob:*", "STORE", "tenant:alice:out"}
+	if err != nil || !slices.Equal(rewritten, want) {
+		t.Errorf("Expected SORT patterns to be prefixed, got %v (%v)", rewritten, err)
+	}
+	bob.Set(ctx, "secret", "bob's secret", 0)
+	alice.Set(ctx, "weight_1", "2", 0)
+	alice.Set(ctx, "weight_2", "1", 0)
+	alice.Set(ctx, "name_1", "one", 0)
+	alice.Set(ctx, "name_2", "two", 0)
+	alice.RPush(ctx, "ids", "1", "2")
+	sorted, err := alice.Sort(ctx, "ids", &redis.Sort{By: "weight_*", Get: []string{"#", "name_*"}}).Result()
+	switch {
+	case err != nil && strings.Contains(err.Error(), "unknown command"):
+		t.Logf("Server does not support SORT: %v", err)
+	case err != nil || !slices.Equal(sorted, []string{"2", "two", "1", "one"}):
+		t.Errorf("Expected SORT BY and GET to read alice's keys, got %v (%v)", sorted, err)
+	default:
+		alice.RPush(ctx, "probe", "secret")
+		leaked, _ := alice.Sort(ctx, "probe", &redis.Sort{By: "nosort", Get: []string{"tenant:bob:*"}, Alpha: true}).Result()
+		if slices.Contains(leaked, "bob's secret") {
+			t.Errorf("Expected SORT GET not to reach another tenant's keys, got %v", leaked)
+		}
+	}
+
+	// Cleanup
+	for _, tenant := range []string{"alice", "bob"} {
+		keys, _ := rdb.Keys(ctx, "tenant:"+tenant+":*").Result()
+		if len(keys) > 0 {
+			rdb.Del(ctx, keys...)
+		}
+	}
+}
+
+// TestProxyPubSub tests subscribing through the proxy
+func TestProxyPubSub(t *testing.T) {
+	ctx := context.Background()
+	p, addr := startProxy(t, proxy.Config{})
+	defer p.Close()
+
+	for _, protocol := range []int{2, 3} {
+		client := redis.NewClient(&redis.Options{Addr: addr, Protocol: protocol})
+		sub := client.Subscribe(ctx, "proxy:channel")
+		if _, err := sub.Receive(ctx); err != nil {
+			t.Fatalf("Error subscribing through the proxy: %v", err)
+		}
+		if err := client.Publish(ctx, "proxy:channel", "hi").Err(); err != nil {
+			t.Fatalf("Error publishing through the proxy: %v", err)
+		}
+		select {
+		case msg := <-sub.Channel():
+			if msg.Payload != "hi" {
+				t.Errorf("RESP%d: expected hi, got %q", protocol, msg.Payload)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("RESP%d: expected the message within 2s", protocol)
+		}
+		sub.Close()
+		client.Close()
+	}
+}
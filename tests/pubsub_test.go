@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Redis/pubsub"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dialRedis opens a raw connection to the server the tests use
+func dialRedis() (net.Conn, error) {
+	if cfg := tlsconf.FromEnv(); cfg != nil {
+		return tls.Dial("tcp", "localhost:6379", cfg)
+	}
+	return net.Dial("tcp", "localhost:6379")
+}
+
+// cutProxy forwards to Redis and can drop every connection on demand
+type cutProxy struct {
+	addr  string
+	mu    sync.Mutex
+	conns []net.Conn
+	down  bool
+}
+
+func startCutProxy(t *testing.T) *cutProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	p := &cutProxy{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			down := p.down
+			if !down {
+				p.conns = append(p.conns, conn)
+			}
+			p.mu.Unlock()
+			if down {
+				conn.Close()
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				backend, err := dialRedis()
+				if err != nil {
+					return
+				}
+				defer backend.Close()
+				go io.Copy(backend, conn)
+				io.Copy(conn, backend)
+			}()
+		}
+	}()
+	return p
+}
+
+// cut closes open connections and refuses new ones until restore
+func (p *cutProxy) cut() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = true
+	for _, c := range p.conns {
+		c.Close()
+	}
+	p.conns = nil
+}
+
+func (p *cutProxy) restore() {
+	p.mu.Lock()
+	p.down = false
+	p.mu.Unlock()
+}
+
+// receiveN waits for n messages
+func receiveN(t *testing.T, ch <-chan pubsub.Message, n int) []pubsub.Message {
+	var msgs []pubsub.Message
+	for len(msgs) < n {
+		select {
+		case m := <-ch:
+			msgs = append(msgs, m)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out after %d of %d messages", len(msgs), n)
+		}
+	}
+	return msgs
+}
+
+// TestReliablePubSubHistory tests sequence numbers and the capped message log
+func TestReliablePubSubHistory(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Del(ctx, "pubsub:{test:history}:seq", "pubsub:{test:history}:log")
+
+	pub := pubsub.NewPublisher(rdb, pubsub.PublisherConfig{MaxLen: 3})
+	for i := 1; i <= 5; i++ {
+		seq, err := pub.Publish(ctx, "test:history", string(rune('a'+i-1)))
+		if err != nil {
+			t.Fatalf("Error publishing: %v", err)
+		}
+		if seq != int64(i) {
+			t.Errorf("Expected sequence %d, got %d", i, seq)
+		}
+	}
+
+	if last, err := pubsub.LastSeq(ctx, rdb, pubsub.DefaultPrefix, "test:history"); err != nil || last != 5 {
+		t.Errorf("Expected last sequence 5, got %d: %v", last, err)
+	}
+	msgs, err := pubsub.History(ctx, rdb, pubsub.DefaultPrefix, "test:history", 0, 0)
+	if err != nil {
+		t.Fatalf("Error reading history: %v", err)
+	}
+	if len(msgs) != 3 || msgs[0].Seq != 3 || msgs[0].Payload != "c" || msgs[2].Seq != 5 {
+		t.Fatalf("Expected messages 3-5, got %+v", msgs)
+	}
+	if msgs[0].Missed != 2 || msgs[1].Missed != 0 {
+		t.Errorf("Expected 2 trimmed messages reported once, got %+v", msgs)
+	}
+	if msgs, _ := pubsub.History(ctx, rdb, pubsub.DefaultPrefix, "test:history", 4, 0); len(msgs) != 1 || msgs[0].Missed != 0 {
+		t.Errorf("Expected only message 5 after 4, got %+v", msgs)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "pubsub:{test:history}:seq", "pubsub:{test:history}:log")
+}
+
+// TestReliablePubSubReconnect tests that a subscriber backfills messages published while it was disconnected
+func TestReliablePubSubReconnect(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Del(ctx, "pubsub:{test:reliable}:seq", "pubsub:{test:reliable}:log")
+	pub := pubsub.NewPublisher(rdb, pubsub.PublisherConfig{})
+
+	proxy := startCutProxy(t)
+	subClient := redis.NewClient(&redis.Options{Addr: proxy.addr, MaxRetries: -1})
+	defer subClient.Close()
+
+	received := make(chan pubsub.Message, 100)
+	sub := pubsub.NewSubscriber(subClient, pubsub.Config{
+		Channels:     []string{"test:reliable"},
+		PingInterval: 200 * time.Millisecond,
+		MaxBackoff:   100 * time.Millisecond,
+	}, func(m pubsub.Message) { received <- m })
+	if err := sub.Start(ctx); err != nil {
+		t.Fatalf("Error starting subscriber: %v", err)
+	}
+	defer sub.Close()
+
+	// Subscribed on return, so nothing published from here on is lost
+	pub.Publish(ctx, "test:reliable", "one")
+	pub.Publish(ctx, "test:reliable", "two")
+	live := receiveN(t, received, 2)
+	if live[0].Payload != "one" || live[1].Seq != 2 || live[1].Backfilled {
+		t.Errorf("Expected live messages 1 and 2, got %+v", live)
+	}
+
+	proxy.cut()
+	for _, p := range []string{"three", "four", "five"} {
+		pub.Publish(ctx, "test:reliable", p)
+	}
+	proxy.restore()
+
+	backfilled := receiveN(t, received, 3)
+	for i, m := range backfilled {
+		if m.Seq != int64(i+3) || !m.Backfilled {
+			t.Errorf("Expected backfilled message %d, got %+v", i+3, m)
+		}
+	}
+	if sub.Reconnects() < 1 {
+		t.Errorf("Expected a reconnect, got %d", sub.Reconnects())
+	}
+
+	pub.Publish(ctx, "test:reliable", "six")
+	if m := receiveN(t, received, 1)[0]; m.Seq != 6 || m.Backfilled || m.Missed != 0 {
+		t.Errorf("Expected live message 6, got %+v", m)
+	}
+	select {
+	case m := <-received:
+		t.Errorf("Expected no duplicates, got %+v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A new subscriber resumes from a saved position
+	position := sub.Position("test:reliable")
+	if position != 6 {
+		t.Errorf("Expected position 6, got %d", position)
+	}
+	resumed := make(chan pubsub.Message, 100)
+	sub2 := pubsub.NewSubscriber(rdb, pubsub.Config{
+		Channels: []string{"test:reliable"},
+		After:    map[string]int64{"test:reliable": 4},
+	}, func(m pubsub.Message) { resumed <- m })
+	if err := sub2.Start(ctx); err != nil {
+		t.Fatalf("Error starting subscriber: %v", err)
+	}
+	defer sub2.Close()
+	if msgs := receiveN(t, resumed, 2); msgs[0].Payload != "five" || msgs[1].Payload != "six" {
+		t.Errorf("Expected messages 5 and 6 after position 4, got %+v", msgs)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "pubsub:{test:reliable}:seq", "pubsub:{test:reliable}:log")
+}
+
+// clusterClient connects to the cluster from scripts/docker-compose.cluster.yml
+func clusterClient(t *testing.T) *redis.ClusterClient {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS is not set; start scripts/docker-compose.cluster.yml to run sharded Pub/Sub tests")
+	}
+	cc := redis.NewClusterClient(&redis.ClusterOptions{Addrs: strings.Split(addrs, ",")})
+	if err := cc.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("Error connecting to cluster: %v", err)
+	}
+	return cc
+}
+
+// channelsPerShard returns one channel name for each master
+func channelsPerShard(t *testing.T, ctx context.Context, cc *redis.ClusterClient, prefix string) map[string]string {
+	masters := 0
+	cc.ForEachMaster(ctx, func(ctx context.Context, m *redis.Client) error {
+		masters++
+		return nil
+	})
+	channels := make(map[string]string) // master address -> channel
+	for i := 0; len(channels) < masters && i < 1000; i++ {
+		ch := fmt.Sprintf("%s:%d", prefix, i)
+		master, err := cc.MasterForKey(ctx, ch)
+		if err != nil {
+			t.Fatalf("Error locating %s: %v", ch, err)
+		}
+		if _, ok := channels[master.Options().Addr]; !ok {
+			channels[master.Options().Addr] = ch
+		}
+	}
+	return channels
+}
+
+// migrateSlot moves slot and its keys between two masters the way
+// redis-cli --cluster reshard does
+func migrateSlot(t *testing.T, ctx context.Context, cc *redis.ClusterClient, slot int64, from, to *redis.Client) {
+	fromID := from.Do(ctx, "cluster", "myid").Val()
+	toID := to.Do(ctx, "cluster", "myid").Val()
+	host, port, _ := net.SplitHostPort(to.Options().Addr)
+
+	steps := []struct {
+		node *redis.Client
+		args []interface{}
+	}{
+		{to, []interface{}{"cluster", "setslot", slot, "importing", fromID}},
+		{from, []interface{}{"cluster", "setslot", slot, "migrating", toID}},
+	}
+	for _, s := range steps {
+		if err := s.node.Do(ctx, s.args...).Err(); err != nil {
+			t.Fatalf("Error running %v: %v", s.args, err)
+		}
+	}
+	keys, err := from.ClusterGetKeysInSlot(ctx, int(slot), 1000).Result()
+	if err != nil {
+		t.Fatalf("Error listing keys in slot %d: %v", slot, err)
+	}
+	if len(keys) > 0 {
+		args := []interface{}{"migrate", host, port, "", 0, 5000, "keys"}
+		for _, k := range keys {
+			args = append(args, k)
+		}
+		if err := from.Do(ctx, args...).Err(); err != nil {
+			t.Fatalf("Error migrating keys: %v", err)
+		}
+	}
+	for _, node := range []*redis.Client{to, from} {
+		if err := node.Do(ctx, "cluster", "setslot", slot, "node", toID).Err(); err != nil {
+			t.Fatalf("Error assigning slot %d: %v", slot, err)
+		}
+	}
+	cc.ForEachMaster(ctx, func(ctx context.Context, m *redis.Client) error {
+		return m.Do(ctx, "cluster", "setslot", slot, "node", toID).Err()
+	})
+	cc.ReloadState(ctx)
+}
+
+// TestShardedPubSub tests SPUBLISH/SSUBSCRIBE with one subscriber connection per shard
+func TestShardedPubSub(t *testing.T) {
+	cc := clusterClient(t)
+	defer cc.Close()
+	ctx := context.Background()
+
+	byMaster := channelsPerShard(t, ctx, cc, "test:sharded")
+	var channels []string
+	for _, ch := range byMaster {
+		channels = append(channels, ch)
+	}
+	if len(channels) < 2 {
+		t.Fatalf("Expected a cluster with several masters, got %d", len(channels))
+	}
+	for _, ch := range channels {
+		cc.Del(ctx, "pubsub:{"+ch+"}:seq", "pubsub:{"+ch+"}:log")
+	}
+
+	received := make(chan pubsub.Message, 100)
+	sub := pubsub.NewSubscriber(cc, pubsub.Config{Channels: channels, Sharded: true}, func(m pubsub.Message) { received <- m })
+	if err := sub.Start(ctx); err != nil {
+		t.Fatalf("Error starting sharded subscriber: %v", err)
+	}
+	defer sub.Close()
+
+	pub := pubsub.NewPublisher(cc, pubsub.PublisherConfig{Sharded: true})
+	for _, ch := range channels {
+		for i := 0; i < 2; i++ {
+			if _, err := pub.Publish(ctx, ch, "hello"); err != nil {
+				t.Fatalf("Error publishing to %s: %v", ch, err)
+			}
+		}
+	}
+	counts := make(map[string]int)
+	for _, m := range receiveN(t, received, 2*len(channels)) {
+		counts[m.Channel]++
+		if m.Seq != int64(counts[m.Channel]) {
+			t.Errorf("Expected message %d on %s, got %+v", counts[m.Channel], m.Channel, m)
+		}
+	}
+
+	// Each master serves its own channel on exactly one connection
+	for addr, ch := range byMaster {
+		master, _ := cc.MasterForKey(ctx, ch)
+		subs, err := master.PubSubShardNumSub(ctx, ch).Result()
+		if err != nil {
+			t.Fatalf("Error reading SHARDNUMSUB on %s: %v", addr, err)
+		}
+		if subs[ch] != 1 {
+			t.Errorf("Expected 1 shard subscriber for %s on %s, got %d", ch, addr, subs[ch])
+		}
+		list, _ := master.ClientList(ctx).Result()
+		if n := strings.Count(list, "ssub=1"); n != 1 {
+			t.Errorf("Expected 1 shard subscriber connection on %s, got %d", addr, n)
+		}
+	}
+
+	// Cleanup
+	for _, ch := range channels {
+		cc.Del(ctx, "pubsub:{"+ch+"}:seq", "pubsub:{"+ch+"}:log")
+	}
+}
+
+// TestShardedPubSubSlots tests subscribing to channels of one master that hash to different slots
+func TestShardedPubSubSlots(t *testing.T) {
+	cc := clusterClient(t)
+	defer cc.Close()
+	ctx := context.Background()
+
+	// Two channels owned by the same master, in different slots
+	var channels []string
+	var owner string
+	for i := 0; len(channels) < 2 && i < 1000; i++ {
+		ch := fmt.Sprintf("test:slots:%d", i)
+		master, err := cc.MasterForKey(ctx, ch)
+		if err != nil {
+			t.Fatalf("Error locating %s: %v", ch, err)
+		}
+		switch addr := master.Options().Addr; {
+		case owner == "":
+			owner, channels = addr, []string{ch}
+		case addr == owner && cc.ClusterKeySlot(ctx, ch).Val() != cc.ClusterKeySlot(ctx, channels[0]).Val():
+			channels = append(channels, ch)
+		}
+	}
+	if len(channels) < 2 {
+		t.Fatalf("Expected two channels on %s in different slots, got %v", owner, channels)
+	}
+	for _, ch := range channels {
+		cc.Del(ctx, "pubsub:{"+ch+"}:seq", "pubsub:{"+ch+"}:log")
+	}
+
+	received := make(chan pubsub.Message, 10)
+	sub := pubsub.NewSubscriber(cc, pubsub.Config{Channels: channels, Sharded: true}, func(m pubsub.Message) { received <- m })
+	if err := sub.Start(ctx); err != nil {
+		t.Fatalf("Error starting sharded subscriber: %v", err)
+	}
+	defer sub.Close()
+
+	pub := pubsub.NewPublisher(cc, pubsub.PublisherConfig{Sharded: true})
+	for _, ch := range channels {
+		if _, err := pub.Publish(ctx, ch, "hello"); err != nil {
+			t.Fatalf("Error publishing to %s: %v", ch, err)
+		}
+	}
+	got := make(map[string]bool)
+	for _, m := range receiveN(t, received, len(channels)) {
+		got[m.Channel] = true
+	}
+	if len(got) != len(channels) {
+		t.Errorf("Expected a message on each of %v, got %v", channels, got)
+	}
+
+	// Both slots share the master's one connection
+	master, _ := cc.MasterForKey(ctx, channels[0])
+	if list, _ := master.ClientList(ctx).Result(); !strings.Contains(list, "ssub=2") {
+		t.Errorf("Expected one connection with 2 shard subscriptions on %s, got:\n%s", owner, list)
+	}
+
+	// Cleanup
+	for _, ch := range channels {
+		cc.Del(ctx, "pubsub:{"+ch+"}:seq", "pubsub:{"+ch+"}:log")
+	}
+}
+
+// TestShardedPubSubSlotMigration tests that a subscriber follows its channel to another shard without losing messages
+func TestShardedPubSubSlotMigration(t *testing.T) {
+	cc := clusterClient(t)
+	defer cc.Close()
+	ctx := context.Background()
+
+	byMaster := channelsPerShard(t, ctx, cc, "test:migrate")
+	var from, to *redis.Client
+	var channel string
+	for _, ch := range byMaster {
+		master, _ := cc.MasterForKey(ctx, ch)
+		if from == nil {
+			from, channel = master, ch
+		} else {
+			to = master
+		}
+	}
+	if to == nil {
+		t.Fatalf("Expected a cluster with several masters")
+	}
+	slot := cc.ClusterKeySlot(ctx, channel).Val()
+	cc.Del(ctx, "pubsub:{"+channel+"}:seq", "pubsub:{"+channel+"}:log")
+
+	received := make(chan pubsub.Message, 100)
+	sub := pubsub.NewSubscriber(cc, pubsub.Config{
+		Channels:   []string{channel},
+		Sharded:    true,
+		MaxBackoff: 200 * time.Millisecond,
+	}, func(m pubsub.Message) { received <- m })
+	if err := sub.Start(ctx); err != nil {
+		t.Fatalf("Error starting sharded subscriber: %v", err)
+	}
+	defer sub.Close()
+
+	pub := pubsub.NewPublisher(cc, pubsub.PublisherConfig{Sharded: true})
+	pub.Publish(ctx, channel, "before")
+	if m := receiveN(t, received, 1)[0]; m.Payload != "before" {
+		t.Fatalf("Expected the first message, got %+v", m)
+	}
+
+	// The old owner ends the subscription (SUNSUBSCRIBE) once the slot moves
+	migrateSlot(t, ctx, cc, slot, from, to)
+	defer migrateSlot(t, ctx, cc, slot, to, from)
+	for _, p := range []string{"during", "after"} {
+		if _, err := pub.Publish(ctx, channel, p); err != nil {
+			t.Fatalf("Error publishing after migration: %v", err)
+		}
+	}
+
+	msgs := receiveN(t, received, 2)
+	if msgs[0].Seq != 2 || msgs[1].Seq != 3 || msgs[1].Payload != "after" {
+		t.Errorf("Expected messages 2 and 3 after the migration, got %+v", msgs)
+	}
+	if sub.Reconnects() < 1 {
+		t.Errorf("Expected the subscriber to reconnect, got %d", sub.Reconnects())
+	}
+	if subs, _ := to.PubSubShardNumSub(ctx, channel).Result(); subs[channel] != 1 {
+		t.Errorf("Expected the subscription on the new owner, got %v", subs)
+	}
+
+	// Cleanup
+	cc.Del(ctx, "pubsub:{"+channel+"}:seq", "pubsub:{"+channel+"}:log")
+}
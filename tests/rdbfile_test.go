@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"Redis/dataset"
+	"Redis/rdbfile"
+)
+
+// rdbString encodes a length-prefixed RDB string
+func rdbString(s string) []byte {
+	return append(rdbLen(len(s)), s...)
+}
+
+// rdbLen encodes an RDB length
+func rdbLen(n int) []byte {
+	switch {
+	case n < 1<<6:
+		return []byte{byte(n)}
+	case n < 1<<14:
+		return []byte{0x40 | byte(n>>8), byte(n)}
+	}
+	b := []byte{0x80, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(n))
+	return b
+}
+
+// listpack encodes items the way Redis 7 does, using integer encodings where possible
+func listpack(items ...string) []byte {
+	var body []byte
+	for _, item := range items {
+		var entry []byte
+		if v, err := strconv.ParseInt(item, 10, 64); err == nil && strconv.FormatInt(v, 10) == item {
+			switch {
+			case v >= 0 && v <= 127:
+				entry = []byte{byte(v)}
+			case v >= -4096 && v < 4096:
+				u := uint16(v) & 0x1fff
+				entry = []byte{0xc0 | byte(u>>8), byte(u)}
+			default:
+				entry = []byte{0xf3, 0, 0, 0, 0}
+				binary.LittleEndian.PutUint32(entry[1:], uint32(int32(v)))
+			}
+		} else if len(item) < 64 {
+			entry = append([]byte{0x80 | byte(len(item))}, item...)
+		} else {
+			entry = append([]byte{0xe0 | byte(len(item)>>8), byte(len(item))}, item...)
+		}
+		body = append(body, entry...)
+		if len(entry) <= 127 {
+			body = append(body, byte(len(entry)))
+		} else {
+			body = append(body, byte(len(entry)>>7), byte(len(entry)&127)|128)
+		}
+	}
+
+	lp := make([]byte, 6, 6+len(body)+1)
+	lp = append(lp, body...)
+	lp = append(lp, 0xff)
+	binary.LittleEndian.PutUint32(lp[0:4], uint32(len(lp)))
+	binary.LittleEndian.PutUint16(lp[4:6], uint16(len(items)))
+	return lp
+}
+
+// streamID encodes a raw 128-bit stream ID
+func streamID(ms, seq uint64) []byte {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id[:8], ms)
+	binary.BigEndian.PutUint64(id[8:], seq)
+	return id
+}
+
+// le64 encodes a little-endian uint64
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+// buildRDB assembles a version 11 snapshot with one key of each Redis 7 encoding
+func buildRDB(checksum uint64) []byte {
+	var b bytes.Buffer
+	b.WriteString("REDIS0011")
+	b.Write([]byte{0xfa})
+	b.Write(rdbString("redis-ver"))
+	b.Write(rdbString("7.2.4"))
+	b.Write([]byte{0xfe, 0x00, 0xfb, 0x06, 0x01})
+
+	// String with a millisecond expiry
+	b.Write([]byte{0xfc})
+	b.Write(le64(4102444800000))
+	b.Write([]byte{0})
+	b.Write(rdbString("session:1"))
+	b.Write(rdbString("token"))
+
+	// Listpack hash
+	b.Write([]byte{16})
+	b.Write(rdbString("user:1"))
+	b.Write(rdbString(string(listpack("name", "Alice", "age", "30"))))
+
+	// Listpack sorted set with a negative integer score
+	b.Write([]byte{17})
+	b.Write(rdbString("scores"))
+	b.Write(rdbString(string(listpack("low", "-100", "high", "2.5"))))
+
+	// Quicklist v2 with a packed and a plain node
+	b.Write([]byte{18})
+	b.Write(rdbString("queue"))
+	b.Write(rdbLen(2))
+	b.Write(rdbLen(2))
+	b.Write(rdbString(string(listpack("a", "b"))))
+	b.Write(rdbLen(1))
+	b.Write(rdbString("big-plain-element"))
+
+	// Intset with 16-bit members
+	b.Write([]byte{11})
+	b.Write(rdbString("ids"))
+	intset := []byte{2, 0, 0, 0, 2, 0, 0, 0, 0xff, 0xff, 7, 0}
+	b.Write(rdbString(string(intset)))
+
+	// Listpack set
+	b.Write([]byte{20})
+	b.Write(rdbString("tags"))
+	b.Write(rdbString(string(listpack("go", "redis"))))
+
+	// Stream v3: one node holding a same-fields entry, a different-fields
+	// entry and a deleted entry, plus a consumer group with a pending entry
+	b.Write([]byte{21})
+	b.Write(rdbString("events"))
+	b.Write(rdbLen(1))
+	b.Write(rdbString(string(streamID(1000, 0))))
+	b.Write(rdbString(string(listpack(
+		"2", "1", "2", "a", "b", "0", // master: count, deleted, fields, terminator
+		"2", "0", "0", "1", "2", "5", // same fields as master
+		"0", "5", "0", "1", "c", "3", "6", // own fields
+		"3", "6", "0", "x", "y", "5", // deleted
+	))))
+	b.Write(rdbLen(2))                          // length
+	b.Write(rdbLen(1006))                       // last ID ms
+	b.Write(rdbLen(0))                          // last ID seq
+	b.Write(append(rdbLen(1000), rdbLen(0)...)) // first ID
+	b.Write(append(rdbLen(1006), rdbLen(0)...)) // max deleted ID
+	b.Write(rdbLen(3))                          // entries added
+	b.Write(rdbLen(1))                          // groups
+	b.Write(rdbString("workers"))               //
+	b.Write(append(rdbLen(1000), rdbLen(0)...)) // last delivered
+	b.Write(rdbLen(1))                          // entries read
+	b.Write(rdbLen(1))                          // PEL size
+	b.Write(streamID(1000, 0))                  //
+	b.Write(le64(1700000000000))                // delivery time
+	b.Write(rdbLen(1))                          // delivery count
+	b.Write(rdbLen(1))                          // consumers
+	b.Write(rdbString("worker-1"))              //
+	b.Write(le64(1700000000000))                // seen time
+	b.Write(le64(1700000000000))                // active time
+	b.Write(rdbLen(1))                          // consumer PEL
+	b.Write(streamID(1000, 0))                  //
+
+	b.Write([]byte{0xff})
+	b.Write(le64(checksum))
+	return b.Bytes()
+}
+
+// TestRDBParseRedis7Encodings tests decoding of Redis 7 compact encodings and streams
+func TestRDBParseRedis7Encodings(t *testing.T) {
+	entries := make(map[string]*rdbfile.Entry)
+	info, err := rdbfile.Parse(bytes.NewReader(buildRDB(0)), func(e *rdbfile.Entry) error {
+		entries[e.Key] = e
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error parsing RDB: %v", err)
+	}
+
+	if info.Version != 11 || info.Aux["redis-ver"] != "7.2.4" || info.Keys != 7 {
+		t.Errorf("Unexpected file info: %+v", info)
+	}
+
+	if e := entries["session:1"]; e.Value != "token" || e.ExpireAt != 4102444800000 {
+		t.Errorf("Expected string with expiry, got %+v", e)
+	}
+	if v := entries["user:1"].Value; !reflect.DeepEqual(v, map[string]string{"name": "Alice", "age": "30"}) {
+		t.Errorf("Unexpected hash value %v", v)
+	}
+	wantZSet := []dataset.ZMember{{Member: "low", Score: -100}, {Member: "high", Score: 2.5}}
+	if v := entries["scores"].Value; !reflect.DeepEqual(v, wantZSet) {
+		t.Errorf("Unexpected zset value %v", v)
+	}
+	if v := entries["queue"].Value; !reflect.DeepEqual(v, []string{"a", "b", "big-plain-element"}) {
+		t.Errorf("Unexpected list value %v", v)
+	}
+	if e := entries["ids"]; e.Encoding != "intset" || !reflect.DeepEqual(e.Value, []string{"-1", "7"}) {
+		t.Errorf("Unexpected intset %s %v", e.Encoding, e.Value)
+	}
+	if v := entries["tags"].Value; !reflect.DeepEqual(v, []string{"go", "redis"}) {
+		t.Errorf("Unexpected set value %v", v)
+	}
+
+	stream, ok := entries["events"].Value.(*dataset.Stream)
+	if !ok {
+		t.Fatalf("Expected stream value, got %T", entries["events"].Value)
+	}
+	wantEntries := []dataset.StreamEntry{
+		{ID: "1000-0", Fields: []string{"a", "1", "b", "2"}},
+		{ID: "1005-0", Fields: []string{"c", "3"}},
+	}
+	if !reflect.DeepEqual(stream.Entries, wantEntries) {
+		t.Errorf("Unexpected stream entries %+v", stream.Entries)
+	}
+	if stream.LastID != "1006-0" || len(stream.Groups) != 1 || stream.Groups[0].Name != "workers" {
+		t.Errorf("Unexpected stream metadata %+v", stream)
+	}
+}
+
+// TestRDBChecksumMismatch tests that a corrupted checksum is reported
+func TestRDBChecksumMismatch(t *testing.T) {
+	_, err := rdbfile.Parse(bytes.NewReader(buildRDB(0xdeadbeef)), func(*rdbfile.Entry) error { return nil })
+	if !errors.Is(err, rdbfile.ErrChecksum) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+}
+
+// TestRDBTruncated tests that a truncated file is an error rather than a short read
+func TestRDBTruncated(t *testing.T) {
+	data := buildRDB(0)
+	_, err := rdbfile.Parse(bytes.NewReader(data[:len(data)/2]), func(*rdbfile.Entry) error { return nil })
+	if err == nil {
+		t.Errorf("Expected error for truncated file")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"Redis/seed"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// seedSnapshot seeds a small dataset and returns a few representative values
+func seedSnapshot(t *testing.T, rdb *redis.Client, concurrency int) map[string]interface{} {
+	ctx := context.Background()
+
+	cfg := seed.DefaultConfig()
+	cfg.Scale = 0.05
+	cfg.Seed = 42
+	cfg.Concurrency = concurrency
+	cfg.BatchSize = 50
+
+	gen, err := seed.NewGenerator(rdb, cfg)
+	if err != nil {
+		t.Fatalf("Error creating generator: %v", err)
+	}
+	report, err := gen.Run(ctx)
+	if err != nil {
+		t.Fatalf("Error seeding data: %v", err)
+	}
+	if report.Commands() == 0 {
+		t.Fatalf("Expected commands to be sent, got 0")
+	}
+
+	user, err := rdb.HGetAll(ctx, "user:7").Result()
+	if err != nil {
+		t.Fatalf("Error reading user:7: %v", err)
+	}
+	top, err := rdb.ZRevRangeWithScores(ctx, "game_leaderboard", 0, 4).Result()
+	if err != nil {
+		t.Fatalf("Error reading leaderboard: %v", err)
+	}
+	events, err := rdb.XRange(ctx, "events", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("Error reading events: %v", err)
+	}
+	chat, err := rdb.LRange(ctx, "chat_history:general", 0, 9).Result()
+	if err != nil {
+		t.Fatalf("Error reading chat history: %v", err)
+	}
+
+	return map[string]interface{}{
+		"user":   user,
+		"top":    top,
+		"events": events,
+		"chat":   chat,
+	}
+}
+
+// TestSeedDeterministic tests that equal seeds produce equal data regardless of concurrency
+func TestSeedDeterministic(t *testing.T) {
+	// The generator flushes its database, so keep it away from DB 0
+	rdb := redis.NewClient(&redis.Options{
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        15,
+		TLSConfig: tlsconf.FromEnv(),
+	})
+	defer rdb.Close()
+
+	first := seedSnapshot(t, rdb, 1)
+	second := seedSnapshot(t, rdb, 8)
+
+	for name, want := range first {
+		if got := second[name]; !reflect.DeepEqual(want, got) {
+			t.Errorf("Expected %s to match between runs\nfirst:  %v\nsecond: %v", name, want, got)
+		}
+	}
+
+	rdb.FlushDB(context.Background())
+}
+
+// TestSeedRegenerate tests regenerating some datasets without flushing replaces them rather than appending
+func TestSeedRegenerate(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:      "localhost:6379",
+		Password:  "",
+		DB:        15,
+		TLSConfig: tlsconf.FromEnv(),
+	})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	first := seedSnapshot(t, rdb, 4)
+	chatLen := rdb.LLen(ctx, "chat_history:general").Val()
+
+	cfg := seed.DefaultConfig()
+	cfg.Scale = 0.05
+	cfg.Seed = 42
+	cfg.Datasets = []string{seed.DatasetEvents, seed.DatasetChat}
+	cfg.Flush = false
+	gen, err := seed.NewGenerator(rdb, cfg)
+	if err != nil {
+		t.Fatalf("Error creating generator: %v", err)
+	}
+	if _, err := gen.Run(ctx); err != nil {
+		t.Fatalf("Error regenerating datasets: %v", err)
+	}
+
+	if got := rdb.LLen(ctx, "chat_history:general").Val(); got != chatLen {
+		t.Errorf("Expected chat history of %d messages, got %d", chatLen, got)
+	}
+	events, _ := rdb.XRange(ctx, "events", "-", "+").Result()
+	if !reflect.DeepEqual(first["events"], events) {
+		t.Errorf("Expected the regenerated stream to match the first one")
+	}
+	if user, _ := rdb.HGetAll(ctx, "user:7").Result(); !reflect.DeepEqual(first["user"], user) {
+		t.Errorf("Expected datasets not selected to be kept")
+	}
+
+	// Cleanup
+	rdb.FlushDB(ctx)
+}
+
+// TestSeedConfigValidation tests rejection of invalid generator settings
+func TestSeedConfigValidation(t *testing.T) {
+	cfg := seed.DefaultConfig()
+	cfg.Scale = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected error for zero scale")
+	}
+
+	cfg = seed.DefaultConfig()
+	cfg.Datasets = []string{"unknown"}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("Expected error for unknown dataset")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Redis/capture"
+	"Redis/tlsconf"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// startTLSProxy terminates mutual TLS with the certificates in dir and
+// forwards connections to the Redis server the other tests use
+func startTLSProxy(t *testing.T, dir string) string {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, tlsconf.ServerCert), filepath.Join(dir, tlsconf.ServerKey))
+	if err != nil {
+		t.Fatalf("Error loading server certificate: %v", err)
+	}
+	client, err := tlsconf.Load(dir)
+	if err != nil {
+		t.Fatalf("Error loading CA: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    client.RootCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+				var backend net.Conn
+				if cfg := tlsconf.FromEnv(); cfg != nil {
+					backend, err = tls.Dial("tcp", "localhost:6379", cfg)
+				} else {
+					backend, err = net.Dial("tcp", "localhost:6379")
+				}
+				if err != nil {
+					return
+				}
+				defer backend.Close()
+				go io.Copy(backend, conn)
+				io.Copy(conn, backend)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// TestTLSCertificates tests generating a CA with server and client certificates and using them for mutual TLS
+func TestTLSCertificates(t *testing.T) {
+	dir := t.TempDir()
+	if err := tlsconf.Generate(dir, tlsconf.GenerateOptions{Hosts: []string{"redis.internal"}}); err != nil {
+		t.Fatalf("Error generating certificates: %v", err)
+	}
+	if err := tlsconf.Generate(dir, tlsconf.GenerateOptions{}); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected existing certificates to be kept, got %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, tlsconf.ClientKey)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected client key to be private, got %v: %v", info.Mode(), err)
+	}
+
+	cfg, err := tlsconf.Load(dir)
+	if err != nil {
+		t.Fatalf("Error loading certificates: %v", err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("Expected a client certificate, got %d", len(cfg.Certificates))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, tlsconf.ServerCert))
+	block, _ := pem.Decode(data)
+	server, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Error parsing server certificate: %v", err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "redis", "redis.internal"} {
+		opts := x509.VerifyOptions{DNSName: host, Roots: cfg.RootCAs}
+		if _, err := server.Verify(opts); err != nil {
+			t.Errorf("Expected server certificate to be valid for %s: %v", host, err)
+		}
+	}
+
+	// Mutual TLS in front of the test server
+	addr := startTLSProxy(t, dir)
+	ctx := context.Background()
+
+	rdb := redis.NewClient(&redis.Options{Addr: addr, TLSConfig: cfg})
+	defer rdb.Close()
+	if err := rdb.Set(ctx, "tls:test", "ok", 0).Err(); err != nil {
+		t.Fatalf("Error with client certificate: %v", err)
+	}
+	if v, err := rdb.Get(ctx, "tls:test").Result(); err != nil || v != "ok" {
+		t.Errorf("Expected ok over TLS, got %q: %v", v, err)
+	}
+
+	anonymous := cfg.Clone()
+	anonymous.Certificates = nil
+	noCert := redis.NewClient(&redis.Options{Addr: addr, TLSConfig: anonymous, MaxRetries: -1})
+	defer noCert.Close()
+	if err := noCert.Ping(ctx).Err(); err == nil {
+		t.Errorf("Expected a client without a certificate to be rejected")
+	}
+
+	plain := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1})
+	defer plain.Close()
+	if err := plain.Ping(ctx).Err(); err == nil {
+		t.Errorf("Expected a plaintext client to be rejected")
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "tls:test")
+}
+
+// TestTLSCaptureRecord tests that MONITOR captures dial over TLS when the client is configured for it
+func TestTLSCaptureRecord(t *testing.T) {
+	dir := t.TempDir()
+	if err := tlsconf.Generate(dir, tlsconf.GenerateOptions{}); err != nil {
+		t.Fatalf("Error generating certificates: %v", err)
+	}
+	cfg, err := tlsconf.Load(dir)
+	if err != nil {
+		t.Fatalf("Error loading certificates: %v", err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: startTLSProxy(t, dir), TLSConfig: cfg})
+	defer rdb.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan capture.Entry, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- capture.Record(ctx, rdb, func(e capture.Entry) error {
+			if e.Name() == "set" && len(e.Args) == 3 && e.Args[1] == "tls:capture" {
+				select {
+				case entries <- e:
+				default:
+				}
+			}
+			return nil
+		})
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		rdb.Set(ctx, "tls:capture", "value", 0)
+		select {
+		case err := <-done:
+			// The server answered, so the handshake worked
+			if err != nil && strings.Contains(err.Error(), "unknown command") {
+				t.Skipf("Server does not support MONITOR: %v", err)
+			}
+			t.Fatalf("Error recording over TLS: %v", err)
+		case <-entries:
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Expected a clean stop, got %v", err)
+			}
+			rdb.Del(context.Background(), "tls:capture")
+			return
+		case <-deadline:
+			t.Fatalf("No command captured within 5s")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// TestTLSServerRejectsClientWithoutCert tests a Redis server started with
+// scripts/docker-compose.tls.yml; it runs when REDIS_TLS_DIR is set
+func TestTLSServerRejectsClientWithoutCert(t *testing.T) {
+	cfg := tlsconf.FromEnv()
+	if cfg == nil {
+		t.Skipf("%s is not set; Redis is not running with TLS", tlsconf.EnvDir)
+	}
+	if os.Getenv("REDIS_TLS_AUTH_CLIENTS") == "optional" {
+		t.Skip("Server does not require client certificates")
+	}
+	ctx := context.Background()
+
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: cfg})
+	defer rdb.Close()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Fatalf("Error connecting with client certificate: %v", err)
+	}
+
+	anonymous := cfg.Clone()
+	anonymous.Certificates = nil
+	noCert := redis.NewClient(&redis.Options{Addr: "localhost:6379", TLSConfig: anonymous, MaxRetries: -1})
+	defer noCert.Close()
+	if err := noCert.Ping(ctx).Err(); err == nil {
+		t.Errorf("Expected a client without a certificate to be rejected")
+	}
+}
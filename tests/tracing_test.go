@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"Redis/tlsconf"
+	"Redis/tracing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// exportedSpan is the part of an OTLP/JSON span line the tests look at
+type exportedSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Attributes   map[string]string
+	StatusCode   int
+}
+
+// readSpans decodes the OTLP/JSON lines written by a WriterExporter
+func readSpans(t *testing.T, data []byte) []exportedSpan {
+	var spans []exportedSpan
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID, SpanID, ParentSpanID, Name string
+						Attributes                          []struct {
+							Key   string
+							Value map[string]interface{}
+						}
+						Status struct{ Code int }
+					}
+				}
+			}
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			t.Fatalf("Error decoding span %s: %v", line, err)
+		}
+		s := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+		span := exportedSpan{TraceID: s.TraceID, SpanID: s.SpanID, ParentSpanID: s.ParentSpanID, Name: s.Name, StatusCode: s.Status.Code, Attributes: map[string]string{}}
+		for _, a := range s.Attributes {
+			for _, v := range a.Value {
+				span.Attributes[a.Key] = fmt.Sprint(v)
+			}
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// TestTracingHookSpans tests command and pipeline spans, sanitizing and status
+func TestTracingHookSpans(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+	rdb.Ping(ctx) // Dial before tracing starts
+
+	var out bytes.Buffer
+	tracer := tracing.NewTracer("test", tracing.NewWriterExporter(&out))
+	tracing.Instrument(rdb, tracer)
+
+	parentCtx, parent := tracer.Start(ctx, "checkout", tracing.KindInternal)
+	rdb.Set(parentCtx, "trace:string", "secret-value", 0)
+	rdb.Get(parentCtx, "trace:missing")
+	rdb.HGet(parentCtx, "trace:string", "field")
+	rdb.Pipelined(parentCtx, func(pipe redis.Pipeliner) error {
+		pipe.MSet(parentCtx, "trace:a", "1", "trace:b", "2")
+		pipe.Incr(parentCtx, "trace:a")
+		return nil
+	})
+	parent.End()
+
+	spans := readSpans(t, out.Bytes())
+	if len(spans) != 5 {
+		t.Fatalf("Expected 5 spans, got %d", len(spans))
+	}
+	root := spans[4]
+	for _, s := range spans[:4] {
+		if s.TraceID != root.TraceID || s.ParentSpanID != root.SpanID {
+			t.Errorf("Expected %s to be a child of checkout", s.Name)
+		}
+	}
+
+	set, get, hget, pipe := spans[0], spans[1], spans[2], spans[3]
+	if set.Attributes["db.statement"] != "SET trace:string ?" || strings.Contains(out.String(), "secret-value") {
+		t.Errorf("Expected the value to be sanitized, got %q", set.Attributes["db.statement"])
+	}
+	if get.StatusCode != tracing.StatusUnset || hget.StatusCode != tracing.StatusError {
+		t.Errorf("Expected only WRONGTYPE to be an error, got GET=%d HGET=%d", get.StatusCode, hget.StatusCode)
+	}
+	if pipe.Name != "PIPELINE" || pipe.Attributes["db.operation.batch.size"] != "2" || pipe.Attributes["db.redis.key_count"] != "3" {
+		t.Errorf("Unexpected pipeline span %+v", pipe)
+	}
+	if pipe.Attributes["db.statement"] != "MSET trace:a ? trace:b ?\nINCR trace:a" {
+		t.Errorf("Unexpected pipeline statement %q", pipe.Attributes["db.statement"])
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "trace:string", "trace:a", "trace:b")
+}
+
+// TestTracingPropagation tests trace context carried in stream and queue messages
+func TestTracingPropagation(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "", DB: 0, TLSConfig: tlsconf.FromEnv()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	tracer := tracing.NewTracer("test", tracing.NewWriterExporter(&out))
+	produceCtx, produce := tracer.Start(ctx, "produce", tracing.KindProducer)
+	produce.End()
+	want := produce.SpanContext()
+
+	err := rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: "trace:stream",
+		Values: tracing.StreamValues(produceCtx, "order", "42"),
+	}).Err()
+	if err != nil {
+		t.Fatalf("Error adding entry: %v", err)
+	}
+	entries, err := rdb.XRange(ctx, "trace:stream", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Error reading entry: %v", err)
+	}
+	if got := tracing.SpanContextFromContext(tracing.ExtractStream(ctx, entries[0].Values)); got != want {
+		t.Errorf("Stream entry carried %v, expected %v", got, want)
+	}
+
+	rdb.LPush(ctx, "trace:queue", tracing.WrapMessage(produceCtx, "job-1"))
+	msg, err := rdb.RPop(ctx, "trace:queue").Result()
+	if err != nil {
+		t.Fatalf("Error popping job: %v", err)
+	}
+	jobCtx, payload := tracing.UnwrapMessage(ctx, msg)
+	_, consume := tracer.Start(jobCtx, "consume", tracing.KindConsumer)
+	consume.End()
+	if payload != "job-1" || consume.SpanContext().TraceID != want.TraceID {
+		t.Errorf("Expected job-1 in trace %s, got %q in %s", want.TraceID, payload, consume.SpanContext().TraceID)
+	}
+	if _, plain := tracing.UnwrapMessage(ctx, "plain"); plain != "plain" {
+		t.Errorf("Expected unwrapped messages to pass through, got %q", plain)
+	}
+
+	// Cleanup
+	rdb.Del(ctx, "trace:stream", "trace:queue")
+}
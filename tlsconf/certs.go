@@ -0,0 +1,165 @@
+// Package tlsconf generates a local certificate authority with server and
+// client certificates for running Redis over TLS, and builds the client
+// side tls.Config the examples and tests use when TLS is enabled.
+package tlsconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File names written by Generate and read by Load
+const (
+	CACert     = "ca.crt"
+	CAKey      = "ca.key"
+	ServerCert = "server.crt"
+	ServerKey  = "server.key"
+	ClientCert = "client.crt"
+	ClientKey  = "client.key"
+)
+
+// DefaultHosts are the names the server certificate is valid for: the host
+// running the examples, and the compose service name for containers
+var DefaultHosts = []string{"localhost", "127.0.0.1", "::1", "redis"}
+
+// GenerateOptions controls Generate
+type GenerateOptions struct {
+	Hosts    []string      // Extra DNS names or IPs for the server certificate
+	Validity time.Duration // Lifetime of the leaf certificates; default one year
+	Force    bool          // Overwrite existing files
+}
+
+// Generate writes a new CA and a server and client certificate signed by it
+// to dir. The CA lives ten years; regenerating leaves clients that trusted
+// the old CA unable to connect, so existing files are kept unless Force is
+// set and an error wrapping fs.ErrExist is returned.
+func Generate(dir string, opts GenerateOptions) error {
+	if !opts.Force {
+		for _, name := range []string{CACert, CAKey, ServerCert, ServerKey, ClientCert, ClientKey} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return fmt.Errorf("%s: %w", filepath.Join(dir, name), fs.ErrExist)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	if opts.Validity <= 0 {
+		opts.Validity = 365 * 24 * time.Hour
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	now := time.Now().Add(-time.Hour) // Tolerate clock skew between host and container
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	ca := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Redis Practice CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := sign(ca, ca, caKey, caKey)
+	if err != nil {
+		return err
+	}
+	// Re-parse so the leaves carry the CA's subject key id as their issuer
+	if ca, err = x509.ParseCertificate(caDER); err != nil {
+		return err
+	}
+
+	server := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "redis"},
+		NotBefore:   now,
+		NotAfter:    now.Add(opts.Validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range append(append([]string{}, DefaultHosts...), opts.Hosts...) {
+		if ip := net.ParseIP(h); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else {
+			server.DNSNames = append(server.DNSNames, h)
+		}
+	}
+	client := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "redis-client"},
+		NotBefore:   now,
+		NotAfter:    now.Add(opts.Validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if err := writeCert(dir, CACert, caDER); err != nil {
+		return err
+	}
+	if err := writeKey(dir, CAKey, caKey, 0o600); err != nil {
+		return err
+	}
+	// The server key is read by the redis user inside the container, which
+	// does not own the mounted file, so it cannot be private to the owner
+	if err := issue(dir, ServerCert, ServerKey, 0o644, server, ca, caKey); err != nil {
+		return err
+	}
+	return issue(dir, ClientCert, ClientKey, 0o600, client, ca, caKey)
+}
+
+// issue creates a key pair for template, signs it with the CA and writes both
+func issue(dir, certName, keyName string, keyMode os.FileMode, template, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := sign(template, ca, key, caKey)
+	if err != nil {
+		return err
+	}
+	if err := writeCert(dir, certName, der); err != nil {
+		return err
+	}
+	return writeKey(dir, keyName, key, keyMode)
+}
+
+func sign(template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+	return x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+}
+
+func writeCert(dir, name string, der []byte) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
+
+func writeKey(dir, name string, key *ecdsa.PrivateKey, mode os.FileMode) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file and is subject to umask
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,69 @@
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// EnvDir is the environment variable that switches examples and tests to
+// TLS. It names a directory written by Generate.
+const EnvDir = "REDIS_TLS_DIR"
+
+// Load builds a client configuration from a directory written by Generate.
+// The server is verified against ca.crt; client.crt and client.key are
+// presented when present, which servers with tls-auth-clients yes require.
+func Load(dir string) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(filepath.Join(dir, CACert))
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s: no certificates found", filepath.Join(dir, CACert))
+	}
+	cfg := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	certFile, keyFile := filepath.Join(dir, ClientCert), filepath.Join(dir, ClientKey)
+	if _, err := os.Stat(certFile); errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+	return cfg, nil
+}
+
+var fromEnv struct {
+	sync.Mutex
+	dir string
+	cfg *tls.Config
+}
+
+// FromEnv returns the client configuration for the directory in
+// REDIS_TLS_DIR, or nil when it is unset so clients stay on plaintext. It is
+// meant for redis.Options literals in examples and tests, and panics when
+// the certificates cannot be loaded rather than silently falling back.
+func FromEnv() *tls.Config {
+	dir := os.Getenv(EnvDir)
+	if dir == "" {
+		return nil
+	}
+	fromEnv.Lock()
+	defer fromEnv.Unlock()
+	if fromEnv.dir != dir {
+		cfg, err := Load(dir)
+		if err != nil {
+			panic(fmt.Sprintf("tlsconf: %s=%s: %v", EnvDir, dir, err))
+		}
+		fromEnv.dir, fromEnv.cfg = dir, cfg
+	}
+	return fromEnv.cfg.Clone()
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Hook starts a client span for every command and pipeline sent through a
+// go-redis client, as a child of the span in the ctx passed to go-redis
+type Hook struct {
+	tracer *Tracer
+	attrs  []attribute // Server and database attributes set on every span
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// NewHook returns a hook reporting to t. opt, when not nil, supplies the
+// server address and database number recorded on each span.
+func NewHook(t *Tracer, opt *redis.Options) *Hook {
+	h := &Hook{tracer: t, attrs: []attribute{{"db.system", "redis"}}}
+	if opt != nil {
+		if host, port, err := net.SplitHostPort(opt.Addr); err == nil {
+			h.attrs = append(h.attrs, attribute{"server.address", host})
+			if p, err := strconv.Atoi(port); err == nil {
+				h.attrs = append(h.attrs, attribute{"server.port", int64(p)})
+			}
+		}
+		h.attrs = append(h.attrs, attribute{"db.redis.database_index", int64(opt.DB)})
+	}
+	return h
+}
+
+// Instrument adds a tracing hook for t to rdb
+func Instrument(rdb *redis.Client, t *Tracer) *Hook {
+	h := NewHook(t, rdb.Options())
+	rdb.AddHook(h)
+	return h
+}
+
+func (h *Hook) start(ctx context.Context, name string) (context.Context, *Span) {
+	ctx, span := h.tracer.Start(ctx, name, KindClient)
+	for _, a := range h.attrs {
+		span.SetAttr(a.key, a.value)
+	}
+	return ctx, span
+}
+
+// DialHook traces new connections
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.start(ctx, "redis.dial")
+		defer span.End()
+		conn, err := next(ctx, network, addr)
+		span.SetError(err)
+		return conn, err
+	}
+}
+
+// ProcessHook traces a single command. A nil reply is not an error.
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		statement, keys := Sanitize(cmd.Args())
+		ctx, span := h.start(ctx, strings.ToUpper(cmd.FullName()))
+		defer span.End()
+		span.SetAttr("db.operation", strings.ToUpper(cmd.Name()))
+		span.SetAttr("db.statement", statement)
+		span.SetAttr("db.redis.key_count", keys)
+
+		err := next(ctx, cmd)
+		if err != redis.Nil {
+			span.SetError(err)
+		}
+		return err
+	}
+}
+
+// maxPipelineStatements caps the statements recorded for one pipeline
+const maxPipelineStatements = 20
+
+// ProcessPipelineHook traces a pipeline, or a transaction when it is wrapped
+// in MULTI/EXEC, as one span. Its status is the first command error.
+func (h *Hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		name, inner := "PIPELINE", cmds
+		if len(cmds) >= 2 && cmds[0].Name() == "multi" && cmds[len(cmds)-1].Name() == "exec" {
+			name, inner = "MULTI", cmds[1:len(cmds)-1]
+		}
+
+		statements := make([]string, 0, min(len(inner), maxPipelineStatements+1))
+		keys := 0
+		for i, cmd := range inner {
+			statement, n := Sanitize(cmd.Args())
+			keys += n
+			if i < maxPipelineStatements {
+				statements = append(statements, statement)
+			}
+		}
+		if len(inner) > maxPipelineStatements {
+			statements = append(statements, fmt.Sprintf("... %d more", len(inner)-maxPipelineStatements))
+		}
+
+		ctx, span := h.start(ctx, name)
+		defer span.End()
+		span.SetAttr("db.operation", name)
+		span.SetAttr("db.operation.batch.size", len(inner))
+		span.SetAttr("db.statement", strings.Join(statements, "\n"))
+		span.SetAttr("db.redis.key_count", keys)
+
+		err := next(ctx, cmds)
+		if err != nil && err != redis.Nil {
+			span.SetError(err)
+			return err
+		}
+		for _, cmd := range inner {
+			if cerr := cmd.Err(); cerr != nil && cerr != redis.Nil {
+				span.SetError(cerr)
+				break
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TraceparentField is the message field that carries trace context
+const TraceparentField = "traceparent"
+
+// StreamValues appends the traceparent of ctx to XADD field/value pairs, so
+// consumers can continue the producer's trace. Without an active span the
+// values are returned unchanged.
+func StreamValues(ctx context.Context, values ...interface{}) []interface{} {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return values
+	}
+	return append(values, TraceparentField, sc.Traceparent())
+}
+
+// ExtractStream returns ctx carrying the trace context found in the fields
+// of a stream entry, such as redis.XMessage.Values
+func ExtractStream(ctx context.Context, values map[string]interface{}) context.Context {
+	tp, _ := values[TraceparentField].(string)
+	return extract(ctx, tp)
+}
+
+// envelope wraps list queue messages, which are plain strings
+type envelope struct {
+	Traceparent string `json:"traceparent"`
+	Payload     string `json:"payload"`
+}
+
+// WrapMessage returns payload wrapped with the traceparent of ctx, for queues
+// built on lists. Without an active span the payload is returned as is.
+func WrapMessage(ctx context.Context, payload string) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return payload
+	}
+	data, _ := json.Marshal(envelope{Traceparent: sc.Traceparent(), Payload: payload})
+	return string(data)
+}
+
+// UnwrapMessage reverses WrapMessage, returning ctx carrying the message's
+// trace context. Messages that were not wrapped are returned unchanged.
+func UnwrapMessage(ctx context.Context, msg string) (context.Context, string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(msg), &env); err != nil || env.Traceparent == "" {
+		return ctx, msg
+	}
+	return extract(ctx, env.Traceparent), env.Payload
+}
+
+func extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"Redis/keyspec"
+)
+
+// maxStatementArgs caps the arguments recorded in db.statement
+const maxStatementArgs = 32
+
+// Sanitize formats a command for db.statement with every argument that is
+// not a key or a subcommand replaced by "?", so values never reach the
+// trace, and returns the number of keys it addresses
+func Sanitize(args []interface{}) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	strs := make([]string, len(args))
+	for i, a := range args {
+		strs[i] = fmt.Sprint(a)
+	}
+	name := strings.ToLower(strs[0])
+
+	keep := make(map[int]bool)
+	keys := keyIndexes(strs)
+	for _, i := range keys {
+		keep[i] = true
+	}
+	first := 1
+	if containers[name] && len(strs) > 1 {
+		first = 2
+	}
+
+	parts := []string{strings.ToUpper(strs[0])}
+	if first == 2 {
+		parts = append(parts, strings.ToUpper(strs[1]))
+	}
+	for i := first; i < len(strs) && i <= maxStatementArgs; i++ {
+		if keep[i] {
+			parts = append(parts, strs[i])
+		} else {
+			parts = append(parts, "?")
+		}
+	}
+	if len(strs) > maxStatementArgs+1 {
+		parts = append(parts, fmt.Sprintf("... %d more", len(strs)-maxStatementArgs-1))
+	}
+	return strings.Join(parts, " "), len(keys)
+}
+
+// containers are commands whose first argument is a subcommand, kept in
+// the statement so that CONFIG SET and CONFIG GET read differently. The key
+// positions come from keyspec; this is only about formatting.
+var containers = map[string]bool{
+	"acl": true, "client": true, "cluster": true, "command": true, "config": true,
+	"function": true, "latency": true, "memory": true, "module": true, "object": true,
+	"pubsub": true, "script": true, "slowlog": true, "xgroup": true, "xinfo": true,
+}
+
+// keyIndexes returns the positions of the keys in args, from the built-in
+// command table rather than the server's so that tracing never waits on a
+// round trip. Commands it does not list are assumed to have one key at
+// position 1.
+func keyIndexes(args []string) []int {
+	idx, err := keyspec.Static().Keys(args)
+	if errors.Is(err, keyspec.ErrUnknownCommand) && len(args) > 1 {
+		return []int{1}
+	}
+	return idx
+}
+
+// Keys returns the keys args addresses, by the same rules as Sanitize
+func Keys(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	idx := keyIndexes(args)
+	keys := make([]string, len(idx))
+	for i, j := range idx {
+		keys[i] = args[j]
+	}
+	return keys
+}
@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter receives finished spans
+type Exporter interface {
+	ExportSpan(s *Span) error
+}
+
+// Tracer starts spans and hands them to its exporter when they end
+type Tracer struct {
+	service  string
+	exporter Exporter
+
+	mu  sync.Mutex
+	err error // First export error
+}
+
+// NewTracer returns a tracer that reports spans under the given service name
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Err returns the first error the exporter returned, if any
+func (t *Tracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Start begins a span as a child of the span (local or remote) in ctx, or as
+// the root of a new trace, and returns ctx carrying it
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		s.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+		s.parent = parent.SpanID
+	} else {
+		s.sc = SpanContext{TraceID: newTraceID(), Sampled: true}
+	}
+	s.sc.SpanID = newSpanID()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (t *Tracer) export(s *Span) {
+	if !s.sc.Sampled {
+		return
+	}
+	if err := t.exporter.ExportSpan(s); err != nil {
+		t.mu.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mu.Unlock()
+	}
+}
+
+// WriterExporter writes each span as one line of OTLP/JSON, an
+// ExportTraceServiceRequest holding a single span
+type WriterExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// NewWriterExporter writes spans to w
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// NewFileExporter appends spans to the file at path, or writes them to
+// stdout when path is "-"
+func NewFileExporter(path string) (*WriterExporter, error) {
+	if path == "-" {
+		return NewWriterExporter(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterExporter{w: f, c: f}, nil
+}
+
+// Close closes the underlying file, if the exporter opened one
+func (e *WriterExporter) Close() error {
+	if e.c == nil {
+		return nil
+	}
+	return e.c.Close()
+}
+
+// ExportSpan writes s
+func (e *WriterExporter) ExportSpan(s *Span) error {
+	data, err := json.Marshal(s.otlp())
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+// OTLP/JSON shapes, see opentelemetry-proto's trace.proto
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func otlpValue(v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case int64:
+		// OTLP/JSON encodes 64-bit integers as strings
+		return map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	default:
+		return map[string]interface{}{"stringValue": v}
+	}
+}
+
+func (s *Span) otlp() otlpRequest {
+	s.mu.Lock()
+	span := otlpSpan{
+		TraceID:           s.sc.TraceID.String(),
+		SpanID:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: s.status, Message: s.statusMsg},
+	}
+	for _, a := range s.attrs {
+		span.Attributes = append(span.Attributes, otlpKeyValue{a.key, otlpValue(a.value)})
+	}
+	s.mu.Unlock()
+	if s.parent.IsValid() {
+		span.ParentSpanID = s.parent.String()
+	}
+
+	scope := otlpScopeSpans{Spans: []otlpSpan{span}}
+	scope.Scope.Name = "Redis/tracing"
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{"service.name", otlpValue(s.tracer.service)},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}}
+}